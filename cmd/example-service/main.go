@@ -3,11 +3,13 @@ package main
 import (
 	"bytes"
 	"context"
+	"embed"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"os/signal"
+	"regexp"
 	"syscall"
 	"time"
 
@@ -15,18 +17,36 @@ import (
 	"github.com/yourorg/go-service-kit/pkg/blobclient"
 	"github.com/yourorg/go-service-kit/pkg/config"
 	"github.com/yourorg/go-service-kit/pkg/csvutil"
+	"github.com/yourorg/go-service-kit/pkg/db"
 	"github.com/yourorg/go-service-kit/pkg/errors"
 	"github.com/yourorg/go-service-kit/pkg/httpservice"
+	"github.com/yourorg/go-service-kit/pkg/jwt"
 	"github.com/yourorg/go-service-kit/pkg/logging"
 	"github.com/yourorg/go-service-kit/pkg/pdfutil"
+	"github.com/yourorg/go-service-kit/pkg/ratelimit"
 	"github.com/yourorg/go-service-kit/pkg/servicebusclient"
+	"github.com/yourorg/go-service-kit/pkg/utils"
 )
 
+// storeMigrations create the tables of the Postgres-backed stores used below. They are
+// applied at startup; the migrator's advisory lock lets replicas start concurrently.
+var storeMigrations = []struct {
+	fsys  embed.FS
+	table string
+}{
+	{blobclient.DedupMigrations, blobclient.DedupMigrationsTable},
+	{httpservice.IdempotencyMigrations, httpservice.IdempotencyMigrationsTable},
+	{ratelimit.Migrations, ratelimit.MigrationsTable},
+}
+
 type App struct {
 	config          *config.Config
 	logger          logging.Logger
 	blobClient      blobclient.BlobClient
+	csvStore        *blobclient.DedupStore
 	serviceBusClient servicebusclient.ServiceBusClient
+	database        db.DB
+	idempotency     gin.HandlerFunc
 	server          *httpservice.Server
 }
 
@@ -48,22 +68,18 @@ func main() {
 	
 	logger.Info("Starting example service", logging.NewField("version", cfg.AppVersion))
 	
-	// Create blob client (use mock for local development)
-	var blobClient blobclient.BlobClient
-	if cfg.BlobStorageAccountName == "" {
-		logger.Info("Using mock blob client (no account name configured)")
-		blobClient = blobclient.NewMockBlobClient()
-	} else {
-		blobClient, err = blobclient.NewAzureBlobClient(
-			cfg.BlobStorageAccountName,
-			cfg.BlobStorageAccountKey,
-			false,
-			logger,
-		)
-		if err != nil {
-			logger.Error("Failed to create blob client", logging.NewField("error", err))
-			os.Exit(1)
-		}
+	// Create blob client (BLOB_PROVIDER selects azure, filesystem or mock; mock when nothing is configured)
+	storageClient, err := blobclient.NewFromConfig(cfg, logger)
+	if err != nil {
+		logger.Error("Failed to create blob client", logging.NewField("error", err))
+		os.Exit(1)
+	}
+	
+	// Bound blob calls with deadlines, retry idempotent ones (RETRY_*) and fail fast while storage is down
+	blobClient, err := blobclient.NewResilientBlobClient(storageClient, blobclient.ResilienceConfigFromConfig(cfg), logger)
+	if err != nil {
+		logger.Error("Failed to create resilient blob client", logging.NewField("error", err))
+		os.Exit(1)
 	}
 	
 	// Create Service Bus client (use mock for local development)
@@ -85,12 +101,178 @@ func main() {
 		}
 	}
 	
+	// Background work started below is stopped on shutdown
+	bgCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+	
+	// Apply blob retention rules (BLOB_LIFECYCLE_RULES), e.g. to delete temporary uploads
+	if len(cfg.BlobLifecycleRules) > 0 {
+		sweeper, err := blobclient.NewLifecycleSweeper(blobClient, blobclient.LifecycleConfigFromConfig(cfg), logger)
+		if err != nil {
+			logger.Error("Failed to create blob lifecycle sweeper", logging.NewField("error", err))
+			os.Exit(1)
+		}
+		sweeper.Start(bgCtx)
+	}
+	
+	// Connect to the database (optional)
+	healthChecks := make(map[string]httpservice.HealthCheckFunc)
+	
+	// Report the blob storage circuit breaker on /health and in the logs
+	healthChecks["blob_storage"] = blobClient.Breaker().HealthCheck
+	utils.StartResilienceReporter(bgCtx, time.Minute, logger, nil, []*utils.CircuitBreaker{blobClient.Breaker()}, nil)
+	var database *db.PostgresDB
+	if cfg.DatabaseURL != "" {
+		pgCfg := db.PostgresConfigFromConfig(cfg)
+		database, err = db.NewPostgresDBWithConfig(bgCtx, pgCfg, logger)
+		if err != nil {
+			logger.Error("Failed to connect to database", logging.NewField("error", err))
+			os.Exit(1)
+		}
+		
+		for _, store := range storeMigrations {
+			migrator, err := db.NewMigrator(database, store.fsys, db.MigratorConfig{Dir: "migrations", Table: store.table}, logger)
+			if err == nil {
+				_, err = migrator.Up(bgCtx)
+			}
+			if err != nil {
+				logger.Error("Failed to apply migrations", logging.NewField("table", store.table), logging.NewField("error", err))
+				os.Exit(1)
+			}
+		}
+		
+		db.StartStatsReporter(bgCtx, database, pgCfg.StatsInterval, logger, nil)
+		healthChecks["database"] = database.Ping
+	} else {
+		logger.Info("No database configured")
+	}
+	
+	// Route reads to replicas when configured
+	var appDB db.DB
+	if database != nil {
+		appDB = database
+	}
+	if database != nil && len(cfg.DatabaseReplicaURLs) > 0 {
+		var replicas []db.DB
+		for _, replicaURL := range cfg.DatabaseReplicaURLs {
+			replicaCfg := db.PostgresConfigFromConfig(cfg)
+			replicaCfg.ConnectionString = replicaURL
+			replica, err := db.NewPostgresDBWithConfig(bgCtx, replicaCfg, logger)
+			if err != nil {
+				logger.Error("Failed to connect to read replica", logging.NewField("error", err))
+				os.Exit(1)
+			}
+			replicas = append(replicas, replica)
+		}
+		
+		replicated, err := db.NewReplicatedDB(database, replicas, db.ReplicaConfig{}, logger)
+		if err != nil {
+			logger.Error("Failed to create replicated database", logging.NewField("error", err))
+			os.Exit(1)
+		}
+		replicated.Start(bgCtx)
+		appDB = replicated
+	}
+	if appDB != nil {
+		instrumented, err := db.NewInstrumentedDB(appDB, db.InstrumentationConfig{
+			SlowQueryThreshold: time.Duration(cfg.DBSlowQueryThreshold) * time.Millisecond,
+			AnnotateQueries:    true,
+		}, logger)
+		if err != nil {
+			logger.Error("Failed to instrument database", logging.NewField("error", err))
+			os.Exit(1)
+		}
+		appDB = instrumented
+		defer appDB.Close()
+	}
+	
+	// Archived CSV uploads are stored content-addressed, so repeated uploads are kept once.
+	// Names are indexed in Postgres when configured, else in blob metadata.
+	var csvIndex blobclient.DedupIndex = blobclient.NewMetadataDedupIndex(blobClient, cfg.BlobArchiveContainer)
+	if appDB != nil {
+		pgIndex, err := blobclient.NewPostgresDedupIndex(appDB, "")
+		if err != nil {
+			logger.Error("Failed to create CSV dedup index", logging.NewField("error", err))
+			os.Exit(1)
+		}
+		csvIndex = pgIndex
+	}
+	csvStore, err := blobclient.NewDedupStore(blobClient, cfg.BlobArchiveContainer, csvIndex, logger)
+	if err != nil {
+		logger.Error("Failed to create CSV store", logging.NewField("error", err))
+		os.Exit(1)
+	}
+	csvStore.StartGarbageCollector(bgCtx, time.Hour)
+	
+	// Retried uploads and conversions with an Idempotency-Key get the first response,
+	// shared by all replicas when a database is configured
+	var idempotencyStore httpservice.IdempotencyStore = httpservice.NewIdempotencyMemoryStore()
+	if appDB != nil {
+		pgStore, err := httpservice.NewIdempotencyPostgresStore(appDB, "")
+		if err != nil {
+			logger.Error("Failed to create idempotency store", logging.NewField("error", err))
+			os.Exit(1)
+		}
+		utils.StartCleanup(bgCtx, "idempotency", pgStore, time.Hour, logger)
+		idempotencyStore = pgStore
+	}
+	
 	// Create app
 	app := &App{
 		config:           cfg,
 		logger:           logger,
 		blobClient:       blobClient,
+		csvStore:         csvStore,
 		serviceBusClient: serviceBusClient,
+		database:         appDB,
+		idempotency:      httpservice.IdempotencyMiddleware(httpservice.IdempotencyConfig{
+			Store:  idempotencyStore,
+			Logger: logger,
+		}),
+	}
+	
+	// Rate limits by route, method and role (RATE_LIMIT_POLICIES), shared by all replicas
+	// when RATE_LIMIT_STORE=postgres
+	rateLimit, err := httpservice.RateLimitPolicyConfigFromConfig(cfg)
+	if err != nil {
+		logger.Error("Invalid rate limit configuration", logging.NewField("error", err))
+		os.Exit(1)
+	}
+	if cfg.RateLimitStore == "postgres" {
+		if appDB == nil {
+			logger.Error("RATE_LIMIT_STORE=postgres requires DATABASE_URL")
+			os.Exit(1)
+		}
+		store, err := ratelimit.NewPostgresStore(appDB, "")
+		if err != nil {
+			logger.Error("Failed to create rate limit store", logging.NewField("error", err))
+			os.Exit(1)
+		}
+		rateLimit.Store = store
+	}
+	// Roles and exempt roles are read from the bearer token, as the rate limits run
+	// before any JWT middleware
+	if cfg.JWTSecretKey != "" {
+		tokens, err := jwt.NewJWTServiceFromConfig(jwt.Config{
+			SecretKey:             cfg.JWTSecretKey,
+			AccessTokenExpiryMins: cfg.JWTAccessTokenExpiry,
+			RefreshTokenExpiryHrs: cfg.JWTRefreshTokenExpiry,
+		}, logger)
+		if err != nil {
+			logger.Error("Failed to create JWT service", logging.NewField("error", err))
+			os.Exit(1)
+		}
+		rateLimit.Tokens = tokens
+	} else {
+		for _, policy := range rateLimit.Policies {
+			if len(policy.Roles) > 0 {
+				logger.Error("Rate limit policies with roles require JWT_SECRET_KEY", logging.NewField("policy", policy.Name))
+				os.Exit(1)
+			}
+		}
+		if len(rateLimit.ExemptRoles) > 0 {
+			logger.Warn("JWT_SECRET_KEY not set, rate limit exempt roles have no effect")
+		}
 	}
 	
 	// Create HTTP server
@@ -100,6 +282,8 @@ func main() {
 		WriteTimeout: time.Duration(cfg.HTTPWriteTimeout) * time.Second,
 		IdleTimeout: time.Duration(cfg.HTTPIdleTimeout) * time.Second,
 		Logger:       logger,
+		RateLimit:    rateLimit,
+		HealthChecks: healthChecks,
 	}, app)
 	if err != nil {
 		logger.Error("Failed to create server", logging.NewField("error", err))
@@ -136,8 +320,9 @@ func main() {
 func (a *App) Register(router *gin.Engine) {
 	api := router.Group("/api/v1")
 	{
-		api.POST("/csv-to-pdf", a.handleCSVToPDF)
-		api.POST("/upload-csv", a.handleUploadCSV)
+		api.POST("/csv-to-pdf", a.idempotency, a.handleCSVToPDF)
+		api.POST("/upload-csv", a.idempotency, a.handleUploadCSV)
+		api.GET("/reports/:id/download-url", a.handleReportDownloadURL)
 	}
 }
 
@@ -224,7 +409,49 @@ func (a *App) handleCSVToPDF(c *gin.Context) {
 	c.Data(http.StatusOK, "application/pdf", pdfBytes)
 }
 
-// handleUploadCSV handles CSV file upload, parses it, and enqueues messages.
+// reportIDPattern restricts report IDs to characters safe in blob names and headers.
+var reportIDPattern = regexp.MustCompile(`^[0-9A-Za-z_-]{1,128}$`)
+
+// handleReportDownloadURL returns a short-lived signed URL so clients download a report
+// directly from blob storage instead of through the service.
+func (a *App) handleReportDownloadURL(c *gin.Context) {
+	// The ID goes into the blob name and the signed Content-Disposition header
+	id := c.Param("id")
+	if !reportIDPattern.MatchString(id) {
+		httpservice.HandleError(c, errors.NewValidationError("Invalid report ID"))
+		return
+	}
+	blobName := fmt.Sprintf("reports/%s.pdf", id)
+	
+	exists, err := a.blobClient.Exists(c.Request.Context(), a.config.BlobContainer, blobName)
+	if err != nil {
+		httpservice.HandleError(c, errors.NewInternalError("Failed to look up report: "+err.Error()))
+		return
+	}
+	if !exists {
+		httpservice.HandleError(c, errors.NewNotFoundError("Report not found"))
+		return
+	}
+	
+	expiresIn := 5 * time.Minute
+	url, err := a.blobClient.GenerateReadURL(c.Request.Context(), a.config.BlobContainer, blobName, blobclient.SASOptions{
+		ExpiresIn:          expiresIn,
+		IPRange:            c.ClientIP(),
+		ContentDisposition: fmt.Sprintf(`attachment; filename="report-%s.pdf"`, id),
+	})
+	if err != nil {
+		a.logger.Error("Failed to generate download URL", logging.NewField("error", err))
+		httpservice.HandleError(c, errors.NewInternalError("Failed to generate download URL"))
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"url":        url,
+		"expires_in": int(expiresIn.Seconds()),
+	})
+}
+
+// handleUploadCSV handles CSV file upload, parses it, archives it deduplicated, and enqueues messages.
 func (a *App) handleUploadCSV(c *gin.Context) {
 	file, err := c.FormFile("csv_file")
 	if err != nil {
@@ -246,33 +473,45 @@ func (a *App) handleUploadCSV(c *gin.Context) {
 	}
 	defer src.Close()
 	
-	// Upload to blob storage
-	blobName := fmt.Sprintf("csv/%s", file.Filename)
-	url, err := a.blobClient.Upload(
-		c.Request.Context(),
-		a.config.BlobContainer,
-		blobName,
-		src,
-		"text/csv",
-	)
+	// Validate the CSV without keeping its rows; they are read again from the file below
+	parser := csvutil.NewParser(csvutil.DefaultParserConfig())
+	rowCount := 0
+	err = parser.Parse(src, func(rowNum int, headers []string, row []string) error {
+		rowCount++
+		return nil
+	})
+	if err != nil {
+		logger.Error("Failed to parse CSV", logging.NewField("error", err))
+		httpservice.HandleError(c, errors.NewValidationError("Invalid CSV: "+err.Error()))
+		return
+	}
+	
+	// Re-read file for archiving (reset to beginning)
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		logger.Error("Failed to rewind file", logging.NewField("error", err))
+		httpservice.HandleError(c, errors.NewInternalError("Failed to read file: "+err.Error()))
+		return
+	}
+	
+	// Archive the parsed upload; content that was uploaded before is stored only once
+	// and consumers read it from the existing URL
+	stored, err := a.csvStore.Put(c.Request.Context(), fmt.Sprintf("csv/%s", file.Filename), src, "text/csv")
 	if err != nil {
 		logger.Error("Failed to upload CSV", logging.NewField("error", err))
 		httpservice.HandleError(c, errors.NewInternalError("Failed to upload CSV: "+err.Error()))
 		return
 	}
+	url := stored.URL
 	
-	// Re-read file for parsing (reset to beginning)
-	src.Seek(0, io.SeekStart)
-	
-	// Parse CSV
-	parser := csvutil.NewParser(csvutil.DefaultParserConfig())
-	rowCount := 0
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		logger.Error("Failed to rewind file", logging.NewField("error", err))
+		httpservice.HandleError(c, errors.NewInternalError("Failed to read file: "+err.Error()))
+		return
+	}
 	
+	// Send each row as a message to Service Bus while streaming the file
+	// In production, you might batch these
 	err = parser.Parse(src, func(rowNum int, headers []string, row []string) error {
-		rowCount++
-		
-		// Send each row as a message to Service Bus
-		// In production, you might batch these
 		rowData := fmt.Sprintf(`{"row": %d, "data": %v, "blobUrl": "%s"}`, rowNum, row, url)
 		_, sendErr := a.serviceBusClient.Send(
 			c.Request.Context(),
@@ -287,19 +526,19 @@ func (a *App) handleUploadCSV(c *gin.Context) {
 		if sendErr != nil {
 			logger.Warn("Failed to send message", logging.NewField("error", sendErr), logging.NewField("row", rowNum))
 		}
-		
 		return nil
 	})
 	if err != nil {
-		logger.Error("Failed to parse CSV", logging.NewField("error", err))
-		httpservice.HandleError(c, errors.NewValidationError("Invalid CSV: "+err.Error()))
+		logger.Error("Failed to read CSV", logging.NewField("error", err))
+		httpservice.HandleError(c, errors.NewInternalError("Failed to read CSV: "+err.Error()))
 		return
 	}
 	
 	c.JSON(http.StatusOK, gin.H{
-		"blob_url":  url,
-		"row_count": rowCount,
-		"message":   "CSV uploaded and processed",
+		"blob_url":     url,
+		"row_count":    rowCount,
+		"deduplicated": stored.Deduplicated,
+		"message":      "CSV uploaded and processed",
 	})
 }
 
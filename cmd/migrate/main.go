@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/yourorg/go-service-kit/pkg/config"
+	"github.com/yourorg/go-service-kit/pkg/db"
+	"github.com/yourorg/go-service-kit/pkg/logging"
+)
+
+const usage = `Usage: migrate [flags] <command>
+
+Commands:
+  up       apply all pending migrations
+  down     roll back the latest migrations (see -steps)
+  status   list migrations and whether they are applied
+
+The database connection is read from DATABASE_URL.
+
+Flags:
+`
+
+func main() {
+	dir := flag.String("dir", "migrations", "directory containing NNNN_name.up.sql / NNNN_name.down.sql files")
+	table := flag.String("table", db.DefaultMigratorConfig().Table, "table recording applied migrations")
+	steps := flag.Int("steps", 1, "number of migrations to roll back with 'down'")
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	command := flag.Arg(0)
+
+	cfg, err := config.LoadConfigFromEnv()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load config: %v\n", err)
+		os.Exit(1)
+	}
+	if cfg.DatabaseURL == "" {
+		fmt.Fprintln(os.Stderr, "DATABASE_URL is required")
+		os.Exit(1)
+	}
+
+	logger, err := logging.NewLogger(cfg.LogLevel, cfg.LogFormat)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to create logger: %v\n", err)
+		os.Exit(1)
+	}
+	defer logging.Sync(logger)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	pgCfg := db.PostgresConfigFromConfig(cfg)
+	pgCfg.MaxOpenConns = 2
+	pgCfg.StatsInterval = 0
+
+	database, err := db.NewPostgresDBWithConfig(ctx, pgCfg, logger)
+	if err != nil {
+		logger.Error("Failed to connect to database", logging.NewField("error", err))
+		os.Exit(1)
+	}
+	defer database.Close()
+
+	migrator, err := db.NewMigrator(database, os.DirFS(*dir), db.MigratorConfig{Table: *table}, logger)
+	if err != nil {
+		logger.Error("Failed to create migrator", logging.NewField("error", err))
+		os.Exit(1)
+	}
+
+	if err := run(ctx, migrator, command, *steps); err != nil {
+		logger.Error("Migration command failed",
+			logging.NewField("command", command),
+			logging.NewField("error", err),
+		)
+		database.Close()
+		os.Exit(1)
+	}
+}
+
+// run executes a single migrate command.
+func run(ctx context.Context, migrator *db.Migrator, command string, steps int) error {
+	switch command {
+	case "up":
+		count, err := migrator.Up(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Applied %d migration(s)\n", count)
+
+	case "down":
+		count, err := migrator.Down(ctx, steps)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Rolled back %d migration(s)\n", count)
+
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+		for _, s := range statuses {
+			state := "pending"
+			appliedAt := ""
+			if s.Applied {
+				state = "applied"
+				appliedAt = s.AppliedAt.Format(time.RFC3339)
+			}
+			if s.Drifted {
+				state = "applied (checksum drift)"
+			}
+			if s.Missing {
+				state = "applied (file missing)"
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", s.Version, s.Name, state, appliedAt)
+		}
+		return w.Flush()
+
+	default:
+		return fmt.Errorf("unknown command %q", command)
+	}
+
+	return nil
+}
@@ -1,14 +1,29 @@
 package blobclient
 
 import (
+	"bytes"
 	"context"
+	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
+	"net/url"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
 	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blockblob"
+	azcontainer "github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/lease"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/sas"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/service"
 	"github.com/yourorg/go-service-kit/pkg/logging"
 )
 
@@ -17,18 +32,42 @@ type AzureBlobClient struct {
 	client      *azblob.Client
 	logger      logging.Logger
 	accountName string
+	serviceURL  string // without trailing slash or query
+
+	defaultAccessTier string
+
+	// sharedKey signs SAS URLs; nil when using Azure AD (user delegation SAS) or a SAS connection string
+	sharedKey *azblob.SharedKeyCredential
+	usesAAD   bool
+
+	delegationMu     sync.Mutex
+	delegation       *service.UserDelegationCredential
+	delegationExpiry time.Time
+}
+
+// AzureBlobOption configures an AzureBlobClient.
+type AzureBlobOption func(*AzureBlobClient)
+
+// WithDefaultAccessTier sets the access tier applied to uploads that do not specify one
+// (e.g. config.Config.BlobAccessTier). An empty tier leaves the account default in place.
+func WithDefaultAccessTier(tier string) AzureBlobOption {
+	return func(a *AzureBlobClient) {
+		a.defaultAccessTier = tier
+	}
 }
 
 // NewAzureBlobClient creates a new Azure Blob Storage client.
 // accountName: Azure storage account name
 // accountKey: Azure storage account key (optional if using managed identity)
 // useManagedIdentity: if true, uses managed identity instead of account key
-func NewAzureBlobClient(accountName, accountKey string, useManagedIdentity bool, logger logging.Logger) (*AzureBlobClient, error) {
+func NewAzureBlobClient(accountName, accountKey string, useManagedIdentity bool, logger logging.Logger, opts ...AzureBlobOption) (*AzureBlobClient, error) {
 	serviceURL := fmt.Sprintf("https://%s.blob.core.windows.net/", accountName)
 
 	var client *azblob.Client
+	var sharedKey *azblob.SharedKeyCredential
+	usesAAD := useManagedIdentity || accountKey == ""
 
-	if useManagedIdentity || accountKey == "" {
+	if usesAAD {
 		// Use managed identity (for Azure environments) or default credentials
 		cred, err := azidentity.NewDefaultAzureCredential(nil)
 		if err != nil {
@@ -48,17 +87,87 @@ func NewAzureBlobClient(accountName, accountKey string, useManagedIdentity bool,
 		if err != nil {
 			return nil, fmt.Errorf("failed to create Azure blob client: %w", err)
 		}
+		sharedKey = cred
+	}
+
+	return newAzureBlobClient(client, accountName, sharedKey, usesAAD, logger, opts)
+}
+
+// AzuriteConnectionString is the well-known connection string of the Azurite emulator,
+// also selected by "UseDevelopmentStorage=true".
+const AzuriteConnectionString = "DefaultEndpointsProtocol=http;AccountName=devstoreaccount1;" +
+	"AccountKey=Eby8vdM02xNOcqFlqUwJPLlmEtlCDXJ1OUzFT50uSRZ6IFsuFq2UVErCz4I6tq/K1SZFPTOtr/KBHBeksoGMGw==;" +
+	"BlobEndpoint=http://127.0.0.1:10000/devstoreaccount1;"
+
+// NewAzureBlobClientFromConnectionString creates a client from a storage connection string,
+// e.g. AzuriteConnectionString for local development. Both account key and
+// SharedAccessSignature connection strings are supported; signed URLs require an account key.
+func NewAzureBlobClientFromConnectionString(connectionString string, logger logging.Logger, opts ...AzureBlobOption) (*AzureBlobClient, error) {
+	if strings.EqualFold(strings.TrimRight(strings.TrimSpace(connectionString), ";"), "UseDevelopmentStorage=true") {
+		connectionString = AzuriteConnectionString
 	}
 
-	return &AzureBlobClient{
+	client, err := azblob.NewClientFromConnectionString(connectionString, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Azure blob client: %w", err)
+	}
+
+	fields := parseConnectionString(connectionString)
+	var sharedKey *azblob.SharedKeyCredential
+	if fields["AccountName"] != "" && fields["AccountKey"] != "" {
+		sharedKey, err = azblob.NewSharedKeyCredential(fields["AccountName"], fields["AccountKey"])
+		if err != nil {
+			return nil, fmt.Errorf("failed to create shared key credential: %w", err)
+		}
+	}
+
+	return newAzureBlobClient(client, fields["AccountName"], sharedKey, false, logger, opts)
+}
+
+// parseConnectionString splits a connection string into its key=value fields.
+func parseConnectionString(connectionString string) map[string]string {
+	fields := make(map[string]string)
+	for _, part := range strings.Split(connectionString, ";") {
+		if key, value, ok := strings.Cut(strings.TrimSpace(part), "="); ok {
+			fields[key] = value
+		}
+	}
+	return fields
+}
+
+// newAzureBlobClient applies options to a client built by one of the constructors.
+func newAzureBlobClient(client *azblob.Client, accountName string, sharedKey *azblob.SharedKeyCredential, usesAAD bool, logger logging.Logger, opts []AzureBlobOption) (*AzureBlobClient, error) {
+	serviceURL, _, _ := strings.Cut(client.URL(), "?")
+
+	a := &AzureBlobClient{
 		client:      client,
 		logger:      logger,
 		accountName: accountName,
-	}, nil
+		serviceURL:  strings.TrimSuffix(serviceURL, "/"),
+		sharedKey:   sharedKey,
+		usesAAD:     usesAAD,
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+
+	if a.defaultAccessTier != "" {
+		if _, err := parseAccessTier(a.defaultAccessTier); err != nil {
+			return nil, err
+		}
+	}
+
+	return a, nil
 }
 
-// Upload uploads data to Azure Blob Storage.
+// Upload uploads data to Azure Blob Storage with the given content type.
 func (a *AzureBlobClient) Upload(ctx context.Context, container, blobName string, data io.Reader, contentType string) (string, error) {
+	return a.UploadWithOptions(ctx, container, blobName, data, UploadOptions{ContentType: contentType})
+}
+
+// UploadWithOptions uploads data to Azure Blob Storage with HTTP headers, access tier,
+// metadata and blob index tags. If opts.AccessTier is empty the client default is used.
+func (a *AzureBlobClient) UploadWithOptions(ctx context.Context, container, blobName string, data io.Reader, opts UploadOptions) (string, error) {
 	logger := a.logger.With(
 		logging.NewField("operation", "blob.upload"),
 		logging.NewField("container", container),
@@ -67,29 +176,156 @@ func (a *AzureBlobClient) Upload(ctx context.Context, container, blobName string
 
 	logger.Info("Starting blob upload")
 
+	uploadOptions, err := a.uploadStreamOptions(opts)
+	if err != nil {
+		return "", err
+	}
+
 	// Ensure container exists
-	_, err := a.client.CreateContainer(ctx, container, nil)
+	_, err = a.client.CreateContainer(ctx, container, nil)
 	if err != nil {
 		// Container might already exist, which is fine
 		logger.Debug("Container create result (may already exist)", logging.NewField("error", err.Error()))
 	}
 
-	uploadOptions := &azblob.UploadStreamOptions{}
-	// Note: ContentType can be set via HTTPHeaders if needed
-	// For now, using basic upload - extend as needed
-
 	_, err = a.client.UploadStream(ctx, container, blobName, data, uploadOptions)
 	if err != nil {
 		logger.Error("Failed to upload blob", logging.NewField("error", err))
-		return "", fmt.Errorf("failed to upload blob: %w", err)
+		return "", azureError(err, "failed to upload blob", container, blobName)
 	}
 
-	url := fmt.Sprintf("https://%s.blob.core.windows.net/%s/%s", a.accountName, container, blobName)
+	url := a.blobURL(container, blobName)
 	logger.Info("Blob upload successful", logging.NewField("url", url))
 
 	return url, nil
 }
 
+// uploadStreamOptions converts UploadOptions into SDK upload options.
+func (a *AzureBlobClient) uploadStreamOptions(opts UploadOptions) (*azblob.UploadStreamOptions, error) {
+	uploadOptions := &azblob.UploadStreamOptions{
+		HTTPHeaders: &blob.HTTPHeaders{
+			BlobContentType:        optionalString(opts.ContentType),
+			BlobCacheControl:       optionalString(opts.CacheControl),
+			BlobContentDisposition: optionalString(opts.ContentDisposition),
+		},
+		Tags:             opts.Tags,
+		AccessConditions: accessConditions(opts.IfMatch, opts.IfNoneMatch, opts.LeaseID),
+	}
+
+	if len(opts.Metadata) > 0 {
+		uploadOptions.Metadata = make(map[string]*string, len(opts.Metadata))
+		for key, value := range opts.Metadata {
+			uploadOptions.Metadata[key] = &value
+		}
+	}
+
+	tier := opts.AccessTier
+	if tier == "" {
+		tier = a.defaultAccessTier
+	}
+	if tier != "" {
+		accessTier, err := parseAccessTier(tier)
+		if err != nil {
+			return nil, err
+		}
+		uploadOptions.AccessTier = &accessTier
+	}
+
+	return uploadOptions, nil
+}
+
+// UploadChunked uploads data as parallel staged blocks and commits them in order.
+// Blocks already staged by an interrupted upload of the same data are not re-sent.
+func (a *AzureBlobClient) UploadChunked(ctx context.Context, container, blobName string, data io.Reader, opts UploadOptions, transfer TransferOptions) (string, error) {
+	logger := a.logger.With(
+		logging.NewField("operation", "blob.upload_chunked"),
+		logging.NewField("container", container),
+		logging.NewField("blob", blobName),
+	)
+
+	logger.Info("Starting chunked blob upload")
+
+	// Validate options before staging anything
+	if _, err := a.uploadStreamOptions(opts); err != nil {
+		return "", err
+	}
+
+	_, err := a.client.CreateContainer(ctx, container, nil)
+	if err != nil {
+		logger.Debug("Container create result (may already exist)", logging.NewField("error", err.Error()))
+	}
+
+	url, err := uploadChunked(ctx, a, container, blobName, data, opts, transfer)
+	if err != nil {
+		logger.Error("Failed to upload blob", logging.NewField("error", err))
+		return "", fmt.Errorf("failed to upload blob: %w", err)
+	}
+
+	logger.Info("Chunked blob upload successful", logging.NewField("url", url))
+	return url, nil
+}
+
+// prepareBlocks returns the uncommitted blocks of a blob, or none if the blob does not exist.
+func (a *AzureBlobClient) prepareBlocks(ctx context.Context, container, blobName string, opts UploadOptions, resumeID string) (string, map[string]int64, error) {
+	resp, err := a.blockBlobClient(container, blobName).GetBlockList(ctx, blockblob.BlockListTypeUncommitted, nil)
+	if err != nil {
+		if bloberror.HasCode(err, bloberror.BlobNotFound, bloberror.ContainerNotFound) {
+			return "", map[string]int64{}, nil
+		}
+		return "", nil, err
+	}
+
+	staged := make(map[string]int64, len(resp.UncommittedBlocks))
+	for _, block := range resp.UncommittedBlocks {
+		if block != nil && block.Name != nil && block.Size != nil {
+			staged[*block.Name] = *block.Size
+		}
+	}
+	return "", staged, nil
+}
+
+// stageBlock uploads an uncommitted block with transactional validation.
+func (a *AzureBlobClient) stageBlock(ctx context.Context, container, blobName, upload, leaseID, blockID string, data []byte, algorithm ChecksumAlgorithm, sum []byte) error {
+	options := &blockblob.StageBlockOptions{}
+	if leaseID != "" {
+		options.LeaseAccessConditions = &blob.LeaseAccessConditions{LeaseID: &leaseID}
+	}
+	switch algorithm {
+	case ChecksumMD5:
+		options.TransactionalValidation = blob.TransferValidationTypeMD5(sum)
+	case ChecksumCRC64:
+		options.TransactionalValidation = blob.TransferValidationTypeCRC64(binary.LittleEndian.Uint64(sum))
+	}
+
+	_, err := a.blockBlobClient(container, blobName).StageBlock(ctx, blockID, readSeekNopCloser{bytes.NewReader(data)}, options)
+	if err != nil {
+		return azureError(err, "failed to stage block", container, blobName)
+	}
+	return nil
+}
+
+// commitBlocks commits the block list with the upload options applied.
+func (a *AzureBlobClient) commitBlocks(ctx context.Context, container, blobName, upload string, blockIDs []string, opts UploadOptions, contentMD5 []byte) (string, error) {
+	uploadOptions, err := a.uploadStreamOptions(opts)
+	if err != nil {
+		return "", err
+	}
+	uploadOptions.HTTPHeaders.BlobContentMD5 = contentMD5
+
+	_, err = a.blockBlobClient(container, blobName).CommitBlockList(ctx, blockIDs, &blockblob.CommitBlockListOptions{
+		HTTPHeaders:      uploadOptions.HTTPHeaders,
+		Metadata:         uploadOptions.Metadata,
+		Tier:             uploadOptions.AccessTier,
+		Tags:             uploadOptions.Tags,
+		AccessConditions: uploadOptions.AccessConditions,
+	})
+	if err != nil {
+		return "", azureError(err, "failed to commit block list", container, blobName)
+	}
+
+	return a.blobURL(container, blobName), nil
+}
+
 // Get retrieves a blob from Azure Blob Storage.
 func (a *AzureBlobClient) Get(ctx context.Context, container, blobName string) (io.ReadCloser, error) {
 	logger := a.logger.With(
@@ -103,15 +339,284 @@ func (a *AzureBlobClient) Get(ctx context.Context, container, blobName string) (
 	downloadResponse, err := a.client.DownloadStream(ctx, container, blobName, nil)
 	if err != nil {
 		logger.Error("Failed to download blob", logging.NewField("error", err))
-		return nil, fmt.Errorf("failed to download blob: %w", err)
+		return nil, azureError(err, "failed to download blob", container, blobName)
 	}
 
 	logger.Info("Blob retrieved successfully")
 	return downloadResponse.Body, nil
 }
 
+// GetRange retrieves count bytes of a blob starting at offset. A count of 0 reads to the end.
+func (a *AzureBlobClient) GetRange(ctx context.Context, container, blobName string, offset, count int64) (io.ReadCloser, error) {
+	return a.GetRangeWithOptions(ctx, container, blobName, offset, count, GetOptions{})
+}
+
+// GetRangeWithOptions retrieves a byte range of a blob if it matches opts.IfMatch.
+func (a *AzureBlobClient) GetRangeWithOptions(ctx context.Context, container, blobName string, offset, count int64, opts GetOptions) (io.ReadCloser, error) {
+	downloadResponse, err := a.client.DownloadStream(ctx, container, blobName, &azblob.DownloadStreamOptions{
+		Range: azblob.HTTPRange{
+			Offset: offset,
+			Count:  count,
+		},
+		AccessConditions: accessConditions(opts.IfMatch, "", ""),
+	})
+	if err != nil {
+		a.logger.Error("Failed to download blob range",
+			logging.NewField("container", container),
+			logging.NewField("blob", blobName),
+			logging.NewField("offset", offset),
+			logging.NewField("error", err),
+		)
+		return nil, azureError(err, "failed to download blob range", container, blobName)
+	}
+
+	return downloadResponse.Body, nil
+}
+
+// DownloadChunked writes a blob to w using parallel ranged reads, verifying checksums
+// as configured in transfer.
+func (a *AzureBlobClient) DownloadChunked(ctx context.Context, container, blobName string, w io.Writer, transfer TransferOptions) (int64, error) {
+	logger := a.logger.With(
+		logging.NewField("operation", "blob.download_chunked"),
+		logging.NewField("container", container),
+		logging.NewField("blob", blobName),
+	)
+
+	logger.Info("Starting chunked blob download")
+
+	written, err := downloadChunked(ctx, a, container, blobName, w, transfer)
+	if err != nil {
+		logger.Error("Failed to download blob", logging.NewField("error", err))
+		return written, err
+	}
+
+	logger.Info("Chunked blob download successful", logging.NewField("bytes", written))
+	return written, nil
+}
+
+// readRange reads a byte range, requesting the range MD5 from the service if rangeMD5 is set.
+func (a *AzureBlobClient) readRange(ctx context.Context, container, blobName string, offset, count int64, ifMatch string, rangeMD5 bool) ([]byte, []byte, error) {
+	options := &azblob.DownloadStreamOptions{
+		Range: azblob.HTTPRange{
+			Offset: offset,
+			Count:  count,
+		},
+		AccessConditions: accessConditions(ifMatch, "", ""),
+	}
+	if rangeMD5 {
+		options.RangeGetContentMD5 = &rangeMD5
+	}
+
+	resp, err := a.client.DownloadStream(ctx, container, blobName, options)
+	if err != nil {
+		return nil, nil, azureError(err, "failed to download blob range", container, blobName)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, resp.ContentMD5, nil
+}
+
+// GetProperties returns the headers, access tier, metadata and tags of a blob.
+func (a *AzureBlobClient) GetProperties(ctx context.Context, container, blobName string) (*BlobProperties, error) {
+	logger := a.logger.With(
+		logging.NewField("operation", "blob.get_properties"),
+		logging.NewField("container", container),
+		logging.NewField("blob", blobName),
+	)
+
+	blobClient := a.blobClient(container, blobName)
+
+	resp, err := blobClient.GetProperties(ctx, nil)
+	if err != nil {
+		logger.Error("Failed to get blob properties", logging.NewField("error", err))
+		return nil, azureError(err, "failed to get blob properties", container, blobName)
+	}
+
+	props := &BlobProperties{
+		Name:               blobName,
+		ContentType:        stringValue(resp.ContentType),
+		CacheControl:       stringValue(resp.CacheControl),
+		ContentDisposition: stringValue(resp.ContentDisposition),
+		ContentMD5:         resp.ContentMD5,
+		AccessTier:         stringValue(resp.AccessTier),
+		Metadata:           make(map[string]string, len(resp.Metadata)),
+		Tags:               make(map[string]string),
+		URL:                a.blobURL(container, blobName),
+	}
+	if resp.ETag != nil {
+		props.ETag = string(*resp.ETag)
+	}
+	if resp.ContentLength != nil {
+		props.Size = *resp.ContentLength
+	}
+	if resp.LastModified != nil {
+		props.LastModified = resp.LastModified.Format(time.RFC3339)
+	}
+	for key, value := range resp.Metadata {
+		props.Metadata[key] = stringValue(value)
+	}
+
+	// Tags are not part of the properties response; only fetch them when there are any
+	if resp.TagCount != nil && *resp.TagCount > 0 {
+		tagsResponse, err := blobClient.GetTags(ctx, nil)
+		if err != nil {
+			logger.Error("Failed to get blob tags", logging.NewField("error", err))
+			return nil, azureError(err, "failed to get blob tags", container, blobName)
+		}
+		for _, tag := range tagsResponse.BlobTagSet {
+			if tag != nil && tag.Key != nil {
+				props.Tags[*tag.Key] = stringValue(tag.Value)
+			}
+		}
+	}
+
+	return props, nil
+}
+
+// SetMetadata replaces the metadata of a blob.
+func (a *AzureBlobClient) SetMetadata(ctx context.Context, container, blobName string, metadata map[string]string, opts SetMetadataOptions) error {
+	values := make(map[string]*string, len(metadata))
+	for key, value := range metadata {
+		values[key] = &value
+	}
+
+	_, err := a.blobClient(container, blobName).SetMetadata(ctx, values, &blob.SetMetadataOptions{
+		AccessConditions: accessConditions(opts.IfMatch, "", opts.LeaseID),
+	})
+	if err != nil {
+		a.logger.Error("Failed to set blob metadata",
+			logging.NewField("container", container),
+			logging.NewField("blob", blobName),
+			logging.NewField("error", err),
+		)
+		return azureError(err, "failed to set blob metadata", container, blobName)
+	}
+	return nil
+}
+
+// SetAccessTier moves a blob to another access tier. Blobs moved out of Archive are
+// rehydrated by the service, which can take hours.
+func (a *AzureBlobClient) SetAccessTier(ctx context.Context, container, blobName, tier string) error {
+	accessTier, err := parseAccessTier(tier)
+	if err != nil {
+		return err
+	}
+	if _, err := a.blobClient(container, blobName).SetTier(ctx, accessTier, nil); err != nil {
+		a.logger.Error("Failed to set blob access tier",
+			logging.NewField("container", container),
+			logging.NewField("blob", blobName),
+			logging.NewField("tier", tier),
+			logging.NewField("error", err),
+		)
+		return azureError(err, "failed to set access tier", container, blobName)
+	}
+	return nil
+}
+
+// GenerateReadURL returns a signed URL granting read access to a blob.
+// With managed identity the URL is a user delegation SAS.
+func (a *AzureBlobClient) GenerateReadURL(ctx context.Context, container, blobName string, opts SASOptions) (string, error) {
+	return a.generateSASURL(ctx, container, blobName, opts, readPermissions)
+}
+
+// GenerateWriteURL returns a signed URL granting create and write access to a blob.
+// Clients upload with a PUT carrying the x-ms-blob-type: BlockBlob header.
+func (a *AzureBlobClient) GenerateWriteURL(ctx context.Context, container, blobName string, opts SASOptions) (string, error) {
+	return a.generateSASURL(ctx, container, blobName, opts, writePermissions)
+}
+
+// generateSASURL signs a blob SAS with the shared key, or with a user delegation key
+// when the client authenticates with Azure AD.
+func (a *AzureBlobClient) generateSASURL(ctx context.Context, container, blobName string, opts SASOptions, defaults SASPermissions) (string, error) {
+	opts, err := opts.resolve(defaults)
+	if err != nil {
+		return "", err
+	}
+	start, end, _ := parseIPRange(opts.IPRange)
+
+	// Emulators such as Azurite serve plain http and reject https-only signatures
+	protocol := sas.ProtocolHTTPS
+	if strings.HasPrefix(a.serviceURL, "http://") {
+		protocol = sas.ProtocolHTTPSandHTTP
+	}
+
+	now := time.Now().UTC()
+	values := sas.BlobSignatureValues{
+		Protocol:           protocol,
+		StartTime:          now.Add(-sasClockSkew),
+		ExpiryTime:         now.Add(opts.ExpiresIn),
+		Permissions:        opts.Permissions.String(),
+		IPRange:            sas.IPRange{Start: start, End: end},
+		ContainerName:      container,
+		BlobName:           blobName,
+		ContentDisposition: opts.ContentDisposition,
+		ContentType:        opts.ContentType,
+	}
+
+	var params sas.QueryParameters
+	if a.sharedKey != nil {
+		params, err = values.SignWithSharedKey(a.sharedKey)
+	} else if !a.usesAAD {
+		return "", fmt.Errorf("signed URLs require an account key or Azure AD credential")
+	} else {
+		var delegation *service.UserDelegationCredential
+		delegation, err = a.userDelegationCredential(ctx, values.ExpiryTime)
+		if err != nil {
+			return "", err
+		}
+		params, err = values.SignWithUserDelegation(delegation)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to sign SAS: %w", err)
+	}
+
+	blobURL := a.client.ServiceClient().NewContainerClient(container).NewBlobClient(blobName).URL()
+	return blobURL + "?" + params.Encode(), nil
+}
+
+// userDelegationCredential returns a cached user delegation key valid until at least
+// expiry, requesting a new one (valid for at least an hour) when needed.
+func (a *AzureBlobClient) userDelegationCredential(ctx context.Context, expiry time.Time) (*service.UserDelegationCredential, error) {
+	a.delegationMu.Lock()
+	defer a.delegationMu.Unlock()
+
+	if a.delegation != nil && !a.delegationExpiry.Before(expiry) {
+		return a.delegation, nil
+	}
+
+	now := time.Now().UTC()
+	keyExpiry := now.Add(time.Hour)
+	if expiry.After(keyExpiry) {
+		keyExpiry = expiry
+	}
+
+	startText := now.Add(-sasClockSkew).Format(sas.TimeFormat)
+	expiryText := keyExpiry.Format(sas.TimeFormat)
+	delegation, err := a.client.ServiceClient().GetUserDelegationCredential(ctx, service.KeyInfo{
+		Start:  &startText,
+		Expiry: &expiryText,
+	}, nil)
+	if err != nil {
+		a.logger.Error("Failed to get user delegation key", logging.NewField("error", err))
+		return nil, fmt.Errorf("failed to get user delegation key: %w", err)
+	}
+
+	a.delegation = delegation
+	a.delegationExpiry = keyExpiry
+	return delegation, nil
+}
+
 // Delete deletes a blob from Azure Blob Storage.
 func (a *AzureBlobClient) Delete(ctx context.Context, container, blobName string) error {
+	return a.DeleteWithOptions(ctx, container, blobName, DeleteOptions{})
+}
+
+// DeleteWithOptions deletes a blob if its ETag and lease match opts.
+func (a *AzureBlobClient) DeleteWithOptions(ctx context.Context, container, blobName string, opts DeleteOptions) error {
 	logger := a.logger.With(
 		logging.NewField("operation", "blob.delete"),
 		logging.NewField("container", container),
@@ -120,30 +625,149 @@ func (a *AzureBlobClient) Delete(ctx context.Context, container, blobName string
 
 	logger.Info("Deleting blob")
 
-	_, err := a.client.DeleteBlob(ctx, container, blobName, nil)
+	// Snapshots must be deleted with their base blob; with soft delete enabled on the
+	// account both can be restored with Undelete
+	_, err := a.client.DeleteBlob(ctx, container, blobName, &blob.DeleteOptions{
+		DeleteSnapshots:  to.Ptr(blob.DeleteSnapshotsOptionTypeInclude),
+		AccessConditions: accessConditions(opts.IfMatch, "", opts.LeaseID),
+	})
 	if err != nil {
 		logger.Error("Failed to delete blob", logging.NewField("error", err))
-		return fmt.Errorf("failed to delete blob: %w", err)
+		return azureError(err, "failed to delete blob", container, blobName)
 	}
 
 	logger.Info("Blob deleted successfully")
 	return nil
 }
 
+// Copy starts a server-side copy within the account. Copies of small blobs usually
+// complete immediately; larger ones continue in the background.
+func (a *AzureBlobClient) Copy(ctx context.Context, srcContainer, srcBlob, dstContainer, dstBlob string) (*CopyStatus, error) {
+	logger := a.logger.With(
+		logging.NewField("operation", "blob.copy"),
+		logging.NewField("container", srcContainer),
+		logging.NewField("blob", srcBlob),
+		logging.NewField("dst_container", dstContainer),
+		logging.NewField("dst_blob", dstBlob),
+	)
+
+	logger.Info("Starting blob copy")
+
+	// Ensure destination container exists
+	_, err := a.client.CreateContainer(ctx, dstContainer, nil)
+	if err != nil {
+		logger.Debug("Container create result (may already exist)", logging.NewField("error", err.Error()))
+	}
+
+	resp, err := a.blobClient(dstContainer, dstBlob).StartCopyFromURL(ctx, a.blobClient(srcContainer, srcBlob).URL(), nil)
+	if err != nil {
+		logger.Error("Failed to start blob copy", logging.NewField("error", err))
+		return nil, azureError(err, "failed to copy blob", srcContainer, srcBlob)
+	}
+
+	status := &CopyStatus{ID: stringValue(resp.CopyID), Status: CopyStatusPending}
+	if resp.CopyStatus != nil {
+		status.Status = string(*resp.CopyStatus)
+	}
+	logger.Info("Blob copy started", logging.NewField("copy_status", status.Status))
+	return status, nil
+}
+
+// GetCopyStatus returns the status of the last copy into a blob.
+func (a *AzureBlobClient) GetCopyStatus(ctx context.Context, container, blobName string) (*CopyStatus, error) {
+	resp, err := a.blobClient(container, blobName).GetProperties(ctx, nil)
+	if err != nil {
+		return nil, azureError(err, "failed to get copy status", container, blobName)
+	}
+	if resp.CopyStatus == nil {
+		return nil, blobError(ErrBlobNotFound, fmt.Sprintf("no copy found for blob: %s/%s", container, blobName), nil)
+	}
+
+	status := &CopyStatus{
+		ID:          stringValue(resp.CopyID),
+		Status:      string(*resp.CopyStatus),
+		Description: stringValue(resp.CopyStatusDescription),
+	}
+	// Progress is reported as "<bytes copied>/<total bytes>"
+	if copied, total, ok := strings.Cut(stringValue(resp.CopyProgress), "/"); ok {
+		status.BytesCopied, _ = strconv.ParseInt(copied, 10, 64)
+		status.TotalBytes, _ = strconv.ParseInt(total, 10, 64)
+	}
+	return status, nil
+}
+
+// Move copies a blob, waits for the copy to complete and deletes the source.
+func (a *AzureBlobClient) Move(ctx context.Context, srcContainer, srcBlob, dstContainer, dstBlob string) (string, error) {
+	if err := moveBlob(ctx, a, DefaultCopyPollInterval, srcContainer, srcBlob, dstContainer, dstBlob); err != nil {
+		return "", err
+	}
+	return a.blobURL(dstContainer, dstBlob), nil
+}
+
+// Snapshot creates a read-only snapshot of a blob and returns its ID.
+func (a *AzureBlobClient) Snapshot(ctx context.Context, container, blobName string) (string, error) {
+	resp, err := a.blobClient(container, blobName).CreateSnapshot(ctx, nil)
+	if err != nil {
+		a.logger.Error("Failed to create blob snapshot",
+			logging.NewField("container", container),
+			logging.NewField("blob", blobName),
+			logging.NewField("error", err),
+		)
+		return "", azureError(err, "failed to create snapshot", container, blobName)
+	}
+	return stringValue(resp.Snapshot), nil
+}
+
+// ListSnapshots lists the snapshots of a blob, oldest first.
+func (a *AzureBlobClient) ListSnapshots(ctx context.Context, container, blobName string) ([]BlobInfo, error) {
+	pager := a.client.ServiceClient().NewContainerClient(container).NewListBlobsFlatPager(&azcontainer.ListBlobsFlatOptions{
+		Include: azcontainer.ListBlobsInclude{Snapshots: true},
+		Prefix:  &blobName,
+	})
+
+	snapshots := []BlobInfo{}
+	for pager.More() {
+		resp, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, azureError(err, "failed to list snapshots", container, blobName)
+		}
+		if resp.Segment == nil {
+			continue
+		}
+		for _, item := range resp.Segment.BlobItems {
+			// The prefix also matches other blobs; the base blob has no snapshot ID
+			if stringValue(item.Name) != blobName || stringValue(item.Snapshot) == "" {
+				continue
+			}
+			snapshot := a.blobInfo(container, item)
+			snapshot.Snapshot = *item.Snapshot
+			snapshot.URL += "?snapshot=" + url.QueryEscape(snapshot.Snapshot)
+			snapshots = append(snapshots, snapshot)
+		}
+	}
+
+	// The service lists snapshots oldest first, before the base blob
+	return snapshots, nil
+}
+
+// Undelete restores a soft-deleted blob and its snapshots. Soft delete must be enabled on
+// the storage account.
+func (a *AzureBlobClient) Undelete(ctx context.Context, container, blobName string) error {
+	if _, err := a.blobClient(container, blobName).Undelete(ctx, nil); err != nil {
+		return azureError(err, "failed to undelete blob", container, blobName)
+	}
+	return nil
+}
+
 // Exists checks if a blob exists in Azure Blob Storage.
 func (a *AzureBlobClient) Exists(ctx context.Context, container, blobName string) (bool, error) {
-	// Try a minimal download to check existence
-	_, err := a.client.DownloadStream(ctx, container, blobName, &azblob.DownloadStreamOptions{
-		Range: azblob.HTTPRange{
-			Offset: 0,
-			Count:  1,
-		},
-	})
+	_, err := a.blobClient(container, blobName).GetProperties(ctx, nil)
 	if err != nil {
-		if strings.Contains(err.Error(), "404") || strings.Contains(err.Error(), "BlobNotFound") {
+		err = azureError(err, "failed to check blob existence", container, blobName)
+		if errors.Is(err, ErrBlobNotFound) || errors.Is(err, ErrContainerNotFound) {
 			return false, nil
 		}
-		return false, fmt.Errorf("failed to check blob existence: %w", err)
+		return false, err
 	}
 
 	return true, nil
@@ -170,35 +794,304 @@ func (a *AzureBlobClient) List(ctx context.Context, container, prefix string) ([
 		page, err := pager.NextPage(ctx)
 		if err != nil {
 			logger.Error("Failed to list blobs", logging.NewField("error", err))
-			return nil, fmt.Errorf("failed to list blobs: %w", err)
+			return nil, azureError(err, "failed to list blobs", container, "")
 		}
 
 		for _, item := range page.Segment.BlobItems {
-			blobInfo := BlobInfo{
-				Name:        *item.Name,
-				Size:        *item.Properties.ContentLength,
-				ContentType: "",
-				URL:         fmt.Sprintf("https://%s.blob.core.windows.net/%s/%s", a.accountName, container, *item.Name),
-			}
+			blobs = append(blobs, a.blobInfo(container, item))
+		}
+	}
 
-			if item.Properties.ContentType != nil {
-				blobInfo.ContentType = *item.Properties.ContentType
-			}
+	logger.Info("Blob listing completed", logging.NewField("count", len(blobs)))
+	return blobs, nil
+}
+
+// ListPage returns one page of blobs in name order.
+func (a *AzureBlobClient) ListPage(ctx context.Context, container, prefix, continuationToken string, pageSize int) (*BlobPage, error) {
+	return a.ListPageWithOptions(ctx, container, continuationToken, ListOptions{Prefix: prefix, PageSize: pageSize})
+}
+
+// ListPageWithOptions returns one page of blobs and virtual directories in name order.
+// The service may return fewer than PageSize entries (at most 5000) before the last page.
+func (a *AzureBlobClient) ListPageWithOptions(ctx context.Context, container, continuationToken string, opts ListOptions) (*BlobPage, error) {
+	logger := a.logger.With(
+		logging.NewField("operation", "blob.list_page"),
+		logging.NewField("container", container),
+		logging.NewField("prefix", opts.Prefix),
+	)
+
+	pageSize := opts.PageSize
+	if pageSize <= 0 {
+		pageSize = DefaultListPageSize
+	}
+	maxResults := int32(min(pageSize, 5000))
+	include := azcontainer.ListBlobsInclude{Metadata: opts.IncludeMetadata, Tags: opts.IncludeTags}
+	containerClient := a.client.ServiceClient().NewContainerClient(container)
+
+	var items []*azcontainer.BlobItem
+	var prefixes []*azcontainer.BlobPrefix
+	var nextMarker *string
+	if opts.Delimiter != "" {
+		pager := containerClient.NewListBlobsHierarchyPager(opts.Delimiter, &azcontainer.ListBlobsHierarchyOptions{
+			Include:    include,
+			Marker:     optionalString(continuationToken),
+			MaxResults: &maxResults,
+			Prefix:     optionalString(opts.Prefix),
+		})
+		resp, err := pager.NextPage(ctx)
+		if err != nil {
+			logger.Error("Failed to list blobs", logging.NewField("error", err))
+			return nil, azureError(err, "failed to list blobs", container, "")
+		}
+		if resp.Segment != nil {
+			items, prefixes = resp.Segment.BlobItems, resp.Segment.BlobPrefixes
+		}
+		nextMarker = resp.NextMarker
+	} else {
+		pager := containerClient.NewListBlobsFlatPager(&azcontainer.ListBlobsFlatOptions{
+			Include:    include,
+			Marker:     optionalString(continuationToken),
+			MaxResults: &maxResults,
+			Prefix:     optionalString(opts.Prefix),
+		})
+		resp, err := pager.NextPage(ctx)
+		if err != nil {
+			logger.Error("Failed to list blobs", logging.NewField("error", err))
+			return nil, azureError(err, "failed to list blobs", container, "")
+		}
+		if resp.Segment != nil {
+			items = resp.Segment.BlobItems
+		}
+		nextMarker = resp.NextMarker
+	}
 
-			if item.Properties.LastModified != nil {
-				blobInfo.LastModified = item.Properties.LastModified.Format(time.RFC3339)
+	page := &BlobPage{Blobs: make([]BlobInfo, 0, len(items)+len(prefixes)), ContinuationToken: stringValue(nextMarker)}
+	for _, item := range items {
+		blobInfo := a.blobInfo(container, item)
+		if opts.IncludeMetadata {
+			blobInfo.Metadata = make(map[string]string, len(item.Metadata))
+			for key, value := range item.Metadata {
+				blobInfo.Metadata[key] = stringValue(value)
 			}
+		}
+		if opts.IncludeTags {
+			blobInfo.Tags = make(map[string]string)
+			if item.BlobTags != nil {
+				for _, tag := range item.BlobTags.BlobTagSet {
+					if tag != nil && tag.Key != nil {
+						blobInfo.Tags[*tag.Key] = stringValue(tag.Value)
+					}
+				}
+			}
+		}
+		page.Blobs = append(page.Blobs, blobInfo)
+	}
+	for _, prefix := range prefixes {
+		if prefix != nil && prefix.Name != nil {
+			page.Blobs = append(page.Blobs, BlobInfo{Name: *prefix.Name, IsPrefix: true})
+		}
+	}
+	// The service returns blobs and prefixes separately
+	sortBlobs(page.Blobs)
 
-			blobs = append(blobs, blobInfo)
+	return page, nil
+}
+
+// blobInfo converts a listed blob item.
+func (a *AzureBlobClient) blobInfo(container string, item *azcontainer.BlobItem) BlobInfo {
+	blobInfo := BlobInfo{
+		Name: stringValue(item.Name),
+		URL:  a.blobURL(container, stringValue(item.Name)),
+	}
+	if item.Properties != nil {
+		if item.Properties.ContentLength != nil {
+			blobInfo.Size = *item.Properties.ContentLength
+		}
+		blobInfo.ContentType = stringValue(item.Properties.ContentType)
+		if item.Properties.ETag != nil {
+			blobInfo.ETag = string(*item.Properties.ETag)
+		}
+		if item.Properties.LastModified != nil {
+			blobInfo.LastModified = item.Properties.LastModified.Format(time.RFC3339)
+		}
+		if item.Properties.AccessTier != nil {
+			blobInfo.AccessTier = string(*item.Properties.AccessTier)
 		}
 	}
+	return blobInfo
+}
 
-	logger.Info("Blob listing completed", logging.NewField("count", len(blobs)))
-	return blobs, nil
+// blobURL returns the URL of a blob in the account.
+func (a *AzureBlobClient) blobURL(container, blobName string) string {
+	return fmt.Sprintf("%s/%s/%s", a.serviceURL, container, blobName)
+}
+
+// AcquireLease takes a lease on a blob.
+func (a *AzureBlobClient) AcquireLease(ctx context.Context, container, blobName string, duration time.Duration, proposedLeaseID string) (string, error) {
+	if err := validateLeaseDuration(duration); err != nil {
+		return "", err
+	}
+	leaseClient, err := a.leaseClient(container, blobName, proposedLeaseID)
+	if err != nil {
+		return "", err
+	}
+
+	seconds := int32(-1)
+	if duration != InfiniteLease {
+		seconds = int32(duration / time.Second)
+	}
+	resp, err := leaseClient.AcquireLease(ctx, seconds, nil)
+	if err != nil {
+		return "", azureError(err, "failed to acquire lease", container, blobName)
+	}
+	return stringValue(resp.LeaseID), nil
+}
+
+// RenewLease restarts the duration of a lease.
+func (a *AzureBlobClient) RenewLease(ctx context.Context, container, blobName, leaseID string) error {
+	leaseClient, err := a.leaseClient(container, blobName, leaseID)
+	if err != nil {
+		return err
+	}
+	if _, err := leaseClient.RenewLease(ctx, nil); err != nil {
+		return azureError(err, "failed to renew lease", container, blobName)
+	}
+	return nil
+}
+
+// ReleaseLease ends a lease.
+func (a *AzureBlobClient) ReleaseLease(ctx context.Context, container, blobName, leaseID string) error {
+	leaseClient, err := a.leaseClient(container, blobName, leaseID)
+	if err != nil {
+		return err
+	}
+	if _, err := leaseClient.ReleaseLease(ctx, nil); err != nil {
+		return azureError(err, "failed to release lease", container, blobName)
+	}
+	return nil
+}
+
+// BreakLease ends a lease after breakPeriod or the remaining lease time, if shorter.
+func (a *AzureBlobClient) BreakLease(ctx context.Context, container, blobName string, breakPeriod time.Duration) (time.Duration, error) {
+	if err := validateBreakPeriod(breakPeriod); err != nil {
+		return 0, err
+	}
+	leaseClient, err := a.leaseClient(container, blobName, "")
+	if err != nil {
+		return 0, err
+	}
+
+	resp, err := leaseClient.BreakLease(ctx, &lease.BlobBreakOptions{BreakPeriod: to.Ptr(int32(breakPeriod / time.Second))})
+	if err != nil {
+		return 0, azureError(err, "failed to break lease", container, blobName)
+	}
+	if resp.LeaseTime == nil {
+		return 0, nil
+	}
+	return time.Duration(*resp.LeaseTime) * time.Second, nil
+}
+
+// leaseClient returns a lease client for a blob. An empty leaseID lets the SDK generate one.
+func (a *AzureBlobClient) leaseClient(container, blobName, leaseID string) (*lease.BlobClient, error) {
+	leaseClient, err := lease.NewBlobClient(a.blobClient(container, blobName), &lease.BlobClientOptions{LeaseID: optionalString(leaseID)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create lease client: %w", err)
+	}
+	return leaseClient, nil
+}
+
+// blobClient returns the blob client for a blob.
+func (a *AzureBlobClient) blobClient(container, blobName string) *blob.Client {
+	return a.client.ServiceClient().NewContainerClient(container).NewBlobClient(blobName)
+}
+
+// blockBlobClient returns the block blob client for a blob.
+func (a *AzureBlobClient) blockBlobClient(container, blobName string) *blockblob.Client {
+	return a.client.ServiceClient().NewContainerClient(container).NewBlockBlobClient(blobName)
+}
+
+// accessConditions converts ETag conditions and a lease ID into SDK access conditions.
+func accessConditions(ifMatch, ifNoneMatch, leaseID string) *blob.AccessConditions {
+	if ifMatch == "" && ifNoneMatch == "" && leaseID == "" {
+		return nil
+	}
+
+	conditions := &blob.AccessConditions{
+		ModifiedAccessConditions: &blob.ModifiedAccessConditions{},
+		LeaseAccessConditions:    &blob.LeaseAccessConditions{LeaseID: optionalString(leaseID)},
+	}
+	if ifMatch != "" {
+		conditions.ModifiedAccessConditions.IfMatch = to.Ptr(azcore.ETag(ifMatch))
+	}
+	if ifNoneMatch != "" {
+		conditions.ModifiedAccessConditions.IfNoneMatch = to.Ptr(azcore.ETag(ifNoneMatch))
+	}
+	return conditions
+}
+
+// azureError maps a storage service error to the typed BlobClient errors and wraps other
+// errors with message.
+func azureError(err error, message, container, blobName string) error {
+	var respErr *azcore.ResponseError
+	if !errors.As(err, &respErr) {
+		return fmt.Errorf("%s: %w", message, err)
+	}
+
+	switch bloberror.Code(respErr.ErrorCode) {
+	case bloberror.ContainerNotFound:
+		return containerNotFound(container, err)
+	case bloberror.BlobNotFound:
+		return blobNotFound(container, blobName, err)
+	case bloberror.BlobAlreadyExists:
+		// Returned instead of 412 when IfNoneMatch "*" finds an existing blob
+		return blobError(ErrPreconditionFailed, message+": "+respErr.ErrorCode, err)
+	}
+	if sentinel := classifyStatus(respErr.StatusCode); sentinel == ErrBlobNotFound {
+		return blobNotFound(container, blobName, err)
+	} else if sentinel != nil {
+		return blobError(sentinel, message+": "+respErr.ErrorCode, err)
+	}
+	return fmt.Errorf("%s: %w", message, err)
+}
+
+// readSeekNopCloser adapts an io.ReadSeeker to the io.ReadSeekCloser the SDK expects.
+type readSeekNopCloser struct {
+	io.ReadSeeker
+}
+
+// Close does nothing.
+func (readSeekNopCloser) Close() error {
+	return nil
+}
+
+// parseAccessTier matches tier case-insensitively against the tiers supported by the service.
+func parseAccessTier(tier string) (blob.AccessTier, error) {
+	for _, supported := range blob.PossibleAccessTierValues() {
+		if strings.EqualFold(string(supported), tier) {
+			return supported, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported access tier: %s", tier)
+}
+
+// optionalString returns a pointer to s, or nil if s is empty.
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// stringValue dereferences s, returning "" for nil.
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
 }
 
 // Note: For local development, you can use Azurite emulator:
 // 1. Install: npm install -g azurite
 // 2. Run: azurite --silent --location ~/azurite --debug ~/azurite/debug.log
-// 3. Set connection string to: DefaultEndpointsProtocol=http;AccountName=devstoreaccount1;AccountKey=Eby8vdM02xNOcqFlqUwJPLlmEtlCDXJ1OUzFT50uSRZ6IFsuFq2UVErCz4I6rqj1nZA==;BlobEndpoint=http://127.0.0.1:10000/devstoreaccount1;
-// 4. Update NewAzureBlobClient to accept connection string as an alternative
+// 3. Set BLOB_CONNECTION_STRING=UseDevelopmentStorage=true (see AzuriteConnectionString)
+// 4. Create the client with NewAzureBlobClientFromConnectionString, or NewFromConfig
@@ -3,6 +3,7 @@ package blobclient
 import (
 	"context"
 	"io"
+	"time"
 )
 
 // BlobClient defines the interface for blob storage operations.
@@ -10,17 +11,96 @@ type BlobClient interface {
 	// Upload uploads data to blob storage and returns the URL.
 	Upload(ctx context.Context, container, blobName string, data io.Reader, contentType string) (url string, err error)
 	
+	// UploadWithOptions uploads data with HTTP headers, access tier, metadata and tags and returns the URL.
+	UploadWithOptions(ctx context.Context, container, blobName string, data io.Reader, opts UploadOptions) (url string, err error)
+	
+	// UploadChunked uploads data as parallel blocks with progress reporting and checksums.
+	// Re-running an interrupted upload with the same data only uploads the missing blocks.
+	UploadChunked(ctx context.Context, container, blobName string, data io.Reader, opts UploadOptions, transfer TransferOptions) (url string, err error)
+	
 	// Get retrieves a blob from storage.
 	Get(ctx context.Context, container, blobName string) (io.ReadCloser, error)
 	
+	// GetRange retrieves count bytes of a blob starting at offset. A count of 0 reads to the end.
+	GetRange(ctx context.Context, container, blobName string, offset, count int64) (io.ReadCloser, error)
+	
+	// GetRangeWithOptions is GetRange with read conditions. A failed IfMatch returns
+	// ErrPreconditionFailed, e.g. when the blob was overwritten between reads.
+	GetRangeWithOptions(ctx context.Context, container, blobName string, offset, count int64, opts GetOptions) (io.ReadCloser, error)
+	
+	// DownloadChunked writes a blob to w using parallel ranged reads and returns the bytes written.
+	DownloadChunked(ctx context.Context, container, blobName string, w io.Writer, transfer TransferOptions) (int64, error)
+	
 	// Delete deletes a blob from storage.
 	Delete(ctx context.Context, container, blobName string) error
 	
+	// DeleteWithOptions deletes a blob only if opts.IfMatch matches its ETag, with the lease
+	// ID required to delete a leased blob. Failed conditions return ErrPreconditionFailed.
+	DeleteWithOptions(ctx context.Context, container, blobName string, opts DeleteOptions) error
+	
 	// Exists checks if a blob exists.
 	Exists(ctx context.Context, container, blobName string) (bool, error)
 	
 	// List lists blobs in a container with optional prefix.
 	List(ctx context.Context, container, prefix string) ([]BlobInfo, error)
+	
+	// ListPage returns up to pageSize blobs in name order, starting after continuationToken
+	// (empty for the first page). The returned ContinuationToken is empty on the last page.
+	ListPage(ctx context.Context, container, prefix, continuationToken string, pageSize int) (*BlobPage, error)
+	
+	// ListPageWithOptions is ListPage with delimiter-based hierarchical listing and
+	// optional metadata and tags. See NewBlobIterator for streaming through all pages.
+	ListPageWithOptions(ctx context.Context, container, continuationToken string, opts ListOptions) (*BlobPage, error)
+	
+	// Copy starts a server-side copy of a blob. Small copies usually complete immediately;
+	// otherwise the status is CopyStatusPending (see GetCopyStatus and WaitForCopy).
+	Copy(ctx context.Context, srcContainer, srcBlob, dstContainer, dstBlob string) (*CopyStatus, error)
+	
+	// GetCopyStatus returns the status of the last copy into a blob.
+	GetCopyStatus(ctx context.Context, container, blobName string) (*CopyStatus, error)
+	
+	// Move copies a blob server-side, waits for the copy to complete, deletes the source
+	// and returns the URL of the destination.
+	Move(ctx context.Context, srcContainer, srcBlob, dstContainer, dstBlob string) (url string, err error)
+	
+	// Snapshot creates a read-only, point-in-time snapshot of a blob and returns its ID.
+	Snapshot(ctx context.Context, container, blobName string) (snapshot string, err error)
+	
+	// ListSnapshots lists the snapshots of a blob, oldest first.
+	ListSnapshots(ctx context.Context, container, blobName string) ([]BlobInfo, error)
+	
+	// Undelete restores a soft-deleted blob and its snapshots.
+	Undelete(ctx context.Context, container, blobName string) error
+	
+	// AcquireLease takes an exclusive write lease on a blob for duration (MinLeaseDuration to
+	// MaxLeaseDuration, or InfiniteLease) and returns its ID. proposedLeaseID may be empty.
+	// While the lease is active, writes and deletes must pass the lease ID. See HoldLease.
+	AcquireLease(ctx context.Context, container, blobName string, duration time.Duration, proposedLeaseID string) (leaseID string, err error)
+	
+	// RenewLease restarts the duration of a lease.
+	RenewLease(ctx context.Context, container, blobName, leaseID string) error
+	
+	// ReleaseLease ends a lease so another client can acquire one immediately.
+	ReleaseLease(ctx context.Context, container, blobName, leaseID string) error
+	
+	// BreakLease ends a lease without its ID after breakPeriod (0 to MaxLeaseDuration), or
+	// the remaining lease time if shorter, and returns the time until the lease is broken.
+	BreakLease(ctx context.Context, container, blobName string, breakPeriod time.Duration) (remaining time.Duration, err error)
+	
+	// GetProperties returns the headers, access tier, metadata and tags of a blob.
+	GetProperties(ctx context.Context, container, blobName string) (*BlobProperties, error)
+	
+	// SetMetadata replaces the metadata of a blob without rewriting its content.
+	SetMetadata(ctx context.Context, container, blobName string, metadata map[string]string, opts SetMetadataOptions) error
+	
+	// SetAccessTier moves a blob to another access tier (Hot, Cool, Cold or Archive).
+	SetAccessTier(ctx context.Context, container, blobName, tier string) error
+	
+	// GenerateReadURL returns a time-limited signed URL for downloading a blob directly.
+	GenerateReadURL(ctx context.Context, container, blobName string, opts SASOptions) (string, error)
+	
+	// GenerateWriteURL returns a time-limited signed URL for uploading a blob directly.
+	GenerateWriteURL(ctx context.Context, container, blobName string, opts SASOptions) (string, error)
 }
 
 // BlobInfo contains information about a blob.
@@ -30,12 +110,65 @@ type BlobInfo struct {
 	ContentType  string
 	LastModified string
 	URL          string
+	ETag         string            // changes on every write; use with IfMatch for optimistic concurrency
+	AccessTier   string
+	IsPrefix     bool              // virtual directory of a delimiter listing; only Name is set
+	Snapshot     string            // snapshot ID; only set by ListSnapshots
+	Metadata     map[string]string // only with ListOptions.IncludeMetadata
+	Tags         map[string]string // only with ListOptions.IncludeTags
+}
+
+// ListOptions contains optional parameters for paginated listing.
+type ListOptions struct {
+	Prefix          string
+	Delimiter       string // e.g. "/" to list virtual directories as IsPrefix entries
+	PageSize        int    // default: DefaultListPageSize
+	IncludeMetadata bool
+	IncludeTags     bool
+}
+
+// BlobPage is one page of a listing.
+type BlobPage struct {
+	Blobs             []BlobInfo // blobs and virtual directories in name order
+	ContinuationToken string     // empty on the last page
+}
+
+// BlobProperties contains the properties of a single blob.
+type BlobProperties struct {
+	Name               string
+	Size               int64
+	ContentType        string
+	CacheControl       string
+	ContentDisposition string
+	ContentMD5         []byte
+	AccessTier         string
+	Metadata           map[string]string
+	Tags               map[string]string
+	LastModified       string
+	ETag               string
+	URL                string
 }
 
 // UploadOptions contains optional parameters for upload operations.
 type UploadOptions struct {
-	ContentType string
-	AccessTier  string // Hot, Cool, Archive
-	Metadata    map[string]string
+	ContentType        string
+	CacheControl       string // e.g. "public, max-age=3600"
+	ContentDisposition string // e.g. `attachment; filename="report.csv"`
+	AccessTier         string // Hot, Cool, Cold, Archive; empty uses the client default
+	Metadata           map[string]string
+	Tags               map[string]string // blob index tags, queryable across containers
+	IfMatch            string            // only overwrite if the blob's ETag matches
+	IfNoneMatch        string            // "*" only creates the blob if it does not exist
+	LeaseID            string            // required to overwrite a blob with an active lease
+}
+
+// GetOptions contains optional parameters for reads.
+type GetOptions struct {
+	IfMatch string // only read if the blob's ETag matches
 }
 
+// SetMetadataOptions contains optional parameters for SetMetadata.
+type SetMetadataOptions struct {
+	IfMatch string // only update if the blob's ETag matches
+	LeaseID string // required to update a blob with an active lease
+}
@@ -0,0 +1,388 @@
+package blobclient
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc64"
+	"io"
+	"sync"
+)
+
+// ChecksumAlgorithm selects the integrity check used by chunked transfers.
+type ChecksumAlgorithm int
+
+const (
+	// ChecksumNone disables integrity verification.
+	ChecksumNone ChecksumAlgorithm = iota
+	// ChecksumMD5 verifies every block and range with MD5 and stores the whole-blob
+	// MD5 as the blob's Content-MD5.
+	ChecksumMD5
+	// ChecksumCRC64 verifies every uploaded block with the storage service's CRC64 and
+	// stores the whole-blob CRC64 in the blob metadata (see ContentCRC64MetadataKey).
+	ChecksumCRC64
+)
+
+const (
+	// DefaultBlockSize is the block size used by chunked transfers when none is set.
+	DefaultBlockSize = 8 << 20
+	// DefaultConcurrency is the number of blocks transferred in parallel when none is set.
+	DefaultConcurrency = 4
+	// ContentCRC64MetadataKey is the metadata key holding the hex whole-blob CRC64
+	// written by UploadChunked with ChecksumCRC64.
+	ContentCRC64MetadataKey = "content_crc64"
+
+	// maxVerifiedRangeSize is the largest range for which Azure returns a range MD5.
+	maxVerifiedRangeSize = 4 << 20
+)
+
+// ErrChecksumMismatch is returned when transferred data does not match its checksum.
+var ErrChecksumMismatch = errors.New("blob checksum mismatch")
+
+// crc64Table uses the polynomial of the Azure Storage CRC64 (x-ms-content-crc64).
+var crc64Table = crc64.MakeTable(0x9A6C9329AC4BC9B5)
+
+// TransferOptions configures UploadChunked and DownloadChunked.
+type TransferOptions struct {
+	// BlockSize is the size of each uploaded block or downloaded range (default: DefaultBlockSize).
+	// Downloads verified with ChecksumMD5 use at most 4 MiB ranges.
+	BlockSize int64
+	// Concurrency is the number of blocks or ranges in flight (default: DefaultConcurrency).
+	Concurrency int
+	// Progress is called with the cumulative number of bytes transferred. Calls are serialised
+	// and the value never decreases. Blocks skipped on resume count as transferred.
+	Progress func(bytesTransferred int64)
+	// Checksum selects integrity verification in both directions.
+	Checksum ChecksumAlgorithm
+	// ResumeID continues the upload reported by an IncompleteUploadError. It is required to
+	// resume S3 uploads; Azure and the file system resume from the blocks staged for the blob.
+	ResumeID string
+}
+
+// IncompleteUploadError is returned by UploadChunked when an upload failed after it was
+// started on the service. Retrying with the same data and options and ResumeID set only
+// uploads the blocks that are missing.
+type IncompleteUploadError struct {
+	ResumeID string
+	Err      error
+}
+
+func (e *IncompleteUploadError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *IncompleteUploadError) Unwrap() error {
+	return e.Err
+}
+
+// withDefaults fills in unset transfer options.
+func (o TransferOptions) withDefaults() TransferOptions {
+	if o.BlockSize <= 0 {
+		o.BlockSize = DefaultBlockSize
+	}
+	if o.Concurrency <= 0 {
+		o.Concurrency = DefaultConcurrency
+	}
+	return o
+}
+
+// blockStore is implemented by backends that can stage and commit blocks.
+type blockStore interface {
+	// prepareBlocks readies a blob for staging and returns the block IDs already staged
+	// by an earlier attempt with their sizes. opts are the options later passed to commitBlocks.
+	// upload identifies the attempt to stageBlock and commitBlocks and is its resume ID;
+	// backends that stage blocks per blob return an empty upload and ignore resumeID.
+	prepareBlocks(ctx context.Context, container, blobName string, opts UploadOptions, resumeID string) (upload string, staged map[string]int64, err error)
+	// stageBlock uploads an uncommitted block. sum is the block checksum for algorithm.
+	// leaseID is required if the blob has an active lease.
+	stageBlock(ctx context.Context, container, blobName, upload, leaseID, blockID string, data []byte, algorithm ChecksumAlgorithm, sum []byte) error
+	// commitBlocks assembles the blob from blockIDs and applies opts. contentMD5 may be nil.
+	commitBlocks(ctx context.Context, container, blobName, upload string, blockIDs []string, opts UploadOptions, contentMD5 []byte) (string, error)
+}
+
+// rangeStore is implemented by backends that can read verified byte ranges.
+type rangeStore interface {
+	GetProperties(ctx context.Context, container, blobName string) (*BlobProperties, error)
+	// readRange reads count bytes at offset, failing with ErrPreconditionFailed unless the
+	// blob's ETag matches ifMatch (if set). If rangeMD5 is set it also returns the MD5 of
+	// the range as reported by the service.
+	readRange(ctx context.Context, container, blobName string, offset, count int64, ifMatch string, rangeMD5 bool) (data []byte, serviceMD5 []byte, err error)
+}
+
+// uploadChunked reads data block by block and stages the blocks in parallel, then commits
+// them in order. Block IDs are derived from each block's index and content, so re-running
+// an interrupted upload with the same data skips blocks the service already holds.
+func uploadChunked(ctx context.Context, store blockStore, container, blobName string, data io.Reader, opts UploadOptions, transfer TransferOptions) (string, error) {
+	transfer = transfer.withDefaults()
+
+	upload, staged, err := store.prepareBlocks(ctx, container, blobName, opts, transfer.ResumeID)
+	if err != nil {
+		return "", fmt.Errorf("failed to list staged blocks: %w", err)
+	}
+
+	blobURL, err := stageAndCommit(ctx, store, container, blobName, upload, staged, data, opts, transfer)
+	if err != nil && upload != "" {
+		return "", &IncompleteUploadError{ResumeID: upload, Err: err}
+	}
+	return blobURL, err
+}
+
+// stageAndCommit stages the blocks of data missing from staged and commits the blob.
+func stageAndCommit(ctx context.Context, store blockStore, container, blobName, upload string, staged map[string]int64, data io.Reader, opts UploadOptions, transfer TransferOptions) (string, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		failOnce sync.Once
+		firstErr error
+	)
+	fail := func(err error) {
+		failOnce.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+
+	sem := make(chan struct{}, transfer.Concurrency)
+	progress := newProgressTracker(transfer.Progress)
+	wholeMD5 := md5.New()
+	wholeCRC := crc64.New(crc64Table)
+
+	var blockIDs []string
+read:
+	for index := 0; ; index++ {
+		buf := make([]byte, transfer.BlockSize)
+		n, readErr := io.ReadFull(data, buf)
+		if n > 0 {
+			block := buf[:n]
+			wholeMD5.Write(block)
+			wholeCRC.Write(block)
+
+			blockMD5 := md5.Sum(block)
+			id := blockID(index, blockMD5[:])
+			blockIDs = append(blockIDs, id)
+
+			if size, ok := staged[id]; ok && size == int64(n) {
+				progress.add(int64(n))
+			} else {
+				select {
+				case sem <- struct{}{}:
+				case <-ctx.Done():
+					break read
+				}
+
+				wg.Add(1)
+				go func(index int, block []byte, blockMD5 []byte) {
+					defer wg.Done()
+					defer func() { <-sem }()
+
+					sum := blockChecksum(block, transfer.Checksum, blockMD5)
+					if err := store.stageBlock(ctx, container, blobName, upload, opts.LeaseID, id, block, transfer.Checksum, sum); err != nil {
+						fail(fmt.Errorf("failed to stage block %d: %w", index, err))
+						return
+					}
+					progress.add(int64(len(block)))
+				}(index, block, blockMD5[:])
+			}
+		}
+
+		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+			break
+		}
+		if readErr != nil {
+			fail(fmt.Errorf("failed to read data: %w", readErr))
+			break
+		}
+	}
+
+	wg.Wait()
+	if firstErr != nil {
+		return "", firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	var contentMD5 []byte
+	switch transfer.Checksum {
+	case ChecksumMD5:
+		contentMD5 = wholeMD5.Sum(nil)
+	case ChecksumCRC64:
+		opts.Metadata = copyStringMap(opts.Metadata)
+		opts.Metadata[ContentCRC64MetadataKey] = hex.EncodeToString(wholeCRC.Sum(nil))
+	}
+
+	return store.commitBlocks(ctx, container, blobName, upload, blockIDs, opts, contentMD5)
+}
+
+// downloadChunked reads a blob in parallel ranges and writes them to w in order.
+// With ChecksumMD5 every range is checked against the service's range MD5 (where the
+// service provides one) and the whole blob against its Content-MD5; with ChecksumCRC64 the whole blob is checked against the
+// CRC64 stored by UploadChunked. Checks for which the blob carries no checksum are skipped.
+// Every range is read with If-Match on the ETag seen first, so a blob overwritten during
+// the download fails with ErrPreconditionFailed instead of mixing versions.
+// On ErrChecksumMismatch or ErrPreconditionFailed, data already written to w must be discarded.
+func downloadChunked(ctx context.Context, store rangeStore, container, blobName string, w io.Writer, transfer TransferOptions) (int64, error) {
+	transfer = transfer.withDefaults()
+	verifyRanges := transfer.Checksum == ChecksumMD5
+	if verifyRanges && transfer.BlockSize > maxVerifiedRangeSize {
+		transfer.BlockSize = maxVerifiedRangeSize
+	}
+
+	props, err := store.GetProperties(ctx, container, blobName)
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type chunk struct {
+		data []byte
+		err  error
+	}
+
+	// Each range gets its own result channel, queued in order; the queue bounds the
+	// number of ranges in flight.
+	results := make(chan chan chunk, transfer.Concurrency-1)
+	go func() {
+		defer close(results)
+		for offset := int64(0); offset < props.Size; offset += transfer.BlockSize {
+			count := min(transfer.BlockSize, props.Size-offset)
+			result := make(chan chunk, 1)
+			select {
+			case results <- result:
+			case <-ctx.Done():
+				return
+			}
+
+			go func(offset, count int64) {
+				data, serviceMD5, err := store.readRange(ctx, container, blobName, offset, count, props.ETag, verifyRanges)
+				if err == nil && int64(len(data)) != count {
+					err = fmt.Errorf("short read at offset %d: got %d of %d bytes", offset, len(data), count)
+				}
+				if err == nil && verifyRanges {
+					sum := md5.Sum(data)
+					if serviceMD5 != nil && !bytes.Equal(sum[:], serviceMD5) {
+						err = fmt.Errorf("%w: range at offset %d", ErrChecksumMismatch, offset)
+					}
+				}
+				result <- chunk{data: data, err: err}
+			}(offset, count)
+		}
+	}()
+
+	progress := newProgressTracker(transfer.Progress)
+	var wholeHash hash.Hash
+	switch transfer.Checksum {
+	case ChecksumMD5:
+		wholeHash = md5.New()
+	case ChecksumCRC64:
+		wholeHash = crc64.New(crc64Table)
+	}
+
+	var written int64
+	for result := range results {
+		c := <-result
+		if c.err != nil {
+			return written, fmt.Errorf("failed to download range: %w", c.err)
+		}
+
+		n, err := w.Write(c.data)
+		written += int64(n)
+		if err != nil {
+			return written, fmt.Errorf("failed to write data: %w", err)
+		}
+		if wholeHash != nil {
+			wholeHash.Write(c.data)
+		}
+		progress.add(int64(n))
+	}
+
+	if written != props.Size {
+		if err := ctx.Err(); err != nil {
+			return written, err
+		}
+		return written, fmt.Errorf("incomplete download: got %d of %d bytes", written, props.Size)
+	}
+
+	switch transfer.Checksum {
+	case ChecksumMD5:
+		if len(props.ContentMD5) > 0 && !bytes.Equal(wholeHash.Sum(nil), props.ContentMD5) {
+			return written, fmt.Errorf("%w: content MD5 of %s/%s", ErrChecksumMismatch, container, blobName)
+		}
+	case ChecksumCRC64:
+		if expected, ok := props.Metadata[ContentCRC64MetadataKey]; ok && expected != hex.EncodeToString(wholeHash.Sum(nil)) {
+			return written, fmt.Errorf("%w: content CRC64 of %s/%s", ErrChecksumMismatch, container, blobName)
+		}
+	}
+
+	return written, nil
+}
+
+// blockID returns the base64 block ID for the block at index with the given MD5.
+// All IDs have the same length, as required by the service.
+func blockID(index int, blockMD5 []byte) string {
+	return base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%010d-%x", index, blockMD5)))
+}
+
+// blockChecksum returns the transactional checksum of block for algorithm.
+// CRC64 sums are 8 bytes, little-endian, as sent in x-ms-content-crc64.
+func blockChecksum(block []byte, algorithm ChecksumAlgorithm, blockMD5 []byte) []byte {
+	switch algorithm {
+	case ChecksumMD5:
+		return blockMD5
+	case ChecksumCRC64:
+		return binary.LittleEndian.AppendUint64(nil, crc64.Checksum(block, crc64Table))
+	default:
+		return nil
+	}
+}
+
+// verifyBlockChecksum checks block against a transactional checksum, as the service does.
+func verifyBlockChecksum(block []byte, algorithm ChecksumAlgorithm, sum []byte) error {
+	var expected []byte
+	switch algorithm {
+	case ChecksumMD5:
+		blockMD5 := md5.Sum(block)
+		expected = blockMD5[:]
+	case ChecksumCRC64:
+		expected = blockChecksum(block, ChecksumCRC64, nil)
+	default:
+		return nil
+	}
+	if !bytes.Equal(expected, sum) {
+		return ErrChecksumMismatch
+	}
+	return nil
+}
+
+// progressTracker serialises progress callbacks.
+type progressTracker struct {
+	mu    sync.Mutex
+	total int64
+	fn    func(int64)
+}
+
+// newProgressTracker returns a tracker reporting to fn (which may be nil).
+func newProgressTracker(fn func(int64)) *progressTracker {
+	return &progressTracker{fn: fn}
+}
+
+// add records n transferred bytes and reports the new total.
+func (p *progressTracker) add(n int64) {
+	if p.fn == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.total += n
+	p.fn(p.total)
+}
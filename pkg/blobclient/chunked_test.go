@@ -0,0 +1,215 @@
+package blobclient
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"sync/atomic"
+	"testing"
+)
+
+func testPayload(size int) []byte {
+	data := make([]byte, size)
+	rand.New(rand.NewSource(1)).Read(data)
+	return data
+}
+
+func TestUploadChunked_RoundTrip(t *testing.T) {
+	for _, checksum := range []ChecksumAlgorithm{ChecksumNone, ChecksumMD5, ChecksumCRC64} {
+		client := NewMockBlobClient()
+		ctx := context.Background()
+		data := testPayload(10_000)
+
+		var last int64
+		transfer := TransferOptions{
+			BlockSize:   1024,
+			Concurrency: 3,
+			Checksum:    checksum,
+			Progress: func(n int64) {
+				if n < last {
+					t.Errorf("Progress went backwards: %d after %d", n, last)
+				}
+				last = n
+			},
+		}
+
+		if _, err := client.UploadChunked(ctx, "exports", "big.csv", bytes.NewReader(data), UploadOptions{ContentType: "text/csv"}, transfer); err != nil {
+			t.Fatalf("UploadChunked failed: %v", err)
+		}
+		if last != int64(len(data)) {
+			t.Errorf("Expected final progress %d, got %d", len(data), last)
+		}
+
+		var out bytes.Buffer
+		transfer.Progress = nil
+		n, err := client.DownloadChunked(ctx, "exports", "big.csv", &out, transfer)
+		if err != nil {
+			t.Fatalf("DownloadChunked failed: %v", err)
+		}
+		if n != int64(len(data)) || !bytes.Equal(out.Bytes(), data) {
+			t.Errorf("Downloaded data does not match upload (checksum %d)", checksum)
+		}
+	}
+}
+
+// failingReader returns an error once limit bytes have been read.
+type failingReader struct {
+	r     io.Reader
+	limit int
+}
+
+func (f *failingReader) Read(p []byte) (int, error) {
+	if f.limit <= 0 {
+		return 0, errors.New("connection reset")
+	}
+	if len(p) > f.limit {
+		p = p[:f.limit]
+	}
+	n, err := f.r.Read(p)
+	f.limit -= n
+	return n, err
+}
+
+// countingStore counts staged blocks.
+type countingStore struct {
+	*MockBlobClient
+	stages atomic.Int32
+}
+
+func (c *countingStore) stageBlock(ctx context.Context, container, blobName, upload, leaseID, blockID string, data []byte, algorithm ChecksumAlgorithm, sum []byte) error {
+	c.stages.Add(1)
+	return c.MockBlobClient.stageBlock(ctx, container, blobName, upload, leaseID, blockID, data, algorithm, sum)
+}
+
+func TestUploadChunked_ResumesAfterFailure(t *testing.T) {
+	mock := NewMockBlobClient()
+	store := &countingStore{MockBlobClient: mock}
+	ctx := context.Background()
+	data := testPayload(8 * 1024)
+	transfer := TransferOptions{BlockSize: 1024, Concurrency: 1, Checksum: ChecksumMD5}
+
+	_, err := uploadChunked(ctx, store, "archive", "doc.pdf", &failingReader{r: bytes.NewReader(data), limit: 5 * 1024}, UploadOptions{}, transfer)
+	if err == nil {
+		t.Fatal("Expected interrupted upload to fail")
+	}
+	if exists, _ := mock.Exists(ctx, "archive", "doc.pdf"); exists {
+		t.Fatal("Expected no committed blob after failure")
+	}
+	firstRun := store.stages.Load()
+
+	if _, err := uploadChunked(ctx, store, "archive", "doc.pdf", bytes.NewReader(data), UploadOptions{}, transfer); err != nil {
+		t.Fatalf("Resumed upload failed: %v", err)
+	}
+	if resumed := store.stages.Load() - firstRun; resumed != 8-firstRun {
+		t.Errorf("Expected only %d missing blocks to be staged, got %d", 8-firstRun, resumed)
+	}
+
+	reader, err := mock.Get(ctx, "archive", "doc.pdf")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	defer reader.Close()
+	got, _ := io.ReadAll(reader)
+	if !bytes.Equal(got, data) {
+		t.Error("Resumed blob does not match source data")
+	}
+}
+
+func TestDownloadChunked_DetectsCorruption(t *testing.T) {
+	client := NewMockBlobClient()
+	ctx := context.Background()
+	data := testPayload(4096)
+
+	for _, checksum := range []ChecksumAlgorithm{ChecksumMD5, ChecksumCRC64} {
+		if _, err := client.UploadChunked(ctx, "exports", "report.pdf", bytes.NewReader(data), UploadOptions{}, TransferOptions{BlockSize: 1024, Checksum: checksum}); err != nil {
+			t.Fatalf("UploadChunked failed: %v", err)
+		}
+
+		// Flip a byte behind the client's back
+		client.blobs["exports"]["report.pdf"].data[100] ^= 0xFF
+
+		_, err := client.DownloadChunked(ctx, "exports", "report.pdf", io.Discard, TransferOptions{BlockSize: 1024, Checksum: checksum})
+		if !errors.Is(err, ErrChecksumMismatch) {
+			t.Errorf("Expected ErrChecksumMismatch for checksum %d, got %v", checksum, err)
+		}
+	}
+}
+
+func TestStageBlock_RejectsBadChecksum(t *testing.T) {
+	client := NewMockBlobClient()
+	err := client.stageBlock(context.Background(), "c", "b", "", "", "id", []byte("data"), ChecksumCRC64, make([]byte, 8))
+	if !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("Expected ErrChecksumMismatch, got %v", err)
+	}
+}
+
+func TestMockBlobClient_GetRange(t *testing.T) {
+	client := NewMockBlobClient()
+	ctx := context.Background()
+
+	if _, err := client.Upload(ctx, "c", "b", bytes.NewReader([]byte("0123456789")), "text/plain"); err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+
+	tests := []struct {
+		offset, count int64
+		want          string
+	}{
+		{2, 3, "234"},
+		{7, 0, "789"},
+		{8, 100, "89"},
+	}
+	for _, tt := range tests {
+		reader, err := client.GetRange(ctx, "c", "b", tt.offset, tt.count)
+		if err != nil {
+			t.Fatalf("GetRange(%d, %d) failed: %v", tt.offset, tt.count, err)
+		}
+		got, _ := io.ReadAll(reader)
+		reader.Close()
+		if string(got) != tt.want {
+			t.Errorf("GetRange(%d, %d) = %q, want %q", tt.offset, tt.count, got, tt.want)
+		}
+	}
+
+	if _, err := client.GetRange(ctx, "c", "b", 10, 1); err == nil {
+		t.Error("Expected error for range past the end")
+	}
+}
+
+// overwritingWriter replaces the blob being downloaded after the first write.
+type overwritingWriter struct {
+	client BlobClient
+	writes int
+}
+
+func (w *overwritingWriter) Write(p []byte) (int, error) {
+	w.writes++
+	if w.writes == 1 {
+		if _, err := w.client.Upload(context.Background(), "exports", "report.pdf", bytes.NewReader(bytes.Repeat([]byte("v2"), 2048)), "application/pdf"); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+func TestDownloadChunked_FailsWhenOverwritten(t *testing.T) {
+	fsClient, _ := newTestFileSystemClient(t)
+	_, s3Client := newFakeS3(t)
+	for name, client := range map[string]BlobClient{"mock": NewMockBlobClient(), "filesystem": fsClient, "s3": s3Client} {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			if _, err := client.Upload(ctx, "exports", "report.pdf", bytes.NewReader(testPayload(4096)), "application/pdf"); err != nil {
+				t.Fatalf("Upload failed: %v", err)
+			}
+
+			// With one range in flight, the second range is read after the overwrite
+			w := &overwritingWriter{client: client}
+			_, err := client.DownloadChunked(ctx, "exports", "report.pdf", w, TransferOptions{BlockSize: 1024, Concurrency: 1})
+			if !errors.Is(err, ErrPreconditionFailed) {
+				t.Errorf("Expected ErrPreconditionFailed, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package blobclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	apperrors "github.com/yourorg/go-service-kit/pkg/errors"
+	"github.com/yourorg/go-service-kit/pkg/logging"
+)
+
+// azureConformanceEnv names the connection string of a storage account to run the
+// conformance suite against Azure. The suite creates and deletes blobs in the account.
+const azureConformanceEnv = "BLOBCLIENT_TEST_AZURE_CONNECTION_STRING"
+
+// expectBlobError fails the test unless err is sentinel wrapped in an AppError with code.
+func expectBlobError(t *testing.T, op string, err, sentinel error, code apperrors.ErrorCode) {
+	t.Helper()
+	if !errors.Is(err, sentinel) {
+		t.Errorf("%s: expected %v, got %v", op, sentinel, err)
+		return
+	}
+	appErr := apperrors.FromError(err)
+	if appErr.Code != code || appErr.HTTPStatus != apperrors.ToHTTPStatus(code) {
+		t.Errorf("%s: expected AppError %s/%d, got %s/%d", op, code, apperrors.ToHTTPStatus(code), appErr.Code, appErr.HTTPStatus)
+	}
+}
+
+// runConformance checks the errors every BlobClient must return.
+func runConformance(t *testing.T, client BlobClient) {
+	ctx := context.Background()
+	const container = "conformance"
+	if _, err := client.Upload(ctx, container, "present.txt", strings.NewReader("here"), "text/plain"); err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+	defer client.Delete(ctx, container, "present.txt")
+
+	t.Run("BlobNotFound", func(t *testing.T) {
+		_, err := client.Get(ctx, container, "missing.txt")
+		expectBlobError(t, "Get", err, ErrBlobNotFound, apperrors.ErrorCodeNotFound)
+		_, err = client.GetRange(ctx, container, "missing.txt", 0, 1)
+		expectBlobError(t, "GetRange", err, ErrBlobNotFound, apperrors.ErrorCodeNotFound)
+		_, err = client.GetProperties(ctx, container, "missing.txt")
+		expectBlobError(t, "GetProperties", err, ErrBlobNotFound, apperrors.ErrorCodeNotFound)
+		err = client.Delete(ctx, container, "missing.txt")
+		expectBlobError(t, "Delete", err, ErrBlobNotFound, apperrors.ErrorCodeNotFound)
+		err = client.SetMetadata(ctx, container, "missing.txt", nil, SetMetadataOptions{})
+		expectBlobError(t, "SetMetadata", err, ErrBlobNotFound, apperrors.ErrorCodeNotFound)
+		_, err = client.Copy(ctx, container, "missing.txt", container, "copy.txt")
+		expectBlobError(t, "Copy", err, ErrBlobNotFound, apperrors.ErrorCodeNotFound)
+
+		if exists, err := client.Exists(ctx, container, "missing.txt"); exists || err != nil {
+			t.Errorf("Exists: expected false, nil, got %v, %v", exists, err)
+		}
+	})
+
+	t.Run("ContainerNotFound", func(t *testing.T) {
+		_, err := client.Get(ctx, "conformance-missing", "missing.txt")
+		expectBlobError(t, "Get", err, ErrContainerNotFound, apperrors.ErrorCodeNotFound)
+
+		if exists, err := client.Exists(ctx, "conformance-missing", "missing.txt"); exists || err != nil {
+			t.Errorf("Exists: expected false, nil, got %v, %v", exists, err)
+		}
+	})
+
+	t.Run("PreconditionFailed", func(t *testing.T) {
+		_, err := client.UploadWithOptions(ctx, container, "present.txt", strings.NewReader("again"), UploadOptions{ContentType: "text/plain", IfNoneMatch: "*"})
+		expectBlobError(t, "Upload IfNoneMatch", err, ErrPreconditionFailed, apperrors.ErrorCodeConflict)
+
+		stale := `"0x1"`
+		_, err = client.UploadWithOptions(ctx, container, "present.txt", strings.NewReader("again"), UploadOptions{ContentType: "text/plain", IfMatch: stale})
+		expectBlobError(t, "Upload IfMatch", err, ErrPreconditionFailed, apperrors.ErrorCodeConflict)
+		err = client.DeleteWithOptions(ctx, container, "present.txt", DeleteOptions{IfMatch: stale})
+		expectBlobError(t, "Delete IfMatch", err, ErrPreconditionFailed, apperrors.ErrorCodeConflict)
+		err = client.SetMetadata(ctx, container, "present.txt", map[string]string{"a": "b"}, SetMetadataOptions{IfMatch: stale})
+		expectBlobError(t, "SetMetadata IfMatch", err, ErrPreconditionFailed, apperrors.ErrorCodeConflict)
+		_, err = client.GetRangeWithOptions(ctx, container, "present.txt", 0, 2, GetOptions{IfMatch: stale})
+		expectBlobError(t, "GetRange IfMatch", err, ErrPreconditionFailed, apperrors.ErrorCodeConflict)
+	})
+
+	t.Run("LeaseConflict", func(t *testing.T) {
+		leaseID, err := client.AcquireLease(ctx, container, "present.txt", MinLeaseDuration, "")
+		if errors.Is(err, ErrNotSupported) {
+			t.Skip("leases not supported")
+		}
+		if err != nil {
+			t.Fatalf("AcquireLease failed: %v", err)
+		}
+		defer client.ReleaseLease(ctx, container, "present.txt", leaseID)
+
+		_, err = client.AcquireLease(ctx, container, "present.txt", MinLeaseDuration, "")
+		expectBlobError(t, "AcquireLease", err, ErrConflict, apperrors.ErrorCodeConflict)
+		_, err = client.Upload(ctx, container, "present.txt", strings.NewReader("again"), "text/plain")
+		expectBlobError(t, "Upload without lease", err, ErrPreconditionFailed, apperrors.ErrorCodeConflict)
+	})
+}
+
+func TestConformance(t *testing.T) {
+	_, s3Client := newFakeS3(t)
+	fsClient, _ := newTestFileSystemClient(t)
+
+	t.Run("mock", func(t *testing.T) { runConformance(t, NewMockBlobClient()) })
+	t.Run("filesystem", func(t *testing.T) { runConformance(t, fsClient) })
+	t.Run("s3", func(t *testing.T) { runConformance(t, s3Client) })
+	t.Run("resilient", func(t *testing.T) {
+		logger, _ := logging.NewLogger("error", "json")
+		client, err := NewResilientBlobClient(NewMockBlobClient(), DefaultResilienceConfig(), logger)
+		if err != nil {
+			t.Fatalf("NewResilientBlobClient failed: %v", err)
+		}
+		runConformance(t, client)
+	})
+	t.Run("azure", func(t *testing.T) {
+		connectionString := os.Getenv(azureConformanceEnv)
+		if connectionString == "" {
+			t.Skip(azureConformanceEnv + " not set")
+		}
+		logger, _ := logging.NewLogger("error", "json")
+		client, err := NewAzureBlobClientFromConnectionString(connectionString, logger)
+		if err != nil {
+			t.Fatalf("NewAzureBlobClientFromConnectionString failed: %v", err)
+		}
+		runConformance(t, client)
+	})
+}
+
+func TestAzureError(t *testing.T) {
+	tests := []struct {
+		status   int
+		code     string
+		sentinel error
+	}{
+		{http.StatusNotFound, "BlobNotFound", ErrBlobNotFound},
+		{http.StatusNotFound, "ContainerNotFound", ErrContainerNotFound},
+		{http.StatusNotFound, "CannotVerifyCopySource", ErrBlobNotFound},
+		{http.StatusConflict, "BlobAlreadyExists", ErrPreconditionFailed},
+		{http.StatusPreconditionFailed, "ConditionNotMet", ErrPreconditionFailed},
+		{http.StatusConflict, "LeaseAlreadyPresent", ErrConflict},
+		{http.StatusForbidden, "AuthorizationFailure", ErrAccessDenied},
+		{http.StatusServiceUnavailable, "ServerBusy", ErrThrottled},
+	}
+	for _, tt := range tests {
+		cause := &azcore.ResponseError{StatusCode: tt.status, ErrorCode: tt.code}
+		err := azureError(cause, "failed", "docs", "a.txt")
+		if !errors.Is(err, tt.sentinel) {
+			t.Errorf("%s: expected %v, got %v", tt.code, tt.sentinel, err)
+		}
+		var respErr *azcore.ResponseError
+		if !errors.As(err, &respErr) {
+			t.Errorf("%s: expected the response error to stay reachable", tt.code)
+		}
+	}
+
+	err := azureError(&azcore.ResponseError{StatusCode: http.StatusInternalServerError, ErrorCode: "InternalError"}, "failed", "docs", "a.txt")
+	if apperrors.FromError(err).Code != apperrors.ErrorCodeInternal {
+		t.Errorf("Expected unmapped errors to stay internal, got %v", err)
+	}
+}
+
+func TestS3BlobError(t *testing.T) {
+	tests := []struct {
+		status   int
+		code     string
+		sentinel error
+	}{
+		{http.StatusNotFound, "NoSuchKey", ErrBlobNotFound},
+		{http.StatusNotFound, "NoSuchBucket", ErrContainerNotFound},
+		{http.StatusNotFound, "", ErrBlobNotFound},
+		{http.StatusPreconditionFailed, "PreconditionFailed", ErrPreconditionFailed},
+		{http.StatusConflict, "ConditionalRequestConflict", ErrConflict},
+		{http.StatusForbidden, "AccessDenied", ErrAccessDenied},
+		{http.StatusServiceUnavailable, "SlowDown", ErrThrottled},
+		{http.StatusTooManyRequests, "", ErrThrottled},
+	}
+	for _, tt := range tests {
+		err := s3BlobError(&s3Error{StatusCode: tt.status, Code: tt.code}, "failed", "docs", "a.txt")
+		if !errors.Is(err, tt.sentinel) {
+			t.Errorf("%d %s: expected %v, got %v", tt.status, tt.code, tt.sentinel, err)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package blobclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Copy states reported in CopyStatus.Status.
+const (
+	CopyStatusPending = "pending"
+	CopyStatusSuccess = "success"
+	CopyStatusAborted = "aborted"
+	CopyStatusFailed  = "failed"
+)
+
+// DefaultCopyPollInterval is the interval at which Move polls the status of a pending copy.
+const DefaultCopyPollInterval = 2 * time.Second
+
+// snapshotIDFormat is the timestamp format of Azure snapshot IDs, also used by the
+// mock and file system clients.
+const snapshotIDFormat = "2006-01-02T15:04:05.0000000Z"
+
+// ErrNotSupported is returned for operations a storage backend cannot provide.
+var ErrNotSupported = errors.New("operation not supported by blob backend")
+
+// CopyStatus describes a server-side copy.
+type CopyStatus struct {
+	ID          string
+	Status      string // CopyStatusPending, CopyStatusSuccess, CopyStatusAborted or CopyStatusFailed
+	BytesCopied int64
+	TotalBytes  int64
+	Description string // reason for a failed or aborted copy
+}
+
+// WaitForCopy polls GetCopyStatus every pollInterval until the copy into a blob completes.
+// It returns an error if the copy fails, is aborted or ctx is done.
+func WaitForCopy(ctx context.Context, client BlobClient, container, blobName string, pollInterval time.Duration) (*CopyStatus, error) {
+	if pollInterval <= 0 {
+		pollInterval = DefaultCopyPollInterval
+	}
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		status, err := client.GetCopyStatus(ctx, container, blobName)
+		if err != nil {
+			return nil, err
+		}
+		if err := copyError(status); err != nil || status.Status != CopyStatusPending {
+			return status, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return status, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// copyError returns an error for a failed or aborted copy.
+func copyError(status *CopyStatus) error {
+	switch status.Status {
+	case CopyStatusFailed, CopyStatusAborted:
+		return fmt.Errorf("copy %s %s: %s", status.ID, status.Status, status.Description)
+	default:
+		return nil
+	}
+}
+
+// moveBlob implements Move on top of Copy, WaitForCopy and Delete.
+func moveBlob(ctx context.Context, client BlobClient, pollInterval time.Duration, srcContainer, srcBlob, dstContainer, dstBlob string) error {
+	if srcContainer == dstContainer && srcBlob == dstBlob {
+		return fmt.Errorf("cannot move blob onto itself: %s/%s", srcContainer, srcBlob)
+	}
+
+	status, err := client.Copy(ctx, srcContainer, srcBlob, dstContainer, dstBlob)
+	if err != nil {
+		return err
+	}
+	if status.Status == CopyStatusPending {
+		status, err = WaitForCopy(ctx, client, dstContainer, dstBlob, pollInterval)
+	} else {
+		err = copyError(status)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to copy blob: %w", err)
+	}
+
+	if err := client.Delete(ctx, srcContainer, srcBlob); err != nil {
+		return fmt.Errorf("failed to delete source blob: %w", err)
+	}
+	return nil
+}
+
+// newSnapshotID returns a timestamp snapshot ID for which taken returns false.
+func newSnapshotID(taken func(id string) bool) string {
+	for t := time.Now().UTC(); ; t = t.Add(100 * time.Nanosecond) {
+		if id := t.Format(snapshotIDFormat); !taken(id) {
+			return id
+		}
+	}
+}
@@ -0,0 +1,251 @@
+package blobclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yourorg/go-service-kit/pkg/tenant"
+)
+
+// readBlob returns the content of a blob, failing the test on error.
+func readBlob(t *testing.T, client BlobClient, container, blobName string) string {
+	t.Helper()
+	reader, err := client.Get(context.Background(), container, blobName)
+	if err != nil {
+		t.Fatalf("Get %s/%s failed: %v", container, blobName, err)
+	}
+	defer reader.Close()
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("Read %s/%s failed: %v", container, blobName, err)
+	}
+	return string(data)
+}
+
+func TestCopyAndMove(t *testing.T) {
+	_, s3Client := newFakeS3(t)
+	fsClient, _ := newTestFileSystemClient(t)
+	clients := map[string]BlobClient{
+		"mock":       NewMockBlobClient(),
+		"filesystem": fsClient,
+		"s3":         s3Client,
+	}
+
+	ctx := context.Background()
+	for name, client := range clients {
+		_, err := client.UploadWithOptions(ctx, "incoming", "report.csv", strings.NewReader("a,b\n1,2\n"), UploadOptions{
+			ContentType: "text/csv",
+			Metadata:    map[string]string{"source": "upload"},
+		})
+		if err != nil {
+			t.Fatalf("%s: Upload failed: %v", name, err)
+		}
+
+		status, err := client.Copy(ctx, "incoming", "report.csv", "backup", "report-copy.csv")
+		if err != nil {
+			t.Fatalf("%s: Copy failed: %v", name, err)
+		}
+		if status.Status != CopyStatusSuccess || status.BytesCopied != 8 || status.TotalBytes != 8 {
+			t.Errorf("%s: expected completed copy of 8 bytes, got %+v", name, status)
+		}
+		if got := readBlob(t, client, "backup", "report-copy.csv"); got != "a,b\n1,2\n" {
+			t.Errorf("%s: copied content = %q", name, got)
+		}
+		props, err := client.GetProperties(ctx, "backup", "report-copy.csv")
+		if err != nil {
+			t.Fatalf("%s: GetProperties failed: %v", name, err)
+		}
+		if props.ContentType != "text/csv" || props.Metadata["source"] != "upload" {
+			t.Errorf("%s: expected properties to be copied, got %+v", name, props)
+		}
+
+		url, err := client.Move(ctx, "incoming", "report.csv", "archive", "2024/report.csv")
+		if err != nil {
+			t.Fatalf("%s: Move failed: %v", name, err)
+		}
+		if !strings.Contains(url, "archive") || !strings.HasSuffix(url, "2024/report.csv") {
+			t.Errorf("%s: unexpected moved URL %q", name, url)
+		}
+		if exists, _ := client.Exists(ctx, "incoming", "report.csv"); exists {
+			t.Errorf("%s: expected source to be deleted after move", name)
+		}
+		if got := readBlob(t, client, "archive", "2024/report.csv"); got != "a,b\n1,2\n" {
+			t.Errorf("%s: moved content = %q", name, got)
+		}
+
+		if _, err := client.Copy(ctx, "incoming", "missing.csv", "backup", "missing.csv"); err == nil {
+			t.Errorf("%s: expected error copying a missing blob", name)
+		}
+		if _, err := client.Move(ctx, "archive", "2024/report.csv", "archive", "2024/report.csv"); err == nil {
+			t.Errorf("%s: expected error moving a blob onto itself", name)
+		}
+	}
+}
+
+func TestMockBlobClient_AsyncCopy(t *testing.T) {
+	client := NewMockBlobClient()
+	ctx := context.Background()
+	client.Upload(ctx, "docs", "big.bin", strings.NewReader("0123456789"), "application/octet-stream")
+	client.SimulateAsyncCopies(2)
+
+	status, err := client.Copy(ctx, "docs", "big.bin", "docs", "big-copy.bin")
+	if err != nil {
+		t.Fatalf("Copy failed: %v", err)
+	}
+	if status.Status != CopyStatusPending || status.ID == "" {
+		t.Fatalf("Expected pending copy with ID, got %+v", status)
+	}
+	if exists, _ := client.Exists(ctx, "docs", "big-copy.bin"); exists {
+		t.Error("Expected destination to be absent while the copy is pending")
+	}
+
+	status, err = WaitForCopy(ctx, client, "docs", "big-copy.bin", time.Millisecond)
+	if err != nil {
+		t.Fatalf("WaitForCopy failed: %v", err)
+	}
+	if status.Status != CopyStatusSuccess || status.BytesCopied != 10 {
+		t.Errorf("Expected completed copy, got %+v", status)
+	}
+	if got := readBlob(t, client, "docs", "big-copy.bin"); got != "0123456789" {
+		t.Errorf("Copied content = %q", got)
+	}
+
+	// Move waits for pending copies before deleting the source
+	if _, err := client.Move(ctx, "docs", "big.bin", "archive", "big.bin"); err != nil {
+		t.Fatalf("Move failed: %v", err)
+	}
+	if exists, _ := client.Exists(ctx, "archive", "big.bin"); !exists {
+		t.Error("Expected moved blob to exist")
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	client.Copy(ctx, "archive", "big.bin", "docs", "again.bin")
+	if _, err := WaitForCopy(canceled, client, "docs", "again.bin", time.Hour); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
+
+func TestSnapshots(t *testing.T) {
+	fsClient, _ := newTestFileSystemClient(t)
+	clients := map[string]BlobClient{
+		"mock":       NewMockBlobClient(),
+		"filesystem": fsClient,
+	}
+
+	ctx := context.Background()
+	for name, client := range clients {
+		client.Upload(ctx, "docs", "contract.txt", strings.NewReader("v1"), "text/plain")
+		first, err := client.Snapshot(ctx, "docs", "contract.txt")
+		if err != nil {
+			t.Fatalf("%s: Snapshot failed: %v", name, err)
+		}
+		client.Upload(ctx, "docs", "contract.txt", strings.NewReader("version 2"), "text/plain")
+		second, err := client.Snapshot(ctx, "docs", "contract.txt")
+		if err != nil {
+			t.Fatalf("%s: Snapshot failed: %v", name, err)
+		}
+		if first == second {
+			t.Errorf("%s: expected unique snapshot IDs, got %q twice", name, first)
+		}
+
+		snapshots, err := client.ListSnapshots(ctx, "docs", "contract.txt")
+		if err != nil {
+			t.Fatalf("%s: ListSnapshots failed: %v", name, err)
+		}
+		if len(snapshots) != 2 || snapshots[0].Snapshot != first || snapshots[1].Snapshot != second {
+			t.Fatalf("%s: expected snapshots [%s %s], got %+v", name, first, second, snapshots)
+		}
+		if snapshots[0].Size != 2 || snapshots[1].Size != 9 || snapshots[0].Name != "contract.txt" {
+			t.Errorf("%s: unexpected snapshot info %+v", name, snapshots)
+		}
+
+		if _, err := client.Snapshot(ctx, "docs", "missing.txt"); err == nil {
+			t.Errorf("%s: expected error snapshotting a missing blob", name)
+		}
+		if snapshots, _ := client.ListSnapshots(ctx, "docs", "other.txt"); len(snapshots) != 0 {
+			t.Errorf("%s: expected no snapshots for another blob, got %+v", name, snapshots)
+		}
+
+		// Deleting a blob deletes its snapshots
+		if err := client.Delete(ctx, "docs", "contract.txt"); err != nil {
+			t.Fatalf("%s: Delete failed: %v", name, err)
+		}
+		if snapshots, _ := client.ListSnapshots(ctx, "docs", "contract.txt"); len(snapshots) != 0 {
+			t.Errorf("%s: expected snapshots to be deleted, got %+v", name, snapshots)
+		}
+	}
+}
+
+func TestMockBlobClient_Undelete(t *testing.T) {
+	client := NewMockBlobClient()
+	ctx := context.Background()
+	client.Upload(ctx, "docs", "notes.txt", strings.NewReader("keep me"), "text/plain")
+	client.Snapshot(ctx, "docs", "notes.txt")
+
+	if err := client.Delete(ctx, "docs", "notes.txt"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := client.Undelete(ctx, "docs", "notes.txt"); err != nil {
+		t.Fatalf("Undelete failed: %v", err)
+	}
+	if got := readBlob(t, client, "docs", "notes.txt"); got != "keep me" {
+		t.Errorf("Restored content = %q", got)
+	}
+	if snapshots, _ := client.ListSnapshots(ctx, "docs", "notes.txt"); len(snapshots) != 1 {
+		t.Errorf("Expected snapshot to be restored, got %+v", snapshots)
+	}
+
+	if err := client.Undelete(ctx, "docs", "never-existed.txt"); err == nil {
+		t.Error("Expected error undeleting a blob that was never deleted")
+	}
+}
+
+func TestUnsupportedOperations(t *testing.T) {
+	_, s3Client := newFakeS3(t)
+	fsClient, _ := newTestFileSystemClient(t)
+	ctx := context.Background()
+
+	if err := fsClient.Undelete(ctx, "docs", "a.txt"); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("filesystem Undelete: expected ErrNotSupported, got %v", err)
+	}
+	if _, err := s3Client.Snapshot(ctx, "docs", "a.txt"); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("s3 Snapshot: expected ErrNotSupported, got %v", err)
+	}
+	if _, err := s3Client.ListSnapshots(ctx, "docs", "a.txt"); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("s3 ListSnapshots: expected ErrNotSupported, got %v", err)
+	}
+	if err := s3Client.Undelete(ctx, "docs", "a.txt"); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("s3 Undelete: expected ErrNotSupported, got %v", err)
+	}
+}
+
+func TestTenantBlobClient_CopyAndSnapshots(t *testing.T) {
+	mock := NewMockBlobClient()
+	client := NewTenantBlobClient(mock)
+	acme := tenant.WithTenant(context.Background(), "acme")
+
+	client.Upload(acme, "docs", "in.txt", strings.NewReader("acme"), "text/plain")
+	if _, err := client.Move(acme, "docs", "in.txt", "archive", "out.txt"); err != nil {
+		t.Fatalf("Move failed: %v", err)
+	}
+	if exists, _ := mock.Exists(context.Background(), "archive", "tenants/acme/out.txt"); !exists {
+		t.Error("Expected moved blob under tenant prefix")
+	}
+
+	if _, err := client.Snapshot(acme, "archive", "out.txt"); err != nil {
+		t.Fatalf("Snapshot failed: %v", err)
+	}
+	snapshots, err := client.ListSnapshots(acme, "archive", "out.txt")
+	if err != nil || len(snapshots) != 1 || snapshots[0].Name != "out.txt" {
+		t.Errorf("Expected one snapshot named out.txt, got %+v (err %v)", snapshots, err)
+	}
+
+	if _, err := client.Copy(context.Background(), "archive", "out.txt", "docs", "x.txt"); err != tenant.ErrMissingTenant {
+		t.Errorf("Expected ErrMissingTenant, got %v", err)
+	}
+}
@@ -0,0 +1,301 @@
+package blobclient
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/yourorg/go-service-kit/pkg/logging"
+)
+
+// Blob name prefixes used by a DedupStore in its container.
+const (
+	DedupContentPrefix = "sha256/" // content, named by the hex SHA-256 of its data
+	DedupRefsPrefix    = "refs/"   // name pointers of a MetadataDedupIndex
+)
+
+// DefaultDedupGracePeriod is how long unreferenced content is kept before garbage collection.
+const DefaultDedupGracePeriod = time.Hour
+
+// DedupIndex maps names to content hashes and counts the names referencing each hash.
+type DedupIndex interface {
+	// Lookup returns the hash name points at, or ErrBlobNotFound.
+	Lookup(ctx context.Context, name string) (string, error)
+	// Link points name at hash and returns the hash it pointed at before, if any.
+	Link(ctx context.Context, name, hash string) (previous string, err error)
+	// Unlink removes name and returns the hash it pointed at, or ErrBlobNotFound.
+	Unlink(ctx context.Context, name string) (string, error)
+	// RefCount returns the number of names pointing at hash.
+	RefCount(ctx context.Context, hash string) (int, error)
+}
+
+// DedupResult describes content stored by DedupStore.Put.
+type DedupResult struct {
+	Name         string
+	Hash         string // hex SHA-256 of the content
+	Size         int64
+	URL          string // URL of the content blob, shared by all names with the same content
+	Deduplicated bool   // the content was already stored
+}
+
+// DedupOption configures a DedupStore.
+type DedupOption func(*DedupStore)
+
+// WithDedupGracePeriod sets how long unreferenced content survives garbage collection
+// (default: DefaultDedupGracePeriod). It must exceed the time a Put takes, since content
+// is stored before the name is linked to it.
+func WithDedupGracePeriod(period time.Duration) DedupOption {
+	return func(d *DedupStore) {
+		d.gracePeriod = period
+	}
+}
+
+// DedupStore is a content-addressed layer over a BlobClient: content is stored once under
+// its SHA-256 in a container, and names are mapped to content through a reference-counted
+// DedupIndex. Storing content that already exists only adds a reference.
+type DedupStore struct {
+	client      BlobClient
+	container   string
+	index       DedupIndex
+	logger      logging.Logger
+	gracePeriod time.Duration
+}
+
+// NewDedupStore creates a store keeping content in container and names in index.
+func NewDedupStore(client BlobClient, container string, index DedupIndex, logger logging.Logger, opts ...DedupOption) (*DedupStore, error) {
+	if client == nil {
+		return nil, fmt.Errorf("blob client is required")
+	}
+	if index == nil {
+		return nil, fmt.Errorf("dedup index is required")
+	}
+	if logger == nil {
+		return nil, fmt.Errorf("logger is required")
+	}
+	if container == "" {
+		return nil, fmt.Errorf("container is required")
+	}
+
+	d := &DedupStore{
+		client:      client,
+		container:   container,
+		index:       index,
+		logger:      logger.With(logging.NewField("operation", "blob.dedup"), logging.NewField("container", container)),
+		gracePeriod: DefaultDedupGracePeriod,
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d, nil
+}
+
+// Put stores data under name. If the same content is already stored, only a reference is
+// added and the URL of the existing content is returned. Replacing a name releases its
+// reference to the previous content.
+func (d *DedupStore) Put(ctx context.Context, name string, data io.Reader, contentType string) (*DedupResult, error) {
+	if name == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+
+	content, hash, size, cleanup, err := hashContent(data)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	result := &DedupResult{Name: name, Hash: hash, Size: size}
+	result.URL, result.Deduplicated, err = d.storeContent(ctx, hash, content, contentType)
+	if err != nil {
+		return nil, err
+	}
+
+	previous, err := d.index.Link(ctx, name, hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to link %s: %w", name, err)
+	}
+
+	d.logger.Debug("Stored content",
+		logging.NewField("name", name),
+		logging.NewField("hash", hash),
+		logging.NewField("deduplicated", result.Deduplicated),
+		logging.NewField("previous", previous),
+	)
+	return result, nil
+}
+
+// storeContent uploads content unless it exists. Existing content is touched, which gives
+// it a new ETag and modification time, so a concurrent garbage collection cannot delete it
+// before it is linked.
+func (d *DedupStore) storeContent(ctx context.Context, hash string, content io.ReadSeeker, contentType string) (string, bool, error) {
+	blobName := DedupContentPrefix + hash
+	props, err := d.client.GetProperties(ctx, d.container, blobName)
+	if err == nil {
+		err = d.client.SetMetadata(ctx, d.container, blobName, props.Metadata, SetMetadataOptions{IfMatch: props.ETag})
+		// A precondition failure means someone else touched it at the same time
+		if err == nil || errors.Is(err, ErrPreconditionFailed) {
+			return props.URL, true, nil
+		}
+	}
+	if !errors.Is(err, ErrBlobNotFound) && !errors.Is(err, ErrContainerNotFound) {
+		return "", false, fmt.Errorf("failed to check content %s: %w", hash, err)
+	}
+
+	url, err := d.client.UploadWithOptions(ctx, d.container, blobName, content, UploadOptions{ContentType: contentType, IfNoneMatch: "*"})
+	if errors.Is(err, ErrPreconditionFailed) {
+		// Uploaded concurrently with the same content
+		props, err := d.client.GetProperties(ctx, d.container, blobName)
+		if err != nil {
+			return "", false, fmt.Errorf("failed to check content %s: %w", hash, err)
+		}
+		return props.URL, true, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("failed to upload content %s: %w", hash, err)
+	}
+	return url, false, nil
+}
+
+// Get opens the content stored under name.
+func (d *DedupStore) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	hash, err := d.index.Lookup(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return d.client.Get(ctx, d.container, DedupContentPrefix+hash)
+}
+
+// Stat returns the hash and URL of the content stored under name.
+func (d *DedupStore) Stat(ctx context.Context, name string) (*DedupResult, error) {
+	hash, err := d.index.Lookup(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	props, err := d.client.GetProperties(ctx, d.container, DedupContentPrefix+hash)
+	if err != nil {
+		return nil, err
+	}
+	return &DedupResult{Name: name, Hash: hash, Size: props.Size, URL: props.URL, Deduplicated: true}, nil
+}
+
+// Delete removes name. Its content is deleted by GarbageCollect once no name references it.
+func (d *DedupStore) Delete(ctx context.Context, name string) error {
+	_, err := d.index.Unlink(ctx, name)
+	return err
+}
+
+// StartGarbageCollector runs GarbageCollect every interval in the background until ctx is
+// cancelled. A zero interval disables it.
+func (d *DedupStore) StartGarbageCollector(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := d.GarbageCollect(ctx); err != nil && ctx.Err() == nil {
+					d.logger.Error("Dedup garbage collection failed", logging.NewField("error", err))
+				}
+			}
+		}
+	}()
+}
+
+// GarbageCollect deletes content that no name references and that was not modified within
+// the grace period, and returns the number of deleted blobs.
+func (d *DedupStore) GarbageCollect(ctx context.Context) (int, error) {
+	blobs, err := d.client.List(ctx, d.container, DedupContentPrefix)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list content: %w", err)
+	}
+
+	now := time.Now()
+	deleted := 0
+	var errs []error
+	for _, blob := range blobs {
+		if err := ctx.Err(); err != nil {
+			return deleted, err
+		}
+		modified, err := time.Parse(time.RFC3339, blob.LastModified)
+		if err != nil || now.Sub(modified) < d.gracePeriod {
+			continue
+		}
+
+		hash := strings.TrimPrefix(blob.Name, DedupContentPrefix)
+		refs, err := d.index.RefCount(ctx, hash)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to count references to %s: %w", hash, err))
+			continue
+		}
+		if refs > 0 {
+			continue
+		}
+
+		// The ETag changes if a Put touched the content since it was listed
+		err = d.client.DeleteWithOptions(ctx, d.container, blob.Name, DeleteOptions{IfMatch: blob.ETag})
+		switch {
+		case err == nil:
+			deleted++
+		case errors.Is(err, ErrPreconditionFailed), errors.Is(err, ErrBlobNotFound):
+		default:
+			errs = append(errs, fmt.Errorf("failed to delete content %s: %w", hash, err))
+		}
+	}
+
+	d.logger.Info("Dedup garbage collection completed",
+		logging.NewField("scanned", len(blobs)),
+		logging.NewField("deleted", deleted),
+		logging.NewField("failed", len(errs)),
+	)
+	return deleted, errors.Join(errs...)
+}
+
+// hashContent returns the SHA-256 and size of data and a reader positioned at its start.
+// Data that cannot seek is spooled to a temporary file, removed by cleanup.
+func hashContent(data io.Reader) (io.ReadSeeker, string, int64, func(), error) {
+	hasher := sha256.New()
+	if seeker, ok := data.(io.ReadSeeker); ok {
+		start, err := seeker.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, "", 0, nil, fmt.Errorf("failed to hash content: %w", err)
+		}
+		size, err := io.Copy(hasher, seeker)
+		if err != nil {
+			return nil, "", 0, nil, fmt.Errorf("failed to hash content: %w", err)
+		}
+		if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+			return nil, "", 0, nil, fmt.Errorf("failed to hash content: %w", err)
+		}
+		return seeker, hex.EncodeToString(hasher.Sum(nil)), size, func() {}, nil
+	}
+
+	tmp, err := os.CreateTemp("", "dedup-*")
+	if err != nil {
+		return nil, "", 0, nil, fmt.Errorf("failed to spool content: %w", err)
+	}
+	cleanup := func() {
+		tmp.Close()
+		os.Remove(tmp.Name())
+	}
+	size, err := io.Copy(io.MultiWriter(tmp, hasher), data)
+	if err == nil {
+		_, err = tmp.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		cleanup()
+		return nil, "", 0, nil, fmt.Errorf("failed to spool content: %w", err)
+	}
+	return tmp, hex.EncodeToString(hasher.Sum(nil)), size, cleanup, nil
+}
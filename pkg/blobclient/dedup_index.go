@@ -0,0 +1,236 @@
+package blobclient
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/yourorg/go-service-kit/pkg/db"
+)
+
+// Metadata keys of a MetadataDedupIndex.
+const (
+	dedupHashMetadataKey     = "sha256"   // on name pointers: the content hash
+	dedupRefCountMetadataKey = "refcount" // on content: the number of names pointing at it
+)
+
+// dedupMaxAttempts bounds the optimistic concurrency retries of a MetadataDedupIndex.
+const dedupMaxAttempts = 10
+
+// MetadataDedupIndex is a DedupIndex kept in blob storage itself: every name is an empty
+// pointer blob under DedupRefsPrefix whose metadata holds the content hash, and the
+// content blob's metadata holds its reference count. Updates use ETag conditions, so
+// concurrent writers never lose a reference; a crash between steps can leave a count too
+// high, which only delays garbage collection.
+type MetadataDedupIndex struct {
+	client    BlobClient
+	container string
+}
+
+// NewMetadataDedupIndex creates an index in the container of the DedupStore using it.
+func NewMetadataDedupIndex(client BlobClient, container string) *MetadataDedupIndex {
+	return &MetadataDedupIndex{client: client, container: container}
+}
+
+// Lookup returns the hash name points at.
+func (m *MetadataDedupIndex) Lookup(ctx context.Context, name string) (string, error) {
+	hash, _, err := m.pointer(ctx, name)
+	return hash, err
+}
+
+// Link points name at hash, adding a reference to hash before releasing the previous one.
+func (m *MetadataDedupIndex) Link(ctx context.Context, name, hash string) (string, error) {
+	if err := m.addRef(ctx, hash, 1); err != nil {
+		return "", err
+	}
+
+	err := blobError(ErrConflict, "failed to update name pointer "+name, nil)
+	for attempt := 0; attempt < dedupMaxAttempts; attempt++ {
+		var previous, etag string
+		previous, etag, err = m.pointer(ctx, name)
+		if err != nil && !errors.Is(err, ErrBlobNotFound) {
+			break
+		}
+
+		opts := UploadOptions{Metadata: map[string]string{dedupHashMetadataKey: hash}, IfMatch: etag}
+		if etag == "" {
+			opts.IfNoneMatch = "*"
+		}
+		_, err = m.client.UploadWithOptions(ctx, m.container, DedupRefsPrefix+name, bytes.NewReader(nil), opts)
+		if errors.Is(err, ErrPreconditionFailed) {
+			err = blobError(ErrConflict, "failed to update name pointer "+name, err)
+			continue
+		}
+		if err != nil {
+			break
+		}
+
+		if previous != "" {
+			if err := m.addRef(ctx, previous, -1); err != nil {
+				return previous, fmt.Errorf("failed to release reference to %s: %w", previous, err)
+			}
+		}
+		return previous, nil
+	}
+
+	// Give back the reference taken above
+	m.addRef(ctx, hash, -1)
+	return "", err
+}
+
+// Unlink removes name and releases its reference.
+func (m *MetadataDedupIndex) Unlink(ctx context.Context, name string) (string, error) {
+	for attempt := 0; attempt < dedupMaxAttempts; attempt++ {
+		hash, etag, err := m.pointer(ctx, name)
+		if err != nil {
+			return "", err
+		}
+		err = m.client.DeleteWithOptions(ctx, m.container, DedupRefsPrefix+name, DeleteOptions{IfMatch: etag})
+		if errors.Is(err, ErrPreconditionFailed) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		return hash, m.addRef(ctx, hash, -1)
+	}
+	return "", blobError(ErrConflict, "failed to remove name pointer "+name, nil)
+}
+
+// RefCount returns the reference count stored on the content; missing content has none.
+func (m *MetadataDedupIndex) RefCount(ctx context.Context, hash string) (int, error) {
+	props, err := m.client.GetProperties(ctx, m.container, DedupContentPrefix+hash)
+	if errors.Is(err, ErrBlobNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return parseRefCount(props.Metadata), nil
+}
+
+// pointer returns the hash and ETag of the pointer blob of name.
+func (m *MetadataDedupIndex) pointer(ctx context.Context, name string) (string, string, error) {
+	props, err := m.client.GetProperties(ctx, m.container, DedupRefsPrefix+name)
+	if err != nil {
+		return "", "", err
+	}
+	return props.Metadata[dedupHashMetadataKey], props.ETag, nil
+}
+
+// addRef changes the reference count of content by delta.
+func (m *MetadataDedupIndex) addRef(ctx context.Context, hash string, delta int) error {
+	blobName := DedupContentPrefix + hash
+	for attempt := 0; attempt < dedupMaxAttempts; attempt++ {
+		props, err := m.client.GetProperties(ctx, m.container, blobName)
+		if err != nil {
+			if delta < 0 && errors.Is(err, ErrBlobNotFound) {
+				return nil
+			}
+			return err
+		}
+
+		metadata := copyStringMap(props.Metadata)
+		metadata[dedupRefCountMetadataKey] = strconv.Itoa(max(parseRefCount(props.Metadata)+delta, 0))
+		err = m.client.SetMetadata(ctx, m.container, blobName, metadata, SetMetadataOptions{IfMatch: props.ETag})
+		if !errors.Is(err, ErrPreconditionFailed) {
+			return err
+		}
+	}
+	return blobError(ErrConflict, "failed to update reference count of "+hash, nil)
+}
+
+// parseRefCount reads the reference count from content metadata.
+func parseRefCount(metadata map[string]string) int {
+	refs, _ := strconv.Atoi(metadata[dedupRefCountMetadataKey])
+	return refs
+}
+
+// DefaultDedupTable is the table of a PostgresDedupIndex.
+const DefaultDedupTable = "blob_dedup_refs"
+
+// DedupMigrations create the DefaultDedupTable of a PostgresDedupIndex. Apply them with a
+// db.Migrator using Dir "migrations" and Table DedupMigrationsTable, as their versions
+// are numbered independently of other packages' migrations.
+//
+//go:embed migrations/*.sql
+var DedupMigrations embed.FS
+
+// DedupMigrationsTable records the applied DedupMigrations.
+const DedupMigrationsTable = "blob_dedup_schema_migrations"
+
+// PostgresDedupIndex is a DedupIndex in a Postgres table with one row per name. Reference
+// counts are the number of rows per hash, so they cannot drift from the names. The table
+// (name TEXT PRIMARY KEY, hash TEXT, updated_at TIMESTAMPTZ, indexed by hash) is created
+// by DedupMigrations.
+type PostgresDedupIndex struct {
+	db    db.DB
+	table string
+}
+
+// NewPostgresDedupIndex creates an index in table (default: DefaultDedupTable).
+func NewPostgresDedupIndex(database db.DB, table string) (*PostgresDedupIndex, error) {
+	if database == nil {
+		return nil, fmt.Errorf("database is required")
+	}
+	if table == "" {
+		table = DefaultDedupTable
+	}
+	if err := db.ValidateTableName(table); err != nil {
+		return nil, fmt.Errorf("invalid dedup table: %w", err)
+	}
+	return &PostgresDedupIndex{db: database, table: table}, nil
+}
+
+// Lookup returns the hash name points at.
+func (p *PostgresDedupIndex) Lookup(ctx context.Context, name string) (string, error) {
+	var hash string
+	err := p.db.QueryRow(ctx, fmt.Sprintf("SELECT hash FROM %s WHERE name = $1", p.table), name).Scan(&hash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", blobError(ErrBlobNotFound, "name not found: "+name, nil)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to look up %s: %w", name, err)
+	}
+	return hash, nil
+}
+
+// Link points name at hash in a single statement.
+func (p *PostgresDedupIndex) Link(ctx context.Context, name, hash string) (string, error) {
+	var previous sql.NullString
+	err := p.db.QueryRow(db.WithPrimary(ctx), fmt.Sprintf(`WITH previous AS (SELECT hash FROM %[1]s WHERE name = $1 FOR UPDATE)
+INSERT INTO %[1]s (name, hash, updated_at) VALUES ($1, $2, now())
+ON CONFLICT (name) DO UPDATE SET hash = EXCLUDED.hash, updated_at = EXCLUDED.updated_at
+RETURNING (SELECT hash FROM previous)`, p.table), name, hash).Scan(&previous)
+	if err != nil {
+		return "", fmt.Errorf("failed to link %s: %w", name, err)
+	}
+	return previous.String, nil
+}
+
+// Unlink removes name.
+func (p *PostgresDedupIndex) Unlink(ctx context.Context, name string) (string, error) {
+	var hash string
+	err := p.db.QueryRow(db.WithPrimary(ctx), fmt.Sprintf("DELETE FROM %s WHERE name = $1 RETURNING hash", p.table), name).Scan(&hash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", blobError(ErrBlobNotFound, "name not found: "+name, nil)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to unlink %s: %w", name, err)
+	}
+	return hash, nil
+}
+
+// RefCount counts the names pointing at hash, reading from the primary.
+func (p *PostgresDedupIndex) RefCount(ctx context.Context, hash string) (int, error) {
+	var refs int
+	err := p.db.QueryRow(db.WithPrimary(ctx), fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE hash = $1", p.table), hash).Scan(&refs)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count references to %s: %w", hash, err)
+	}
+	return refs, nil
+}
@@ -0,0 +1,195 @@
+package blobclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yourorg/go-service-kit/pkg/db"
+	"github.com/yourorg/go-service-kit/pkg/logging"
+)
+
+// newTestDedupStore returns a store over client with a metadata index and no grace period.
+func newTestDedupStore(t *testing.T, client BlobClient) *DedupStore {
+	t.Helper()
+	logger, _ := logging.NewLogger("error", "json")
+	store, err := NewDedupStore(client, "uploads", NewMetadataDedupIndex(client, "uploads"), logger, WithDedupGracePeriod(0))
+	if err != nil {
+		t.Fatalf("NewDedupStore failed: %v", err)
+	}
+	return store
+}
+
+// onlyReader hides the Seek method of a reader.
+type onlyReader struct{ io.Reader }
+
+func TestDedupStore(t *testing.T) {
+	_, s3Client := newFakeS3(t)
+	fsClient, _ := newTestFileSystemClient(t)
+	clients := map[string]BlobClient{
+		"mock":       NewMockBlobClient(),
+		"filesystem": fsClient,
+		"s3":         s3Client,
+	}
+
+	ctx := context.Background()
+	for name, client := range clients {
+		store := newTestDedupStore(t, client)
+		index := NewMetadataDedupIndex(client, "uploads")
+
+		first, err := store.Put(ctx, "csv/jan.csv", strings.NewReader("id,amount\n1,10\n"), "text/csv")
+		if err != nil {
+			t.Fatalf("%s: Put failed: %v", name, err)
+		}
+		// Non-seekable data is spooled before upload
+		second, err := store.Put(ctx, "csv/jan-again.csv", onlyReader{strings.NewReader("id,amount\n1,10\n")}, "text/csv")
+		if err != nil {
+			t.Fatalf("%s: Put failed: %v", name, err)
+		}
+		if first.Deduplicated || !second.Deduplicated || first.Hash != second.Hash || first.URL != second.URL || second.Size != 15 {
+			t.Errorf("%s: expected the second upload to reuse the first, got %+v and %+v", name, first, second)
+		}
+		if blobs, _ := client.List(ctx, "uploads", DedupContentPrefix); len(blobs) != 1 {
+			t.Errorf("%s: expected content to be stored once, got %d blobs", name, len(blobs))
+		}
+		if refs, _ := index.RefCount(ctx, first.Hash); refs != 2 {
+			t.Errorf("%s: expected 2 references, got %d", name, refs)
+		}
+
+		reader, err := store.Get(ctx, "csv/jan-again.csv")
+		if err != nil {
+			t.Fatalf("%s: Get failed: %v", name, err)
+		}
+		data, _ := io.ReadAll(reader)
+		reader.Close()
+		if string(data) != "id,amount\n1,10\n" {
+			t.Errorf("%s: Get = %q", name, data)
+		}
+
+		// Replacing a name releases its old content
+		replaced, err := store.Put(ctx, "csv/jan.csv", strings.NewReader("id,amount\n1,20\n"), "text/csv")
+		if err != nil {
+			t.Fatalf("%s: Put failed: %v", name, err)
+		}
+		if refs, _ := index.RefCount(ctx, first.Hash); refs != 1 {
+			t.Errorf("%s: expected 1 reference after replacing, got %d", name, refs)
+		}
+		if deleted, err := store.GarbageCollect(ctx); err != nil || deleted != 0 {
+			t.Errorf("%s: expected nothing to collect, got %d (err %v)", name, deleted, err)
+		}
+
+		if err := store.Delete(ctx, "csv/jan-again.csv"); err != nil {
+			t.Fatalf("%s: Delete failed: %v", name, err)
+		}
+		if _, err := store.Get(ctx, "csv/jan-again.csv"); !errors.Is(err, ErrBlobNotFound) {
+			t.Errorf("%s: expected ErrBlobNotFound after Delete, got %v", name, err)
+		}
+		if err := store.Delete(ctx, "csv/jan-again.csv"); !errors.Is(err, ErrBlobNotFound) {
+			t.Errorf("%s: expected ErrBlobNotFound deleting twice, got %v", name, err)
+		}
+		if deleted, err := store.GarbageCollect(ctx); err != nil || deleted != 1 {
+			t.Errorf("%s: expected the unreferenced content to be collected, got %d (err %v)", name, deleted, err)
+		}
+		if exists, _ := client.Exists(ctx, "uploads", DedupContentPrefix+first.Hash); exists {
+			t.Errorf("%s: expected %s to be deleted", name, first.Hash)
+		}
+		if stat, err := store.Stat(ctx, "csv/jan.csv"); err != nil || stat.Hash != replaced.Hash {
+			t.Errorf("%s: expected the referenced content to survive, got %+v (err %v)", name, stat, err)
+		}
+
+		// Content collected while unreferenced is uploaded again
+		again, err := store.Put(ctx, "csv/jan-again.csv", strings.NewReader("id,amount\n1,10\n"), "text/csv")
+		if err != nil || again.Deduplicated {
+			t.Errorf("%s: expected a fresh upload, got %+v (err %v)", name, again, err)
+		}
+	}
+}
+
+func TestDedupStore_GracePeriod(t *testing.T) {
+	clock := &lifecycleClock{now: time.Now().Add(-2 * time.Hour)}
+	client := NewMockBlobClient()
+	client.SetClock(clock.Now)
+	logger, _ := logging.NewLogger("error", "json")
+	store, _ := NewDedupStore(client, "uploads", NewMetadataDedupIndex(client, "uploads"), logger)
+
+	ctx := context.Background()
+	old, _ := store.Put(ctx, "old.csv", strings.NewReader("old"), "text/csv")
+	store.Delete(ctx, "old.csv")
+	clock.now = time.Now()
+	recent, _ := store.Put(ctx, "recent.csv", strings.NewReader("recent"), "text/csv")
+	store.Delete(ctx, "recent.csv")
+
+	if deleted, err := store.GarbageCollect(ctx); err != nil || deleted != 1 {
+		t.Fatalf("Expected only old content to be collected, got %d (err %v)", deleted, err)
+	}
+	if exists, _ := client.Exists(ctx, "uploads", DedupContentPrefix+old.Hash); exists {
+		t.Error("Expected old content to be deleted")
+	}
+	if exists, _ := client.Exists(ctx, "uploads", DedupContentPrefix+recent.Hash); !exists {
+		t.Error("Expected content within the grace period to be kept")
+	}
+}
+
+func TestDedupMigrations(t *testing.T) {
+	logger, _ := logging.NewLogger("error", "json")
+	migrator, err := db.NewMigrator(db.NewMockDB(), DedupMigrations, db.MigratorConfig{Dir: "migrations", Table: DedupMigrationsTable}, logger)
+	if err != nil {
+		t.Fatalf("NewMigrator failed: %v", err)
+	}
+	migrations, err := migrator.Migrations()
+	if err != nil {
+		t.Fatalf("Migrations failed: %v", err)
+	}
+	if len(migrations) != 1 || !strings.Contains(migrations[0].UpSQL, DefaultDedupTable) || migrations[0].DownSQL == "" {
+		t.Errorf("Expected one migration creating %s, got %+v", DefaultDedupTable, migrations)
+	}
+}
+
+func TestPostgresDedupIndex(t *testing.T) {
+	mock := db.NewMockDB()
+	ctx := context.Background()
+	index, err := NewPostgresDedupIndex(mock, "")
+	if err != nil {
+		t.Fatalf("NewPostgresDedupIndex failed: %v", err)
+	}
+
+	mock.ExpectQuery("INSERT INTO blob_dedup_refs").WithArgs("a.csv", "h1").WillReturnRows(db.NewMockRows("hash").AddRow(nil))
+	mock.ExpectQuery("INSERT INTO blob_dedup_refs").WithArgs("a.csv", "h2").WillReturnRows(db.NewMockRows("hash").AddRow("h1"))
+	mock.ExpectQuery("SELECT hash FROM blob_dedup_refs WHERE name = $1").WithArgs("a.csv").WillReturnRows(db.NewMockRows("hash").AddRow("h2"))
+	mock.ExpectQuery("SELECT COUNT(*) FROM blob_dedup_refs WHERE hash = $1").WithArgs("h2").WillReturnRows(db.NewMockRows("count").AddRow(1))
+	mock.ExpectQuery("DELETE FROM blob_dedup_refs WHERE name = $1 RETURNING hash").WithArgs("a.csv").WillReturnRows(db.NewMockRows("hash").AddRow("h2"))
+	mock.ExpectQuery("DELETE FROM blob_dedup_refs").WithArgs("a.csv").WillReturnRows(db.NewMockRows("hash"))
+	mock.ExpectQuery("SELECT hash FROM blob_dedup_refs").WithArgs("a.csv").WillReturnRows(db.NewMockRows("hash"))
+
+	if previous, err := index.Link(ctx, "a.csv", "h1"); err != nil || previous != "" {
+		t.Errorf("Link = %q, %v", previous, err)
+	}
+	if previous, err := index.Link(ctx, "a.csv", "h2"); err != nil || previous != "h1" {
+		t.Errorf("Link = %q, %v", previous, err)
+	}
+	if hash, err := index.Lookup(ctx, "a.csv"); err != nil || hash != "h2" {
+		t.Errorf("Lookup = %q, %v", hash, err)
+	}
+	if refs, err := index.RefCount(ctx, "h2"); err != nil || refs != 1 {
+		t.Errorf("RefCount = %d, %v", refs, err)
+	}
+	if hash, err := index.Unlink(ctx, "a.csv"); err != nil || hash != "h2" {
+		t.Errorf("Unlink = %q, %v", hash, err)
+	}
+	if _, err := index.Unlink(ctx, "a.csv"); !errors.Is(err, ErrBlobNotFound) {
+		t.Errorf("Unlink missing: expected ErrBlobNotFound, got %v", err)
+	}
+	if _, err := index.Lookup(ctx, "a.csv"); !errors.Is(err, ErrBlobNotFound) {
+		t.Errorf("Lookup missing: expected ErrBlobNotFound, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+
+	if _, err := NewPostgresDedupIndex(mock, "refs; DROP TABLE users"); err == nil {
+		t.Error("Expected error for an invalid table name")
+	}
+}
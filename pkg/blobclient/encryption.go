@@ -0,0 +1,756 @@
+package blobclient
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/yourorg/go-service-kit/pkg/logging"
+)
+
+// EncryptionMetadataKey is the metadata entry holding the wrapped data key and encryption
+// parameters of a blob written by EncryptedBlobClient.
+const EncryptionMetadataKey = "encryptiondata"
+
+// DefaultEncryptionSegmentSize is the plaintext size of each separately authenticated
+// segment, and so the amount of data buffered while encrypting or decrypting a stream.
+const DefaultEncryptionSegmentSize = 4 << 20
+
+const (
+	encryptionAlgorithm = "AES_256_GCM_SEGMENTED"
+	encryptionVersion   = 1
+	dataKeySize         = 32
+	noncePrefixSize     = 7
+	segmentOverhead     = 16 // GCM tag
+)
+
+var (
+	// ErrNotEncrypted is returned when reading a blob that was not written by an
+	// EncryptedBlobClient.
+	ErrNotEncrypted = errors.New("blob is not client-side encrypted")
+	// ErrDecryptionFailed is returned when blob content fails authentication, e.g. because
+	// it was modified, truncated or overwritten while being read.
+	ErrDecryptionFailed = errors.New("blob decryption failed")
+)
+
+// encryptionData is stored as JSON in the EncryptionMetadataKey metadata of a blob.
+type encryptionData struct {
+	Version     int    `json:"version"`
+	Algorithm   string `json:"algorithm"`
+	KeyID       string `json:"key_id"`
+	WrappedKey  []byte `json:"wrapped_key"`
+	NoncePrefix []byte `json:"nonce_prefix"`
+	SegmentSize int    `json:"segment_size"`
+}
+
+// plaintextSize returns the plaintext size of content encrypted to size bytes.
+func (d *encryptionData) plaintextSize(size int64) int64 {
+	segment := int64(d.SegmentSize + segmentOverhead)
+	segments := (size + segment - 1) / segment
+	return size - segments*segmentOverhead
+}
+
+// EncryptionOption configures an EncryptedBlobClient.
+type EncryptionOption func(*EncryptedBlobClient)
+
+// WithSegmentSize sets the plaintext size of encrypted segments for new blobs
+// (default: DefaultEncryptionSegmentSize). Existing blobs keep their segment size.
+func WithSegmentSize(size int) EncryptionOption {
+	return func(e *EncryptedBlobClient) {
+		e.segmentSize = size
+	}
+}
+
+// EncryptedBlobClient is a BlobClient decorator that encrypts blob content on the client
+// with envelope encryption: every blob is encrypted with its own AES-256 data key, which is
+// wrapped by a key-encryption key (KEK) from a KeyProvider and stored in the blob's metadata.
+//
+// Content is encrypted and decrypted as a stream of AES-GCM segments, so blobs of any size
+// are processed in constant memory and ranged reads only fetch the segments they need.
+// Rotating the KEK re-wraps data keys (see RewrapKey and RotateKeys) without re-encrypting
+// content.
+//
+// Sizes reported by GetProperties and by listings with IncludeMetadata are plaintext sizes;
+// other listings and copy statuses report stored sizes. Signed URLs would bypass
+// encryption and are not supported.
+type EncryptedBlobClient struct {
+	client      BlobClient
+	keys        KeyProvider
+	logger      logging.Logger
+	segmentSize int
+}
+
+// NewEncryptedBlobClient wraps client with client-side encryption using keys.
+func NewEncryptedBlobClient(client BlobClient, keys KeyProvider, logger logging.Logger, opts ...EncryptionOption) (*EncryptedBlobClient, error) {
+	if client == nil {
+		return nil, fmt.Errorf("blob client is required")
+	}
+	if keys == nil {
+		return nil, fmt.Errorf("key provider is required")
+	}
+	if logger == nil {
+		return nil, fmt.Errorf("logger is required")
+	}
+
+	e := &EncryptedBlobClient{
+		client:      client,
+		keys:        keys,
+		logger:      logger,
+		segmentSize: DefaultEncryptionSegmentSize,
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	if e.segmentSize <= 0 {
+		return nil, fmt.Errorf("invalid segment size: %d", e.segmentSize)
+	}
+	return e, nil
+}
+
+// newDataKey generates a data key, wraps it with the current KEK and returns the cipher
+// for the content and the encryption data to store with the blob.
+func (e *EncryptedBlobClient) newDataKey(ctx context.Context) (cipher.AEAD, *encryptionData, error) {
+	keyID, err := e.keys.CurrentKeyID(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get current key: %w", err)
+	}
+
+	dataKey := make([]byte, dataKeySize)
+	noncePrefix := make([]byte, noncePrefixSize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, nil, fmt.Errorf("failed to generate data key: %w", err)
+	}
+	if _, err := rand.Read(noncePrefix); err != nil {
+		return nil, nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	wrappedKey, err := e.keys.WrapKey(ctx, keyID, dataKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to wrap data key: %w", err)
+	}
+	aead, err := newContentCipher(dataKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return aead, &encryptionData{
+		Version:     encryptionVersion,
+		Algorithm:   encryptionAlgorithm,
+		KeyID:       keyID,
+		WrappedKey:  wrappedKey,
+		NoncePrefix: noncePrefix,
+		SegmentSize: e.segmentSize,
+	}, nil
+}
+
+// newContentCipher returns the AES-GCM cipher for a data key.
+func newContentCipher(dataKey []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(dataKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// parseEncryptionData reads the encryption data from blob metadata.
+func parseEncryptionData(container, blobName string, metadata map[string]string) (*encryptionData, error) {
+	value, ok := metadata[EncryptionMetadataKey]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s/%s", ErrNotEncrypted, container, blobName)
+	}
+	var data encryptionData
+	if err := json.Unmarshal([]byte(value), &data); err != nil {
+		return nil, fmt.Errorf("failed to parse encryption data of %s/%s: %w", container, blobName, err)
+	}
+	if data.Version != encryptionVersion || data.Algorithm != encryptionAlgorithm || data.SegmentSize <= 0 || len(data.NoncePrefix) != noncePrefixSize {
+		return nil, fmt.Errorf("unsupported encryption data on %s/%s: version %d, algorithm %s", container, blobName, data.Version, data.Algorithm)
+	}
+	return &data, nil
+}
+
+// openBlob reads the properties of a blob and unwraps its data key.
+func (e *EncryptedBlobClient) openBlob(ctx context.Context, container, blobName string) (*encryptedBlob, error) {
+	props, err := e.client.GetProperties(ctx, container, blobName)
+	if err != nil {
+		return nil, err
+	}
+	data, err := parseEncryptionData(container, blobName, props.Metadata)
+	if err != nil {
+		return nil, err
+	}
+	dataKey, err := e.keys.UnwrapKey(ctx, data.KeyID, data.WrappedKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key of %s/%s: %w", container, blobName, err)
+	}
+	aead, err := newContentCipher(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	return &encryptedBlob{client: e.client, props: props, data: data, aead: aead}, nil
+}
+
+// encryptOptions returns opts with the encryption data added to the metadata.
+func encryptOptions(opts UploadOptions, data *encryptionData) (UploadOptions, error) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return opts, fmt.Errorf("failed to encode encryption data: %w", err)
+	}
+	metadata := copyStringMap(opts.Metadata)
+	metadata[EncryptionMetadataKey] = string(encoded)
+	opts.Metadata = metadata
+	return opts, nil
+}
+
+// Upload encrypts data and uploads it.
+func (e *EncryptedBlobClient) Upload(ctx context.Context, container, blobName string, data io.Reader, contentType string) (string, error) {
+	return e.UploadWithOptions(ctx, container, blobName, data, UploadOptions{ContentType: contentType})
+}
+
+// UploadWithOptions encrypts data and uploads it with options.
+func (e *EncryptedBlobClient) UploadWithOptions(ctx context.Context, container, blobName string, data io.Reader, opts UploadOptions) (string, error) {
+	aead, encData, err := e.newDataKey(ctx)
+	if err != nil {
+		return "", err
+	}
+	if opts, err = encryptOptions(opts, encData); err != nil {
+		return "", err
+	}
+	return e.client.UploadWithOptions(ctx, container, blobName, newEncryptReader(data, aead, encData), opts)
+}
+
+// UploadChunked encrypts data and uploads it as parallel blocks. Every attempt uses a new
+// data key, so resuming an interrupted upload re-sends all blocks.
+func (e *EncryptedBlobClient) UploadChunked(ctx context.Context, container, blobName string, data io.Reader, opts UploadOptions, transfer TransferOptions) (string, error) {
+	aead, encData, err := e.newDataKey(ctx)
+	if err != nil {
+		return "", err
+	}
+	if opts, err = encryptOptions(opts, encData); err != nil {
+		return "", err
+	}
+	return e.client.UploadChunked(ctx, container, blobName, newEncryptReader(data, aead, encData), opts, transfer)
+}
+
+// Get returns a reader decrypting a blob as it is read. Reads fail with
+// ErrDecryptionFailed if the content does not authenticate, so callers must check the
+// error of the final read before trusting the data. The content read is pinned to the
+// ETag of the properties the data key was taken from, so a concurrent overwrite fails
+// with ErrPreconditionFailed.
+func (e *EncryptedBlobClient) Get(ctx context.Context, container, blobName string) (io.ReadCloser, error) {
+	blob, err := e.openBlob(ctx, container, blobName)
+	if err != nil {
+		return nil, err
+	}
+	return blob.openRange(ctx, container, blobName, 0, 0, blob.props.ETag)
+}
+
+// GetRange decrypts count bytes of a blob starting at offset as they are read, fetching
+// only the segments that contain the range. A count of 0 reads to the end. Like Get,
+// reads fail with ErrDecryptionFailed if a segment does not authenticate.
+func (e *EncryptedBlobClient) GetRange(ctx context.Context, container, blobName string, offset, count int64) (io.ReadCloser, error) {
+	return e.GetRangeWithOptions(ctx, container, blobName, offset, count, GetOptions{})
+}
+
+// GetRangeWithOptions is GetRange with read conditions on the stored blob.
+func (e *EncryptedBlobClient) GetRangeWithOptions(ctx context.Context, container, blobName string, offset, count int64, opts GetOptions) (io.ReadCloser, error) {
+	blob, err := e.openBlob(ctx, container, blobName)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkConditions(blob.props.ETag, opts.IfMatch, ""); err != nil {
+		return nil, err
+	}
+	return blob.openRange(ctx, container, blobName, offset, count, blob.props.ETag)
+}
+
+// DownloadChunked decrypts a blob to w using parallel ranged reads. Every segment is
+// authenticated, so transfer.Checksum adds no further verification.
+func (e *EncryptedBlobClient) DownloadChunked(ctx context.Context, container, blobName string, w io.Writer, transfer TransferOptions) (int64, error) {
+	blob, err := e.openBlob(ctx, container, blobName)
+	if err != nil {
+		return 0, err
+	}
+	return downloadChunked(ctx, blob, container, blobName, w, transfer)
+}
+
+// encryptedBlob is an opened encrypted blob. It is the rangeStore for chunked downloads,
+// so the data key is unwrapped once per download.
+type encryptedBlob struct {
+	client BlobClient
+	props  *BlobProperties // as stored
+	data   *encryptionData
+	aead   cipher.AEAD
+}
+
+// GetProperties returns the plaintext properties of the blob.
+func (b *encryptedBlob) GetProperties(ctx context.Context, container, blobName string) (*BlobProperties, error) {
+	props := *b.props
+	props.Size = b.data.plaintextSize(b.props.Size)
+	props.ContentMD5 = nil
+	props.Metadata = copyStringMap(b.props.Metadata)
+	delete(props.Metadata, EncryptionMetadataKey)
+	delete(props.Metadata, ContentCRC64MetadataKey)
+	return &props, nil
+}
+
+// readRange decrypts a plaintext byte range. Range MD5s are not available.
+func (b *encryptedBlob) readRange(ctx context.Context, container, blobName string, offset, count int64, ifMatch string, rangeMD5 bool) ([]byte, []byte, error) {
+	reader, err := b.openRange(ctx, container, blobName, offset, count, ifMatch)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer reader.Close()
+	plain, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	return plain, nil, nil
+}
+
+// openRange returns a reader decrypting a plaintext byte range segment by segment,
+// reading only the stored segments that contain it. A count of 0 reads to the end.
+func (b *encryptedBlob) openRange(ctx context.Context, container, blobName string, offset, count int64, ifMatch string) (io.ReadCloser, error) {
+	storedSize := b.props.Size
+	size := b.data.plaintextSize(storedSize)
+	if offset < 0 || offset >= size && size > 0 || count < 0 {
+		return nil, fmt.Errorf("invalid range: offset %d, count %d, size %d", offset, count, size)
+	}
+	end := size
+	if count > 0 && offset+count < size {
+		end = offset + count
+	}
+	if end == offset && size > 0 {
+		return io.NopCloser(bytes.NewReader(nil)), nil
+	}
+
+	// Empty content is a single empty final segment, which is read to authenticate it
+	segmentSize := int64(b.data.SegmentSize)
+	storedSegment := segmentSize + segmentOverhead
+	first, last := offset/segmentSize, max((end-1)/segmentSize, 0)
+	storedOffset := first * storedSegment
+	storedEnd := min((last+1)*storedSegment, storedSize)
+
+	reader, err := b.client.GetRangeWithOptions(ctx, container, blobName, storedOffset, storedEnd-storedOffset, GetOptions{IfMatch: ifMatch})
+	if err != nil {
+		return nil, err
+	}
+
+	decrypt := newDecryptReader(reader, b.aead, b.data)
+	decrypt.index, decrypt.last = first, last
+	decrypt.final = (storedSize+storedSegment-1)/storedSegment - 1
+	decrypt.skip = int(offset - first*segmentSize)
+	decrypt.remaining = end - offset
+	return decrypt, nil
+}
+
+// GetProperties returns the properties of a blob with its plaintext size. The encryption
+// metadata and checksums of the stored content are omitted.
+func (e *EncryptedBlobClient) GetProperties(ctx context.Context, container, blobName string) (*BlobProperties, error) {
+	props, err := e.client.GetProperties(ctx, container, blobName)
+	if err != nil {
+		return nil, err
+	}
+	data, err := parseEncryptionData(container, blobName, props.Metadata)
+	if err != nil {
+		return nil, err
+	}
+	return (&encryptedBlob{props: props, data: data}).GetProperties(ctx, container, blobName)
+}
+
+// SetMetadata replaces the metadata of a blob, keeping its encryption data. Without
+// opts.IfMatch the update is conditional on the ETag read, so a concurrent re-wrap or
+// overwrite is never reverted.
+func (e *EncryptedBlobClient) SetMetadata(ctx context.Context, container, blobName string, metadata map[string]string, opts SetMetadataOptions) error {
+	props, err := e.client.GetProperties(ctx, container, blobName)
+	if err != nil {
+		return err
+	}
+	if _, err := parseEncryptionData(container, blobName, props.Metadata); err != nil {
+		return err
+	}
+
+	updated := copyStringMap(metadata)
+	updated[EncryptionMetadataKey] = props.Metadata[EncryptionMetadataKey]
+	if crc, ok := props.Metadata[ContentCRC64MetadataKey]; ok {
+		updated[ContentCRC64MetadataKey] = crc
+	}
+	if opts.IfMatch == "" {
+		opts.IfMatch = props.ETag
+	}
+	return e.client.SetMetadata(ctx, container, blobName, updated, opts)
+}
+
+// SetAccessTier changes the access tier of a blob.
+func (e *EncryptedBlobClient) SetAccessTier(ctx context.Context, container, blobName, tier string) error {
+	return e.client.SetAccessTier(ctx, container, blobName, tier)
+}
+
+// RewrapKey re-wraps the data key of a blob with the current KEK, without re-encrypting
+// its content. It returns false if the blob already uses the current KEK.
+func (e *EncryptedBlobClient) RewrapKey(ctx context.Context, container, blobName string) (bool, error) {
+	currentID, err := e.keys.CurrentKeyID(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to get current key: %w", err)
+	}
+	props, err := e.client.GetProperties(ctx, container, blobName)
+	if err != nil {
+		return false, err
+	}
+	encData, err := parseEncryptionData(container, blobName, props.Metadata)
+	if err != nil {
+		return false, err
+	}
+	if encData.KeyID == currentID {
+		return false, nil
+	}
+
+	dataKey, err := e.keys.UnwrapKey(ctx, encData.KeyID, encData.WrappedKey)
+	if err != nil {
+		return false, fmt.Errorf("failed to unwrap data key of %s/%s: %w", container, blobName, err)
+	}
+	if encData.WrappedKey, err = e.keys.WrapKey(ctx, currentID, dataKey); err != nil {
+		return false, fmt.Errorf("failed to wrap data key of %s/%s: %w", container, blobName, err)
+	}
+	encData.KeyID = currentID
+	encoded, err := json.Marshal(encData)
+	if err != nil {
+		return false, fmt.Errorf("failed to encode encryption data: %w", err)
+	}
+
+	metadata := copyStringMap(props.Metadata)
+	metadata[EncryptionMetadataKey] = string(encoded)
+	if err := e.client.SetMetadata(ctx, container, blobName, metadata, SetMetadataOptions{IfMatch: props.ETag}); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// RotateKeys re-wraps the data keys of all encrypted blobs in a container whose names
+// start with prefix, and returns the number of blobs re-wrapped. Blobs that are not
+// encrypted are skipped; it stops at the first other error and can be re-run to continue.
+func (e *EncryptedBlobClient) RotateKeys(ctx context.Context, container, prefix string) (int, error) {
+	logger := e.logger.With(
+		logging.NewField("operation", "blob.rotate_keys"),
+		logging.NewField("container", container),
+		logging.NewField("prefix", prefix),
+	)
+
+	logger.Info("Starting key rotation")
+
+	rewrapped := 0
+	it := NewBlobIterator(ctx, e.client, container, ListOptions{Prefix: prefix})
+	for it.Next() {
+		name := it.Blob().Name
+		ok, err := e.RewrapKey(ctx, container, name)
+		if errors.Is(err, ErrNotEncrypted) || errors.Is(err, ErrBlobNotFound) {
+			continue
+		}
+		if err != nil {
+			logger.Error("Failed to re-wrap data key", logging.NewField("blob", name), logging.NewField("error", err))
+			return rewrapped, err
+		}
+		if ok {
+			rewrapped++
+		}
+	}
+	if err := it.Err(); err != nil {
+		return rewrapped, fmt.Errorf("failed to list blobs: %w", err)
+	}
+
+	logger.Info("Key rotation completed", logging.NewField("rewrapped", rewrapped))
+	return rewrapped, nil
+}
+
+// Delete deletes a blob.
+func (e *EncryptedBlobClient) Delete(ctx context.Context, container, blobName string) error {
+	return e.client.Delete(ctx, container, blobName)
+}
+
+// DeleteWithOptions deletes a blob if its ETag and lease match opts.
+func (e *EncryptedBlobClient) DeleteWithOptions(ctx context.Context, container, blobName string, opts DeleteOptions) error {
+	return e.client.DeleteWithOptions(ctx, container, blobName, opts)
+}
+
+// Exists checks if a blob exists.
+func (e *EncryptedBlobClient) Exists(ctx context.Context, container, blobName string) (bool, error) {
+	return e.client.Exists(ctx, container, blobName)
+}
+
+// List lists blobs with their stored sizes.
+func (e *EncryptedBlobClient) List(ctx context.Context, container, prefix string) ([]BlobInfo, error) {
+	return e.client.List(ctx, container, prefix)
+}
+
+// ListPage returns one page of blobs with their stored sizes.
+func (e *EncryptedBlobClient) ListPage(ctx context.Context, container, prefix, continuationToken string, pageSize int) (*BlobPage, error) {
+	return e.client.ListPage(ctx, container, prefix, continuationToken, pageSize)
+}
+
+// ListPageWithOptions returns one page of blobs. With opts.IncludeMetadata, encrypted
+// blobs are listed with their plaintext sizes and without encryption metadata.
+func (e *EncryptedBlobClient) ListPageWithOptions(ctx context.Context, container, continuationToken string, opts ListOptions) (*BlobPage, error) {
+	page, err := e.client.ListPageWithOptions(ctx, container, continuationToken, opts)
+	if err != nil {
+		return nil, err
+	}
+	for i := range page.Blobs {
+		blob := &page.Blobs[i]
+		if encData, err := parseEncryptionData(container, blob.Name, blob.Metadata); err == nil {
+			blob.Size = encData.plaintextSize(blob.Size)
+			delete(blob.Metadata, EncryptionMetadataKey)
+			delete(blob.Metadata, ContentCRC64MetadataKey)
+		}
+	}
+	return page, nil
+}
+
+// Copy copies a blob server-side; the copy keeps the encryption data.
+func (e *EncryptedBlobClient) Copy(ctx context.Context, srcContainer, srcBlob, dstContainer, dstBlob string) (*CopyStatus, error) {
+	return e.client.Copy(ctx, srcContainer, srcBlob, dstContainer, dstBlob)
+}
+
+// GetCopyStatus returns the status of the last copy into a blob.
+func (e *EncryptedBlobClient) GetCopyStatus(ctx context.Context, container, blobName string) (*CopyStatus, error) {
+	return e.client.GetCopyStatus(ctx, container, blobName)
+}
+
+// Move moves a blob server-side; the destination keeps the encryption data.
+func (e *EncryptedBlobClient) Move(ctx context.Context, srcContainer, srcBlob, dstContainer, dstBlob string) (string, error) {
+	return e.client.Move(ctx, srcContainer, srcBlob, dstContainer, dstBlob)
+}
+
+// Snapshot creates a snapshot of a blob.
+func (e *EncryptedBlobClient) Snapshot(ctx context.Context, container, blobName string) (string, error) {
+	return e.client.Snapshot(ctx, container, blobName)
+}
+
+// ListSnapshots lists the snapshots of a blob with their stored sizes.
+func (e *EncryptedBlobClient) ListSnapshots(ctx context.Context, container, blobName string) ([]BlobInfo, error) {
+	return e.client.ListSnapshots(ctx, container, blobName)
+}
+
+// Undelete restores a soft-deleted blob and its snapshots.
+func (e *EncryptedBlobClient) Undelete(ctx context.Context, container, blobName string) error {
+	return e.client.Undelete(ctx, container, blobName)
+}
+
+// AcquireLease takes a lease on a blob.
+func (e *EncryptedBlobClient) AcquireLease(ctx context.Context, container, blobName string, duration time.Duration, proposedLeaseID string) (string, error) {
+	return e.client.AcquireLease(ctx, container, blobName, duration, proposedLeaseID)
+}
+
+// RenewLease restarts the duration of a lease.
+func (e *EncryptedBlobClient) RenewLease(ctx context.Context, container, blobName, leaseID string) error {
+	return e.client.RenewLease(ctx, container, blobName, leaseID)
+}
+
+// ReleaseLease ends a lease.
+func (e *EncryptedBlobClient) ReleaseLease(ctx context.Context, container, blobName, leaseID string) error {
+	return e.client.ReleaseLease(ctx, container, blobName, leaseID)
+}
+
+// BreakLease ends a lease without its ID.
+func (e *EncryptedBlobClient) BreakLease(ctx context.Context, container, blobName string, breakPeriod time.Duration) (time.Duration, error) {
+	return e.client.BreakLease(ctx, container, blobName, breakPeriod)
+}
+
+// GenerateReadURL is not supported: a signed URL would serve the encrypted content.
+func (e *EncryptedBlobClient) GenerateReadURL(ctx context.Context, container, blobName string, opts SASOptions) (string, error) {
+	return "", fmt.Errorf("signed URLs bypass client-side encryption: %w", ErrNotSupported)
+}
+
+// GenerateWriteURL is not supported: content uploaded to a signed URL would not be encrypted.
+func (e *EncryptedBlobClient) GenerateWriteURL(ctx context.Context, container, blobName string, opts SASOptions) (string, error) {
+	return "", fmt.Errorf("signed URLs bypass client-side encryption: %w", ErrNotSupported)
+}
+
+// segmentNonce returns the nonce of a segment: the blob's random prefix, the segment
+// index and a flag marking the final segment, so segments cannot be reordered, dropped
+// or truncated undetected.
+func segmentNonce(prefix []byte, index int64, final bool) []byte {
+	nonce := make([]byte, 0, noncePrefixSize+5)
+	nonce = append(nonce, prefix...)
+	nonce = binary.BigEndian.AppendUint32(nonce, uint32(index))
+	if final {
+		return append(nonce, 1)
+	}
+	return append(nonce, 0)
+}
+
+// openSegment decrypts one segment and appends the plaintext to dst.
+func openSegment(dst []byte, aead cipher.AEAD, prefix []byte, index int64, final bool, segment []byte) ([]byte, error) {
+	return aead.Open(dst, segmentNonce(prefix, index, final), segment, nil)
+}
+
+// maxSegments is the number of segments a nonce can address.
+const maxSegments = 1 << 32
+
+// encryptReader encrypts a plaintext stream segment by segment.
+type encryptReader struct {
+	src     *bufio.Reader
+	aead    cipher.AEAD
+	data    *encryptionData
+	plain   []byte
+	out     []byte
+	pos     int
+	index   int64
+	done    bool
+	lastErr error
+}
+
+// newEncryptReader returns a reader of the encrypted form of src.
+func newEncryptReader(src io.Reader, aead cipher.AEAD, data *encryptionData) *encryptReader {
+	return &encryptReader{
+		src:   bufio.NewReader(src),
+		aead:  aead,
+		data:  data,
+		plain: make([]byte, data.SegmentSize),
+	}
+}
+
+// Read implements io.Reader.
+func (r *encryptReader) Read(p []byte) (int, error) {
+	for r.pos == len(r.out) {
+		if r.lastErr != nil {
+			return 0, r.lastErr
+		}
+		if r.done {
+			return 0, io.EOF
+		}
+		if r.lastErr = r.next(); r.lastErr != nil {
+			return 0, r.lastErr
+		}
+	}
+	n := copy(p, r.out[r.pos:])
+	r.pos += n
+	return n, nil
+}
+
+// next encrypts the next segment. A segment is final when the source ends within or
+// right after it; empty content is a single empty final segment.
+func (r *encryptReader) next() error {
+	if r.index >= maxSegments {
+		return fmt.Errorf("blob too large to encrypt: more than %d segments", int64(maxSegments))
+	}
+	n, err := io.ReadFull(r.src, r.plain)
+	final := false
+	switch {
+	case err == io.EOF || err == io.ErrUnexpectedEOF:
+		final = true
+	case err != nil:
+		return err
+	default:
+		if _, err := r.src.Peek(1); err == io.EOF {
+			final = true
+		} else if err != nil {
+			return err
+		}
+	}
+
+	r.out = r.aead.Seal(r.out[:0], segmentNonce(r.data.NoncePrefix, r.index, final), r.plain[:n], nil)
+	r.pos = 0
+	r.index++
+	r.done = final
+	return nil
+}
+
+// decryptReader decrypts the stored segments index to last of a blob, failing with
+// ErrDecryptionFailed on any segment that does not authenticate or is missing. It skips
+// skip bytes of the first segment and returns at most remaining bytes.
+type decryptReader struct {
+	src       *bufio.Reader
+	closer    io.Closer
+	aead      cipher.AEAD
+	data      *encryptionData
+	sealed    []byte
+	out       []byte
+	pos       int
+	index     int64
+	last      int64
+	final     int64 // index of the blob's final segment
+	skip      int
+	remaining int64
+	done      bool
+	lastErr   error
+}
+
+// newDecryptReader returns a reader of the plaintext of the encrypted stream src. The
+// caller sets the segments to read and the bytes to return.
+func newDecryptReader(src io.ReadCloser, aead cipher.AEAD, data *encryptionData) *decryptReader {
+	return &decryptReader{
+		src:    bufio.NewReader(src),
+		closer: src,
+		aead:   aead,
+		data:   data,
+		sealed: make([]byte, data.SegmentSize+segmentOverhead),
+	}
+}
+
+// Read implements io.Reader.
+func (r *decryptReader) Read(p []byte) (int, error) {
+	if r.remaining <= 0 {
+		return 0, io.EOF
+	}
+	for r.pos == len(r.out) {
+		if r.lastErr != nil {
+			return 0, r.lastErr
+		}
+		if r.done {
+			return 0, io.EOF
+		}
+		if r.lastErr = r.next(); r.lastErr != nil {
+			return 0, r.lastErr
+		}
+	}
+	if int64(len(p)) > r.remaining {
+		p = p[:r.remaining]
+	}
+	n := copy(p, r.out[r.pos:])
+	r.pos += n
+	r.remaining -= int64(n)
+	return n, nil
+}
+
+// next reads and decrypts the next segment.
+func (r *decryptReader) next() error {
+	n, err := io.ReadFull(r.src, r.sealed)
+	end := false
+	switch {
+	case err == io.EOF || err == io.ErrUnexpectedEOF:
+		end = true
+	case err != nil:
+		return err
+	default:
+		if _, err := r.src.Peek(1); err == io.EOF {
+			end = true
+		} else if err != nil {
+			return err
+		}
+	}
+	if end != (r.index == r.last) {
+		return fmt.Errorf("%w: segment %d", ErrDecryptionFailed, r.index)
+	}
+
+	r.out, err = openSegment(r.out[:0], r.aead, r.data.NoncePrefix, r.index, r.index == r.final, r.sealed[:n])
+	if err != nil {
+		return fmt.Errorf("%w: segment %d", ErrDecryptionFailed, r.index)
+	}
+	r.pos = min(r.skip, len(r.out))
+	r.skip = 0
+	r.index++
+	r.done = end
+	return nil
+}
+
+// Close closes the underlying stream.
+func (r *decryptReader) Close() error {
+	return r.closer.Close()
+}
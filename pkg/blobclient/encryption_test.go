@@ -0,0 +1,281 @@
+package blobclient
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/yourorg/go-service-kit/pkg/logging"
+)
+
+// testKey returns a 32-byte key filled with b.
+func testKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, 32)
+}
+
+// newTestEncryptedClient wraps client with 16-byte segments and a key provider holding k1.
+func newTestEncryptedClient(t *testing.T, client BlobClient) (*EncryptedBlobClient, *LocalKeyProvider) {
+	t.Helper()
+	keys, err := NewLocalKeyProvider("k1", map[string][]byte{"k1": testKey(1)})
+	if err != nil {
+		t.Fatalf("NewLocalKeyProvider failed: %v", err)
+	}
+	logger, _ := logging.NewLogger("error", "json")
+	encrypted, err := NewEncryptedBlobClient(client, keys, logger, WithSegmentSize(16))
+	if err != nil {
+		t.Fatalf("NewEncryptedBlobClient failed: %v", err)
+	}
+	return encrypted, keys
+}
+
+func TestEncryptedBlobClient_RoundTrip(t *testing.T) {
+	_, s3Client := newFakeS3(t)
+	fsClient, _ := newTestFileSystemClient(t)
+	clients := map[string]BlobClient{
+		"mock":       NewMockBlobClient(),
+		"filesystem": fsClient,
+		"s3":         s3Client,
+	}
+
+	ctx := context.Background()
+	for name, inner := range clients {
+		client, _ := newTestEncryptedClient(t, inner)
+		for _, size := range []int{0, 1, 15, 16, 17, 100} {
+			plain := strings.Repeat("x", size)
+			_, err := client.UploadWithOptions(ctx, "payslips", "p.pdf", strings.NewReader(plain), UploadOptions{
+				ContentType: "application/pdf",
+				Metadata:    map[string]string{"employee": "e1"},
+			})
+			if err != nil {
+				t.Fatalf("%s/%d: Upload failed: %v", name, size, err)
+			}
+
+			if got := readBlob(t, client, "payslips", "p.pdf"); got != plain {
+				t.Errorf("%s/%d: decrypted %q", name, size, got)
+			}
+			stored := readBlob(t, inner, "payslips", "p.pdf")
+			segments := size/16 + 1
+			if size > 0 && size%16 == 0 {
+				segments = size / 16
+			}
+			if len(stored) != size+segments*segmentOverhead || size >= 15 && strings.Contains(stored, plain) {
+				t.Errorf("%s/%d: expected %d encrypted segments, stored %d bytes", name, size, segments, len(stored))
+			}
+
+			props, err := client.GetProperties(ctx, "payslips", "p.pdf")
+			if err != nil {
+				t.Fatalf("%s/%d: GetProperties failed: %v", name, size, err)
+			}
+			if props.Size != int64(size) || props.Metadata["employee"] != "e1" || props.Metadata[EncryptionMetadataKey] != "" {
+				t.Errorf("%s/%d: unexpected properties %+v", name, size, props)
+			}
+		}
+
+		plain := "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+		client.Upload(ctx, "payslips", "range.txt", strings.NewReader(plain), "text/plain")
+		for _, r := range []struct{ offset, count int64 }{{0, 1}, {5, 20}, {15, 2}, {16, 16}, {40, 0}, {61, 10}} {
+			reader, err := client.GetRange(ctx, "payslips", "range.txt", r.offset, r.count)
+			if err != nil {
+				t.Fatalf("%s: GetRange(%d, %d) failed: %v", name, r.offset, r.count, err)
+			}
+			got, _ := io.ReadAll(reader)
+			end := int64(len(plain))
+			if r.count > 0 && r.offset+r.count < end {
+				end = r.offset + r.count
+			}
+			if string(got) != plain[r.offset:end] {
+				t.Errorf("%s: GetRange(%d, %d) = %q", name, r.offset, r.count, got)
+			}
+		}
+
+		var buf bytes.Buffer
+		if _, err := client.DownloadChunked(ctx, "payslips", "range.txt", &buf, TransferOptions{BlockSize: 7, Concurrency: 3, Checksum: ChecksumMD5}); err != nil {
+			t.Fatalf("%s: DownloadChunked failed: %v", name, err)
+		}
+		if buf.String() != plain {
+			t.Errorf("%s: DownloadChunked = %q", name, buf.String())
+		}
+	}
+}
+
+func TestEncryptedBlobClient_UploadChunked(t *testing.T) {
+	mock := NewMockBlobClient()
+	client, _ := newTestEncryptedClient(t, mock)
+	ctx := context.Background()
+
+	plain := strings.Repeat("payslip ", 100)
+	if _, err := client.UploadChunked(ctx, "payslips", "big.pdf", strings.NewReader(plain), UploadOptions{}, TransferOptions{BlockSize: 64, Checksum: ChecksumCRC64}); err != nil {
+		t.Fatalf("UploadChunked failed: %v", err)
+	}
+	if got := readBlob(t, client, "payslips", "big.pdf"); got != plain {
+		t.Errorf("Decrypted content does not match")
+	}
+
+	var buf bytes.Buffer
+	if _, err := client.DownloadChunked(ctx, "payslips", "big.pdf", &buf, TransferOptions{BlockSize: 50, Checksum: ChecksumCRC64}); err != nil || buf.String() != plain {
+		t.Errorf("DownloadChunked failed: %v", err)
+	}
+}
+
+func TestEncryptedBlobClient_Tampering(t *testing.T) {
+	mock := NewMockBlobClient()
+	client, _ := newTestEncryptedClient(t, mock)
+	ctx := context.Background()
+	client.Upload(ctx, "payslips", "p.pdf", strings.NewReader(strings.Repeat("salary", 10)), "application/pdf")
+
+	props, _ := mock.GetProperties(ctx, "payslips", "p.pdf")
+	stored := []byte(readBlob(t, mock, "payslips", "p.pdf"))
+	reupload := func(data []byte) {
+		mock.UploadWithOptions(ctx, "payslips", "p.pdf", bytes.NewReader(data), UploadOptions{Metadata: props.Metadata})
+	}
+	readAll := func() error {
+		reader, err := client.Get(ctx, "payslips", "p.pdf")
+		if err != nil {
+			return err
+		}
+		defer reader.Close()
+		_, err = io.ReadAll(reader)
+		return err
+	}
+
+	flipped := bytes.Clone(stored)
+	flipped[20] ^= 1
+	reupload(flipped)
+	if err := readAll(); !errors.Is(err, ErrDecryptionFailed) {
+		t.Errorf("Modified content: expected ErrDecryptionFailed, got %v", err)
+	}
+	rangeReader, err := client.GetRange(ctx, "payslips", "p.pdf", 8, 4)
+	if err != nil {
+		t.Fatalf("GetRange failed: %v", err)
+	}
+	if _, err := io.ReadAll(rangeReader); !errors.Is(err, ErrDecryptionFailed) {
+		t.Errorf("Modified range: expected ErrDecryptionFailed, got %v", err)
+	}
+	rangeReader.Close()
+
+	// Dropping the final segment must not go unnoticed
+	reupload(stored[:2*(16+segmentOverhead)])
+	if err := readAll(); !errors.Is(err, ErrDecryptionFailed) {
+		t.Errorf("Truncated content: expected ErrDecryptionFailed, got %v", err)
+	}
+
+	mock.Upload(ctx, "payslips", "plain.pdf", strings.NewReader("plain"), "application/pdf")
+	if _, err := client.Get(ctx, "payslips", "plain.pdf"); !errors.Is(err, ErrNotEncrypted) {
+		t.Errorf("Unencrypted blob: expected ErrNotEncrypted, got %v", err)
+	}
+	if _, err := client.Get(ctx, "payslips", "missing.pdf"); !errors.Is(err, ErrBlobNotFound) {
+		t.Errorf("Missing blob: expected ErrBlobNotFound, got %v", err)
+	}
+	if _, err := client.GenerateReadURL(ctx, "payslips", "p.pdf", SASOptions{}); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("GenerateReadURL: expected ErrNotSupported, got %v", err)
+	}
+}
+
+// overwritingPropertiesClient overwrites a blob right after its properties are read.
+type overwritingPropertiesClient struct {
+	BlobClient
+}
+
+func (c *overwritingPropertiesClient) GetProperties(ctx context.Context, container, blobName string) (*BlobProperties, error) {
+	props, err := c.BlobClient.GetProperties(ctx, container, blobName)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := c.BlobClient.Upload(ctx, container, blobName, strings.NewReader("replaced"), "application/pdf"); err != nil {
+		return nil, err
+	}
+	return props, nil
+}
+
+func TestEncryptedBlobClient_GetPinsETag(t *testing.T) {
+	mock := NewMockBlobClient()
+	writer, _ := newTestEncryptedClient(t, mock)
+	ctx := context.Background()
+	if _, err := writer.Upload(ctx, "payslips", "p.pdf", strings.NewReader(strings.Repeat("salary", 10)), "application/pdf"); err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+
+	client, _ := newTestEncryptedClient(t, &overwritingPropertiesClient{BlobClient: mock})
+	if _, err := client.Get(ctx, "payslips", "p.pdf"); !errors.Is(err, ErrPreconditionFailed) {
+		t.Errorf("Get: expected ErrPreconditionFailed, got %v", err)
+	}
+}
+
+func TestEncryptedBlobClient_KeyRotation(t *testing.T) {
+	_, s3Client := newFakeS3(t)
+	fsClient, _ := newTestFileSystemClient(t)
+	clients := map[string]BlobClient{
+		"mock":       NewMockBlobClient(),
+		"filesystem": fsClient,
+		"s3":         s3Client,
+	}
+
+	ctx := context.Background()
+	logger, _ := logging.NewLogger("error", "json")
+	for name, inner := range clients {
+		client, keys := newTestEncryptedClient(t, inner)
+		for _, blobName := range []string{"2024/01.pdf", "2024/02.pdf"} {
+			client.UploadWithOptions(ctx, "payslips", blobName, strings.NewReader("net pay "+blobName), UploadOptions{Metadata: map[string]string{"period": "2024"}})
+		}
+		inner.Upload(ctx, "payslips", "2024/readme.txt", strings.NewReader("not encrypted"), "text/plain")
+		stored := readBlob(t, inner, "payslips", "2024/01.pdf")
+
+		if err := keys.AddKey("k2", testKey(2), true); err != nil {
+			t.Fatalf("%s: AddKey failed: %v", name, err)
+		}
+		rewrapped, err := client.RotateKeys(ctx, "payslips", "2024/")
+		if err != nil || rewrapped != 2 {
+			t.Fatalf("%s: expected 2 re-wrapped blobs, got %d (err %v)", name, rewrapped, err)
+		}
+		if again, _ := client.RotateKeys(ctx, "payslips", "2024/"); again != 0 {
+			t.Errorf("%s: expected nothing to re-wrap, got %d", name, again)
+		}
+
+		// Content is unchanged and readable with the new key alone
+		if got := readBlob(t, inner, "payslips", "2024/01.pdf"); got != stored {
+			t.Errorf("%s: expected stored content to be unchanged", name)
+		}
+		onlyK2, _ := NewLocalKeyProvider("k2", map[string][]byte{"k2": testKey(2)})
+		rotated, _ := NewEncryptedBlobClient(inner, onlyK2, logger)
+		if got := readBlob(t, rotated, "payslips", "2024/02.pdf"); got != "net pay 2024/02.pdf" {
+			t.Errorf("%s: decrypted with new key %q", name, got)
+		}
+		props, _ := rotated.GetProperties(ctx, "payslips", "2024/01.pdf")
+		if props.Metadata["period"] != "2024" {
+			t.Errorf("%s: expected metadata to survive rotation, got %+v", name, props.Metadata)
+		}
+
+		// Metadata updates keep the encryption data
+		if err := rotated.SetMetadata(ctx, "payslips", "2024/01.pdf", map[string]string{"period": "2024-01"}, SetMetadataOptions{}); err != nil {
+			t.Fatalf("%s: SetMetadata failed: %v", name, err)
+		}
+		if got := readBlob(t, rotated, "payslips", "2024/01.pdf"); got != "net pay 2024/01.pdf" {
+			t.Errorf("%s: decrypted after SetMetadata %q", name, got)
+		}
+	}
+}
+
+func TestLocalKeyProvider(t *testing.T) {
+	if _, err := NewLocalKeyProvider("k1", map[string][]byte{"k1": []byte("short")}); err == nil {
+		t.Error("Expected error for a short key")
+	}
+	if _, err := NewLocalKeyProvider("k2", map[string][]byte{"k1": testKey(1)}); err == nil {
+		t.Error("Expected error for an unknown current key")
+	}
+
+	ctx := context.Background()
+	keys, _ := NewLocalKeyProvider("k1", map[string][]byte{"k1": testKey(1), "k2": testKey(2)})
+	wrapped, err := keys.WrapKey(ctx, "k1", testKey(9))
+	if err != nil {
+		t.Fatalf("WrapKey failed: %v", err)
+	}
+	if unwrapped, err := keys.UnwrapKey(ctx, "k1", wrapped); err != nil || !bytes.Equal(unwrapped, testKey(9)) {
+		t.Errorf("UnwrapKey = %x, %v", unwrapped, err)
+	}
+	if _, err := keys.UnwrapKey(ctx, "k2", wrapped); err == nil {
+		t.Error("Expected error unwrapping with another key")
+	}
+}
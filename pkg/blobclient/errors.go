@@ -0,0 +1,100 @@
+package blobclient
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	apperrors "github.com/yourorg/go-service-kit/pkg/errors"
+)
+
+// Errors returned by every BlobClient, as the Err of an *errors.AppError with the matching
+// code, so both errors.Is(err, ErrBlobNotFound) and errors.FromError(err) work:
+//
+//	ErrBlobNotFound, ErrContainerNotFound  ErrorCodeNotFound
+//	ErrPreconditionFailed, ErrConflict     ErrorCodeConflict
+//	ErrAccessDenied                        ErrorCodeForbidden
+//	ErrThrottled, ErrCircuitOpen           ErrorCodeServiceUnavailable
+//	ErrTimeout                             ErrorCodeTimeout
+//	ErrInvalidBlobName                     ErrorCodeBadRequest
+var (
+	// ErrBlobNotFound is returned when a blob does not exist.
+	ErrBlobNotFound = errors.New("blob not found")
+	// ErrContainerNotFound is returned when reading from a container that does not exist.
+	ErrContainerNotFound = errors.New("container not found")
+	// ErrPreconditionFailed is returned when an IfMatch/IfNoneMatch condition fails or a
+	// write lacks the lease ID of an active lease.
+	ErrPreconditionFailed = errors.New("precondition failed")
+	// ErrConflict is returned when a blob's state conflicts with the operation, e.g. a lease
+	// is acquired on a blob that is already leased.
+	ErrConflict = errors.New("conflict")
+	// ErrAccessDenied is returned when the credentials are invalid or lack permission.
+	ErrAccessDenied = errors.New("access denied")
+	// ErrThrottled is returned when the service rejects requests because of load; retry later.
+	ErrThrottled = errors.New("request throttled")
+	// ErrCircuitOpen is returned without calling the backend while a ResilientBlobClient
+	// considers it unhealthy.
+	ErrCircuitOpen = errors.New("circuit breaker open")
+	// ErrTimeout is returned when an operation exceeds its ResilientBlobClient deadline.
+	ErrTimeout = errors.New("operation timed out")
+	// ErrInvalidBlobName is returned when a blob name could resolve outside its scope.
+	ErrInvalidBlobName = errors.New("invalid blob name")
+)
+
+// errorCodes maps each sentinel error to its AppError code.
+var errorCodes = map[error]apperrors.ErrorCode{
+	ErrBlobNotFound:       apperrors.ErrorCodeNotFound,
+	ErrContainerNotFound:  apperrors.ErrorCodeNotFound,
+	ErrPreconditionFailed: apperrors.ErrorCodeConflict,
+	ErrConflict:           apperrors.ErrorCodeConflict,
+	ErrAccessDenied:       apperrors.ErrorCodeForbidden,
+	ErrThrottled:          apperrors.ErrorCodeServiceUnavailable,
+	ErrCircuitOpen:        apperrors.ErrorCodeServiceUnavailable,
+	ErrTimeout:            apperrors.ErrorCodeTimeout,
+	ErrInvalidBlobName:    apperrors.ErrorCodeBadRequest,
+}
+
+// blobError returns an AppError for a sentinel error. cause is the underlying service
+// error, if any, and stays reachable with errors.As.
+func blobError(sentinel error, message string, cause error) error {
+	err := sentinel
+	if cause != nil {
+		err = fmt.Errorf("%w: %w", sentinel, cause)
+	}
+	code := errorCodes[sentinel]
+	return apperrors.NewAppErrorWithErr(code, message, apperrors.ToHTTPStatus(code), err)
+}
+
+// blobNotFound returns ErrBlobNotFound for a blob.
+func blobNotFound(container, blobName string, cause error) error {
+	return blobError(ErrBlobNotFound, fmt.Sprintf("blob not found: %s/%s", container, blobName), cause)
+}
+
+// containerNotFound returns ErrContainerNotFound for a container.
+func containerNotFound(container string, cause error) error {
+	return blobError(ErrContainerNotFound, "container not found: "+container, cause)
+}
+
+// invalidBlobName returns ErrInvalidBlobName for a blob name.
+func invalidBlobName(blobName string) error {
+	return blobError(ErrInvalidBlobName, fmt.Sprintf("invalid blob name: %q", blobName), nil)
+}
+
+// classifyStatus returns the sentinel error for an HTTP status from a storage service, or
+// nil if the status has no typed error. Backends refine 404s and 409s by service error code.
+func classifyStatus(status int) error {
+	switch status {
+	case http.StatusNotFound:
+		return ErrBlobNotFound
+	case http.StatusPreconditionFailed:
+		return ErrPreconditionFailed
+	case http.StatusConflict:
+		return ErrConflict
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return ErrAccessDenied
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		return ErrThrottled
+	default:
+		return nil
+	}
+}
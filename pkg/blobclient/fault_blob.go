@@ -0,0 +1,312 @@
+package blobclient
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// Fault is a failure injected by a FaultInjectingBlobClient.
+type Fault struct {
+	// Err is returned instead of calling the wrapped client. Nil calls it after Latency.
+	Err error
+	// Latency delays the call, or fails it with the context error if the context ends first.
+	Latency time.Duration
+	// Times is the number of calls the fault applies to; zero applies it until cleared.
+	Times int
+}
+
+// FaultInjectingBlobClient is a BlobClient decorator for tests that fails or delays calls
+// to selected operations, to exercise retry, timeout and circuit breaker behaviour.
+type FaultInjectingBlobClient struct {
+	client BlobClient
+
+	mu     sync.Mutex
+	faults map[string]*Fault
+	calls  map[string]int
+}
+
+// NewFaultInjectingBlobClient wraps client without any faults.
+func NewFaultInjectingBlobClient(client BlobClient) *FaultInjectingBlobClient {
+	return &FaultInjectingBlobClient{client: client, faults: make(map[string]*Fault), calls: make(map[string]int)}
+}
+
+// Inject applies fault to op, a BlobClient method name such as "Get", or "*" for every
+// operation without a fault of its own.
+func (f *FaultInjectingBlobClient) Inject(op string, fault Fault) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.faults[op] = &fault
+}
+
+// Clear removes all faults.
+func (f *FaultInjectingBlobClient) Clear() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.faults = make(map[string]*Fault)
+}
+
+// Calls returns the number of calls made to op, including failed ones.
+func (f *FaultInjectingBlobClient) Calls(op string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls[op]
+}
+
+// fault counts a call to op and applies its fault, if any.
+func (f *FaultInjectingBlobClient) fault(ctx context.Context, op string) error {
+	f.mu.Lock()
+	f.calls[op]++
+	key := op
+	fault, ok := f.faults[key]
+	if !ok {
+		key = "*"
+		fault, ok = f.faults[key]
+	}
+	var applied Fault
+	if ok {
+		applied = *fault
+		if fault.Times > 0 {
+			if fault.Times--; fault.Times == 0 {
+				delete(f.faults, key)
+			}
+		}
+	}
+	f.mu.Unlock()
+
+	if applied.Latency > 0 {
+		timer := time.NewTimer(applied.Latency)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+	return applied.Err
+}
+
+// Upload uploads data unless a fault is injected.
+func (f *FaultInjectingBlobClient) Upload(ctx context.Context, container, blobName string, data io.Reader, contentType string) (string, error) {
+	if err := f.fault(ctx, "Upload"); err != nil {
+		return "", err
+	}
+	return f.client.Upload(ctx, container, blobName, data, contentType)
+}
+
+// UploadWithOptions uploads data unless a fault is injected.
+func (f *FaultInjectingBlobClient) UploadWithOptions(ctx context.Context, container, blobName string, data io.Reader, opts UploadOptions) (string, error) {
+	if err := f.fault(ctx, "UploadWithOptions"); err != nil {
+		return "", err
+	}
+	return f.client.UploadWithOptions(ctx, container, blobName, data, opts)
+}
+
+// UploadChunked uploads data in blocks unless a fault is injected.
+func (f *FaultInjectingBlobClient) UploadChunked(ctx context.Context, container, blobName string, data io.Reader, opts UploadOptions, transfer TransferOptions) (string, error) {
+	if err := f.fault(ctx, "UploadChunked"); err != nil {
+		return "", err
+	}
+	return f.client.UploadChunked(ctx, container, blobName, data, opts, transfer)
+}
+
+// Get opens a blob unless a fault is injected.
+func (f *FaultInjectingBlobClient) Get(ctx context.Context, container, blobName string) (io.ReadCloser, error) {
+	if err := f.fault(ctx, "Get"); err != nil {
+		return nil, err
+	}
+	return f.client.Get(ctx, container, blobName)
+}
+
+// GetRange opens a byte range of a blob unless a fault is injected.
+func (f *FaultInjectingBlobClient) GetRange(ctx context.Context, container, blobName string, offset, count int64) (io.ReadCloser, error) {
+	if err := f.fault(ctx, "GetRange"); err != nil {
+		return nil, err
+	}
+	return f.client.GetRange(ctx, container, blobName, offset, count)
+}
+
+// GetRangeWithOptions opens a byte range of a blob unless a fault is injected.
+func (f *FaultInjectingBlobClient) GetRangeWithOptions(ctx context.Context, container, blobName string, offset, count int64, opts GetOptions) (io.ReadCloser, error) {
+	if err := f.fault(ctx, "GetRangeWithOptions"); err != nil {
+		return nil, err
+	}
+	return f.client.GetRangeWithOptions(ctx, container, blobName, offset, count, opts)
+}
+
+// DownloadChunked downloads a blob unless a fault is injected.
+func (f *FaultInjectingBlobClient) DownloadChunked(ctx context.Context, container, blobName string, w io.Writer, transfer TransferOptions) (int64, error) {
+	if err := f.fault(ctx, "DownloadChunked"); err != nil {
+		return 0, err
+	}
+	return f.client.DownloadChunked(ctx, container, blobName, w, transfer)
+}
+
+// Delete deletes a blob unless a fault is injected.
+func (f *FaultInjectingBlobClient) Delete(ctx context.Context, container, blobName string) error {
+	if err := f.fault(ctx, "Delete"); err != nil {
+		return err
+	}
+	return f.client.Delete(ctx, container, blobName)
+}
+
+// DeleteWithOptions deletes a blob unless a fault is injected.
+func (f *FaultInjectingBlobClient) DeleteWithOptions(ctx context.Context, container, blobName string, opts DeleteOptions) error {
+	if err := f.fault(ctx, "DeleteWithOptions"); err != nil {
+		return err
+	}
+	return f.client.DeleteWithOptions(ctx, container, blobName, opts)
+}
+
+// Exists checks whether a blob exists unless a fault is injected.
+func (f *FaultInjectingBlobClient) Exists(ctx context.Context, container, blobName string) (bool, error) {
+	if err := f.fault(ctx, "Exists"); err != nil {
+		return false, err
+	}
+	return f.client.Exists(ctx, container, blobName)
+}
+
+// List lists blobs unless a fault is injected.
+func (f *FaultInjectingBlobClient) List(ctx context.Context, container, prefix string) ([]BlobInfo, error) {
+	if err := f.fault(ctx, "List"); err != nil {
+		return nil, err
+	}
+	return f.client.List(ctx, container, prefix)
+}
+
+// ListPage lists a page of blobs unless a fault is injected.
+func (f *FaultInjectingBlobClient) ListPage(ctx context.Context, container, prefix, continuationToken string, pageSize int) (*BlobPage, error) {
+	if err := f.fault(ctx, "ListPage"); err != nil {
+		return nil, err
+	}
+	return f.client.ListPage(ctx, container, prefix, continuationToken, pageSize)
+}
+
+// ListPageWithOptions lists a page of blobs unless a fault is injected.
+func (f *FaultInjectingBlobClient) ListPageWithOptions(ctx context.Context, container, continuationToken string, opts ListOptions) (*BlobPage, error) {
+	if err := f.fault(ctx, "ListPageWithOptions"); err != nil {
+		return nil, err
+	}
+	return f.client.ListPageWithOptions(ctx, container, continuationToken, opts)
+}
+
+// Copy copies a blob unless a fault is injected.
+func (f *FaultInjectingBlobClient) Copy(ctx context.Context, srcContainer, srcBlob, dstContainer, dstBlob string) (*CopyStatus, error) {
+	if err := f.fault(ctx, "Copy"); err != nil {
+		return nil, err
+	}
+	return f.client.Copy(ctx, srcContainer, srcBlob, dstContainer, dstBlob)
+}
+
+// GetCopyStatus returns the status of a copy unless a fault is injected.
+func (f *FaultInjectingBlobClient) GetCopyStatus(ctx context.Context, container, blobName string) (*CopyStatus, error) {
+	if err := f.fault(ctx, "GetCopyStatus"); err != nil {
+		return nil, err
+	}
+	return f.client.GetCopyStatus(ctx, container, blobName)
+}
+
+// Move moves a blob unless a fault is injected.
+func (f *FaultInjectingBlobClient) Move(ctx context.Context, srcContainer, srcBlob, dstContainer, dstBlob string) (string, error) {
+	if err := f.fault(ctx, "Move"); err != nil {
+		return "", err
+	}
+	return f.client.Move(ctx, srcContainer, srcBlob, dstContainer, dstBlob)
+}
+
+// Snapshot creates a snapshot unless a fault is injected.
+func (f *FaultInjectingBlobClient) Snapshot(ctx context.Context, container, blobName string) (string, error) {
+	if err := f.fault(ctx, "Snapshot"); err != nil {
+		return "", err
+	}
+	return f.client.Snapshot(ctx, container, blobName)
+}
+
+// ListSnapshots lists the snapshots of a blob unless a fault is injected.
+func (f *FaultInjectingBlobClient) ListSnapshots(ctx context.Context, container, blobName string) ([]BlobInfo, error) {
+	if err := f.fault(ctx, "ListSnapshots"); err != nil {
+		return nil, err
+	}
+	return f.client.ListSnapshots(ctx, container, blobName)
+}
+
+// Undelete restores a deleted blob unless a fault is injected.
+func (f *FaultInjectingBlobClient) Undelete(ctx context.Context, container, blobName string) error {
+	if err := f.fault(ctx, "Undelete"); err != nil {
+		return err
+	}
+	return f.client.Undelete(ctx, container, blobName)
+}
+
+// AcquireLease acquires a lease unless a fault is injected.
+func (f *FaultInjectingBlobClient) AcquireLease(ctx context.Context, container, blobName string, duration time.Duration, proposedLeaseID string) (string, error) {
+	if err := f.fault(ctx, "AcquireLease"); err != nil {
+		return "", err
+	}
+	return f.client.AcquireLease(ctx, container, blobName, duration, proposedLeaseID)
+}
+
+// RenewLease renews a lease unless a fault is injected.
+func (f *FaultInjectingBlobClient) RenewLease(ctx context.Context, container, blobName, leaseID string) error {
+	if err := f.fault(ctx, "RenewLease"); err != nil {
+		return err
+	}
+	return f.client.RenewLease(ctx, container, blobName, leaseID)
+}
+
+// ReleaseLease releases a lease unless a fault is injected.
+func (f *FaultInjectingBlobClient) ReleaseLease(ctx context.Context, container, blobName, leaseID string) error {
+	if err := f.fault(ctx, "ReleaseLease"); err != nil {
+		return err
+	}
+	return f.client.ReleaseLease(ctx, container, blobName, leaseID)
+}
+
+// BreakLease breaks a lease unless a fault is injected.
+func (f *FaultInjectingBlobClient) BreakLease(ctx context.Context, container, blobName string, breakPeriod time.Duration) (time.Duration, error) {
+	if err := f.fault(ctx, "BreakLease"); err != nil {
+		return 0, err
+	}
+	return f.client.BreakLease(ctx, container, blobName, breakPeriod)
+}
+
+// GetProperties returns the properties of a blob unless a fault is injected.
+func (f *FaultInjectingBlobClient) GetProperties(ctx context.Context, container, blobName string) (*BlobProperties, error) {
+	if err := f.fault(ctx, "GetProperties"); err != nil {
+		return nil, err
+	}
+	return f.client.GetProperties(ctx, container, blobName)
+}
+
+// SetMetadata replaces the metadata of a blob unless a fault is injected.
+func (f *FaultInjectingBlobClient) SetMetadata(ctx context.Context, container, blobName string, metadata map[string]string, opts SetMetadataOptions) error {
+	if err := f.fault(ctx, "SetMetadata"); err != nil {
+		return err
+	}
+	return f.client.SetMetadata(ctx, container, blobName, metadata, opts)
+}
+
+// SetAccessTier changes the access tier of a blob unless a fault is injected.
+func (f *FaultInjectingBlobClient) SetAccessTier(ctx context.Context, container, blobName, tier string) error {
+	if err := f.fault(ctx, "SetAccessTier"); err != nil {
+		return err
+	}
+	return f.client.SetAccessTier(ctx, container, blobName, tier)
+}
+
+// GenerateReadURL returns a signed read URL unless a fault is injected.
+func (f *FaultInjectingBlobClient) GenerateReadURL(ctx context.Context, container, blobName string, opts SASOptions) (string, error) {
+	if err := f.fault(ctx, "GenerateReadURL"); err != nil {
+		return "", err
+	}
+	return f.client.GenerateReadURL(ctx, container, blobName, opts)
+}
+
+// GenerateWriteURL returns a signed write URL unless a fault is injected.
+func (f *FaultInjectingBlobClient) GenerateWriteURL(ctx context.Context, container, blobName string, opts SASOptions) (string, error) {
+	if err := f.fault(ctx, "GenerateWriteURL"); err != nil {
+		return "", err
+	}
+	return f.client.GenerateWriteURL(ctx, container, blobName, opts)
+}
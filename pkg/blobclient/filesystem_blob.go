@@ -0,0 +1,927 @@
+package blobclient
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yourorg/go-service-kit/pkg/logging"
+)
+
+const (
+	// sidecarSuffix marks the metadata file stored next to each blob.
+	sidecarSuffix = ".blobmeta.json"
+	// tempDirName and blocksDirName live under the root, outside any container
+	// (container names cannot start with a dot).
+	tempDirName      = ".tmp"
+	blocksDirName    = ".blocks"
+	snapshotsDirName = ".snapshots"
+)
+
+// containerNamePattern follows the Azure container naming rules.
+var containerNamePattern = regexp.MustCompile(`^[a-z0-9](?:[a-z0-9]|-[a-z0-9]){2,62}$`)
+
+// FileSystemBlobClient implements BlobClient on a local directory, for development
+// without cloud storage. Containers are directories under the root, blob names map to
+// relative paths, and properties are kept in a JSON sidecar file next to each blob.
+// Writes go to a temporary file that is renamed into place, so readers never observe
+// partial blobs. Leases are held in memory and only exclude writers in the same process.
+type FileSystemBlobClient struct {
+	root   string
+	logger logging.Logger
+	signer *urlSigner
+
+	mu     sync.RWMutex
+	leases *leaseTable
+}
+
+// fsSidecar is the content of a blob's metadata sidecar.
+type fsSidecar struct {
+	ContentType        string            `json:"content_type,omitempty"`
+	CacheControl       string            `json:"cache_control,omitempty"`
+	ContentDisposition string            `json:"content_disposition,omitempty"`
+	ContentMD5         []byte            `json:"content_md5,omitempty"`
+	AccessTier         string            `json:"access_tier,omitempty"`
+	Metadata           map[string]string `json:"metadata,omitempty"`
+	Tags               map[string]string `json:"tags,omitempty"`
+	ETag               string            `json:"etag,omitempty"`
+}
+
+// NewFileSystemBlobClient creates a blob client storing data under root, creating it if needed.
+func NewFileSystemBlobClient(root string, logger logging.Logger) (*FileSystemBlobClient, error) {
+	if logger == nil {
+		return nil, fmt.Errorf("logger is required")
+	}
+
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve blob root: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Join(absRoot, tempDirName), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create blob root: %w", err)
+	}
+
+	return &FileSystemBlobClient{
+		root:   absRoot,
+		logger: logger,
+		signer: newURLSigner("fs"),
+		leases: newLeaseTable(),
+	}, nil
+}
+
+// Upload writes data to a blob with the given content type.
+func (f *FileSystemBlobClient) Upload(ctx context.Context, container, blobName string, data io.Reader, contentType string) (string, error) {
+	return f.UploadWithOptions(ctx, container, blobName, data, UploadOptions{ContentType: contentType})
+}
+
+// UploadWithOptions writes data to a blob and records its properties in the sidecar.
+func (f *FileSystemBlobClient) UploadWithOptions(ctx context.Context, container, blobName string, data io.Reader, opts UploadOptions) (string, error) {
+	path, err := f.blobPath(container, blobName)
+	if err != nil {
+		return "", err
+	}
+
+	tmp, err := f.writeTemp(data)
+	if err != nil {
+		return "", fmt.Errorf("failed to upload blob: %w", err)
+	}
+	defer os.Remove(tmp)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.checkWrite(path, container, blobName, opts); err != nil {
+		return "", err
+	}
+	if err := f.commit(tmp, path, sidecarFromOptions(opts, nil)); err != nil {
+		return "", fmt.Errorf("failed to upload blob: %w", err)
+	}
+
+	f.logger.Debug("Blob written to file system",
+		logging.NewField("container", container),
+		logging.NewField("blob", blobName),
+	)
+	return fileURL(path), nil
+}
+
+// UploadChunked uploads data as staged blocks under the root, supporting resume.
+func (f *FileSystemBlobClient) UploadChunked(ctx context.Context, container, blobName string, data io.Reader, opts UploadOptions, transfer TransferOptions) (string, error) {
+	if _, err := f.blobPath(container, blobName); err != nil {
+		return "", err
+	}
+	return uploadChunked(ctx, f, container, blobName, data, opts, transfer)
+}
+
+// prepareBlocks returns the blocks staged for a blob.
+func (f *FileSystemBlobClient) prepareBlocks(ctx context.Context, container, blobName string, opts UploadOptions, resumeID string) (string, map[string]int64, error) {
+	entries, err := os.ReadDir(f.blocksDir(container, blobName))
+	if os.IsNotExist(err) {
+		return "", map[string]int64{}, nil
+	}
+	if err != nil {
+		return "", nil, err
+	}
+
+	staged := make(map[string]int64, len(entries))
+	for _, entry := range entries {
+		id, err := hex.DecodeString(entry.Name())
+		if err != nil {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return "", nil, err
+		}
+		staged[string(id)] = info.Size()
+	}
+	return "", staged, nil
+}
+
+// stageBlock verifies and stores a block file.
+func (f *FileSystemBlobClient) stageBlock(ctx context.Context, container, blobName, upload, leaseID, blockID string, data []byte, algorithm ChecksumAlgorithm, sum []byte) error {
+	if err := verifyBlockChecksum(data, algorithm, sum); err != nil {
+		return err
+	}
+
+	f.mu.RLock()
+	err := f.leases.checkWrite(container+"/"+blobName, leaseID)
+	f.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	dir := f.blocksDir(container, blobName)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := f.writeTemp(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+	return os.Rename(tmp, filepath.Join(dir, hex.EncodeToString([]byte(blockID))))
+}
+
+// commitBlocks concatenates staged blocks into the blob and removes the staging directory.
+func (f *FileSystemBlobClient) commitBlocks(ctx context.Context, container, blobName, upload string, blockIDs []string, opts UploadOptions, contentMD5 []byte) (string, error) {
+	path, err := f.blobPath(container, blobName)
+	if err != nil {
+		return "", err
+	}
+	dir := f.blocksDir(container, blobName)
+
+	readers := make([]io.Reader, 0, len(blockIDs))
+	for _, id := range blockIDs {
+		block, err := os.Open(filepath.Join(dir, hex.EncodeToString([]byte(id))))
+		if err != nil {
+			return "", fmt.Errorf("block not found: %s", id)
+		}
+		defer block.Close()
+		readers = append(readers, block)
+	}
+
+	tmp, err := f.writeTemp(io.MultiReader(readers...))
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.checkWrite(path, container, blobName, opts); err != nil {
+		return "", err
+	}
+	if err := f.commit(tmp, path, sidecarFromOptions(opts, contentMD5)); err != nil {
+		return "", err
+	}
+	os.RemoveAll(dir)
+
+	return fileURL(path), nil
+}
+
+// Get opens a blob for reading.
+func (f *FileSystemBlobClient) Get(ctx context.Context, container, blobName string) (io.ReadCloser, error) {
+	path, err := f.blobPath(container, blobName)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, f.notFound(err, container, blobName)
+	}
+	return file, nil
+}
+
+// GetRange opens count bytes of a blob starting at offset. A count of 0 reads to the end.
+func (f *FileSystemBlobClient) GetRange(ctx context.Context, container, blobName string, offset, count int64) (io.ReadCloser, error) {
+	return f.GetRangeWithOptions(ctx, container, blobName, offset, count, GetOptions{})
+}
+
+// GetRangeWithOptions opens a byte range of a blob if it matches opts.IfMatch.
+func (f *FileSystemBlobClient) GetRangeWithOptions(ctx context.Context, container, blobName string, offset, count int64, opts GetOptions) (io.ReadCloser, error) {
+	path, err := f.blobPath(container, blobName)
+	if err != nil {
+		return nil, err
+	}
+
+	f.mu.RLock()
+	file, err := os.Open(path)
+	if err == nil && opts.IfMatch != "" {
+		err = f.checkRead(file, path, opts.IfMatch)
+		if err != nil {
+			file.Close()
+		}
+	}
+	f.mu.RUnlock()
+	if err != nil {
+		return nil, f.notFound(err, container, blobName)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	size := info.Size()
+	if offset < 0 || count < 0 || offset >= size && size > 0 {
+		file.Close()
+		return nil, fmt.Errorf("invalid range: offset %d, count %d, size %d", offset, count, size)
+	}
+	if count == 0 || offset+count > size {
+		count = size - offset
+	}
+
+	return &sectionReadCloser{SectionReader: io.NewSectionReader(file, offset, count), file: file}, nil
+}
+
+// DownloadChunked writes a blob to w using parallel ranged reads.
+func (f *FileSystemBlobClient) DownloadChunked(ctx context.Context, container, blobName string, w io.Writer, transfer TransferOptions) (int64, error) {
+	return downloadChunked(ctx, f, container, blobName, w, transfer)
+}
+
+// readRange reads a byte range and, if rangeMD5 is set, its MD5.
+func (f *FileSystemBlobClient) readRange(ctx context.Context, container, blobName string, offset, count int64, ifMatch string, rangeMD5 bool) ([]byte, []byte, error) {
+	reader, err := f.GetRangeWithOptions(ctx, container, blobName, offset, count, GetOptions{IfMatch: ifMatch})
+	if err != nil {
+		return nil, nil, err
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !rangeMD5 {
+		return data, nil, nil
+	}
+	sum := md5.Sum(data)
+	return data, sum[:], nil
+}
+
+// GetProperties returns the properties from the blob's sidecar and file info.
+func (f *FileSystemBlobClient) GetProperties(ctx context.Context, container, blobName string) (*BlobProperties, error) {
+	path, err := f.blobPath(container, blobName)
+	if err != nil {
+		return nil, err
+	}
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, f.notFound(err, container, blobName)
+	}
+	sidecar, err := readSidecar(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &BlobProperties{
+		Name:               blobName,
+		Size:               info.Size(),
+		ContentType:        sidecar.ContentType,
+		CacheControl:       sidecar.CacheControl,
+		ContentDisposition: sidecar.ContentDisposition,
+		ContentMD5:         sidecar.ContentMD5,
+		AccessTier:         sidecar.AccessTier,
+		Metadata:           copyStringMap(sidecar.Metadata),
+		Tags:               copyStringMap(sidecar.Tags),
+		LastModified:       info.ModTime().UTC().Format(time.RFC3339),
+		ETag:               fileETag(sidecar, info),
+		URL:                fileURL(path),
+	}, nil
+}
+
+// SetMetadata replaces the metadata in a blob's sidecar file and gives it a new ETag and
+// modification time.
+func (f *FileSystemBlobClient) SetMetadata(ctx context.Context, container, blobName string, metadata map[string]string, opts SetMetadataOptions) error {
+	path, err := f.blobPath(container, blobName)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, err := os.Stat(path); err != nil {
+		return f.notFound(err, container, blobName)
+	}
+	if err := f.checkWrite(path, container, blobName, UploadOptions{IfMatch: opts.IfMatch, LeaseID: opts.LeaseID}); err != nil {
+		return err
+	}
+	sidecar, err := readSidecar(path)
+	if err != nil {
+		return err
+	}
+	sidecar.Metadata = copyStringMap(metadata)
+	sidecar.ETag = newETag()
+
+	if err := f.writeSidecar(path, sidecar); err != nil {
+		return fmt.Errorf("failed to write blob metadata: %w", err)
+	}
+	// Metadata changes update the modification time, as in Azure
+	now := time.Now()
+	if err := os.Chtimes(path, now, now); err != nil {
+		return fmt.Errorf("failed to update blob modification time: %w", err)
+	}
+	return nil
+}
+
+// SetAccessTier records a new access tier in a blob's sidecar file. The file system has
+// no tiers, so the blob stays readable and keeps its ETag.
+func (f *FileSystemBlobClient) SetAccessTier(ctx context.Context, container, blobName, tier string) error {
+	path, err := f.blobPath(container, blobName)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return f.notFound(err, container, blobName)
+	}
+	sidecar, err := readSidecar(path)
+	if err != nil {
+		return err
+	}
+	sidecar.ETag = fileETag(sidecar, info)
+	sidecar.AccessTier = tier
+
+	if err := f.writeSidecar(path, sidecar); err != nil {
+		return fmt.Errorf("failed to write blob access tier: %w", err)
+	}
+	return nil
+}
+
+// GenerateReadURL returns a signed fs:// URL, verifiable with VerifySignedURL.
+func (f *FileSystemBlobClient) GenerateReadURL(ctx context.Context, container, blobName string, opts SASOptions) (string, error) {
+	if _, err := f.blobPath(container, blobName); err != nil {
+		return "", err
+	}
+	return f.signer.sign(container, blobName, opts, readPermissions)
+}
+
+// GenerateWriteURL returns a signed fs:// URL, verifiable with VerifySignedURL.
+func (f *FileSystemBlobClient) GenerateWriteURL(ctx context.Context, container, blobName string, opts SASOptions) (string, error) {
+	if _, err := f.blobPath(container, blobName); err != nil {
+		return "", err
+	}
+	return f.signer.sign(container, blobName, opts, writePermissions)
+}
+
+// VerifySignedURL checks a URL issued by this client and returns the blob it refers to.
+func (f *FileSystemBlobClient) VerifySignedURL(rawURL string, required SASPermissions, clientIP string) (container, blobName string, err error) {
+	container, blobName, _, err = f.signer.verify(rawURL, required, clientIP)
+	return container, blobName, err
+}
+
+// Delete removes a blob, its sidecar and its snapshots.
+func (f *FileSystemBlobClient) Delete(ctx context.Context, container, blobName string) error {
+	return f.DeleteWithOptions(ctx, container, blobName, DeleteOptions{})
+}
+
+// DeleteWithOptions removes a blob if its ETag and lease match opts.
+func (f *FileSystemBlobClient) DeleteWithOptions(ctx context.Context, container, blobName string, opts DeleteOptions) error {
+	path, err := f.blobPath(container, blobName)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.checkWrite(path, container, blobName, UploadOptions{IfMatch: opts.IfMatch, LeaseID: opts.LeaseID}); err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil {
+		return f.notFound(err, container, blobName)
+	}
+	if err := os.Remove(path + sidecarSuffix); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete blob metadata: %w", err)
+	}
+	if err := os.RemoveAll(f.snapshotsDir(container, blobName)); err != nil {
+		return fmt.Errorf("failed to delete blob snapshots: %w", err)
+	}
+	f.leases.remove(container + "/" + blobName)
+	return nil
+}
+
+// Exists checks if a blob exists.
+func (f *FileSystemBlobClient) Exists(ctx context.Context, container, blobName string) (bool, error) {
+	path, err := f.blobPath(container, blobName)
+	if err != nil {
+		return false, err
+	}
+
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to check blob existence: %w", err)
+	}
+	return info.Mode().IsRegular(), nil
+}
+
+// List lists blobs in a container with optional prefix, ordered by name.
+func (f *FileSystemBlobClient) List(ctx context.Context, container, prefix string) ([]BlobInfo, error) {
+	blobs, err := f.blobInfos(container, prefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list blobs: %w", err)
+	}
+	return blobs, nil
+}
+
+// ListPage returns one page of blobs in name order.
+func (f *FileSystemBlobClient) ListPage(ctx context.Context, container, prefix, continuationToken string, pageSize int) (*BlobPage, error) {
+	return f.ListPageWithOptions(ctx, container, continuationToken, ListOptions{Prefix: prefix, PageSize: pageSize})
+}
+
+// ListPageWithOptions returns one page of blobs and virtual directories in name order.
+// Each page walks the container, so very large containers are better served by a real
+// storage service.
+func (f *FileSystemBlobClient) ListPageWithOptions(ctx context.Context, container, continuationToken string, opts ListOptions) (*BlobPage, error) {
+	blobs, err := f.blobInfos(container, opts.Prefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list blobs: %w", err)
+	}
+	return pageBlobs(blobs, opts, continuationToken)
+}
+
+// blobInfos walks a container and returns the blobs matching prefix sorted by name.
+func (f *FileSystemBlobClient) blobInfos(container, prefix string) ([]BlobInfo, error) {
+	if !containerNamePattern.MatchString(container) {
+		return nil, fmt.Errorf("invalid container name: %s", container)
+	}
+	containerDir := filepath.Join(f.root, container)
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	blobs := []BlobInfo{}
+	err := filepath.WalkDir(containerDir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == containerDir {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if entry.IsDir() || strings.HasSuffix(path, sidecarSuffix) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(containerDir, path)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+		if !strings.HasPrefix(name, prefix) {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		sidecar, err := readSidecar(path)
+		if err != nil {
+			return err
+		}
+		contentType := sidecar.ContentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+
+		blobs = append(blobs, BlobInfo{
+			Name:         name,
+			Size:         info.Size(),
+			ContentType:  contentType,
+			LastModified: info.ModTime().UTC().Format(time.RFC3339),
+			URL:          fileURL(path),
+			ETag:         fileETag(sidecar, info),
+			AccessTier:   sidecar.AccessTier,
+			Metadata:     copyStringMap(sidecar.Metadata),
+			Tags:         copyStringMap(sidecar.Tags),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sortBlobs(blobs)
+	return blobs, nil
+}
+
+// Copy copies a blob and its sidecar. File system copies complete before Copy returns.
+func (f *FileSystemBlobClient) Copy(ctx context.Context, srcContainer, srcBlob, dstContainer, dstBlob string) (*CopyStatus, error) {
+	srcPath, err := f.blobPath(srcContainer, srcBlob)
+	if err != nil {
+		return nil, err
+	}
+	dstPath, err := f.blobPath(dstContainer, dstBlob)
+	if err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.leases.checkWrite(dstContainer+"/"+dstBlob, ""); err != nil {
+		return nil, err
+	}
+	size, err := f.copyFile(srcPath, dstPath)
+	if err != nil {
+		return nil, f.notFound(err, srcContainer, srcBlob)
+	}
+	return &CopyStatus{Status: CopyStatusSuccess, BytesCopied: size, TotalBytes: size}, nil
+}
+
+// GetCopyStatus reports a completed copy for any existing blob, since copies are synchronous.
+func (f *FileSystemBlobClient) GetCopyStatus(ctx context.Context, container, blobName string) (*CopyStatus, error) {
+	path, err := f.blobPath(container, blobName)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, f.notFound(err, container, blobName)
+	}
+	return &CopyStatus{Status: CopyStatusSuccess, BytesCopied: info.Size(), TotalBytes: info.Size()}, nil
+}
+
+// Move copies a blob and deletes the source.
+func (f *FileSystemBlobClient) Move(ctx context.Context, srcContainer, srcBlob, dstContainer, dstBlob string) (string, error) {
+	dstPath, err := f.blobPath(dstContainer, dstBlob)
+	if err != nil {
+		return "", err
+	}
+	if err := moveBlob(ctx, f, DefaultCopyPollInterval, srcContainer, srcBlob, dstContainer, dstBlob); err != nil {
+		return "", err
+	}
+	return fileURL(dstPath), nil
+}
+
+// Snapshot copies a blob into its snapshot directory under the root.
+func (f *FileSystemBlobClient) Snapshot(ctx context.Context, container, blobName string) (string, error) {
+	path, err := f.blobPath(container, blobName)
+	if err != nil {
+		return "", err
+	}
+	dir := f.snapshotsDir(container, blobName)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	id := newSnapshotID(func(id string) bool {
+		_, err := os.Stat(filepath.Join(dir, hex.EncodeToString([]byte(id))))
+		return err == nil
+	})
+	if _, err := f.copyFile(path, filepath.Join(dir, hex.EncodeToString([]byte(id)))); err != nil {
+		return "", f.notFound(err, container, blobName)
+	}
+	return id, nil
+}
+
+// ListSnapshots lists the snapshots of a blob, oldest first.
+func (f *FileSystemBlobClient) ListSnapshots(ctx context.Context, container, blobName string) ([]BlobInfo, error) {
+	if _, err := f.blobPath(container, blobName); err != nil {
+		return nil, err
+	}
+	dir := f.snapshotsDir(container, blobName)
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return []BlobInfo{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list snapshots: %w", err)
+	}
+
+	snapshots := []BlobInfo{}
+	for _, entry := range entries {
+		id, err := hex.DecodeString(entry.Name())
+		if err != nil {
+			continue // sidecar
+		}
+		path := filepath.Join(dir, entry.Name())
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		sidecar, err := readSidecar(path)
+		if err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, BlobInfo{
+			Name:         blobName,
+			Size:         info.Size(),
+			ContentType:  sidecar.ContentType,
+			LastModified: info.ModTime().UTC().Format(time.RFC3339),
+			URL:          fileURL(path),
+			Snapshot:     string(id),
+		})
+	}
+
+	// Snapshot IDs are fixed-width timestamps, so name order is creation order
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].Snapshot < snapshots[j].Snapshot })
+	return snapshots, nil
+}
+
+// Undelete is not supported: file system deletes are permanent.
+func (f *FileSystemBlobClient) Undelete(ctx context.Context, container, blobName string) error {
+	return fmt.Errorf("failed to undelete blob %s/%s: %w", container, blobName, ErrNotSupported)
+}
+
+// copyFile copies the blob at src, with its sidecar, to dst and returns its size.
+// Callers must hold the write lock.
+func (f *FileSystemBlobClient) copyFile(src, dst string) (int64, error) {
+	file, err := os.Open(src)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	sidecar, err := readSidecar(src)
+	if err != nil {
+		return 0, err
+	}
+	sidecar.ETag = newETag()
+	tmp, err := f.writeTemp(file)
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp)
+
+	info, err := os.Stat(tmp)
+	if err != nil {
+		return 0, err
+	}
+	if err := f.commit(tmp, dst, sidecar); err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+// AcquireLease takes a lease on a blob. Leases are not shared with other processes.
+func (f *FileSystemBlobClient) AcquireLease(ctx context.Context, container, blobName string, duration time.Duration, proposedLeaseID string) (string, error) {
+	path, err := f.blobPath(container, blobName)
+	if err != nil {
+		return "", err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, err := os.Stat(path); err != nil {
+		return "", f.notFound(err, container, blobName)
+	}
+	return f.leases.acquire(container+"/"+blobName, duration, proposedLeaseID)
+}
+
+// RenewLease restarts the duration of a lease.
+func (f *FileSystemBlobClient) RenewLease(ctx context.Context, container, blobName, leaseID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.leases.renew(container+"/"+blobName, leaseID)
+}
+
+// ReleaseLease ends a lease.
+func (f *FileSystemBlobClient) ReleaseLease(ctx context.Context, container, blobName, leaseID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.leases.release(container+"/"+blobName, leaseID)
+}
+
+// BreakLease ends a lease after breakPeriod or the remaining lease time, if shorter.
+func (f *FileSystemBlobClient) BreakLease(ctx context.Context, container, blobName string, breakPeriod time.Duration) (time.Duration, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.leases.breakLease(container+"/"+blobName, breakPeriod)
+}
+
+// checkRead checks the ETag of an opened blob file against ifMatch. Writes replace the file,
+// so the open file keeps the content that was checked. Callers must hold the lock.
+func (f *FileSystemBlobClient) checkRead(file *os.File, path, ifMatch string) error {
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	sidecar, err := readSidecar(path)
+	if err != nil {
+		return err
+	}
+	return checkConditions(fileETag(sidecar, info), ifMatch, "")
+}
+
+// checkWrite enforces the ETag conditions and lease of a write. Callers must hold the lock.
+func (f *FileSystemBlobClient) checkWrite(path, container, blobName string, opts UploadOptions) error {
+	var etag string
+	if info, err := os.Stat(path); err == nil {
+		sidecar, err := readSidecar(path)
+		if err != nil {
+			return err
+		}
+		etag = fileETag(sidecar, info)
+	}
+	if err := checkConditions(etag, opts.IfMatch, opts.IfNoneMatch); err != nil {
+		return err
+	}
+	return f.leases.checkWrite(container+"/"+blobName, opts.LeaseID)
+}
+
+// blobPath validates container and blob names and returns the blob's file path.
+func (f *FileSystemBlobClient) blobPath(container, blobName string) (string, error) {
+	if !containerNamePattern.MatchString(container) {
+		return "", fmt.Errorf("invalid container name: %s", container)
+	}
+	if blobName == "" || strings.HasSuffix(blobName, sidecarSuffix) {
+		return "", fmt.Errorf("invalid blob name: %s", blobName)
+	}
+
+	containerDir := filepath.Join(f.root, container)
+	path := filepath.Join(containerDir, filepath.FromSlash(blobName))
+	if !strings.HasPrefix(path, containerDir+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid blob name: %s", blobName)
+	}
+	return path, nil
+}
+
+// blocksDir returns the staging directory for a blob's uncommitted blocks.
+func (f *FileSystemBlobClient) blocksDir(container, blobName string) string {
+	sum := sha256.Sum256([]byte(blobName))
+	return filepath.Join(f.root, blocksDirName, container, hex.EncodeToString(sum[:]))
+}
+
+// snapshotsDir returns the directory holding a blob's snapshots.
+func (f *FileSystemBlobClient) snapshotsDir(container, blobName string) string {
+	sum := sha256.Sum256([]byte(blobName))
+	return filepath.Join(f.root, snapshotsDirName, container, hex.EncodeToString(sum[:]))
+}
+
+// writeTemp copies data into a synced temporary file under the root and returns its path.
+func (f *FileSystemBlobClient) writeTemp(data io.Reader) (string, error) {
+	tmp, err := os.CreateTemp(filepath.Join(f.root, tempDirName), "blob-*")
+	if err != nil {
+		return "", err
+	}
+
+	_, err = io.Copy(tmp, data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	return tmp.Name(), nil
+}
+
+// commit moves a temporary data file into place and writes its sidecar.
+// Callers must hold the write lock.
+func (f *FileSystemBlobClient) commit(tmp, path string, sidecar fsSidecar) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+	return f.writeSidecar(path, sidecar)
+}
+
+// writeSidecar atomically replaces the sidecar of the blob at path.
+// Callers must hold the write lock.
+func (f *FileSystemBlobClient) writeSidecar(path string, sidecar fsSidecar) error {
+	encoded, err := json.Marshal(sidecar)
+	if err != nil {
+		return err
+	}
+	tmp, err := f.writeTemp(bytes.NewReader(encoded))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+	return os.Rename(tmp, path+sidecarSuffix)
+}
+
+// notFound converts a file-not-found error into ErrContainerNotFound if the container
+// directory is missing, or ErrBlobNotFound otherwise.
+func (f *FileSystemBlobClient) notFound(err error, container, blobName string) error {
+	if !os.IsNotExist(err) {
+		return err
+	}
+	if _, statErr := os.Stat(filepath.Join(f.root, container)); os.IsNotExist(statErr) {
+		return containerNotFound(container, nil)
+	}
+	return blobNotFound(container, blobName, nil)
+}
+
+// sidecarFromOptions builds the sidecar for an upload.
+func sidecarFromOptions(opts UploadOptions, contentMD5 []byte) fsSidecar {
+	accessTier := opts.AccessTier
+	if accessTier == "" {
+		accessTier = "Hot"
+	}
+	return fsSidecar{
+		ContentType:        opts.ContentType,
+		CacheControl:       opts.CacheControl,
+		ContentDisposition: opts.ContentDisposition,
+		ContentMD5:         contentMD5,
+		AccessTier:         accessTier,
+		Metadata:           opts.Metadata,
+		Tags:               opts.Tags,
+		ETag:               newETag(),
+	}
+}
+
+// readSidecar loads the sidecar of the blob at path. A missing sidecar yields empty properties.
+func readSidecar(path string) (fsSidecar, error) {
+	var sidecar fsSidecar
+	encoded, err := os.ReadFile(path + sidecarSuffix)
+	if os.IsNotExist(err) {
+		return sidecar, nil
+	}
+	if err != nil {
+		return sidecar, fmt.Errorf("failed to read blob metadata: %w", err)
+	}
+	if err := json.Unmarshal(encoded, &sidecar); err != nil {
+		return sidecar, fmt.Errorf("failed to parse blob metadata: %w", err)
+	}
+	return sidecar, nil
+}
+
+// fileETag returns the ETag of a blob, derived from its modification time if the sidecar
+// predates ETags.
+func fileETag(sidecar fsSidecar, info os.FileInfo) string {
+	if sidecar.ETag != "" {
+		return sidecar.ETag
+	}
+	return fmt.Sprintf(`"0x%X"`, info.ModTime().UnixNano())
+}
+
+// fileURL returns the file:// URL of path.
+func fileURL(path string) string {
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+}
+
+// sectionReadCloser closes the underlying file of a section reader.
+type sectionReadCloser struct {
+	*io.SectionReader
+	file *os.File
+}
+
+// Close closes the underlying file.
+func (s *sectionReadCloser) Close() error {
+	return s.file.Close()
+}
@@ -0,0 +1,186 @@
+package blobclient
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yourorg/go-service-kit/pkg/config"
+	"github.com/yourorg/go-service-kit/pkg/logging"
+)
+
+func newTestFileSystemClient(t *testing.T) (*FileSystemBlobClient, string) {
+	t.Helper()
+	logger, _ := logging.NewLogger("error", "json")
+	root := t.TempDir()
+	client, err := NewFileSystemBlobClient(root, logger)
+	if err != nil {
+		t.Fatalf("NewFileSystemBlobClient failed: %v", err)
+	}
+	return client, root
+}
+
+func TestFileSystemBlobClient_UploadGetPersistsAcrossInstances(t *testing.T) {
+	client, root := newTestFileSystemClient(t)
+	ctx := context.Background()
+
+	_, err := client.UploadWithOptions(ctx, "reports", "2024/q1.csv", strings.NewReader("a,b\n1,2"), UploadOptions{
+		ContentType: "text/csv",
+		Metadata:    map[string]string{"source": "import"},
+	})
+	if err != nil {
+		t.Fatalf("UploadWithOptions failed: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(root, "reports", "2024", "q1.csv"+sidecarSuffix)); err != nil {
+		t.Errorf("Expected sidecar file: %v", err)
+	}
+
+	// A new client over the same root sees the blob and its properties
+	logger, _ := logging.NewLogger("error", "json")
+	reopened, err := NewFileSystemBlobClient(root, logger)
+	if err != nil {
+		t.Fatalf("NewFileSystemBlobClient failed: %v", err)
+	}
+
+	reader, err := reopened.Get(ctx, "reports", "2024/q1.csv")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	content, _ := io.ReadAll(reader)
+	reader.Close()
+	if string(content) != "a,b\n1,2" {
+		t.Errorf("Unexpected content %q", content)
+	}
+
+	props, err := reopened.GetProperties(ctx, "reports", "2024/q1.csv")
+	if err != nil {
+		t.Fatalf("GetProperties failed: %v", err)
+	}
+	if props.ContentType != "text/csv" || props.Metadata["source"] != "import" || props.Size != 7 {
+		t.Errorf("Unexpected properties: %+v", props)
+	}
+}
+
+func TestFileSystemBlobClient_ListDeleteExists(t *testing.T) {
+	client, _ := newTestFileSystemClient(t)
+	ctx := context.Background()
+
+	for _, name := range []string{"b/2.txt", "a/1.txt", "b/1.txt"} {
+		if _, err := client.Upload(ctx, "docs", name, strings.NewReader(name), "text/plain"); err != nil {
+			t.Fatalf("Upload failed: %v", err)
+		}
+	}
+
+	blobs, err := client.List(ctx, "docs", "b/")
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(blobs) != 2 || blobs[0].Name != "b/1.txt" || blobs[1].Name != "b/2.txt" {
+		t.Errorf("Unexpected listing: %+v", blobs)
+	}
+
+	if err := client.Delete(ctx, "docs", "b/1.txt"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if exists, _ := client.Exists(ctx, "docs", "b/1.txt"); exists {
+		t.Error("Expected blob to be deleted")
+	}
+	if err := client.Delete(ctx, "docs", "b/1.txt"); err == nil {
+		t.Error("Expected error deleting a missing blob")
+	}
+
+	blobs, err = client.List(ctx, "empty", "")
+	if err != nil || len(blobs) != 0 {
+		t.Errorf("Expected empty listing for missing container, got %v, %v", blobs, err)
+	}
+}
+
+func TestFileSystemBlobClient_RejectsEscapingNames(t *testing.T) {
+	client, _ := newTestFileSystemClient(t)
+	ctx := context.Background()
+
+	tests := []struct{ container, blob string }{
+		{"docs", "../../etc/passwd"},
+		{"docs", "a" + sidecarSuffix},
+		{"../docs", "a.txt"},
+		{".tmp", "a.txt"},
+	}
+	for _, tt := range tests {
+		if _, err := client.Upload(ctx, tt.container, tt.blob, strings.NewReader("x"), "text/plain"); err == nil {
+			t.Errorf("Expected %s/%s to be rejected", tt.container, tt.blob)
+		}
+	}
+}
+
+func TestFileSystemBlobClient_ChunkedTransfers(t *testing.T) {
+	client, root := newTestFileSystemClient(t)
+	ctx := context.Background()
+	data := testPayload(5000)
+	transfer := TransferOptions{BlockSize: 1024, Concurrency: 2, Checksum: ChecksumMD5}
+
+	if _, err := client.UploadChunked(ctx, "exports", "big.bin", bytes.NewReader(data), UploadOptions{}, transfer); err != nil {
+		t.Fatalf("UploadChunked failed: %v", err)
+	}
+	if entries, _ := os.ReadDir(filepath.Join(root, blocksDirName, "exports")); len(entries) != 0 {
+		t.Errorf("Expected staged blocks to be removed after commit, found %d", len(entries))
+	}
+
+	var out bytes.Buffer
+	if _, err := client.DownloadChunked(ctx, "exports", "big.bin", &out, transfer); err != nil {
+		t.Fatalf("DownloadChunked failed: %v", err)
+	}
+	if !bytes.Equal(out.Bytes(), data) {
+		t.Error("Downloaded data does not match upload")
+	}
+
+	reader, err := client.GetRange(ctx, "exports", "big.bin", 4990, 0)
+	if err != nil {
+		t.Fatalf("GetRange failed: %v", err)
+	}
+	tail, _ := io.ReadAll(reader)
+	reader.Close()
+	if !bytes.Equal(tail, data[4990:]) {
+		t.Error("GetRange returned wrong bytes")
+	}
+}
+
+func TestNewFromConfig(t *testing.T) {
+	logger, _ := logging.NewLogger("error", "json")
+
+	client, err := NewFromConfig(&config.Config{}, logger)
+	if err != nil {
+		t.Fatalf("NewFromConfig failed: %v", err)
+	}
+	if _, ok := client.(*MockBlobClient); !ok {
+		t.Errorf("Expected mock client by default, got %T", client)
+	}
+
+	client, err = NewFromConfig(&config.Config{BlobProvider: "filesystem", BlobLocalPath: t.TempDir()}, logger)
+	if err != nil {
+		t.Fatalf("NewFromConfig failed: %v", err)
+	}
+	if _, ok := client.(*FileSystemBlobClient); !ok {
+		t.Errorf("Expected file system client, got %T", client)
+	}
+
+	client, err = NewFromConfig(&config.Config{BlobConnectionString: "UseDevelopmentStorage=true"}, logger)
+	if err != nil {
+		t.Fatalf("NewFromConfig failed: %v", err)
+	}
+	azure, ok := client.(*AzureBlobClient)
+	if !ok {
+		t.Fatalf("Expected Azure client, got %T", client)
+	}
+	if got := azure.blobURL("c", "b"); got != "http://127.0.0.1:10000/devstoreaccount1/c/b" {
+		t.Errorf("Expected Azurite blob URL, got %s", got)
+	}
+
+	if _, err := NewFromConfig(&config.Config{BlobProvider: "ftp"}, logger); err == nil {
+		t.Error("Expected error for unsupported provider")
+	}
+}
@@ -0,0 +1,130 @@
+package blobclient
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"fmt"
+	"sync"
+)
+
+// KeyProvider wraps and unwraps the per-blob data keys of an EncryptedBlobClient with
+// key-encryption keys (KEKs), e.g. keys held in a key vault or KMS. Implementations must
+// keep retired KEKs available for unwrapping until all blobs have been re-wrapped.
+type KeyProvider interface {
+	// CurrentKeyID returns the ID of the KEK used to wrap new data keys.
+	CurrentKeyID(ctx context.Context) (string, error)
+	// WrapKey encrypts a data key with the KEK keyID.
+	WrapKey(ctx context.Context, keyID string, dataKey []byte) ([]byte, error)
+	// UnwrapKey decrypts a data key wrapped with the KEK keyID.
+	UnwrapKey(ctx context.Context, keyID string, wrappedKey []byte) ([]byte, error)
+}
+
+// LocalKeyProvider is a KeyProvider holding 256-bit KEKs in memory, for keys loaded from
+// configuration or a secret store. Data keys are wrapped with AES-GCM.
+type LocalKeyProvider struct {
+	mu        sync.RWMutex
+	currentID string
+	keys      map[string]cipher.AEAD
+}
+
+// NewLocalKeyProvider creates a key provider from 32-byte KEKs by ID. New data keys are
+// wrapped with currentKeyID; the other keys only unwrap existing ones.
+func NewLocalKeyProvider(currentKeyID string, keys map[string][]byte) (*LocalKeyProvider, error) {
+	p := &LocalKeyProvider{keys: make(map[string]cipher.AEAD, len(keys))}
+	for keyID, key := range keys {
+		if err := p.AddKey(keyID, key, false); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.SetCurrentKey(currentKeyID); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// AddKey adds a KEK, making it the current key if current is set.
+func (p *LocalKeyProvider) AddKey(keyID string, key []byte, current bool) error {
+	if keyID == "" {
+		return fmt.Errorf("key ID is required")
+	}
+	if len(key) != 32 {
+		return fmt.Errorf("invalid key %s: must be 32 bytes, got %d", keyID, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return fmt.Errorf("failed to create cipher for key %s: %w", keyID, err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return fmt.Errorf("failed to create cipher for key %s: %w", keyID, err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.keys[keyID] = aead
+	if current {
+		p.currentID = keyID
+	}
+	return nil
+}
+
+// SetCurrentKey selects the KEK used to wrap new data keys.
+func (p *LocalKeyProvider) SetCurrentKey(keyID string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.keys[keyID]; !ok {
+		return fmt.Errorf("unknown key: %s", keyID)
+	}
+	p.currentID = keyID
+	return nil
+}
+
+// CurrentKeyID returns the ID of the KEK used to wrap new data keys.
+func (p *LocalKeyProvider) CurrentKeyID(ctx context.Context) (string, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.currentID, nil
+}
+
+// WrapKey encrypts a data key with a random nonce, bound to the key ID.
+func (p *LocalKeyProvider) WrapKey(ctx context.Context, keyID string, dataKey []byte) ([]byte, error) {
+	aead, err := p.key(keyID)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(dataKey)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return aead.Seal(nonce, nonce, dataKey, []byte(keyID)), nil
+}
+
+// UnwrapKey decrypts a data key wrapped by WrapKey.
+func (p *LocalKeyProvider) UnwrapKey(ctx context.Context, keyID string, wrappedKey []byte) ([]byte, error) {
+	aead, err := p.key(keyID)
+	if err != nil {
+		return nil, err
+	}
+	if len(wrappedKey) < aead.NonceSize() {
+		return nil, fmt.Errorf("invalid wrapped key")
+	}
+	nonce, sealed := wrappedKey[:aead.NonceSize()], wrappedKey[aead.NonceSize():]
+	dataKey, err := aead.Open(nil, nonce, sealed, []byte(keyID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap key with %s: %w", keyID, err)
+	}
+	return dataKey, nil
+}
+
+// key returns the cipher for a KEK.
+func (p *LocalKeyProvider) key(keyID string) (cipher.AEAD, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	aead, ok := p.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("unknown key: %s", keyID)
+	}
+	return aead, nil
+}
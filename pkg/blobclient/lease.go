@@ -0,0 +1,256 @@
+package blobclient
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// InfiniteLease is the duration of a lease that never expires; it must be released or broken.
+const InfiniteLease time.Duration = -1
+
+// Lease duration limits for AcquireLease (besides InfiniteLease) and BreakLease.
+const (
+	MinLeaseDuration = 15 * time.Second
+	MaxLeaseDuration = 60 * time.Second
+)
+
+// DeleteOptions contains optional parameters for DeleteWithOptions.
+type DeleteOptions struct {
+	IfMatch string // only delete if the blob's ETag matches
+	LeaseID string // required to delete a blob with an active lease
+}
+
+// checkConditions applies IfMatch and IfNoneMatch to the current ETag of a blob, which is
+// empty if the blob does not exist. "*" matches any existing blob.
+func checkConditions(etag, ifMatch, ifNoneMatch string) error {
+	if ifMatch != "" && (etag == "" || ifMatch != "*" && ifMatch != etag) {
+		return blobError(ErrPreconditionFailed, "blob ETag does not match "+ifMatch, nil)
+	}
+	if ifNoneMatch != "" && etag != "" && (ifNoneMatch == "*" || ifNoneMatch == etag) {
+		return blobError(ErrPreconditionFailed, "blob already exists", nil)
+	}
+	return nil
+}
+
+// etagSequence makes ETags generated within the same nanosecond unique.
+var etagSequence atomic.Uint64
+
+// newETag returns a new quoted ETag for backends that do not generate their own.
+func newETag() string {
+	return fmt.Sprintf(`"0x%X%04X"`, time.Now().UnixNano(), etagSequence.Add(1)&0xFFFF)
+}
+
+// validateLeaseDuration checks a duration against the limits enforced by Azure.
+func validateLeaseDuration(duration time.Duration) error {
+	if duration != InfiniteLease && (duration < MinLeaseDuration || duration > MaxLeaseDuration) {
+		return fmt.Errorf("invalid lease duration %s: must be between %s and %s or InfiniteLease", duration, MinLeaseDuration, MaxLeaseDuration)
+	}
+	return nil
+}
+
+// validateBreakPeriod checks a break period against the limits enforced by Azure.
+func validateBreakPeriod(breakPeriod time.Duration) error {
+	if breakPeriod < 0 || breakPeriod > MaxLeaseDuration {
+		return fmt.Errorf("invalid break period %s: must be between 0 and %s", breakPeriod, MaxLeaseDuration)
+	}
+	return nil
+}
+
+// HoldLease uses a blob as a distributed lock: it acquires a lease, runs fn while renewing
+// the lease in the background and releases it when fn returns. fn's context is canceled
+// if a renewal fails, e.g. because another process broke the lease.
+func HoldLease(ctx context.Context, client BlobClient, container, blobName string, duration time.Duration, fn func(ctx context.Context, leaseID string) error) error {
+	leaseID, err := client.AcquireLease(ctx, container, blobName, duration, "")
+	if err != nil {
+		return err
+	}
+
+	fnCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var lost error
+	renewed := make(chan struct{})
+	go func() {
+		defer close(renewed)
+		if duration == InfiniteLease {
+			return
+		}
+
+		ticker := time.NewTicker(duration / 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-fnCtx.Done():
+				return
+			case <-ticker.C:
+				if err := client.RenewLease(fnCtx, container, blobName, leaseID); err != nil {
+					if fnCtx.Err() == nil {
+						lost = err
+						cancel()
+					}
+					return
+				}
+			}
+		}
+	}()
+
+	err = fn(fnCtx, leaseID)
+	cancel()
+	<-renewed
+
+	if lost != nil {
+		return fmt.Errorf("lease lost: %w", lost)
+	}
+	// Release even if ctx is done, so other processes need not wait for the lease to expire
+	if releaseErr := client.ReleaseLease(context.WithoutCancel(ctx), container, blobName, leaseID); releaseErr != nil && err == nil {
+		err = fmt.Errorf("failed to release lease: %w", releaseErr)
+	}
+	return err
+}
+
+// leaseTable tracks blob leases for backends without native leasing, following the Azure
+// lease state machine. Callers synchronize access.
+type leaseTable struct {
+	leases map[string]*blobLease // container/blobName -> lease
+	now    func() time.Time
+}
+
+// blobLease is a lease on one blob.
+type blobLease struct {
+	id       string
+	duration time.Duration
+	expires  time.Time // zero for InfiniteLease
+	breakAt  time.Time // zero unless BreakLease was called
+}
+
+// Lease states.
+const (
+	leaseLeased   = "leased"
+	leaseExpired  = "expired"
+	leaseBreaking = "breaking"
+	leaseBroken   = "broken"
+)
+
+// newLeaseTable returns an empty lease table using the system clock.
+func newLeaseTable() *leaseTable {
+	return &leaseTable{leases: make(map[string]*blobLease), now: time.Now}
+}
+
+// state returns the state of a lease at now.
+func (l *blobLease) state(now time.Time) string {
+	switch {
+	case !l.breakAt.IsZero() && now.Before(l.breakAt):
+		return leaseBreaking
+	case !l.breakAt.IsZero():
+		return leaseBroken
+	case l.expires.IsZero() || now.Before(l.expires):
+		return leaseLeased
+	default:
+		return leaseExpired
+	}
+}
+
+// acquire takes a lease on key. Acquiring with the ID of the active lease renews it.
+func (t *leaseTable) acquire(key string, duration time.Duration, proposedID string) (string, error) {
+	if err := validateLeaseDuration(duration); err != nil {
+		return "", err
+	}
+
+	if l := t.leases[key]; l != nil {
+		switch l.state(t.now()) {
+		case leaseLeased:
+			if l.id != proposedID {
+				return "", blobError(ErrConflict, "there is already a lease present", nil)
+			}
+		case leaseBreaking:
+			return "", blobError(ErrConflict, "the lease is breaking and cannot be acquired", nil)
+		}
+	}
+
+	id := proposedID
+	if id == "" {
+		id = uuid.NewString()
+	}
+	l := &blobLease{id: id, duration: duration}
+	if duration != InfiniteLease {
+		l.expires = t.now().Add(duration)
+	}
+	t.leases[key] = l
+	return id, nil
+}
+
+// renew restarts the duration of a lease, which may have expired as long as no other
+// lease was acquired since.
+func (t *leaseTable) renew(key, leaseID string) error {
+	l := t.leases[key]
+	if l == nil || l.id != leaseID {
+		return blobError(ErrConflict, "the lease ID does not match the lease", nil)
+	}
+	if state := l.state(t.now()); state == leaseBreaking || state == leaseBroken {
+		return blobError(ErrConflict, "the lease is broken and cannot be renewed", nil)
+	}
+	if l.duration != InfiniteLease {
+		l.expires = t.now().Add(l.duration)
+	}
+	return nil
+}
+
+// release ends a lease so the blob can be leased again immediately.
+func (t *leaseTable) release(key, leaseID string) error {
+	l := t.leases[key]
+	if l == nil || l.id != leaseID {
+		return blobError(ErrConflict, "the lease ID does not match the lease", nil)
+	}
+	delete(t.leases, key)
+	return nil
+}
+
+// breakLease ends a lease after breakPeriod, or the remaining lease time if shorter, and
+// returns the time until it is broken.
+func (t *leaseTable) breakLease(key string, breakPeriod time.Duration) (time.Duration, error) {
+	if err := validateBreakPeriod(breakPeriod); err != nil {
+		return 0, err
+	}
+
+	now := t.now()
+	l := t.leases[key]
+	if l == nil || l.state(now) == leaseExpired {
+		return 0, blobError(ErrConflict, "there is currently no lease on the blob", nil)
+	}
+
+	switch l.state(now) {
+	case leaseBroken:
+		return 0, nil
+	case leaseBreaking:
+		return l.breakAt.Sub(now), nil
+	}
+	if !l.expires.IsZero() && l.expires.Sub(now) < breakPeriod {
+		breakPeriod = l.expires.Sub(now)
+	}
+	l.breakAt = now.Add(breakPeriod)
+	return breakPeriod, nil
+}
+
+// checkWrite verifies that a write to key carries the ID of its active lease, if any.
+func (t *leaseTable) checkWrite(key, leaseID string) error {
+	l := t.leases[key]
+	active := l != nil && (l.state(t.now()) == leaseLeased || l.state(t.now()) == leaseBreaking)
+	switch {
+	case active && leaseID == "":
+		return blobError(ErrPreconditionFailed, "there is currently a lease on the blob and no lease ID was specified", nil)
+	case active && leaseID != l.id:
+		return blobError(ErrPreconditionFailed, "the lease ID specified did not match the lease ID for the blob", nil)
+	case !active && leaseID != "":
+		return blobError(ErrPreconditionFailed, "there is currently no lease on the blob", nil)
+	}
+	return nil
+}
+
+// remove drops the lease of a deleted blob.
+func (t *leaseTable) remove(key string) {
+	delete(t.leases, key)
+}
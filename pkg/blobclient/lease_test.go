@@ -0,0 +1,199 @@
+package blobclient
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	apperrors "github.com/yourorg/go-service-kit/pkg/errors"
+)
+
+// isConflict reports whether err is an ErrorCodeConflict AppError.
+func isConflict(err error) bool {
+	var appErr *apperrors.AppError
+	return errors.As(err, &appErr) && appErr.Code == apperrors.ErrorCodeConflict
+}
+
+func TestConditionalWrites(t *testing.T) {
+	_, s3Client := newFakeS3(t)
+	fsClient, _ := newTestFileSystemClient(t)
+	clients := map[string]BlobClient{
+		"mock":       NewMockBlobClient(),
+		"filesystem": fsClient,
+		"s3":         s3Client,
+	}
+
+	ctx := context.Background()
+	for name, client := range clients {
+		createOnly := UploadOptions{ContentType: "text/plain", IfNoneMatch: "*"}
+		if _, err := client.UploadWithOptions(ctx, "docs", "state.json", strings.NewReader("v1"), createOnly); err != nil {
+			t.Fatalf("%s: create-only upload of a new blob failed: %v", name, err)
+		}
+		if _, err := client.UploadWithOptions(ctx, "docs", "state.json", strings.NewReader("v2"), createOnly); !isConflict(err) {
+			t.Errorf("%s: expected conflict for create-only upload of an existing blob, got %v", name, err)
+		}
+
+		props, err := client.GetProperties(ctx, "docs", "state.json")
+		if err != nil || props.ETag == "" {
+			t.Fatalf("%s: expected ETag, got %+v (err %v)", name, props, err)
+		}
+		blobs, _ := client.List(ctx, "docs", "state")
+		if len(blobs) != 1 || blobs[0].ETag != props.ETag {
+			t.Errorf("%s: expected listed ETag %s, got %+v", name, props.ETag, blobs)
+		}
+
+		// Optimistic concurrency: the first writer wins, the second sees a stale ETag
+		if _, err := client.UploadWithOptions(ctx, "docs", "state.json", strings.NewReader("v2"), UploadOptions{IfMatch: props.ETag}); err != nil {
+			t.Fatalf("%s: upload with current ETag failed: %v", name, err)
+		}
+		if _, err := client.UploadWithOptions(ctx, "docs", "state.json", strings.NewReader("v3"), UploadOptions{IfMatch: props.ETag}); !isConflict(err) {
+			t.Errorf("%s: expected conflict for upload with stale ETag, got %v", name, err)
+		}
+		if got := readBlob(t, client, "docs", "state.json"); got != "v2" {
+			t.Errorf("%s: content = %q, want v2", name, got)
+		}
+
+		updated, _ := client.GetProperties(ctx, "docs", "state.json")
+		if updated.ETag == props.ETag {
+			t.Errorf("%s: expected ETag to change on write", name)
+		}
+		if err := client.DeleteWithOptions(ctx, "docs", "state.json", DeleteOptions{IfMatch: props.ETag}); !isConflict(err) {
+			t.Errorf("%s: expected conflict for delete with stale ETag, got %v", name, err)
+		}
+		if err := client.DeleteWithOptions(ctx, "docs", "state.json", DeleteOptions{IfMatch: updated.ETag}); err != nil {
+			t.Errorf("%s: delete with current ETag failed: %v", name, err)
+		}
+	}
+}
+
+func TestLeases(t *testing.T) {
+	mock := NewMockBlobClient()
+	fsClient, _ := newTestFileSystemClient(t)
+	clients := map[string]struct {
+		client BlobClient
+		leases *leaseTable
+	}{
+		"mock":       {mock, mock.leases},
+		"filesystem": {fsClient, fsClient.leases},
+	}
+
+	ctx := context.Background()
+	for name, c := range clients {
+		client := c.client
+		now := time.Now()
+		c.leases.now = func() time.Time { return now }
+
+		if _, err := client.AcquireLease(ctx, "docs", "lock", 15*time.Second, ""); err == nil {
+			t.Errorf("%s: expected error leasing a missing blob", name)
+		}
+		client.Upload(ctx, "docs", "lock", strings.NewReader(""), "text/plain")
+		if _, err := client.AcquireLease(ctx, "docs", "lock", 5*time.Second, ""); err == nil {
+			t.Errorf("%s: expected error for lease duration below the minimum", name)
+		}
+
+		leaseID, err := client.AcquireLease(ctx, "docs", "lock", 15*time.Second, "")
+		if err != nil || leaseID == "" {
+			t.Fatalf("%s: AcquireLease failed: %v", name, err)
+		}
+		if _, err := client.AcquireLease(ctx, "docs", "lock", 15*time.Second, ""); !isConflict(err) {
+			t.Errorf("%s: expected conflict acquiring a held lease, got %v", name, err)
+		}
+		if id, err := client.AcquireLease(ctx, "docs", "lock", 15*time.Second, leaseID); err != nil || id != leaseID {
+			t.Errorf("%s: expected re-acquiring with the same ID to succeed, got %q %v", name, id, err)
+		}
+
+		// Writes and deletes require the lease ID
+		if _, err := client.Upload(ctx, "docs", "lock", strings.NewReader("x"), "text/plain"); !isConflict(err) {
+			t.Errorf("%s: expected conflict writing without lease ID, got %v", name, err)
+		}
+		if _, err := client.UploadChunked(ctx, "docs", "lock", strings.NewReader("x"), UploadOptions{}, TransferOptions{}); !isConflict(err) {
+			t.Errorf("%s: expected conflict for chunked write without lease ID, got %v", name, err)
+		}
+		if _, err := client.UploadWithOptions(ctx, "docs", "lock", strings.NewReader("x"), UploadOptions{LeaseID: "other"}); !isConflict(err) {
+			t.Errorf("%s: expected conflict writing with wrong lease ID, got %v", name, err)
+		}
+		if _, err := client.UploadWithOptions(ctx, "docs", "lock", strings.NewReader("x"), UploadOptions{LeaseID: leaseID}); err != nil {
+			t.Errorf("%s: write with lease ID failed: %v", name, err)
+		}
+		if err := client.Delete(ctx, "docs", "lock"); !isConflict(err) {
+			t.Errorf("%s: expected conflict deleting without lease ID, got %v", name, err)
+		}
+
+		// Expired leases no longer block writers but can still be renewed
+		now = now.Add(16 * time.Second)
+		if _, err := client.Upload(ctx, "docs", "lock", strings.NewReader("y"), "text/plain"); err != nil {
+			t.Errorf("%s: write after lease expiry failed: %v", name, err)
+		}
+		if err := client.RenewLease(ctx, "docs", "lock", leaseID); err != nil {
+			t.Errorf("%s: RenewLease failed: %v", name, err)
+		}
+		if err := client.ReleaseLease(ctx, "docs", "lock", "other"); !isConflict(err) {
+			t.Errorf("%s: expected conflict releasing with wrong ID, got %v", name, err)
+		}
+
+		// Breaking: no new lease until the break period ends, no renewal after
+		remaining, err := client.BreakLease(ctx, "docs", "lock", 10*time.Second)
+		if err != nil || remaining != 10*time.Second {
+			t.Fatalf("%s: BreakLease = %s, %v", name, remaining, err)
+		}
+		if _, err := client.AcquireLease(ctx, "docs", "lock", InfiniteLease, ""); !isConflict(err) {
+			t.Errorf("%s: expected conflict acquiring a breaking lease, got %v", name, err)
+		}
+		now = now.Add(10 * time.Second)
+		if err := client.RenewLease(ctx, "docs", "lock", leaseID); !isConflict(err) {
+			t.Errorf("%s: expected conflict renewing a broken lease, got %v", name, err)
+		}
+		newID, err := client.AcquireLease(ctx, "docs", "lock", InfiniteLease, "")
+		if err != nil {
+			t.Fatalf("%s: AcquireLease after break failed: %v", name, err)
+		}
+		if err := client.ReleaseLease(ctx, "docs", "lock", newID); err != nil {
+			t.Errorf("%s: ReleaseLease failed: %v", name, err)
+		}
+		if _, err := client.BreakLease(ctx, "docs", "lock", 0); !isConflict(err) {
+			t.Errorf("%s: expected conflict breaking a released lease, got %v", name, err)
+		}
+		if err := client.Delete(ctx, "docs", "lock"); err != nil {
+			t.Errorf("%s: Delete after release failed: %v", name, err)
+		}
+	}
+}
+
+func TestHoldLease(t *testing.T) {
+	client := NewMockBlobClient()
+	ctx := context.Background()
+	client.Upload(ctx, "locks", "nightly-job", strings.NewReader(""), "text/plain")
+
+	ran := false
+	err := HoldLease(ctx, client, "locks", "nightly-job", MinLeaseDuration, func(ctx context.Context, leaseID string) error {
+		ran = true
+		if _, err := client.AcquireLease(ctx, "locks", "nightly-job", MinLeaseDuration, ""); !isConflict(err) {
+			t.Errorf("Expected lock to be held, got %v", err)
+		}
+		return nil
+	})
+	if err != nil || !ran {
+		t.Fatalf("HoldLease failed: ran=%v err=%v", ran, err)
+	}
+
+	// The lease is released afterwards, also when fn fails
+	jobErr := errors.New("job failed")
+	err = HoldLease(ctx, client, "locks", "nightly-job", InfiniteLease, func(ctx context.Context, leaseID string) error {
+		return jobErr
+	})
+	if !errors.Is(err, jobErr) {
+		t.Errorf("Expected job error, got %v", err)
+	}
+	if _, err := client.AcquireLease(ctx, "locks", "nightly-job", MinLeaseDuration, ""); err != nil {
+		t.Errorf("Expected lease to be released, got %v", err)
+	}
+}
+
+func TestS3BlobClient_LeasesNotSupported(t *testing.T) {
+	_, client := newFakeS3(t)
+	if _, err := client.AcquireLease(context.Background(), "docs", "a.txt", InfiniteLease, ""); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("Expected ErrNotSupported, got %v", err)
+	}
+}
@@ -0,0 +1,344 @@
+package blobclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yourorg/go-service-kit/pkg/config"
+	"github.com/yourorg/go-service-kit/pkg/logging"
+)
+
+// Lifecycle actions recorded in a LifecycleReport.
+const (
+	LifecycleActionDelete = "delete"
+	LifecycleActionTier   = "tier"
+)
+
+// lifecycleTierRank orders access tiers from hot to cold. Blobs only ever move to a colder tier.
+var lifecycleTierRank = map[string]int{
+	"hot":     0,
+	"cool":    1,
+	"cold":    2,
+	"archive": 3,
+}
+
+// LifecycleRule applies retention to the blobs of a container under a prefix. Zero values
+// disable the respective action; deletion takes precedence over tiering.
+type LifecycleRule struct {
+	Container   string
+	Prefix      string
+	DeleteAfter time.Duration // delete blobs last modified longer ago
+	TierAfter   time.Duration // move blobs last modified longer ago to Tier
+	Tier        string        // Cool, Cold or Archive
+
+	// KeepNewestPerDirectory keeps the newest blobs of every virtual directory under the
+	// prefix, e.g. the last 5 exports in reports/daily/, and deletes older ones. Blobs are
+	// compared by name and LastModified only; snapshots and versions are not listed.
+	KeepNewestPerDirectory int
+}
+
+// LifecycleConfig configures a LifecycleSweeper.
+type LifecycleConfig struct {
+	Rules    []LifecycleRule
+	Interval time.Duration // time between sweeps started by Start
+	DryRun   bool          // report what would change without changing anything
+}
+
+// LifecycleConfigFromConfig builds a LifecycleConfig from the application configuration.
+func LifecycleConfigFromConfig(cfg *config.Config) LifecycleConfig {
+	lifecycleCfg := LifecycleConfig{
+		Interval: time.Duration(cfg.BlobLifecycleInterval) * time.Second,
+		DryRun:   cfg.BlobLifecycleDryRun,
+	}
+	for _, rule := range cfg.BlobLifecycleRules {
+		lifecycleCfg.Rules = append(lifecycleCfg.Rules, LifecycleRule{
+			Container:              rule.Container,
+			Prefix:                 rule.Prefix,
+			DeleteAfter:            time.Duration(rule.DeleteAfterDays) * 24 * time.Hour,
+			TierAfter:              time.Duration(rule.TierAfterDays) * 24 * time.Hour,
+			Tier:                   rule.Tier,
+			KeepNewestPerDirectory: rule.KeepNewestPerDirectory,
+		})
+	}
+	return lifecycleCfg
+}
+
+// validate checks that a rule names a container and at least one valid action.
+func (r LifecycleRule) validate() error {
+	if r.Container == "" {
+		return fmt.Errorf("container is required")
+	}
+	if r.DeleteAfter < 0 || r.TierAfter < 0 || r.KeepNewestPerDirectory < 0 {
+		return fmt.Errorf("ages and counts must not be negative")
+	}
+	if r.DeleteAfter == 0 && r.TierAfter == 0 && r.KeepNewestPerDirectory == 0 {
+		return fmt.Errorf("no action configured")
+	}
+	if r.TierAfter > 0 {
+		if rank, ok := lifecycleTierRank[strings.ToLower(r.Tier)]; !ok || rank == 0 {
+			return fmt.Errorf("invalid tier %q: must be Cool, Cold or Archive", r.Tier)
+		}
+	}
+	return nil
+}
+
+// LifecycleAction is a change made, or in a dry run proposed, by a sweep.
+type LifecycleAction struct {
+	Container string
+	BlobName  string
+	Action    string // LifecycleActionDelete or LifecycleActionTier
+	Tier      string // target tier of LifecycleActionTier
+	Reason    string
+	Skipped   bool  // the blob changed or was leased since it was listed
+	Err       error // the action failed
+
+	etag string // version of the blob the action was planned for
+}
+
+// LifecycleReport summarizes a sweep.
+type LifecycleReport struct {
+	DryRun  bool
+	Scanned int // blobs listed across all rules
+	Actions []LifecycleAction
+}
+
+// LifecycleOption configures a LifecycleSweeper.
+type LifecycleOption func(*LifecycleSweeper)
+
+// WithLifecycleClock sets the clock used to compute blob ages (default: time.Now).
+func WithLifecycleClock(now func() time.Time) LifecycleOption {
+	return func(s *LifecycleSweeper) {
+		s.now = now
+	}
+}
+
+// LifecycleSweeper deletes and re-tiers blobs according to lifecycle rules, based on the
+// LastModified time reported by BlobClient.List.
+type LifecycleSweeper struct {
+	client BlobClient
+	cfg    LifecycleConfig
+	logger logging.Logger
+	now    func() time.Time
+	mu     sync.Mutex // serializes sweeps
+}
+
+// NewLifecycleSweeper creates a sweeper applying cfg.Rules to client.
+func NewLifecycleSweeper(client BlobClient, cfg LifecycleConfig, logger logging.Logger, opts ...LifecycleOption) (*LifecycleSweeper, error) {
+	if client == nil {
+		return nil, fmt.Errorf("blob client is required")
+	}
+	if logger == nil {
+		return nil, fmt.Errorf("logger is required")
+	}
+	for i, rule := range cfg.Rules {
+		if err := rule.validate(); err != nil {
+			return nil, fmt.Errorf("invalid lifecycle rule %d: %w", i, err)
+		}
+	}
+
+	s := &LifecycleSweeper{
+		client: client,
+		cfg:    cfg,
+		logger: logger.With(logging.NewField("operation", "blob.lifecycle")),
+		now:    time.Now,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s, nil
+}
+
+// Start runs a sweep every cfg.Interval in the background until ctx is cancelled. The
+// first sweep runs one interval after Start, so restarts do not trigger sweeps. A zero
+// interval disables the background job.
+func (s *LifecycleSweeper) Start(ctx context.Context) {
+	if s.cfg.Interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(s.cfg.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := s.Sweep(ctx); err != nil && ctx.Err() == nil {
+					s.logger.Error("Blob lifecycle sweep failed", logging.NewField("error", err))
+				}
+			}
+		}
+	}()
+}
+
+// Sweep applies all rules once. Failed actions are recorded in the report and returned
+// together as the error; a blob that changed since it was listed is skipped.
+func (s *LifecycleSweeper) Sweep(ctx context.Context) (*LifecycleReport, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	report := &LifecycleReport{DryRun: s.cfg.DryRun}
+	deleted := make(map[string]bool) // container/blobName of blobs deleted by an earlier rule
+	var errs []error
+	for _, rule := range s.cfg.Rules {
+		blobs, err := s.client.List(ctx, rule.Container, rule.Prefix)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to list %s/%s: %w", rule.Container, rule.Prefix, err))
+			continue
+		}
+		report.Scanned += len(blobs)
+
+		for _, action := range s.plan(rule, blobs) {
+			key := action.Container + "/" + action.BlobName
+			if deleted[key] {
+				continue
+			}
+			if action.Action == LifecycleActionDelete {
+				deleted[key] = true
+			}
+
+			if !s.cfg.DryRun {
+				s.apply(ctx, &action)
+				if action.Err != nil {
+					errs = append(errs, fmt.Errorf("failed to %s %s: %w", action.Action, key, action.Err))
+				}
+			}
+			s.logAction(action)
+			report.Actions = append(report.Actions, action)
+		}
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+	}
+
+	s.logger.Info("Blob lifecycle sweep completed",
+		logging.NewField("dry_run", report.DryRun),
+		logging.NewField("scanned", report.Scanned),
+		logging.NewField("actions", len(report.Actions)),
+		logging.NewField("failed", len(errs)),
+	)
+	return report, errors.Join(errs...)
+}
+
+// lifecycleBlob is a listed blob with its parsed modification time.
+type lifecycleBlob struct {
+	info     BlobInfo
+	modified time.Time
+}
+
+// plan returns the actions rule calls for on blobs, at most one per blob.
+func (s *LifecycleSweeper) plan(rule LifecycleRule, blobs []BlobInfo) []LifecycleAction {
+	now := s.now()
+
+	// Group by virtual directory, newest first, to find the blobs to keep
+	dirs := make(map[string][]lifecycleBlob)
+	for _, blob := range blobs {
+		if blob.IsPrefix {
+			continue
+		}
+		modified, err := time.Parse(time.RFC3339, blob.LastModified)
+		if err != nil {
+			s.logger.Warn("Skipping blob without modification time",
+				logging.NewField("container", rule.Container),
+				logging.NewField("blob", blob.Name),
+			)
+			continue
+		}
+		dir := path.Dir(blob.Name)
+		dirs[dir] = append(dirs[dir], lifecycleBlob{info: blob, modified: modified})
+	}
+
+	var actions []LifecycleAction
+	for _, dirBlobs := range dirs {
+		sort.Slice(dirBlobs, func(i, j int) bool {
+			if !dirBlobs[i].modified.Equal(dirBlobs[j].modified) {
+				return dirBlobs[i].modified.After(dirBlobs[j].modified)
+			}
+			return dirBlobs[i].info.Name > dirBlobs[j].info.Name
+		})
+
+		for i, blob := range dirBlobs {
+			age := now.Sub(blob.modified)
+			action := LifecycleAction{Container: rule.Container, BlobName: blob.info.Name, etag: blob.info.ETag}
+			switch {
+			case rule.DeleteAfter > 0 && age >= rule.DeleteAfter:
+				action.Action = LifecycleActionDelete
+				action.Reason = fmt.Sprintf("older than %s", rule.DeleteAfter)
+			case rule.KeepNewestPerDirectory > 0 && i >= rule.KeepNewestPerDirectory:
+				action.Action = LifecycleActionDelete
+				action.Reason = fmt.Sprintf("not among the %d newest in %s", rule.KeepNewestPerDirectory, path.Dir(blob.info.Name))
+			case rule.TierAfter > 0 && age >= rule.TierAfter && colderTier(rule.Tier, blob.info.AccessTier):
+				action.Action = LifecycleActionTier
+				action.Tier = rule.Tier
+				action.Reason = fmt.Sprintf("older than %s", rule.TierAfter)
+			default:
+				continue
+			}
+			actions = append(actions, action)
+		}
+	}
+
+	sort.Slice(actions, func(i, j int) bool { return actions[i].BlobName < actions[j].BlobName })
+	return actions
+}
+
+// apply executes an action, marking it skipped if the blob changed since it was listed.
+func (s *LifecycleSweeper) apply(ctx context.Context, action *LifecycleAction) {
+	var err error
+	switch action.Action {
+	case LifecycleActionDelete:
+		err = s.client.DeleteWithOptions(ctx, action.Container, action.BlobName, DeleteOptions{IfMatch: action.etag})
+	case LifecycleActionTier:
+		err = s.client.SetAccessTier(ctx, action.Container, action.BlobName, action.Tier)
+	}
+	if errors.Is(err, ErrPreconditionFailed) || errors.Is(err, ErrBlobNotFound) {
+		action.Skipped = true
+		err = nil
+	}
+	action.Err = err
+}
+
+// logAction logs an applied, skipped or proposed action.
+func (s *LifecycleSweeper) logAction(action LifecycleAction) {
+	fields := []logging.Field{
+		logging.NewField("container", action.Container),
+		logging.NewField("blob", action.BlobName),
+		logging.NewField("action", action.Action),
+		logging.NewField("reason", action.Reason),
+		logging.NewField("dry_run", s.cfg.DryRun),
+	}
+	if action.Action == LifecycleActionTier {
+		fields = append(fields, logging.NewField("tier", action.Tier))
+	}
+
+	switch {
+	case action.Err != nil:
+		s.logger.Error("Blob lifecycle action failed", append(fields, logging.NewField("error", action.Err))...)
+	case action.Skipped:
+		s.logger.Info("Blob lifecycle action skipped, blob changed since listing", fields...)
+	default:
+		s.logger.Info("Blob lifecycle action", fields...)
+	}
+}
+
+// colderTier reports whether target is colder than current. Blobs without a reported
+// tier are assumed to be Hot.
+func colderTier(target, current string) bool {
+	if current == "" {
+		current = "hot"
+	}
+	currentRank, ok := lifecycleTierRank[strings.ToLower(current)]
+	if !ok {
+		// Unknown tiers, e.g. S3 classes without an Azure equivalent, are left alone
+		return false
+	}
+	return lifecycleTierRank[strings.ToLower(target)] > currentRank
+}
@@ -0,0 +1,267 @@
+package blobclient
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yourorg/go-service-kit/pkg/config"
+	"github.com/yourorg/go-service-kit/pkg/logging"
+)
+
+// lifecycleClock is a settable clock for the mock and the sweeper.
+type lifecycleClock struct {
+	now time.Time
+}
+
+func (c *lifecycleClock) Now() time.Time { return c.now }
+
+// uploadAt uploads a blob with its modification time set to at.
+func uploadAt(t *testing.T, client *MockBlobClient, clock *lifecycleClock, at time.Time, container, blobName string) {
+	t.Helper()
+	clock.now = at
+	if _, err := client.Upload(context.Background(), container, blobName, strings.NewReader(blobName), "text/csv"); err != nil {
+		t.Fatalf("Upload %s failed: %v", blobName, err)
+	}
+}
+
+// newTestSweeper returns a sweeper over client using clock.
+func newTestSweeper(t *testing.T, client BlobClient, clock *lifecycleClock, cfg LifecycleConfig) *LifecycleSweeper {
+	t.Helper()
+	logger, _ := logging.NewLogger("error", "json")
+	sweeper, err := NewLifecycleSweeper(client, cfg, logger, WithLifecycleClock(clock.Now))
+	if err != nil {
+		t.Fatalf("NewLifecycleSweeper failed: %v", err)
+	}
+	return sweeper
+}
+
+// actionsByBlob indexes the actions of a report by blob name.
+func actionsByBlob(report *LifecycleReport) map[string]LifecycleAction {
+	actions := make(map[string]LifecycleAction, len(report.Actions))
+	for _, action := range report.Actions {
+		actions[action.BlobName] = action
+	}
+	return actions
+}
+
+func TestLifecycleSweeper_AgeRules(t *testing.T) {
+	clock := &lifecycleClock{}
+	client := NewMockBlobClient()
+	client.SetClock(clock.Now)
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	uploadAt(t, client, clock, start, "uploads", "tmp/old.csv")
+	uploadAt(t, client, clock, start.Add(25*day), "uploads", "tmp/recent.csv")
+	uploadAt(t, client, clock, start, "uploads", "reports/old.csv")
+	uploadAt(t, client, clock, start.Add(20*day), "uploads", "reports/cooling.csv")
+	uploadAt(t, client, clock, start.Add(29*day), "uploads", "reports/new.csv")
+	clock.now = start.Add(31 * day)
+
+	cfg := LifecycleConfig{Rules: []LifecycleRule{
+		{Container: "uploads", Prefix: "tmp/", DeleteAfter: 7 * day},
+		{Container: "uploads", Prefix: "reports/", TierAfter: 7 * day, Tier: "Cool"},
+		{Container: "uploads", Prefix: "reports/", TierAfter: 30 * day, Tier: "Archive"},
+	}}
+
+	// A dry run reports what would change without changing anything
+	cfg.DryRun = true
+	report, err := newTestSweeper(t, client, clock, cfg).Sweep(context.Background())
+	if err != nil {
+		t.Fatalf("Dry run failed: %v", err)
+	}
+	if !report.DryRun || report.Scanned != 8 {
+		t.Errorf("Expected a dry run over 8 listed blobs, got %+v", report)
+	}
+	actions := actionsByBlob(report)
+	if action := actions["tmp/old.csv"]; action.Action != LifecycleActionDelete {
+		t.Errorf("Expected tmp/old.csv to be deleted, got %+v", action)
+	}
+	if action := actions["reports/old.csv"]; action.Action != LifecycleActionTier || action.Tier != "Archive" {
+		t.Errorf("Expected reports/old.csv to move to Archive, got %+v", action)
+	}
+	if _, ok := actions["tmp/recent.csv"]; ok {
+		t.Error("Expected tmp/recent.csv to be kept")
+	}
+	if exists, _ := client.Exists(context.Background(), "uploads", "tmp/old.csv"); !exists {
+		t.Error("Expected the dry run to keep tmp/old.csv")
+	}
+
+	cfg.DryRun = false
+	if _, err := newTestSweeper(t, client, clock, cfg).Sweep(context.Background()); err != nil {
+		t.Fatalf("Sweep failed: %v", err)
+	}
+	if exists, _ := client.Exists(context.Background(), "uploads", "tmp/old.csv"); exists {
+		t.Error("Expected tmp/old.csv to be deleted")
+	}
+	tiers := map[string]string{"reports/old.csv": "Archive", "reports/cooling.csv": "Cool", "reports/new.csv": "Hot", "tmp/recent.csv": "Hot"}
+	for blobName, tier := range tiers {
+		props, err := client.GetProperties(context.Background(), "uploads", blobName)
+		if err != nil || props.AccessTier != tier {
+			t.Errorf("%s: expected tier %s, got %+v (err %v)", blobName, tier, props, err)
+		}
+	}
+
+	// Archived blobs are never moved back to a warmer tier
+	report, _ = newTestSweeper(t, client, clock, cfg).Sweep(context.Background())
+	if len(report.Actions) != 0 {
+		t.Errorf("Expected nothing left to do, got %+v", report.Actions)
+	}
+}
+
+func TestLifecycleSweeper_KeepNewestPerDirectory(t *testing.T) {
+	clock := &lifecycleClock{}
+	client := NewMockBlobClient()
+	client.SetClock(clock.Now)
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, day := range []string{"01", "02", "03", "04"} {
+		uploadAt(t, client, clock, start.Add(time.Duration(i)*time.Hour), "exports", "daily/"+day+".csv")
+	}
+	uploadAt(t, client, clock, start, "exports", "weekly/01.csv")
+
+	cfg := LifecycleConfig{Rules: []LifecycleRule{{Container: "exports", KeepNewestPerDirectory: 2}}}
+	report, err := newTestSweeper(t, client, clock, cfg).Sweep(context.Background())
+	if err != nil {
+		t.Fatalf("Sweep failed: %v", err)
+	}
+	if len(report.Actions) != 2 {
+		t.Fatalf("Expected 2 deletions, got %+v", report.Actions)
+	}
+
+	blobs, _ := client.List(context.Background(), "exports", "")
+	var names []string
+	for _, blob := range blobs {
+		names = append(names, blob.Name)
+	}
+	if got := strings.Join(names, ","); got != "daily/03.csv,daily/04.csv,weekly/01.csv" {
+		t.Errorf("Expected the 2 newest blobs per directory to remain, got %s", got)
+	}
+}
+
+func TestLifecycleSweeper_SkipsChangedBlobs(t *testing.T) {
+	clock := &lifecycleClock{}
+	client := NewMockBlobClient()
+	client.SetClock(clock.Now)
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	uploadAt(t, client, clock, start, "uploads", "tmp/leased.csv")
+	uploadAt(t, client, clock, start, "uploads", "tmp/old.csv")
+	clock.now = start.Add(30 * 24 * time.Hour)
+
+	leaseID, err := client.AcquireLease(context.Background(), "uploads", "tmp/leased.csv", InfiniteLease, "")
+	if err != nil {
+		t.Fatalf("AcquireLease failed: %v", err)
+	}
+
+	cfg := LifecycleConfig{Rules: []LifecycleRule{{Container: "uploads", Prefix: "tmp/", DeleteAfter: 24 * time.Hour}}}
+	report, err := newTestSweeper(t, client, clock, cfg).Sweep(context.Background())
+	if err != nil {
+		t.Fatalf("Sweep failed: %v", err)
+	}
+	actions := actionsByBlob(report)
+	if !actions["tmp/leased.csv"].Skipped || actions["tmp/old.csv"].Skipped {
+		t.Errorf("Expected only the leased blob to be skipped, got %+v", report.Actions)
+	}
+	client.ReleaseLease(context.Background(), "uploads", "tmp/leased.csv", leaseID)
+	if exists, _ := client.Exists(context.Background(), "uploads", "tmp/leased.csv"); !exists {
+		t.Error("Expected the leased blob to be kept")
+	}
+}
+
+func TestLifecycleSweeper_StartWaitsOneInterval(t *testing.T) {
+	clock := &lifecycleClock{}
+	client := NewMockBlobClient()
+	client.SetClock(clock.Now)
+	uploadAt(t, client, clock, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), "uploads", "tmp/old.csv")
+	clock.now = clock.now.Add(48 * time.Hour)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cfg := LifecycleConfig{Rules: []LifecycleRule{{Container: "uploads", DeleteAfter: 24 * time.Hour}}, Interval: 200 * time.Millisecond}
+	newTestSweeper(t, client, clock, cfg).Start(ctx)
+
+	time.Sleep(50 * time.Millisecond)
+	if exists, _ := client.Exists(ctx, "uploads", "tmp/old.csv"); !exists {
+		t.Fatal("Expected no sweep before the first interval")
+	}
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(20 * time.Millisecond) {
+		if exists, _ := client.Exists(ctx, "uploads", "tmp/old.csv"); !exists {
+			return
+		}
+	}
+	t.Error("Expected the blob to be deleted by the first sweep")
+}
+
+func TestLifecycleSweeper_ListErrors(t *testing.T) {
+	fsClient, _ := newTestFileSystemClient(t)
+	clock := &lifecycleClock{now: time.Now()}
+	cfg := LifecycleConfig{Rules: []LifecycleRule{{Container: "Invalid_Container", DeleteAfter: time.Hour}}}
+	if _, err := newTestSweeper(t, fsClient, clock, cfg).Sweep(context.Background()); err == nil {
+		t.Error("Expected listing errors to be returned")
+	}
+}
+
+func TestNewLifecycleSweeper_Validation(t *testing.T) {
+	logger, _ := logging.NewLogger("error", "json")
+	invalid := []LifecycleRule{
+		{Prefix: "tmp/", DeleteAfter: time.Hour},
+		{Container: "uploads"},
+		{Container: "uploads", TierAfter: time.Hour, Tier: "Hot"},
+		{Container: "uploads", TierAfter: time.Hour},
+		{Container: "uploads", DeleteAfter: -time.Hour},
+	}
+	for _, rule := range invalid {
+		if _, err := NewLifecycleSweeper(NewMockBlobClient(), LifecycleConfig{Rules: []LifecycleRule{rule}}, logger); err == nil {
+			t.Errorf("Expected error for rule %+v", rule)
+		}
+	}
+	if _, err := NewLifecycleSweeper(NewMockBlobClient(), LifecycleConfig{}, nil); err == nil {
+		t.Error("Expected error for a nil logger")
+	}
+}
+
+func TestLifecycleConfigFromConfig(t *testing.T) {
+	cfg := LifecycleConfigFromConfig(&config.Config{
+		BlobLifecycleRules: []config.BlobLifecycleRule{
+			{Container: "uploads", Prefix: "tmp/", DeleteAfterDays: 7, TierAfterDays: 1, Tier: "Cool", KeepNewestPerDirectory: 3},
+		},
+		BlobLifecycleInterval: 600,
+		BlobLifecycleDryRun:   true,
+	})
+	want := LifecycleRule{Container: "uploads", Prefix: "tmp/", DeleteAfter: 7 * 24 * time.Hour, TierAfter: 24 * time.Hour, Tier: "Cool", KeepNewestPerDirectory: 3}
+	if len(cfg.Rules) != 1 || cfg.Rules[0] != want || cfg.Interval != 10*time.Minute || !cfg.DryRun {
+		t.Errorf("Unexpected lifecycle config %+v", cfg)
+	}
+}
+
+func TestBlobClient_SetAccessTier(t *testing.T) {
+	_, s3Client := newFakeS3(t)
+	fsClient, _ := newTestFileSystemClient(t)
+	clients := map[string]BlobClient{
+		"mock":       NewMockBlobClient(),
+		"filesystem": fsClient,
+		"s3":         s3Client,
+	}
+
+	ctx := context.Background()
+	for name, client := range clients {
+		client.Upload(ctx, "docs", "a.txt", strings.NewReader("a"), "text/plain")
+		if err := client.SetAccessTier(ctx, "docs", "a.txt", "Cool"); err != nil {
+			t.Fatalf("%s: SetAccessTier failed: %v", name, err)
+		}
+		blobs, _ := client.List(ctx, "docs", "")
+		if len(blobs) != 1 || blobs[0].AccessTier != "Cool" {
+			t.Errorf("%s: expected the listing to report Cool, got %+v", name, blobs)
+		}
+		if got := readBlob(t, client, "docs", "a.txt"); got != "a" {
+			t.Errorf("%s: expected content to be unchanged, got %q", name, got)
+		}
+		if err := client.SetAccessTier(ctx, "docs", "missing.txt", "Cool"); !errors.Is(err, ErrBlobNotFound) {
+			t.Errorf("%s: expected ErrBlobNotFound, got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package blobclient
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DefaultListPageSize is the page size used when ListOptions.PageSize is not set.
+const DefaultListPageSize = 1000
+
+// BlobIterator streams a listing page by page, holding at most one page in memory.
+//
+//	it := blobclient.NewBlobIterator(ctx, client, "reports", blobclient.ListOptions{Prefix: "2024/"})
+//	for it.Next() {
+//		blob := it.Blob()
+//		...
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type BlobIterator struct {
+	ctx       context.Context
+	client    BlobClient
+	container string
+	opts      ListOptions
+
+	page    []BlobInfo
+	index   int
+	token   string
+	fetched bool
+	current BlobInfo
+	err     error
+}
+
+// NewBlobIterator returns an iterator over the blobs of a container matching opts.
+func NewBlobIterator(ctx context.Context, client BlobClient, container string, opts ListOptions) *BlobIterator {
+	return &BlobIterator{ctx: ctx, client: client, container: container, opts: opts}
+}
+
+// Next advances to the next blob, fetching the next page when needed. It returns false
+// when the listing is exhausted or a page fails to load (see Err).
+func (it *BlobIterator) Next() bool {
+	for it.index >= len(it.page) {
+		if it.err != nil || (it.fetched && it.token == "") {
+			return false
+		}
+
+		page, err := it.client.ListPageWithOptions(it.ctx, it.container, it.token, it.opts)
+		if err != nil {
+			it.err = err
+			return false
+		}
+		it.fetched = true
+		it.page = page.Blobs
+		it.index = 0
+		it.token = page.ContinuationToken
+	}
+
+	it.current = it.page[it.index]
+	it.index++
+	return true
+}
+
+// Blob returns the current blob or virtual directory.
+func (it *BlobIterator) Blob() BlobInfo {
+	return it.current
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (it *BlobIterator) Err() error {
+	return it.err
+}
+
+// pageBlobs pages an in-memory listing for backends without native pagination. blobs must
+// match opts.Prefix and be sorted by name. With a delimiter, blobs below a virtual directory
+// are collapsed into one IsPrefix entry. Continuation tokens encode the last returned name.
+func pageBlobs(blobs []BlobInfo, opts ListOptions, continuationToken string) (*BlobPage, error) {
+	var after string
+	if continuationToken != "" {
+		decoded, err := base64.RawURLEncoding.DecodeString(continuationToken)
+		if err != nil {
+			return nil, fmt.Errorf("invalid continuation token: %w", err)
+		}
+		after = string(decoded)
+	}
+
+	pageSize := opts.PageSize
+	if pageSize <= 0 {
+		pageSize = DefaultListPageSize
+	}
+
+	page := &BlobPage{Blobs: []BlobInfo{}}
+	for _, blob := range blobs {
+		if opts.Delimiter != "" {
+			rest := strings.TrimPrefix(blob.Name, opts.Prefix)
+			if i := strings.Index(rest, opts.Delimiter); i >= 0 {
+				// Blobs below one virtual directory are contiguous in name order
+				dir := opts.Prefix + rest[:i+len(opts.Delimiter)]
+				if n := len(page.Blobs); n > 0 && page.Blobs[n-1].Name == dir {
+					continue
+				}
+				blob = BlobInfo{Name: dir, IsPrefix: true}
+			}
+		}
+		if blob.Name <= after {
+			continue
+		}
+
+		if len(page.Blobs) == pageSize {
+			page.ContinuationToken = base64.RawURLEncoding.EncodeToString([]byte(page.Blobs[pageSize-1].Name))
+			break
+		}
+		if !opts.IncludeMetadata {
+			blob.Metadata = nil
+		}
+		if !opts.IncludeTags {
+			blob.Tags = nil
+		}
+		page.Blobs = append(page.Blobs, blob)
+	}
+
+	return page, nil
+}
+
+// sortBlobs orders a listing by name.
+func sortBlobs(blobs []BlobInfo) {
+	sort.Slice(blobs, func(i, j int) bool { return blobs[i].Name < blobs[j].Name })
+}
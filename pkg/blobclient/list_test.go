@@ -0,0 +1,145 @@
+package blobclient
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/yourorg/go-service-kit/pkg/tenant"
+)
+
+// seedListing uploads a small tree of blobs to the "docs" container.
+func seedListing(t *testing.T, client BlobClient) {
+	t.Helper()
+	for _, name := range []string{"b.txt", "a/2.txt", "a/1.txt", "c/d/e.txt", "a.txt", "c/f.txt"} {
+		_, err := client.UploadWithOptions(context.Background(), "docs", name, strings.NewReader(name), UploadOptions{
+			ContentType: "text/plain",
+			Metadata:    map[string]string{"name": name},
+			Tags:        map[string]string{"kind": "doc"},
+		})
+		if err != nil {
+			t.Fatalf("Upload failed: %v", err)
+		}
+	}
+}
+
+// collectPages lists every page and returns the names, marking virtual directories with "*".
+func collectPages(t *testing.T, client BlobClient, opts ListOptions) (names []string, pages int) {
+	t.Helper()
+	var token string
+	for {
+		page, err := client.ListPageWithOptions(context.Background(), "docs", token, opts)
+		if err != nil {
+			t.Fatalf("ListPageWithOptions failed: %v", err)
+		}
+		if opts.PageSize > 0 && len(page.Blobs) > opts.PageSize {
+			t.Errorf("Page has %d entries, more than page size %d", len(page.Blobs), opts.PageSize)
+		}
+		pages++
+		for _, blob := range page.Blobs {
+			if blob.IsPrefix {
+				names = append(names, blob.Name+"*")
+			} else {
+				names = append(names, blob.Name)
+			}
+		}
+		if page.ContinuationToken == "" {
+			return names, pages
+		}
+		token = page.ContinuationToken
+	}
+}
+
+func TestListPage_PaginationAndHierarchy(t *testing.T) {
+	_, s3Client := newFakeS3(t)
+	clients := map[string]BlobClient{
+		"mock": NewMockBlobClient(),
+		"s3":   s3Client,
+	}
+	fsClient, _ := newTestFileSystemClient(t)
+	clients["filesystem"] = fsClient
+
+	tests := []struct {
+		name  string
+		opts  ListOptions
+		want  []string
+		pages int
+	}{
+		{"flat", ListOptions{PageSize: 4}, []string{"a.txt", "a/1.txt", "a/2.txt", "b.txt", "c/d/e.txt", "c/f.txt"}, 2},
+		{"prefix", ListOptions{Prefix: "a/", PageSize: 1}, []string{"a/1.txt", "a/2.txt"}, 2},
+		{"delimiter", ListOptions{Delimiter: "/", PageSize: 2}, []string{"a.txt", "a/*", "b.txt", "c/*"}, 2},
+		{"nested delimiter", ListOptions{Prefix: "c/", Delimiter: "/"}, []string{"c/d/*", "c/f.txt"}, 1},
+	}
+
+	for clientName, client := range clients {
+		seedListing(t, client)
+		for _, tt := range tests {
+			names, pages := collectPages(t, client, tt.opts)
+			if !reflect.DeepEqual(names, tt.want) || pages != tt.pages {
+				t.Errorf("%s/%s: got %v in %d pages, want %v in %d pages", clientName, tt.name, names, pages, tt.want, tt.pages)
+			}
+		}
+
+		page, err := client.ListPageWithOptions(context.Background(), "docs", "", ListOptions{Prefix: "b", IncludeMetadata: true, IncludeTags: true})
+		if err != nil || len(page.Blobs) != 1 {
+			t.Fatalf("%s: ListPageWithOptions failed: %v", clientName, err)
+		}
+		if page.Blobs[0].Metadata["name"] != "b.txt" || page.Blobs[0].Tags["kind"] != "doc" {
+			t.Errorf("%s: expected metadata and tags, got %+v", clientName, page.Blobs[0])
+		}
+
+		page, _ = client.ListPage(context.Background(), "docs", "b", "", 10)
+		if page.Blobs[0].Metadata != nil || page.Blobs[0].Tags != nil {
+			t.Errorf("%s: expected no metadata or tags unless requested, got %+v", clientName, page.Blobs[0])
+		}
+	}
+}
+
+func TestMockBlobClient_ListIsSorted(t *testing.T) {
+	client := NewMockBlobClient()
+	seedListing(t, client)
+
+	blobs, _ := client.List(context.Background(), "docs", "")
+	for i := 1; i < len(blobs); i++ {
+		if blobs[i-1].Name >= blobs[i].Name {
+			t.Fatalf("List is not sorted: %q before %q", blobs[i-1].Name, blobs[i].Name)
+		}
+	}
+}
+
+func TestBlobIterator(t *testing.T) {
+	client := NewMockBlobClient()
+	seedListing(t, client)
+
+	it := NewBlobIterator(context.Background(), client, "docs", ListOptions{Delimiter: "/", PageSize: 1})
+	var names []string
+	for it.Next() {
+		names = append(names, it.Blob().Name)
+	}
+	if err := it.Err(); err != nil {
+		t.Fatalf("Iterator failed: %v", err)
+	}
+	if want := []string{"a.txt", "a/", "b.txt", "c/"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("Got %v, want %v", names, want)
+	}
+
+	it = NewBlobIterator(context.Background(), client, "empty", ListOptions{})
+	if it.Next() || it.Err() != nil {
+		t.Errorf("Expected empty iteration, got error %v", it.Err())
+	}
+
+	// Errors stop the iteration and are reported by Err
+	it = NewBlobIterator(context.Background(), NewTenantBlobClient(client), "docs", ListOptions{})
+	if it.Next() || !errors.Is(it.Err(), tenant.ErrMissingTenant) {
+		t.Errorf("Expected missing tenant error, got %v", it.Err())
+	}
+}
+
+func TestListPage_InvalidToken(t *testing.T) {
+	client := NewMockBlobClient()
+	if _, err := client.ListPage(context.Background(), "docs", "", "not base64!", 10); err == nil {
+		t.Error("Expected error for invalid continuation token")
+	}
+}
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS blob_dedup_refs;
@@ -0,0 +1,7 @@
+-- Names of archived blobs and the content hash they point at (blobclient.PostgresDedupIndex)
+CREATE TABLE IF NOT EXISTS blob_dedup_refs (
+	name TEXT PRIMARY KEY,
+	hash TEXT NOT NULL,
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS blob_dedup_refs_hash_idx ON blob_dedup_refs (hash);
@@ -3,42 +3,179 @@ package blobclient
 import (
 	"bytes"
 	"context"
+	"crypto/md5"
 	"fmt"
 	"io"
+	"net/url"
+	"strings"
 	"sync"
+	"time"
 )
 
 // MockBlobClient is an in-memory implementation of BlobClient for testing.
 type MockBlobClient struct {
-	blobs map[string]map[string][]byte // container -> blobName -> data
-	mu    sync.RWMutex
+	blobs     map[string]map[string]*mockBlob // container -> blobName -> blob
+	staged    map[string]map[string][]byte    // container/blobName -> blockID -> uncommitted block
+	snapshots map[string][]mockSnapshot       // container/blobName -> snapshots, oldest first
+	deleted   map[string]*mockDeleted         // container/blobName -> soft-deleted blob
+	copies    map[string]*mockCopy            // container/blobName -> last copy into the blob
+	leases    *leaseTable                     // container/blobName -> lease
+	mu        sync.RWMutex
+	
+	copyPolls  int // GetCopyStatus calls before a copy completes (see SimulateAsyncCopies)
+	nextCopyID int
+	
+	signer *urlSigner       // signs and verifies mock:// URLs
+	now    func() time.Time // clock for modification times and leases (see SetClock)
+}
+
+// mockBlob is a stored blob with its properties.
+type mockBlob struct {
+	data  []byte
+	props BlobProperties
 }
 
 // NewMockBlobClient creates a new mock blob client.
 func NewMockBlobClient() *MockBlobClient {
 	return &MockBlobClient{
-		blobs: make(map[string]map[string][]byte),
+		blobs:     make(map[string]map[string]*mockBlob),
+		staged:    make(map[string]map[string][]byte),
+		snapshots: make(map[string][]mockSnapshot),
+		deleted:   make(map[string]*mockDeleted),
+		copies:    make(map[string]*mockCopy),
+		leases:    newLeaseTable(),
+		signer:    newURLSigner("mock"),
+		now:       time.Now,
 	}
 }
 
 // Upload uploads data to the mock storage.
 func (m *MockBlobClient) Upload(ctx context.Context, container, blobName string, data io.Reader, contentType string) (string, error) {
+	return m.UploadWithOptions(ctx, container, blobName, data, UploadOptions{ContentType: contentType})
+}
+
+// UploadWithOptions uploads data to the mock storage, recording headers, access tier,
+// metadata and tags so they can be read back with GetProperties.
+func (m *MockBlobClient) UploadWithOptions(ctx context.Context, container, blobName string, data io.Reader, opts UploadOptions) (string, error) {
+	blobData, err := io.ReadAll(data)
+	if err != nil {
+		return "", fmt.Errorf("failed to read data: %w", err)
+	}
+	
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	
+	if err := m.checkWrite(container, blobName, opts); err != nil {
+		return "", err
+	}
+	return m.put(container, blobName, blobData, opts, nil), nil
+}
+
+// checkWrite enforces the ETag conditions and lease of an upload. Callers must hold the lock.
+func (m *MockBlobClient) checkWrite(container, blobName string, opts UploadOptions) error {
+	var etag string
+	if blob, exists := m.blobs[container][blobName]; exists {
+		etag = blob.props.ETag
+	}
+	if err := checkConditions(etag, opts.IfMatch, opts.IfNoneMatch); err != nil {
+		return err
+	}
+	return m.leases.checkWrite(container+"/"+blobName, opts.LeaseID)
+}
+
+// put stores a blob. Callers must hold the write lock.
+func (m *MockBlobClient) put(container, blobName string, blobData []byte, opts UploadOptions, contentMD5 []byte) string {
 	if m.blobs[container] == nil {
-		m.blobs[container] = make(map[string][]byte)
+		m.blobs[container] = make(map[string]*mockBlob)
 	}
 	
-	blobData, err := io.ReadAll(data)
-	if err != nil {
-		return "", fmt.Errorf("failed to read data: %w", err)
+	url := fmt.Sprintf("mock://%s/%s", container, blobName)
+	
+	accessTier := opts.AccessTier
+	if accessTier == "" {
+		accessTier = "Hot"
 	}
 	
-	m.blobs[container][blobName] = blobData
-	url := fmt.Sprintf("mock://%s/%s", container, blobName)
+	m.blobs[container][blobName] = &mockBlob{
+		data: blobData,
+		props: BlobProperties{
+			Name:               blobName,
+			Size:               int64(len(blobData)),
+			ContentType:        opts.ContentType,
+			CacheControl:       opts.CacheControl,
+			ContentDisposition: opts.ContentDisposition,
+			ContentMD5:         contentMD5,
+			AccessTier:         accessTier,
+			Metadata:           copyStringMap(opts.Metadata),
+			Tags:               copyStringMap(opts.Tags),
+			LastModified:       m.now().UTC().Format(time.RFC3339),
+			ETag:               newETag(),
+			URL:                url,
+		},
+	}
 	
-	return url, nil
+	return url
+}
+
+// UploadChunked uploads data as staged blocks, mirroring the Azure block blob flow,
+// including resume of interrupted uploads and transactional checksum verification.
+func (m *MockBlobClient) UploadChunked(ctx context.Context, container, blobName string, data io.Reader, opts UploadOptions, transfer TransferOptions) (string, error) {
+	return uploadChunked(ctx, m, container, blobName, data, opts, transfer)
+}
+
+// prepareBlocks returns the uncommitted blocks of a blob.
+func (m *MockBlobClient) prepareBlocks(ctx context.Context, container, blobName string, opts UploadOptions, resumeID string) (string, map[string]int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	
+	staged := make(map[string]int64)
+	for id, block := range m.staged[container+"/"+blobName] {
+		staged[id] = int64(len(block))
+	}
+	return "", staged, nil
+}
+
+// stageBlock stores an uncommitted block after verifying its checksum.
+func (m *MockBlobClient) stageBlock(ctx context.Context, container, blobName, upload, leaseID, blockID string, data []byte, algorithm ChecksumAlgorithm, sum []byte) error {
+	if err := verifyBlockChecksum(data, algorithm, sum); err != nil {
+		return err
+	}
+	
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	
+	key := container + "/" + blobName
+	if err := m.leases.checkWrite(key, leaseID); err != nil {
+		return err
+	}
+	if m.staged[key] == nil {
+		m.staged[key] = make(map[string][]byte)
+	}
+	m.staged[key][blockID] = bytes.Clone(data)
+	return nil
+}
+
+// commitBlocks assembles a blob from staged blocks and discards the remaining ones.
+func (m *MockBlobClient) commitBlocks(ctx context.Context, container, blobName, upload string, blockIDs []string, opts UploadOptions, contentMD5 []byte) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	
+	if err := m.checkWrite(container, blobName, opts); err != nil {
+		return "", err
+	}
+	
+	key := container + "/" + blobName
+	var blobData []byte
+	for _, id := range blockIDs {
+		block, ok := m.staged[key][id]
+		if !ok {
+			return "", fmt.Errorf("block not found: %s", id)
+		}
+		blobData = append(blobData, block...)
+	}
+	delete(m.staged, key)
+	
+	return m.put(container, blobName, blobData, opts, contentMD5), nil
 }
 
 // Get retrieves a blob from the mock storage.
@@ -47,25 +184,152 @@ func (m *MockBlobClient) Get(ctx context.Context, container, blobName string) (i
 	defer m.mu.RUnlock()
 	
 	if m.blobs[container] == nil {
-		return nil, fmt.Errorf("container not found: %s", container)
+		return nil, containerNotFound(container, nil)
 	}
 	
-	data, exists := m.blobs[container][blobName]
+	blob, exists := m.blobs[container][blobName]
 	if !exists {
-		return nil, fmt.Errorf("blob not found: %s/%s", container, blobName)
+		return nil, blobNotFound(container, blobName, nil)
 	}
 	
+	return io.NopCloser(bytes.NewReader(blob.data)), nil
+}
+
+// GetRange retrieves count bytes of a blob starting at offset. A count of 0 reads to the end.
+func (m *MockBlobClient) GetRange(ctx context.Context, container, blobName string, offset, count int64) (io.ReadCloser, error) {
+	return m.GetRangeWithOptions(ctx, container, blobName, offset, count, GetOptions{})
+}
+
+// GetRangeWithOptions retrieves a byte range of a blob if it matches opts.IfMatch.
+func (m *MockBlobClient) GetRangeWithOptions(ctx context.Context, container, blobName string, offset, count int64, opts GetOptions) (io.ReadCloser, error) {
+	data, _, err := m.readRange(ctx, container, blobName, offset, count, opts.IfMatch, false)
+	if err != nil {
+		return nil, err
+	}
 	return io.NopCloser(bytes.NewReader(data)), nil
 }
 
+// DownloadChunked writes a blob to w using parallel ranged reads.
+func (m *MockBlobClient) DownloadChunked(ctx context.Context, container, blobName string, w io.Writer, transfer TransferOptions) (int64, error) {
+	return downloadChunked(ctx, m, container, blobName, w, transfer)
+}
+
+// readRange reads a byte range and, if rangeMD5 is set, its MD5.
+func (m *MockBlobClient) readRange(ctx context.Context, container, blobName string, offset, count int64, ifMatch string, rangeMD5 bool) ([]byte, []byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	
+	if m.blobs[container] == nil {
+		return nil, nil, containerNotFound(container, nil)
+	}
+	
+	blob, exists := m.blobs[container][blobName]
+	if !exists {
+		return nil, nil, blobNotFound(container, blobName, nil)
+	}
+	if err := checkConditions(blob.props.ETag, ifMatch, ""); err != nil {
+		return nil, nil, err
+	}
+	
+	size := int64(len(blob.data))
+	if offset < 0 || offset >= size && size > 0 || count < 0 {
+		return nil, nil, fmt.Errorf("invalid range: offset %d, count %d, size %d", offset, count, size)
+	}
+	end := size
+	if count > 0 && offset+count < size {
+		end = offset + count
+	}
+	
+	data := bytes.Clone(blob.data[offset:end])
+	if !rangeMD5 {
+		return data, nil, nil
+	}
+	sum := md5.Sum(data)
+	return data, sum[:], nil
+}
+
+// GetProperties returns the properties recorded at upload time.
+func (m *MockBlobClient) GetProperties(ctx context.Context, container, blobName string) (*BlobProperties, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	
+	if m.blobs[container] == nil {
+		return nil, containerNotFound(container, nil)
+	}
+	
+	blob, exists := m.blobs[container][blobName]
+	if !exists {
+		return nil, blobNotFound(container, blobName, nil)
+	}
+	
+	props := blob.props
+	props.ContentMD5 = bytes.Clone(blob.props.ContentMD5)
+	props.Metadata = copyStringMap(blob.props.Metadata)
+	props.Tags = copyStringMap(blob.props.Tags)
+	return &props, nil
+}
+
+// SetMetadata replaces the metadata of a blob and gives it a new ETag.
+func (m *MockBlobClient) SetMetadata(ctx context.Context, container, blobName string, metadata map[string]string, opts SetMetadataOptions) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	
+	blob, exists := m.blobs[container][blobName]
+	if !exists {
+		return blobNotFound(container, blobName, nil)
+	}
+	if err := m.checkWrite(container, blobName, UploadOptions{IfMatch: opts.IfMatch, LeaseID: opts.LeaseID}); err != nil {
+		return err
+	}
+	
+	blob.props.Metadata = copyStringMap(metadata)
+	blob.props.LastModified = m.now().UTC().Format(time.RFC3339)
+	blob.props.ETag = newETag()
+	return nil
+}
+
+// SetAccessTier records a new access tier for a blob.
+func (m *MockBlobClient) SetAccessTier(ctx context.Context, container, blobName, tier string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	
+	blob, exists := m.blobs[container][blobName]
+	if !exists {
+		return blobNotFound(container, blobName, nil)
+	}
+	blob.props.AccessTier = tier
+	return nil
+}
+
 // Delete deletes a blob from the mock storage.
 func (m *MockBlobClient) Delete(ctx context.Context, container, blobName string) error {
+	return m.DeleteWithOptions(ctx, container, blobName, DeleteOptions{})
+}
+
+// DeleteWithOptions deletes a blob if its ETag and lease match opts.
+func (m *MockBlobClient) DeleteWithOptions(ctx context.Context, container, blobName string, opts DeleteOptions) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	
 	if m.blobs[container] == nil {
-		return fmt.Errorf("container not found: %s", container)
+		return containerNotFound(container, nil)
+	}
+	
+	// Deleted blobs and their snapshots are kept for Undelete, like Azure soft delete
+	key := container + "/" + blobName
+	blob, exists := m.blobs[container][blobName]
+	if !exists {
+		return blobNotFound(container, blobName, nil)
 	}
+	if err := checkConditions(blob.props.ETag, opts.IfMatch, ""); err != nil {
+		return err
+	}
+	if err := m.leases.checkWrite(key, opts.LeaseID); err != nil {
+		return err
+	}
+	m.deleted[key] = &mockDeleted{blob: blob, snapshots: m.snapshots[key]}
+	delete(m.snapshots, key)
+	m.leases.remove(key)
 	
 	delete(m.blobs[container], blobName)
 	return nil
@@ -84,27 +348,352 @@ func (m *MockBlobClient) Exists(ctx context.Context, container, blobName string)
 	return exists, nil
 }
 
-// List lists blobs in a container with optional prefix.
+// List lists blobs in a container with optional prefix, sorted by name.
 func (m *MockBlobClient) List(ctx context.Context, container, prefix string) ([]BlobInfo, error) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 	
-	if m.blobs[container] == nil {
-		return []BlobInfo{}, nil
-	}
+	return m.blobInfos(container, prefix), nil
+}
+
+// ListPage returns one page of blobs in name order.
+func (m *MockBlobClient) ListPage(ctx context.Context, container, prefix, continuationToken string, pageSize int) (*BlobPage, error) {
+	return m.ListPageWithOptions(ctx, container, continuationToken, ListOptions{Prefix: prefix, PageSize: pageSize})
+}
+
+// ListPageWithOptions returns one page of blobs and virtual directories in name order.
+func (m *MockBlobClient) ListPageWithOptions(ctx context.Context, container, continuationToken string, opts ListOptions) (*BlobPage, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 	
-	var blobs []BlobInfo
-	for name, data := range m.blobs[container] {
-		if prefix == "" || len(name) >= len(prefix) && name[:len(prefix)] == prefix {
+	return pageBlobs(m.blobInfos(container, opts.Prefix), opts, continuationToken)
+}
+
+// blobInfos returns the blobs matching prefix sorted by name. The caller must hold the lock.
+func (m *MockBlobClient) blobInfos(container, prefix string) []BlobInfo {
+	blobs := []BlobInfo{}
+	for name, blob := range m.blobs[container] {
+		if strings.HasPrefix(name, prefix) {
+			contentType := blob.props.ContentType
+			if contentType == "" {
+				contentType = "application/octet-stream"
+			}
 			blobs = append(blobs, BlobInfo{
-				Name:        name,
-				Size:        int64(len(data)),
-				ContentType: "application/octet-stream",
-				URL:         fmt.Sprintf("mock://%s/%s", container, name),
+				Name:         name,
+				Size:         int64(len(blob.data)),
+				ContentType:  contentType,
+				LastModified: blob.props.LastModified,
+				URL:          blob.props.URL,
+				ETag:         blob.props.ETag,
+				AccessTier:   blob.props.AccessTier,
+				Metadata:     copyStringMap(blob.props.Metadata),
+				Tags:         copyStringMap(blob.props.Tags),
 			})
 		}
 	}
 	
-	return blobs, nil
+	sortBlobs(blobs)
+	return blobs
+}
+
+// GenerateReadURL returns a signed mock:// URL granting read access, verifiable with
+// VerifySignedURL and usable with OpenSignedURL.
+func (m *MockBlobClient) GenerateReadURL(ctx context.Context, container, blobName string, opts SASOptions) (string, error) {
+	return m.signer.sign(container, blobName, opts, readPermissions)
+}
+
+// GenerateWriteURL returns a signed mock:// URL granting create and write access,
+// usable with UploadToSignedURL.
+func (m *MockBlobClient) GenerateWriteURL(ctx context.Context, container, blobName string, opts SASOptions) (string, error) {
+	return m.signer.sign(container, blobName, opts, writePermissions)
+}
+
+// VerifySignedURL checks that rawURL was issued by this client, has not expired, grants
+// required and allows clientIP (which may be empty when the URL has no IP range).
+// It returns the container and blob the URL refers to.
+func (m *MockBlobClient) VerifySignedURL(rawURL string, required SASPermissions, clientIP string) (container, blobName string, err error) {
+	container, blobName, _, err = m.signer.verify(rawURL, required, clientIP)
+	return container, blobName, err
+}
+
+// OpenSignedURL downloads a blob through a signed URL as a browser would, returning the
+// content and the properties as served, including response header overrides.
+func (m *MockBlobClient) OpenSignedURL(ctx context.Context, rawURL, clientIP string) (io.ReadCloser, *BlobProperties, error) {
+	container, blobName, query, err := m.signer.verify(rawURL, readPermissions, clientIP)
+	if err != nil {
+		return nil, nil, err
+	}
+	
+	props, err := m.GetProperties(ctx, container, blobName)
+	if err != nil {
+		return nil, nil, err
+	}
+	if disposition := query.Get("rscd"); disposition != "" {
+		props.ContentDisposition = disposition
+	}
+	if contentType := query.Get("rsct"); contentType != "" {
+		props.ContentType = contentType
+	}
+	
+	reader, err := m.Get(ctx, container, blobName)
+	if err != nil {
+		return nil, nil, err
+	}
+	return reader, props, nil
+}
+
+// UploadToSignedURL uploads a blob through a signed URL as a browser would.
+func (m *MockBlobClient) UploadToSignedURL(ctx context.Context, rawURL, clientIP string, data io.Reader, contentType string) error {
+	// Write allows creating and overwriting; Create only allows creating new blobs
+	container, blobName, err := m.VerifySignedURL(rawURL, SASPermissions{Write: true}, clientIP)
+	if err != nil {
+		container, blobName, err = m.VerifySignedURL(rawURL, SASPermissions{Create: true}, clientIP)
+		if err != nil {
+			return err
+		}
+		if exists, _ := m.Exists(ctx, container, blobName); exists {
+			return fmt.Errorf("%w: blob exists and permission \"w\" not granted", ErrSignedURLRejected)
+		}
+	}
+	
+	_, err = m.Upload(ctx, container, blobName, data, contentType)
+	return err
+}
+
+// copyStringMap returns a copy of src that is never nil.
+func copyStringMap(src map[string]string) map[string]string {
+	dst := make(map[string]string, len(src))
+	for key, value := range src {
+		dst[key] = value
+	}
+	return dst
+}
+
+// mockSnapshot is a point-in-time copy of a blob.
+type mockSnapshot struct {
+	id   string
+	blob *mockBlob
+}
+
+// mockDeleted is a soft-deleted blob with the snapshots it had.
+type mockDeleted struct {
+	blob      *mockBlob
+	snapshots []mockSnapshot
+}
+
+// mockCopy tracks a copy that completes after a number of status polls.
+type mockCopy struct {
+	status    CopyStatus
+	polls     int
+	remaining int
+	container string
+	blobName  string
+	blob      *mockBlob // copied data, written to the destination on completion
+}
+
+// clone returns a copy of the blob as stored under container/blobName at now.
+func (b *mockBlob) clone(container, blobName string, now time.Time) *mockBlob {
+	props := b.props
+	props.Name = blobName
+	props.ContentMD5 = bytes.Clone(b.props.ContentMD5)
+	props.Metadata = copyStringMap(b.props.Metadata)
+	props.Tags = copyStringMap(b.props.Tags)
+	props.LastModified = now.UTC().Format(time.RFC3339)
+	props.ETag = newETag()
+	props.URL = fmt.Sprintf("mock://%s/%s", container, blobName)
+	return &mockBlob{data: bytes.Clone(b.data), props: props}
+}
+
+// SimulateAsyncCopies makes subsequent copies stay pending until GetCopyStatus has been
+// called polls times, as large copies do in Azure. Zero completes copies immediately.
+func (m *MockBlobClient) SimulateAsyncCopies(polls int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	
+	m.copyPolls = polls
+}
+
+// SetClock replaces the clock used for modification times and lease expiry, so tests can
+// age blobs without waiting.
+func (m *MockBlobClient) SetClock(now func() time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	
+	m.now = now
+	m.leases.now = now
+}
+
+// Copy copies a blob, immediately unless SimulateAsyncCopies is set.
+func (m *MockBlobClient) Copy(ctx context.Context, srcContainer, srcBlob, dstContainer, dstBlob string) (*CopyStatus, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	
+	src, exists := m.blobs[srcContainer][srcBlob]
+	if !exists {
+		return nil, blobNotFound(srcContainer, srcBlob, nil)
+	}
+	if err := m.leases.checkWrite(dstContainer+"/"+dstBlob, ""); err != nil {
+		return nil, err
+	}
+	
+	m.nextCopyID++
+	pending := &mockCopy{
+		status: CopyStatus{
+			ID:         fmt.Sprintf("copy-%d", m.nextCopyID),
+			Status:     CopyStatusPending,
+			TotalBytes: int64(len(src.data)),
+		},
+		polls:     m.copyPolls,
+		remaining: m.copyPolls,
+		container: dstContainer,
+		blobName:  dstBlob,
+		blob:      src.clone(dstContainer, dstBlob, m.now()),
+	}
+	m.copies[dstContainer+"/"+dstBlob] = pending
+	if pending.remaining == 0 {
+		m.completeCopy(pending)
+	}
+	
+	status := pending.status
+	return &status, nil
+}
+
+// completeCopy writes the copied blob to its destination. The caller must hold the lock.
+func (m *MockBlobClient) completeCopy(pending *mockCopy) {
+	if m.blobs[pending.container] == nil {
+		m.blobs[pending.container] = make(map[string]*mockBlob)
+	}
+	m.blobs[pending.container][pending.blobName] = pending.blob
+	pending.status.Status = CopyStatusSuccess
+	pending.status.BytesCopied = pending.status.TotalBytes
+}
+
+// GetCopyStatus returns the status of the last copy into a blob, advancing simulated
+// asynchronous copies by one poll.
+func (m *MockBlobClient) GetCopyStatus(ctx context.Context, container, blobName string) (*CopyStatus, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	
+	pending, exists := m.copies[container+"/"+blobName]
+	if !exists {
+		return nil, blobError(ErrBlobNotFound, fmt.Sprintf("no copy found for blob: %s/%s", container, blobName), nil)
+	}
+	
+	if pending.status.Status == CopyStatusPending {
+		pending.remaining--
+		if pending.remaining <= 0 {
+			m.completeCopy(pending)
+		} else {
+			pending.status.BytesCopied = pending.status.TotalBytes * int64(pending.polls-pending.remaining) / int64(pending.polls)
+		}
+	}
+	
+	status := pending.status
+	return &status, nil
 }
 
+// Move copies a blob to its destination and deletes the source.
+func (m *MockBlobClient) Move(ctx context.Context, srcContainer, srcBlob, dstContainer, dstBlob string) (string, error) {
+	if err := moveBlob(ctx, m, time.Millisecond, srcContainer, srcBlob, dstContainer, dstBlob); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("mock://%s/%s", dstContainer, dstBlob), nil
+}
+
+// Snapshot stores a point-in-time copy of a blob.
+func (m *MockBlobClient) Snapshot(ctx context.Context, container, blobName string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	
+	blob, exists := m.blobs[container][blobName]
+	if !exists {
+		return "", blobNotFound(container, blobName, nil)
+	}
+	
+	key := container + "/" + blobName
+	id := newSnapshotID(func(id string) bool {
+		for _, snapshot := range m.snapshots[key] {
+			if snapshot.id == id {
+				return true
+			}
+		}
+		return false
+	})
+	m.snapshots[key] = append(m.snapshots[key], mockSnapshot{id: id, blob: blob.clone(container, blobName, m.now())})
+	return id, nil
+}
+
+// ListSnapshots lists the snapshots of a blob, oldest first.
+func (m *MockBlobClient) ListSnapshots(ctx context.Context, container, blobName string) ([]BlobInfo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	
+	snapshots := []BlobInfo{}
+	for _, snapshot := range m.snapshots[container+"/"+blobName] {
+		snapshots = append(snapshots, BlobInfo{
+			Name:         blobName,
+			Size:         int64(len(snapshot.blob.data)),
+			ContentType:  snapshot.blob.props.ContentType,
+			LastModified: snapshot.blob.props.LastModified,
+			URL:          snapshot.blob.props.URL + "?snapshot=" + url.QueryEscape(snapshot.id),
+			Snapshot:     snapshot.id,
+		})
+	}
+	return snapshots, nil
+}
+
+// Undelete restores a deleted blob and its snapshots. Undeleting a blob that exists is a no-op.
+func (m *MockBlobClient) Undelete(ctx context.Context, container, blobName string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	
+	if _, exists := m.blobs[container][blobName]; exists {
+		return nil
+	}
+	
+	key := container + "/" + blobName
+	deleted, exists := m.deleted[key]
+	if !exists {
+		return blobError(ErrBlobNotFound, fmt.Sprintf("no deleted blob found: %s/%s", container, blobName), nil)
+	}
+	
+	m.blobs[container][blobName] = deleted.blob
+	m.snapshots[key] = deleted.snapshots
+	delete(m.deleted, key)
+	return nil
+}
+
+// AcquireLease takes a lease on a blob, enforcing the Azure lease state machine.
+func (m *MockBlobClient) AcquireLease(ctx context.Context, container, blobName string, duration time.Duration, proposedLeaseID string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	
+	if _, exists := m.blobs[container][blobName]; !exists {
+		return "", blobNotFound(container, blobName, nil)
+	}
+	return m.leases.acquire(container+"/"+blobName, duration, proposedLeaseID)
+}
+
+// RenewLease restarts the duration of a lease.
+func (m *MockBlobClient) RenewLease(ctx context.Context, container, blobName, leaseID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	
+	return m.leases.renew(container+"/"+blobName, leaseID)
+}
+
+// ReleaseLease ends a lease.
+func (m *MockBlobClient) ReleaseLease(ctx context.Context, container, blobName, leaseID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	
+	return m.leases.release(container+"/"+blobName, leaseID)
+}
+
+// BreakLease ends a lease after breakPeriod or the remaining lease time, if shorter.
+func (m *MockBlobClient) BreakLease(ctx context.Context, container, blobName string, breakPeriod time.Duration) (time.Duration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	
+	return m.leases.breakLease(container+"/"+blobName, breakPeriod)
+}
@@ -106,3 +106,66 @@ func TestMockBlobClient_List(t *testing.T) {
 	}
 }
 
+
+func TestMockBlobClient_UploadWithOptions(t *testing.T) {
+	client := NewMockBlobClient()
+	ctx := context.Background()
+
+	opts := UploadOptions{
+		ContentType:        "text/csv",
+		CacheControl:       "no-cache",
+		ContentDisposition: `attachment; filename="report.csv"`,
+		AccessTier:         "Cool",
+		Metadata:           map[string]string{"source": "import"},
+		Tags:               map[string]string{"tenant": "acme"},
+	}
+	if _, err := client.UploadWithOptions(ctx, "test-container", "report.csv", strings.NewReader("a,b"), opts); err != nil {
+		t.Fatalf("UploadWithOptions failed: %v", err)
+	}
+
+	props, err := client.GetProperties(ctx, "test-container", "report.csv")
+	if err != nil {
+		t.Fatalf("GetProperties failed: %v", err)
+	}
+
+	if props.ContentType != "text/csv" || props.CacheControl != "no-cache" || props.ContentDisposition != opts.ContentDisposition {
+		t.Errorf("Unexpected headers: %+v", props)
+	}
+	if props.AccessTier != "Cool" {
+		t.Errorf("Expected access tier Cool, got %s", props.AccessTier)
+	}
+	if props.Size != 3 {
+		t.Errorf("Expected size 3, got %d", props.Size)
+	}
+	if props.Metadata["source"] != "import" || props.Tags["tenant"] != "acme" {
+		t.Errorf("Unexpected metadata or tags: %v %v", props.Metadata, props.Tags)
+	}
+
+	// Mutating the returned properties must not affect the stored blob
+	props.Metadata["source"] = "changed"
+	props, _ = client.GetProperties(ctx, "test-container", "report.csv")
+	if props.Metadata["source"] != "import" {
+		t.Error("Expected stored metadata to be unchanged")
+	}
+}
+
+func TestMockBlobClient_UploadHonoursContentType(t *testing.T) {
+	client := NewMockBlobClient()
+	ctx := context.Background()
+
+	if _, err := client.Upload(ctx, "test-container", "doc.pdf", strings.NewReader("%PDF"), "application/pdf"); err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+
+	props, err := client.GetProperties(ctx, "test-container", "doc.pdf")
+	if err != nil {
+		t.Fatalf("GetProperties failed: %v", err)
+	}
+	if props.ContentType != "application/pdf" {
+		t.Errorf("Expected application/pdf, got %s", props.ContentType)
+	}
+
+	if _, err := client.GetProperties(ctx, "test-container", "missing"); err == nil {
+		t.Error("Expected error for missing blob")
+	}
+}
@@ -0,0 +1,71 @@
+package blobclient
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/yourorg/go-service-kit/pkg/config"
+	"github.com/yourorg/go-service-kit/pkg/logging"
+)
+
+// Blob storage providers selectable with config.Config.BlobProvider.
+const (
+	ProviderAzure      = "azure"
+	ProviderS3         = "s3"
+	ProviderFileSystem = "filesystem"
+	ProviderMock       = "mock"
+)
+
+// NewFromConfig creates the BlobClient selected by cfg.BlobProvider. When no provider
+// is set, Azure is used if a connection string or account name is configured and the
+// in-memory mock otherwise.
+func NewFromConfig(cfg *config.Config, logger logging.Logger) (BlobClient, error) {
+	provider := strings.ToLower(cfg.BlobProvider)
+	if provider == "" {
+		provider = ProviderMock
+		if cfg.BlobConnectionString != "" || cfg.BlobStorageAccountName != "" {
+			provider = ProviderAzure
+		}
+	}
+
+	switch provider {
+	case ProviderAzure:
+		var client *AzureBlobClient
+		var err error
+		if cfg.BlobConnectionString != "" {
+			client, err = NewAzureBlobClientFromConnectionString(cfg.BlobConnectionString, logger,
+				WithDefaultAccessTier(cfg.BlobAccessTier))
+		} else {
+			client, err = NewAzureBlobClient(cfg.BlobStorageAccountName, cfg.BlobStorageAccountKey, false, logger,
+				WithDefaultAccessTier(cfg.BlobAccessTier))
+		}
+		if err != nil {
+			return nil, err
+		}
+		return client, nil
+	case ProviderS3:
+		client, err := NewS3BlobClient(S3Config{
+			Endpoint:          cfg.S3Endpoint,
+			Region:            cfg.S3Region,
+			AccessKeyID:       cfg.S3AccessKeyID,
+			SecretAccessKey:   cfg.S3SecretAccessKey,
+			SessionToken:      cfg.S3SessionToken,
+			UsePathStyle:      cfg.S3UsePathStyle,
+			DefaultAccessTier: cfg.BlobAccessTier,
+		}, logger)
+		if err != nil {
+			return nil, err
+		}
+		return client, nil
+	case ProviderFileSystem:
+		client, err := NewFileSystemBlobClient(cfg.BlobLocalPath, logger)
+		if err != nil {
+			return nil, err
+		}
+		return client, nil
+	case ProviderMock:
+		return NewMockBlobClient(), nil
+	default:
+		return nil, fmt.Errorf("unsupported blob provider: %s", cfg.BlobProvider)
+	}
+}
@@ -0,0 +1,445 @@
+package blobclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/yourorg/go-service-kit/pkg/config"
+	"github.com/yourorg/go-service-kit/pkg/logging"
+	"github.com/yourorg/go-service-kit/pkg/utils"
+)
+
+// ResilienceConfig configures a ResilientBlobClient. Zero values select the defaults of
+// DefaultResilienceConfig, except TransferTimeout.
+type ResilienceConfig struct {
+	// Retry applies to idempotent operations; other operations are attempted once.
+	Retry utils.RetryConfig
+	// Timeout bounds every attempt of an operation that transfers no content, and the time
+	// until a download starts. Reading a download is bounded by the caller's context.
+	Timeout time.Duration
+	// TransferTimeout bounds uploads, chunked transfers, copies and moves. Zero leaves them
+	// to the caller's context.
+	TransferTimeout time.Duration
+	// FailureThreshold is the number of consecutive transient failures that open the circuit.
+	FailureThreshold int
+	// FailureRate also opens the circuit when this share of calls within a minute fails,
+	// between 0 and 1. Zero disables it.
+	FailureRate float64
+	// OpenTimeout is how long the circuit stays open before a single call probes the backend.
+	OpenTimeout time.Duration
+}
+
+// DefaultResilienceConfig returns the default resilience configuration.
+func DefaultResilienceConfig() ResilienceConfig {
+	return ResilienceConfig{
+		Retry:            utils.DefaultRetryConfig(),
+		Timeout:          30 * time.Second,
+		FailureThreshold: 5,
+		OpenTimeout:      30 * time.Second,
+	}
+}
+
+// ResilienceConfigFromConfig builds a ResilienceConfig using the retry settings of the
+// application configuration.
+func ResilienceConfigFromConfig(cfg *config.Config) ResilienceConfig {
+	resilienceCfg := DefaultResilienceConfig()
+	if cfg.RetryMaxAttempts > 0 {
+		resilienceCfg.Retry.MaxAttempts = cfg.RetryMaxAttempts
+	}
+	if cfg.RetryInitialDelay > 0 {
+		resilienceCfg.Retry.InitialDelay = time.Duration(cfg.RetryInitialDelay) * time.Millisecond
+	}
+	if cfg.RetryMaxDelay > 0 {
+		resilienceCfg.Retry.MaxDelay = time.Duration(cfg.RetryMaxDelay) * time.Millisecond
+	}
+	return resilienceCfg
+}
+
+// ResilientBlobClient is a BlobClient decorator that bounds every operation with a
+// deadline, retries idempotent operations on transient errors and stops calling a backend
+// that keeps failing: after FailureThreshold consecutive transient failures, calls fail
+// with ErrCircuitOpen (ErrorCodeServiceUnavailable) until OpenTimeout has passed and a
+// probe call succeeds. Breaker exposes the circuit breaker for health checks and metrics.
+//
+// Transient errors are throttling, timeouts, network errors and 5xx responses. Errors such
+// as ErrBlobNotFound or ErrPreconditionFailed are returned immediately and count as a
+// healthy backend.
+type ResilientBlobClient struct {
+	client  BlobClient
+	cfg     ResilienceConfig
+	breaker *utils.CircuitBreaker
+}
+
+// ResilienceOption configures a ResilientBlobClient.
+type ResilienceOption func(*resilienceOptions)
+
+// resilienceOptions holds the settings applied by ResilienceOption.
+type resilienceOptions struct {
+	now func() time.Time
+}
+
+// WithResilienceClock sets the clock of the circuit breaker, for tests.
+func WithResilienceClock(now func() time.Time) ResilienceOption {
+	return func(o *resilienceOptions) {
+		o.now = now
+	}
+}
+
+// NewResilientBlobClient wraps client.
+func NewResilientBlobClient(client BlobClient, cfg ResilienceConfig, logger logging.Logger, opts ...ResilienceOption) (*ResilientBlobClient, error) {
+	if client == nil {
+		return nil, fmt.Errorf("blob client is required")
+	}
+	if logger == nil {
+		return nil, fmt.Errorf("logger is required")
+	}
+
+	defaults := DefaultResilienceConfig()
+	if cfg.Retry.MaxAttempts <= 0 {
+		cfg.Retry = defaults.Retry
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaults.Timeout
+	}
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = defaults.FailureThreshold
+	}
+	if cfg.OpenTimeout <= 0 {
+		cfg.OpenTimeout = defaults.OpenTimeout
+	}
+
+	options := resilienceOptions{now: time.Now}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	logger = logger.With(logging.NewField("operation", "blob.resilience"))
+	breaker := utils.NewCircuitBreaker(utils.CircuitBreakerConfig{
+		Name:             "blob_storage",
+		FailureThreshold: cfg.FailureThreshold,
+		FailureRate:      cfg.FailureRate,
+		OpenTimeout:      cfg.OpenTimeout,
+		IsFailure:        isTransientError,
+		OnStateChange: func(name string, from, to utils.CircuitState) {
+			logger.Warn("Blob storage circuit breaker state changed",
+				logging.NewField("from", from.String()),
+				logging.NewField("to", to.String()),
+			)
+		},
+		Clock: options.now,
+	})
+
+	return &ResilientBlobClient{client: client, cfg: cfg, breaker: breaker}, nil
+}
+
+// Breaker returns the circuit breaker, e.g. to register its HealthCheck.
+func (r *ResilientBlobClient) Breaker() *utils.CircuitBreaker {
+	return r.breaker
+}
+
+// resilientCall runs fn through the circuit breaker with a deadline of timeout per
+// attempt, retrying transient failures if the operation is idempotent.
+func resilientCall[T any](r *ResilientBlobClient, ctx context.Context, op string, idempotent bool, timeout time.Duration, fn func(ctx context.Context) (T, error)) (T, error) {
+	var zero T
+	attempt := func() (T, error) {
+		done, err := r.breaker.Allow()
+		if err != nil {
+			return zero, blobError(ErrCircuitOpen, op+" rejected: blob storage is unavailable", err)
+		}
+
+		attemptCtx, cancel := ctx, context.CancelFunc(func() {})
+		if timeout > 0 {
+			attemptCtx, cancel = context.WithTimeout(ctx, timeout)
+		}
+		defer cancel()
+
+		result, err := fn(attemptCtx)
+		if err != nil && ctx.Err() == nil && errors.Is(attemptCtx.Err(), context.DeadlineExceeded) {
+			err = blobError(ErrTimeout, fmt.Sprintf("%s timed out after %s", op, timeout), err)
+		}
+		done(ctx, err)
+		return result, err
+	}
+
+	if !idempotent {
+		return attempt()
+	}
+
+	// Permanent errors end the retries and are returned as they are
+	retry := r.cfg.Retry
+	retry.RetryIf = isTransientError
+	return utils.RetryWithResult(ctx, retry, attempt)
+}
+
+// resilientExec is resilientCall for operations without a result.
+func resilientExec(r *ResilientBlobClient, ctx context.Context, op string, idempotent bool, timeout time.Duration, fn func(ctx context.Context) error) error {
+	_, err := resilientCall(r, ctx, op, idempotent, timeout, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, fn(ctx)
+	})
+	return err
+}
+
+// open starts a download, retrying transient failures. Timeout bounds the time until fn
+// returns; the returned reader keeps its context until it is closed.
+func (r *ResilientBlobClient) open(ctx context.Context, op string, fn func(ctx context.Context) (io.ReadCloser, error)) (io.ReadCloser, error) {
+	return resilientCall(r, ctx, op, true, 0, func(ctx context.Context) (io.ReadCloser, error) {
+		readCtx, cancel := context.WithCancel(ctx)
+		timer := time.AfterFunc(r.cfg.Timeout, cancel)
+		reader, err := fn(readCtx)
+		if !timer.Stop() && ctx.Err() == nil {
+			if reader != nil {
+				reader.Close()
+			}
+			cancel()
+			return nil, blobError(ErrTimeout, fmt.Sprintf("%s timed out after %s", op, r.cfg.Timeout), err)
+		}
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		return &cancelOnClose{ReadCloser: reader, cancel: cancel}, nil
+	})
+}
+
+// cancelOnClose releases the context of a download when it is closed.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+// Close closes the download and cancels its context.
+func (c *cancelOnClose) Close() error {
+	defer c.cancel()
+	return c.ReadCloser.Close()
+}
+
+// isTransientError reports whether err may succeed when retried: throttling, timeouts,
+// dropped connections and server errors.
+func isTransientError(err error) bool {
+	switch {
+	case err == nil, errors.Is(err, ErrCircuitOpen), errors.Is(err, context.Canceled):
+		return false
+	case errors.Is(err, ErrThrottled), errors.Is(err, ErrTimeout), errors.Is(err, context.DeadlineExceeded):
+		return true
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	var respErr *azcore.ResponseError
+	if errors.As(err, &respErr) {
+		return respErr.StatusCode >= 500
+	}
+	var s3Err *s3Error
+	if errors.As(err, &s3Err) {
+		return s3Err.StatusCode >= 500
+	}
+	return false
+}
+
+// Upload uploads data once, bounded by TransferTimeout.
+func (r *ResilientBlobClient) Upload(ctx context.Context, container, blobName string, data io.Reader, contentType string) (string, error) {
+	return resilientCall(r, ctx, "Upload", false, r.cfg.TransferTimeout, func(ctx context.Context) (string, error) {
+		return r.client.Upload(ctx, container, blobName, data, contentType)
+	})
+}
+
+// UploadWithOptions uploads data once, bounded by TransferTimeout.
+func (r *ResilientBlobClient) UploadWithOptions(ctx context.Context, container, blobName string, data io.Reader, opts UploadOptions) (string, error) {
+	return resilientCall(r, ctx, "UploadWithOptions", false, r.cfg.TransferTimeout, func(ctx context.Context) (string, error) {
+		return r.client.UploadWithOptions(ctx, container, blobName, data, opts)
+	})
+}
+
+// UploadChunked uploads data once, bounded by TransferTimeout. The wrapped client retries
+// individual blocks.
+func (r *ResilientBlobClient) UploadChunked(ctx context.Context, container, blobName string, data io.Reader, opts UploadOptions, transfer TransferOptions) (string, error) {
+	return resilientCall(r, ctx, "UploadChunked", false, r.cfg.TransferTimeout, func(ctx context.Context) (string, error) {
+		return r.client.UploadChunked(ctx, container, blobName, data, opts, transfer)
+	})
+}
+
+// Get opens a blob, retrying until the download starts.
+func (r *ResilientBlobClient) Get(ctx context.Context, container, blobName string) (io.ReadCloser, error) {
+	return r.open(ctx, "Get", func(ctx context.Context) (io.ReadCloser, error) {
+		return r.client.Get(ctx, container, blobName)
+	})
+}
+
+// GetRange opens a byte range of a blob, retrying until the download starts.
+func (r *ResilientBlobClient) GetRange(ctx context.Context, container, blobName string, offset, count int64) (io.ReadCloser, error) {
+	return r.open(ctx, "GetRange", func(ctx context.Context) (io.ReadCloser, error) {
+		return r.client.GetRange(ctx, container, blobName, offset, count)
+	})
+}
+
+// GetRangeWithOptions opens a byte range of a blob with conditions, retrying until the
+// download starts.
+func (r *ResilientBlobClient) GetRangeWithOptions(ctx context.Context, container, blobName string, offset, count int64, opts GetOptions) (io.ReadCloser, error) {
+	return r.open(ctx, "GetRangeWithOptions", func(ctx context.Context) (io.ReadCloser, error) {
+		return r.client.GetRangeWithOptions(ctx, container, blobName, offset, count, opts)
+	})
+}
+
+// DownloadChunked downloads a blob once, bounded by TransferTimeout, since w may already
+// hold part of the content when it fails.
+func (r *ResilientBlobClient) DownloadChunked(ctx context.Context, container, blobName string, w io.Writer, transfer TransferOptions) (int64, error) {
+	return resilientCall(r, ctx, "DownloadChunked", false, r.cfg.TransferTimeout, func(ctx context.Context) (int64, error) {
+		return r.client.DownloadChunked(ctx, container, blobName, w, transfer)
+	})
+}
+
+// Delete deletes a blob once; a retry could report ErrBlobNotFound for a successful delete.
+func (r *ResilientBlobClient) Delete(ctx context.Context, container, blobName string) error {
+	return resilientExec(r, ctx, "Delete", false, r.cfg.Timeout, func(ctx context.Context) error {
+		return r.client.Delete(ctx, container, blobName)
+	})
+}
+
+// DeleteWithOptions deletes a blob once.
+func (r *ResilientBlobClient) DeleteWithOptions(ctx context.Context, container, blobName string, opts DeleteOptions) error {
+	return resilientExec(r, ctx, "DeleteWithOptions", false, r.cfg.Timeout, func(ctx context.Context) error {
+		return r.client.DeleteWithOptions(ctx, container, blobName, opts)
+	})
+}
+
+// Exists checks whether a blob exists, with retries.
+func (r *ResilientBlobClient) Exists(ctx context.Context, container, blobName string) (bool, error) {
+	return resilientCall(r, ctx, "Exists", true, r.cfg.Timeout, func(ctx context.Context) (bool, error) {
+		return r.client.Exists(ctx, container, blobName)
+	})
+}
+
+// List lists blobs, with retries.
+func (r *ResilientBlobClient) List(ctx context.Context, container, prefix string) ([]BlobInfo, error) {
+	return resilientCall(r, ctx, "List", true, r.cfg.Timeout, func(ctx context.Context) ([]BlobInfo, error) {
+		return r.client.List(ctx, container, prefix)
+	})
+}
+
+// ListPage lists a page of blobs, with retries.
+func (r *ResilientBlobClient) ListPage(ctx context.Context, container, prefix, continuationToken string, pageSize int) (*BlobPage, error) {
+	return resilientCall(r, ctx, "ListPage", true, r.cfg.Timeout, func(ctx context.Context) (*BlobPage, error) {
+		return r.client.ListPage(ctx, container, prefix, continuationToken, pageSize)
+	})
+}
+
+// ListPageWithOptions lists a page of blobs, with retries.
+func (r *ResilientBlobClient) ListPageWithOptions(ctx context.Context, container, continuationToken string, opts ListOptions) (*BlobPage, error) {
+	return resilientCall(r, ctx, "ListPageWithOptions", true, r.cfg.Timeout, func(ctx context.Context) (*BlobPage, error) {
+		return r.client.ListPageWithOptions(ctx, container, continuationToken, opts)
+	})
+}
+
+// Copy starts a copy once, bounded by TransferTimeout.
+func (r *ResilientBlobClient) Copy(ctx context.Context, srcContainer, srcBlob, dstContainer, dstBlob string) (*CopyStatus, error) {
+	return resilientCall(r, ctx, "Copy", false, r.cfg.TransferTimeout, func(ctx context.Context) (*CopyStatus, error) {
+		return r.client.Copy(ctx, srcContainer, srcBlob, dstContainer, dstBlob)
+	})
+}
+
+// GetCopyStatus returns the status of a copy, with retries.
+func (r *ResilientBlobClient) GetCopyStatus(ctx context.Context, container, blobName string) (*CopyStatus, error) {
+	return resilientCall(r, ctx, "GetCopyStatus", true, r.cfg.Timeout, func(ctx context.Context) (*CopyStatus, error) {
+		return r.client.GetCopyStatus(ctx, container, blobName)
+	})
+}
+
+// Move moves a blob once, bounded by TransferTimeout.
+func (r *ResilientBlobClient) Move(ctx context.Context, srcContainer, srcBlob, dstContainer, dstBlob string) (string, error) {
+	return resilientCall(r, ctx, "Move", false, r.cfg.TransferTimeout, func(ctx context.Context) (string, error) {
+		return r.client.Move(ctx, srcContainer, srcBlob, dstContainer, dstBlob)
+	})
+}
+
+// Snapshot creates a snapshot once.
+func (r *ResilientBlobClient) Snapshot(ctx context.Context, container, blobName string) (string, error) {
+	return resilientCall(r, ctx, "Snapshot", false, r.cfg.Timeout, func(ctx context.Context) (string, error) {
+		return r.client.Snapshot(ctx, container, blobName)
+	})
+}
+
+// ListSnapshots lists the snapshots of a blob, with retries.
+func (r *ResilientBlobClient) ListSnapshots(ctx context.Context, container, blobName string) ([]BlobInfo, error) {
+	return resilientCall(r, ctx, "ListSnapshots", true, r.cfg.Timeout, func(ctx context.Context) ([]BlobInfo, error) {
+		return r.client.ListSnapshots(ctx, container, blobName)
+	})
+}
+
+// Undelete restores a deleted blob, with retries; undeleting an existing blob is a no-op.
+func (r *ResilientBlobClient) Undelete(ctx context.Context, container, blobName string) error {
+	return resilientExec(r, ctx, "Undelete", true, r.cfg.Timeout, func(ctx context.Context) error {
+		return r.client.Undelete(ctx, container, blobName)
+	})
+}
+
+// AcquireLease acquires a lease once.
+func (r *ResilientBlobClient) AcquireLease(ctx context.Context, container, blobName string, duration time.Duration, proposedLeaseID string) (string, error) {
+	return resilientCall(r, ctx, "AcquireLease", false, r.cfg.Timeout, func(ctx context.Context) (string, error) {
+		return r.client.AcquireLease(ctx, container, blobName, duration, proposedLeaseID)
+	})
+}
+
+// RenewLease renews a lease, with retries.
+func (r *ResilientBlobClient) RenewLease(ctx context.Context, container, blobName, leaseID string) error {
+	return resilientExec(r, ctx, "RenewLease", true, r.cfg.Timeout, func(ctx context.Context) error {
+		return r.client.RenewLease(ctx, container, blobName, leaseID)
+	})
+}
+
+// ReleaseLease releases a lease once.
+func (r *ResilientBlobClient) ReleaseLease(ctx context.Context, container, blobName, leaseID string) error {
+	return resilientExec(r, ctx, "ReleaseLease", false, r.cfg.Timeout, func(ctx context.Context) error {
+		return r.client.ReleaseLease(ctx, container, blobName, leaseID)
+	})
+}
+
+// BreakLease breaks a lease once.
+func (r *ResilientBlobClient) BreakLease(ctx context.Context, container, blobName string, breakPeriod time.Duration) (time.Duration, error) {
+	return resilientCall(r, ctx, "BreakLease", false, r.cfg.Timeout, func(ctx context.Context) (time.Duration, error) {
+		return r.client.BreakLease(ctx, container, blobName, breakPeriod)
+	})
+}
+
+// GetProperties returns the properties of a blob, with retries.
+func (r *ResilientBlobClient) GetProperties(ctx context.Context, container, blobName string) (*BlobProperties, error) {
+	return resilientCall(r, ctx, "GetProperties", true, r.cfg.Timeout, func(ctx context.Context) (*BlobProperties, error) {
+		return r.client.GetProperties(ctx, container, blobName)
+	})
+}
+
+// SetMetadata replaces the metadata of a blob once.
+func (r *ResilientBlobClient) SetMetadata(ctx context.Context, container, blobName string, metadata map[string]string, opts SetMetadataOptions) error {
+	return resilientExec(r, ctx, "SetMetadata", false, r.cfg.Timeout, func(ctx context.Context) error {
+		return r.client.SetMetadata(ctx, container, blobName, metadata, opts)
+	})
+}
+
+// SetAccessTier changes the access tier of a blob, with retries.
+func (r *ResilientBlobClient) SetAccessTier(ctx context.Context, container, blobName, tier string) error {
+	return resilientExec(r, ctx, "SetAccessTier", true, r.cfg.Timeout, func(ctx context.Context) error {
+		return r.client.SetAccessTier(ctx, container, blobName, tier)
+	})
+}
+
+// GenerateReadURL returns a signed read URL, with retries.
+func (r *ResilientBlobClient) GenerateReadURL(ctx context.Context, container, blobName string, opts SASOptions) (string, error) {
+	return resilientCall(r, ctx, "GenerateReadURL", true, r.cfg.Timeout, func(ctx context.Context) (string, error) {
+		return r.client.GenerateReadURL(ctx, container, blobName, opts)
+	})
+}
+
+// GenerateWriteURL returns a signed write URL, with retries.
+func (r *ResilientBlobClient) GenerateWriteURL(ctx context.Context, container, blobName string, opts SASOptions) (string, error) {
+	return resilientCall(r, ctx, "GenerateWriteURL", true, r.cfg.Timeout, func(ctx context.Context) (string, error) {
+		return r.client.GenerateWriteURL(ctx, container, blobName, opts)
+	})
+}
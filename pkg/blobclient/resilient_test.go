@@ -0,0 +1,193 @@
+package blobclient
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yourorg/go-service-kit/pkg/config"
+	apperrors "github.com/yourorg/go-service-kit/pkg/errors"
+	"github.com/yourorg/go-service-kit/pkg/logging"
+	"github.com/yourorg/go-service-kit/pkg/utils"
+)
+
+// newTestResilientClient wraps a fault-injecting mock holding docs/a.txt.
+func newTestResilientClient(t *testing.T, cfg ResilienceConfig, opts ...ResilienceOption) (*ResilientBlobClient, *FaultInjectingBlobClient) {
+	t.Helper()
+	mock := NewMockBlobClient()
+	if _, err := mock.Upload(context.Background(), "docs", "a.txt", strings.NewReader("a"), "text/plain"); err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+	faults := NewFaultInjectingBlobClient(mock)
+
+	if cfg.Retry.MaxAttempts == 0 {
+		cfg.Retry = utils.RetryConfig{MaxAttempts: 3, InitialDelay: time.Millisecond, MaxDelay: time.Millisecond, Multiplier: 1}
+	}
+	logger, _ := logging.NewLogger("error", "json")
+	client, err := NewResilientBlobClient(faults, cfg, logger, opts...)
+	if err != nil {
+		t.Fatalf("NewResilientBlobClient failed: %v", err)
+	}
+	return client, faults
+}
+
+// throttled is a transient error as returned by the backends.
+var throttled = blobError(ErrThrottled, "server busy", nil)
+
+func TestResilientBlobClient_RetriesIdempotentOperations(t *testing.T) {
+	client, faults := newTestResilientClient(t, ResilienceConfig{})
+	ctx := context.Background()
+
+	faults.Inject("Get", Fault{Err: throttled, Times: 2})
+	if got := readBlob(t, client, "docs", "a.txt"); got != "a" {
+		t.Errorf("Expected a, got %q", got)
+	}
+	if calls := faults.Calls("Get"); calls != 3 {
+		t.Errorf("Expected 3 Get calls, got %d", calls)
+	}
+
+	faults.Inject("GetProperties", Fault{Err: throttled})
+	_, err := client.GetProperties(ctx, "docs", "a.txt")
+	expectBlobError(t, "GetProperties", err, ErrThrottled, apperrors.ErrorCodeServiceUnavailable)
+	if calls := faults.Calls("GetProperties"); calls != 3 {
+		t.Errorf("Expected 3 GetProperties calls, got %d", calls)
+	}
+
+	// Permanent errors are returned at once
+	faults.Clear()
+	_, err = client.GetProperties(ctx, "docs", "missing.txt")
+	expectBlobError(t, "GetProperties missing", err, ErrBlobNotFound, apperrors.ErrorCodeNotFound)
+	if calls := faults.Calls("GetProperties"); calls != 4 {
+		t.Errorf("Expected a single call for a missing blob, got %d calls in total", calls)
+	}
+}
+
+func TestResilientBlobClient_DoesNotRetryWrites(t *testing.T) {
+	client, faults := newTestResilientClient(t, ResilienceConfig{})
+	ctx := context.Background()
+
+	faults.Inject("Upload", Fault{Err: throttled, Times: 1})
+	_, err := client.Upload(ctx, "docs", "b.txt", strings.NewReader("b"), "text/plain")
+	expectBlobError(t, "Upload", err, ErrThrottled, apperrors.ErrorCodeServiceUnavailable)
+	if calls := faults.Calls("Upload"); calls != 1 {
+		t.Errorf("Expected 1 Upload call, got %d", calls)
+	}
+
+	faults.Inject("Delete", Fault{Err: throttled, Times: 1})
+	client.Delete(ctx, "docs", "a.txt")
+	if calls := faults.Calls("Delete"); calls != 1 {
+		t.Errorf("Expected 1 Delete call, got %d", calls)
+	}
+}
+
+func TestResilientBlobClient_Timeout(t *testing.T) {
+	client, faults := newTestResilientClient(t, ResilienceConfig{
+		Retry:           utils.RetryConfig{MaxAttempts: 1},
+		Timeout:         10 * time.Millisecond,
+		TransferTimeout: 10 * time.Millisecond,
+	})
+	ctx := context.Background()
+
+	faults.Inject("*", Fault{Latency: time.Second})
+	_, err := client.Exists(ctx, "docs", "a.txt")
+	expectBlobError(t, "Exists", err, ErrTimeout, apperrors.ErrorCodeTimeout)
+	_, err = client.Get(ctx, "docs", "a.txt")
+	expectBlobError(t, "Get", err, ErrTimeout, apperrors.ErrorCodeTimeout)
+	_, err = client.Upload(ctx, "docs", "b.txt", strings.NewReader("b"), "text/plain")
+	expectBlobError(t, "Upload", err, ErrTimeout, apperrors.ErrorCodeTimeout)
+
+	// Cancellation by the caller is not a timeout
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := client.Exists(cancelled, "docs", "a.txt"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+
+	// The deadline only covers opening a download, not reading it
+	faults.Clear()
+	reader, err := client.Get(ctx, "docs", "a.txt")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	buf := make([]byte, 1)
+	if _, err := reader.Read(buf); err != nil || buf[0] != 'a' {
+		t.Errorf("Expected to read a after the timeout, got %q (err %v)", buf, err)
+	}
+	reader.Close()
+}
+
+func TestResilientBlobClient_CircuitBreaker(t *testing.T) {
+	clock := &lifecycleClock{now: time.Now()}
+	client, faults := newTestResilientClient(t, ResilienceConfig{
+		Retry:            utils.RetryConfig{MaxAttempts: 1},
+		FailureThreshold: 3,
+		OpenTimeout:      time.Minute,
+	}, WithResilienceClock(clock.Now))
+	ctx := context.Background()
+
+	faults.Inject("*", Fault{Err: throttled})
+	for i := 0; i < 3; i++ {
+		client.Exists(ctx, "docs", "a.txt")
+	}
+
+	// An open circuit fails fast without calling the backend
+	_, err := client.Exists(ctx, "docs", "a.txt")
+	expectBlobError(t, "Exists while open", err, ErrCircuitOpen, apperrors.ErrorCodeServiceUnavailable)
+	if err := client.Breaker().HealthCheck(ctx); !errors.Is(err, utils.ErrCircuitOpen) {
+		t.Errorf("Expected the health check to report the open circuit, got %v", err)
+	}
+	if calls := faults.Calls("Exists"); calls != 3 {
+		t.Errorf("Expected 3 Exists calls, got %d", calls)
+	}
+
+	// A failed probe opens the circuit again
+	clock.now = clock.now.Add(time.Minute)
+	_, err = client.Exists(ctx, "docs", "a.txt")
+	expectBlobError(t, "Exists probe", err, ErrThrottled, apperrors.ErrorCodeServiceUnavailable)
+	_, err = client.Exists(ctx, "docs", "a.txt")
+	expectBlobError(t, "Exists after failed probe", err, ErrCircuitOpen, apperrors.ErrorCodeServiceUnavailable)
+
+	// A successful probe closes it
+	faults.Clear()
+	clock.now = clock.now.Add(time.Minute)
+	if exists, err := client.Exists(ctx, "docs", "a.txt"); err != nil || !exists {
+		t.Fatalf("Expected the probe to succeed, got %v (err %v)", exists, err)
+	}
+	if _, err := client.GetProperties(ctx, "docs", "missing.txt"); !errors.Is(err, ErrBlobNotFound) {
+		t.Errorf("Expected ErrBlobNotFound with a closed circuit, got %v", err)
+	}
+}
+
+func TestIsTransientError(t *testing.T) {
+	tests := []struct {
+		err       error
+		transient bool
+	}{
+		{throttled, true},
+		{blobError(ErrTimeout, "slow", nil), true},
+		{context.DeadlineExceeded, true},
+		{&s3Error{StatusCode: 503}, true},
+		{&s3Error{StatusCode: 403}, false},
+		{context.Canceled, false},
+		{blobError(ErrBlobNotFound, "missing", nil), false},
+		{blobError(ErrCircuitOpen, "open", nil), false},
+	}
+	for _, tt := range tests {
+		if got := isTransientError(tt.err); got != tt.transient {
+			t.Errorf("isTransientError(%v) = %v, want %v", tt.err, got, tt.transient)
+		}
+	}
+}
+
+func TestResilienceConfigFromConfig(t *testing.T) {
+	cfg := ResilienceConfigFromConfig(&config.Config{RetryMaxAttempts: 4, RetryInitialDelay: 50, RetryMaxDelay: 2000})
+	if cfg.Retry.MaxAttempts != 4 || cfg.Retry.InitialDelay != 50*time.Millisecond || cfg.Retry.MaxDelay != 2*time.Second {
+		t.Errorf("Unexpected retry config %+v", cfg.Retry)
+	}
+	if cfg.Timeout != DefaultResilienceConfig().Timeout {
+		t.Errorf("Expected the default timeout, got %s", cfg.Timeout)
+	}
+}
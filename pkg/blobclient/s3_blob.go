@@ -0,0 +1,1176 @@
+package blobclient
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yourorg/go-service-kit/pkg/logging"
+)
+
+const (
+	// s3MinPartSize is the smallest part S3 accepts in a multipart upload (except the last).
+	s3MinPartSize = 5 << 20
+	// s3MaxParts is the largest number of parts in a multipart upload.
+	s3MaxParts = 10000
+	// s3MetadataPrefix prefixes user metadata headers.
+	s3MetadataPrefix = "X-Amz-Meta-"
+)
+
+// S3Config holds the configuration of an S3-compatible object store.
+type S3Config struct {
+	Endpoint          string // e.g. "http://localhost:9000" for MinIO; empty uses AWS for Region
+	Region            string // default "us-east-1"
+	AccessKeyID       string
+	SecretAccessKey   string
+	SessionToken      string // for temporary credentials
+	UsePathStyle      bool   // address buckets as <endpoint>/<bucket> instead of <bucket>.<host>
+	DefaultAccessTier string // Hot, Cool, Cold, Archive or an S3 storage class
+	HTTPClient        *http.Client
+}
+
+// S3BlobClient implements BlobClient for Amazon S3 and S3-compatible stores such as MinIO.
+// Containers map to buckets and access tiers to storage classes.
+type S3BlobClient struct {
+	endpoint   *url.URL
+	pathStyle  bool
+	signer     *s3Signer
+	httpClient *http.Client
+	logger     logging.Logger
+	now        func() time.Time
+
+	defaultStorageClass string
+	minPartSize         int64
+
+	bucketsMu sync.Mutex
+	buckets   map[string]bool // buckets known to exist
+}
+
+// NewS3BlobClient creates a new S3 client.
+func NewS3BlobClient(cfg S3Config, logger logging.Logger) (*S3BlobClient, error) {
+	if logger == nil {
+		return nil, fmt.Errorf("logger is required")
+	}
+	if cfg.AccessKeyID == "" || cfg.SecretAccessKey == "" {
+		return nil, fmt.Errorf("S3 access key ID and secret access key are required")
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", cfg.Region)
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = http.DefaultClient
+	}
+
+	endpoint, err := url.Parse(strings.TrimSuffix(cfg.Endpoint, "/"))
+	if err != nil || endpoint.Host == "" || (endpoint.Scheme != "http" && endpoint.Scheme != "https") {
+		return nil, fmt.Errorf("invalid S3 endpoint: %s", cfg.Endpoint)
+	}
+
+	var storageClass string
+	if cfg.DefaultAccessTier != "" {
+		if storageClass, err = s3StorageClass(cfg.DefaultAccessTier); err != nil {
+			return nil, err
+		}
+	}
+
+	return &S3BlobClient{
+		endpoint:  endpoint,
+		pathStyle: cfg.UsePathStyle,
+		signer: &s3Signer{
+			accessKeyID:     cfg.AccessKeyID,
+			secretAccessKey: cfg.SecretAccessKey,
+			sessionToken:    cfg.SessionToken,
+			region:          cfg.Region,
+		},
+		httpClient:          cfg.HTTPClient,
+		logger:              logger,
+		now:                 time.Now,
+		defaultStorageClass: storageClass,
+		minPartSize:         s3MinPartSize,
+		buckets:             make(map[string]bool),
+	}, nil
+}
+
+// s3Error is an error response returned by the service.
+type s3Error struct {
+	StatusCode int    `xml:"-"`
+	Code       string `xml:"Code"`
+	Message    string `xml:"Message"`
+}
+
+// Error implements error.
+func (e *s3Error) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("S3 request failed with status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("S3 request failed with status %d: %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// objectURL returns the URL of a key in a bucket; an empty key addresses the bucket.
+func (s *S3BlobClient) objectURL(bucket, key string) *url.URL {
+	u := *s.endpoint
+	path := "/" + key
+	if s.pathStyle {
+		path = "/" + bucket + path
+	} else {
+		u.Host = bucket + "." + u.Host
+	}
+	u.Path = path
+	u.RawPath = s3Escape(path, false)
+	return &u
+}
+
+// do sends a signed request and returns the response if its status is 2xx. Other
+// responses are closed and returned as *s3Error.
+func (s *S3BlobClient) do(ctx context.Context, method, bucket, key string, query url.Values, header http.Header, body []byte) (*http.Response, error) {
+	u := s.objectURL(bucket, key)
+	u.RawQuery = canonicalQuery(query)
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = int64(len(body))
+	if body == nil {
+		req.Body = http.NoBody
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+
+	payloadHash := s3EmptyPayloadHash
+	if len(body) > 0 {
+		payloadHash = sha256Hex(body)
+	}
+	s.signer.signRequest(req, payloadHash, s.now())
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+
+	defer resp.Body.Close()
+	s3Err := &s3Error{StatusCode: resp.StatusCode}
+	if data, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10)); err == nil && len(data) > 0 {
+		_ = xml.Unmarshal(data, s3Err)
+	}
+	return nil, s3Err
+}
+
+// doXML sends a request and decodes the XML response body into out. Some operations
+// report failures in the body of a 200 response, which are returned as *s3Error.
+func (s *S3BlobClient) doXML(ctx context.Context, method, bucket, key string, query url.Values, header http.Header, body []byte, out interface{}) error {
+	resp, err := s.do(ctx, method, bucket, key, query, header, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read S3 response: %w", err)
+	}
+	if bytes.Contains(data, []byte("<Error>")) {
+		s3Err := &s3Error{StatusCode: resp.StatusCode}
+		if xml.Unmarshal(data, s3Err) == nil && s3Err.Code != "" {
+			return s3Err
+		}
+	}
+	if err := xml.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to decode S3 response: %w", err)
+	}
+	return nil
+}
+
+// ensureBucket creates a bucket the first time it is written to by this client.
+// Failures are ignored, as the bucket usually exists already or creation is not permitted.
+func (s *S3BlobClient) ensureBucket(ctx context.Context, bucket string, logger logging.Logger) {
+	s.bucketsMu.Lock()
+	known := s.buckets[bucket]
+	s.bucketsMu.Unlock()
+	if known {
+		return
+	}
+
+	var body []byte
+	if s.signer.region != "us-east-1" {
+		body = []byte(`<CreateBucketConfiguration xmlns="http://s3.amazonaws.com/doc/2006-03-01/"><LocationConstraint>` +
+			s.signer.region + `</LocationConstraint></CreateBucketConfiguration>`)
+	}
+	resp, err := s.do(ctx, http.MethodPut, bucket, "", nil, nil, body)
+	if err != nil {
+		// Bucket might already exist, which is fine
+		logger.Debug("Bucket create result (may already exist)", logging.NewField("error", err.Error()))
+	} else {
+		resp.Body.Close()
+	}
+
+	s.bucketsMu.Lock()
+	s.buckets[bucket] = true
+	s.bucketsMu.Unlock()
+}
+
+// Upload uploads data to S3 with the given content type.
+func (s *S3BlobClient) Upload(ctx context.Context, container, blobName string, data io.Reader, contentType string) (string, error) {
+	return s.UploadWithOptions(ctx, container, blobName, data, UploadOptions{ContentType: contentType})
+}
+
+// UploadWithOptions uploads data to S3 with HTTP headers, storage class, metadata and tags.
+// Data larger than DefaultBlockSize is sent as a multipart upload.
+func (s *S3BlobClient) UploadWithOptions(ctx context.Context, container, blobName string, data io.Reader, opts UploadOptions) (string, error) {
+	logger := s.logger.With(
+		logging.NewField("operation", "blob.upload"),
+		logging.NewField("container", container),
+		logging.NewField("blob", blobName),
+	)
+
+	logger.Info("Starting blob upload")
+
+	header, err := s.uploadHeaders(opts)
+	if err != nil {
+		return "", err
+	}
+
+	// Read one byte past the single-request limit to decide between PUT and multipart
+	head, err := io.ReadAll(io.LimitReader(data, DefaultBlockSize+1))
+	if err != nil {
+		return "", fmt.Errorf("failed to read data: %w", err)
+	}
+
+	s.ensureBucket(ctx, container, logger)
+
+	if len(head) > DefaultBlockSize {
+		blobURL, err := uploadChunked(ctx, s, container, blobName, io.MultiReader(bytes.NewReader(head), data), opts,
+			TransferOptions{BlockSize: DefaultBlockSize})
+		if err != nil {
+			logger.Error("Failed to upload blob", logging.NewField("error", err))
+			return "", fmt.Errorf("failed to upload blob: %w", err)
+		}
+		logger.Info("Blob upload successful", logging.NewField("url", blobURL))
+		return blobURL, nil
+	}
+
+	contentMD5 := md5.Sum(head)
+	header.Set("Content-MD5", base64.StdEncoding.EncodeToString(contentMD5[:]))
+	for name, values := range conditionHeaders(opts.IfMatch, opts.IfNoneMatch) {
+		header[name] = values
+	}
+	resp, err := s.do(ctx, http.MethodPut, container, blobName, nil, header, head)
+	if err != nil {
+		logger.Error("Failed to upload blob", logging.NewField("error", err))
+		return "", s3BlobError(err, "failed to upload blob", container, blobName)
+	}
+	resp.Body.Close()
+
+	blobURL := s.objectURL(container, blobName).String()
+	logger.Info("Blob upload successful", logging.NewField("url", blobURL))
+
+	return blobURL, nil
+}
+
+// uploadHeaders converts UploadOptions into S3 request headers.
+func (s *S3BlobClient) uploadHeaders(opts UploadOptions) (http.Header, error) {
+	header := http.Header{}
+	if opts.ContentType != "" {
+		header.Set("Content-Type", opts.ContentType)
+	}
+	if opts.CacheControl != "" {
+		header.Set("Cache-Control", opts.CacheControl)
+	}
+	if opts.ContentDisposition != "" {
+		header.Set("Content-Disposition", opts.ContentDisposition)
+	}
+	for key, value := range opts.Metadata {
+		header.Set(s3MetadataPrefix+key, value)
+	}
+	if len(opts.Tags) > 0 {
+		tags := url.Values{}
+		for key, value := range opts.Tags {
+			tags.Set(key, value)
+		}
+		header.Set("X-Amz-Tagging", tags.Encode())
+	}
+
+	storageClass := s.defaultStorageClass
+	if opts.AccessTier != "" {
+		var err error
+		if storageClass, err = s3StorageClass(opts.AccessTier); err != nil {
+			return nil, err
+		}
+	}
+	if storageClass != "" {
+		header.Set("X-Amz-Storage-Class", storageClass)
+	}
+
+	return header, nil
+}
+
+// UploadChunked uploads data as a multipart upload with parallel parts. Every call starts
+// its own multipart upload, so concurrent uploads of a key do not interfere. A failed
+// upload returns an IncompleteUploadError; retrying with its ResumeID and the same data
+// and options does not re-send the parts already uploaded. If the options changed, the
+// old upload is aborted and a new one started. Uploads that are never resumed should be
+// removed by an AbortIncompleteMultipartUpload lifecycle rule on the bucket.
+//
+// Block sizes below the S3 minimum part size (5 MiB) are raised to it. The whole-blob
+// MD5 and CRC64 are not stored, as S3 cannot set them when completing a multipart upload,
+// so downloads can only verify parts as they are uploaded. Empty data is uploaded with a
+// single PUT, as S3 cannot complete a multipart upload without parts.
+func (s *S3BlobClient) UploadChunked(ctx context.Context, container, blobName string, data io.Reader, opts UploadOptions, transfer TransferOptions) (string, error) {
+	logger := s.logger.With(
+		logging.NewField("operation", "blob.upload_chunked"),
+		logging.NewField("container", container),
+		logging.NewField("blob", blobName),
+	)
+
+	logger.Info("Starting chunked blob upload")
+
+	// Validate options before uploading anything
+	if _, err := s.uploadHeaders(opts); err != nil {
+		return "", err
+	}
+	if transfer.BlockSize < s.minPartSize {
+		transfer.BlockSize = s.minPartSize
+	}
+
+	buffered := bufio.NewReader(data)
+	if _, err := buffered.Peek(1); err == io.EOF {
+		return s.UploadWithOptions(ctx, container, blobName, buffered, opts)
+	}
+
+	s.ensureBucket(ctx, container, logger)
+
+	blobURL, err := uploadChunked(ctx, s, container, blobName, buffered, opts, transfer)
+	if err != nil {
+		logger.Error("Failed to upload blob", logging.NewField("error", err))
+		return "", fmt.Errorf("failed to upload blob: %w", err)
+	}
+
+	logger.Info("Chunked blob upload successful", logging.NewField("url", blobURL))
+	return blobURL, nil
+}
+
+// s3Parts is the ListParts response.
+type s3Parts struct {
+	Parts []struct {
+		PartNumber int    `xml:"PartNumber"`
+		ETag       string `xml:"ETag"`
+		Size       int64  `xml:"Size"`
+	} `xml:"Part"`
+	IsTruncated          bool   `xml:"IsTruncated"`
+	NextPartNumberMarker string `xml:"NextPartNumberMarker"`
+}
+
+// s3UploadFingerprint identifies the key and headers a multipart upload was created with.
+// It prefixes the upload ID in resume IDs, as S3 cannot report the headers of an upload.
+func s3UploadFingerprint(container, blobName string, header http.Header) string {
+	keys := make([]string, 0, len(header))
+	for key := range header {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s\n%s\n", container, blobName)
+	for _, key := range keys {
+		fmt.Fprintf(hash, "%s:%s\n", key, strings.Join(header[key], ","))
+	}
+	return hex.EncodeToString(hash.Sum(nil)[:16])
+}
+
+// prepareBlocks resumes the multipart upload named by resumeID, returning its parts as
+// blocks, if it was created for the same key and headers. Otherwise it aborts that upload
+// and starts a new one with opts applied. The returned upload is the resume ID.
+func (s *S3BlobClient) prepareBlocks(ctx context.Context, container, blobName string, opts UploadOptions, resumeID string) (string, map[string]int64, error) {
+	header, err := s.uploadHeaders(opts)
+	if err != nil {
+		return "", nil, err
+	}
+	fingerprint := s3UploadFingerprint(container, blobName, header)
+
+	if resumeFingerprint, uploadID, ok := strings.Cut(resumeID, ":"); ok {
+		if resumeFingerprint == fingerprint {
+			staged, err := s.listParts(ctx, container, blobName, uploadID)
+			var s3Err *s3Error
+			if err == nil {
+				return resumeID, staged, nil
+			}
+			if !errors.As(err, &s3Err) || s3Err.Code != "NoSuchUpload" {
+				return "", nil, err
+			}
+		} else {
+			s.abortUpload(ctx, container, blobName, uploadID)
+		}
+	}
+
+	var created struct {
+		UploadID string `xml:"UploadId"`
+	}
+	if err := s.doXML(ctx, http.MethodPost, container, blobName, url.Values{"uploads": {""}}, header, nil, &created); err != nil {
+		return "", nil, fmt.Errorf("failed to create multipart upload: %w", err)
+	}
+	return fingerprint + ":" + created.UploadID, map[string]int64{}, nil
+}
+
+// listParts returns the parts of a multipart upload as blocks.
+func (s *S3BlobClient) listParts(ctx context.Context, container, blobName, uploadID string) (map[string]int64, error) {
+	staged := make(map[string]int64)
+	query := url.Values{"uploadId": {uploadID}}
+	for {
+		var parts s3Parts
+		if err := s.doXML(ctx, http.MethodGet, container, blobName, query, nil, nil, &parts); err != nil {
+			return nil, fmt.Errorf("failed to list parts: %w", err)
+		}
+		for _, part := range parts.Parts {
+			if etagMD5, ok := s3ETagMD5(part.ETag); ok {
+				staged[blockID(part.PartNumber-1, etagMD5)] = part.Size
+			}
+		}
+		if !parts.IsTruncated {
+			return staged, nil
+		}
+		query.Set("part-number-marker", parts.NextPartNumberMarker)
+	}
+}
+
+// abortUpload discards a multipart upload that can no longer be resumed. Failures are
+// only logged; the bucket lifecycle removes uploads left behind.
+func (s *S3BlobClient) abortUpload(ctx context.Context, container, blobName, uploadID string) {
+	resp, err := s.do(ctx, http.MethodDelete, container, blobName, url.Values{"uploadId": {uploadID}}, nil, nil)
+	if err != nil {
+		s.logger.Warn("Failed to abort multipart upload",
+			logging.NewField("container", container),
+			logging.NewField("blob", blobName),
+			logging.NewField("error", err))
+		return
+	}
+	resp.Body.Close()
+}
+
+// s3UploadID returns the multipart upload ID of a resume ID returned by prepareBlocks.
+func s3UploadID(upload string) (string, error) {
+	_, uploadID, ok := strings.Cut(upload, ":")
+	if !ok || uploadID == "" {
+		return "", fmt.Errorf("invalid multipart upload: %q", upload)
+	}
+	return uploadID, nil
+}
+
+// stageBlock uploads a block as the part numbered by its index. Parts are always sent
+// with Content-MD5, which S3 verifies; S3 has no equivalent of the Azure CRC64.
+func (s *S3BlobClient) stageBlock(ctx context.Context, container, blobName, upload, leaseID, blockID string, data []byte, algorithm ChecksumAlgorithm, sum []byte) error {
+	uploadID, err := s3UploadID(upload)
+	if err != nil {
+		return err
+	}
+	partNumber, _, err := parseBlockID(blockID)
+	if err != nil {
+		return err
+	}
+	if partNumber > s3MaxParts {
+		return fmt.Errorf("upload exceeds %d parts; use a larger block size", s3MaxParts)
+	}
+
+	partMD5 := md5.Sum(data)
+	if algorithm == ChecksumMD5 && !bytes.Equal(sum, partMD5[:]) {
+		return ErrChecksumMismatch
+	}
+	header := http.Header{}
+	header.Set("Content-MD5", base64.StdEncoding.EncodeToString(partMD5[:]))
+
+	query := url.Values{"partNumber": {strconv.Itoa(partNumber)}, "uploadId": {uploadID}}
+	resp, err := s.do(ctx, http.MethodPut, container, blobName, query, header, data)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// commitBlocks completes the multipart upload with the parts of blockIDs. The options
+// were applied when the upload was created; contentMD5 cannot be stored by S3.
+func (s *S3BlobClient) commitBlocks(ctx context.Context, container, blobName, upload string, blockIDs []string, opts UploadOptions, contentMD5 []byte) (string, error) {
+	uploadID, err := s3UploadID(upload)
+	if err != nil {
+		return "", err
+	}
+
+	type completedPart struct {
+		PartNumber int    `xml:"PartNumber"`
+		ETag       string `xml:"ETag"`
+	}
+	complete := struct {
+		XMLName xml.Name        `xml:"CompleteMultipartUpload"`
+		Parts   []completedPart `xml:"Part"`
+	}{}
+	for _, id := range blockIDs {
+		partNumber, partMD5, err := parseBlockID(id)
+		if err != nil {
+			return "", err
+		}
+		complete.Parts = append(complete.Parts, completedPart{PartNumber: partNumber, ETag: `"` + hex.EncodeToString(partMD5) + `"`})
+	}
+
+	body, err := xml.Marshal(complete)
+	if err != nil {
+		return "", err
+	}
+
+	var result struct {
+		Location string `xml:"Location"`
+	}
+	header := conditionHeaders(opts.IfMatch, opts.IfNoneMatch)
+	if err := s.doXML(ctx, http.MethodPost, container, blobName, url.Values{"uploadId": {uploadID}}, header, body, &result); err != nil {
+		return "", s3BlobError(err, "failed to complete multipart upload", container, blobName)
+	}
+
+	return s.objectURL(container, blobName).String(), nil
+}
+
+// parseBlockID returns the 1-based part number and block MD5 encoded in a block ID.
+func parseBlockID(id string) (int, []byte, error) {
+	decoded, err := base64.StdEncoding.DecodeString(id)
+	if err != nil {
+		return 0, nil, fmt.Errorf("invalid block ID %q: %w", id, err)
+	}
+	indexText, md5Text, ok := strings.Cut(string(decoded), "-")
+	index, indexErr := strconv.Atoi(indexText)
+	blockMD5, md5Err := hex.DecodeString(md5Text)
+	if !ok || indexErr != nil || md5Err != nil {
+		return 0, nil, fmt.Errorf("invalid block ID %q", id)
+	}
+	return index + 1, blockMD5, nil
+}
+
+// s3ETagMD5 returns the MD5 in a quoted ETag, if the ETag is a plain MD5.
+func s3ETagMD5(etag string) ([]byte, bool) {
+	sum, err := hex.DecodeString(strings.Trim(etag, `"`))
+	if err != nil || len(sum) != md5.Size {
+		return nil, false
+	}
+	return sum, true
+}
+
+// Get retrieves an object from S3.
+func (s *S3BlobClient) Get(ctx context.Context, container, blobName string) (io.ReadCloser, error) {
+	logger := s.logger.With(
+		logging.NewField("operation", "blob.get"),
+		logging.NewField("container", container),
+		logging.NewField("blob", blobName),
+	)
+
+	logger.Info("Retrieving blob")
+
+	resp, err := s.do(ctx, http.MethodGet, container, blobName, nil, nil, nil)
+	if err != nil {
+		logger.Error("Failed to download blob", logging.NewField("error", err))
+		return nil, s3BlobError(err, "failed to download blob", container, blobName)
+	}
+
+	logger.Info("Blob retrieved successfully")
+	return resp.Body, nil
+}
+
+// GetRange retrieves count bytes of an object starting at offset. A count of 0 reads to the end.
+func (s *S3BlobClient) GetRange(ctx context.Context, container, blobName string, offset, count int64) (io.ReadCloser, error) {
+	return s.GetRangeWithOptions(ctx, container, blobName, offset, count, GetOptions{})
+}
+
+// GetRangeWithOptions retrieves a byte range of an object if it matches opts.IfMatch.
+func (s *S3BlobClient) GetRangeWithOptions(ctx context.Context, container, blobName string, offset, count int64, opts GetOptions) (io.ReadCloser, error) {
+	resp, err := s.getRange(ctx, container, blobName, offset, count, opts.IfMatch)
+	if err != nil {
+		s.logger.Error("Failed to download blob range",
+			logging.NewField("container", container),
+			logging.NewField("blob", blobName),
+			logging.NewField("offset", offset),
+			logging.NewField("error", err),
+		)
+		return nil, s3BlobError(err, "failed to download blob range", container, blobName)
+	}
+	return resp.Body, nil
+}
+
+// getRange sends a ranged GET, conditional on ifMatch if set.
+func (s *S3BlobClient) getRange(ctx context.Context, container, blobName string, offset, count int64, ifMatch string) (*http.Response, error) {
+	rangeHeader := fmt.Sprintf("bytes=%d-", offset)
+	if count > 0 {
+		rangeHeader += strconv.FormatInt(offset+count-1, 10)
+	}
+	header := conditionHeaders(ifMatch, "")
+	header.Set("Range", rangeHeader)
+	return s.do(ctx, http.MethodGet, container, blobName, nil, header, nil)
+}
+
+// DownloadChunked writes an object to w using parallel ranged reads. S3 returns no range
+// checksums, so ChecksumMD5 only verifies the whole object of single-request uploads.
+func (s *S3BlobClient) DownloadChunked(ctx context.Context, container, blobName string, w io.Writer, transfer TransferOptions) (int64, error) {
+	logger := s.logger.With(
+		logging.NewField("operation", "blob.download_chunked"),
+		logging.NewField("container", container),
+		logging.NewField("blob", blobName),
+	)
+
+	logger.Info("Starting chunked blob download")
+
+	written, err := downloadChunked(ctx, s, container, blobName, w, transfer)
+	if err != nil {
+		logger.Error("Failed to download blob", logging.NewField("error", err))
+		return written, err
+	}
+
+	logger.Info("Chunked blob download successful", logging.NewField("bytes", written))
+	return written, nil
+}
+
+// readRange reads a byte range. S3 has no range MD5, so none is returned.
+func (s *S3BlobClient) readRange(ctx context.Context, container, blobName string, offset, count int64, ifMatch string, rangeMD5 bool) ([]byte, []byte, error) {
+	resp, err := s.getRange(ctx, container, blobName, offset, count, ifMatch)
+	if err != nil {
+		return nil, nil, s3BlobError(err, "failed to download blob range", container, blobName)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, nil, nil
+}
+
+// GetProperties returns the headers, access tier, metadata and tags of an object.
+// ContentMD5 is derived from the ETag, which is only an MD5 for single-request uploads
+// without KMS or customer-provided key encryption.
+func (s *S3BlobClient) GetProperties(ctx context.Context, container, blobName string) (*BlobProperties, error) {
+	logger := s.logger.With(
+		logging.NewField("operation", "blob.get_properties"),
+		logging.NewField("container", container),
+		logging.NewField("blob", blobName),
+	)
+
+	resp, err := s.do(ctx, http.MethodHead, container, blobName, nil, nil, nil)
+	if err != nil {
+		logger.Error("Failed to get blob properties", logging.NewField("error", err))
+		return nil, s3BlobError(err, "failed to get blob properties", container, blobName)
+	}
+	resp.Body.Close()
+
+	props := &BlobProperties{
+		Name:               blobName,
+		Size:               resp.ContentLength,
+		ContentType:        resp.Header.Get("Content-Type"),
+		CacheControl:       resp.Header.Get("Cache-Control"),
+		ContentDisposition: resp.Header.Get("Content-Disposition"),
+		AccessTier:         s3AccessTier(resp.Header.Get("X-Amz-Storage-Class")),
+		Metadata:           make(map[string]string),
+		Tags:               make(map[string]string),
+		ETag:               resp.Header.Get("ETag"),
+		URL:                s.objectURL(container, blobName).String(),
+	}
+	if lastModified, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		props.LastModified = lastModified.UTC().Format(time.RFC3339)
+	}
+	if resp.Header.Get("X-Amz-Server-Side-Encryption") != "aws:kms" && resp.Header.Get("X-Amz-Server-Side-Encryption-Customer-Algorithm") == "" {
+		props.ContentMD5, _ = s3ETagMD5(resp.Header.Get("ETag"))
+	}
+	for name, values := range resp.Header {
+		if strings.HasPrefix(name, s3MetadataPrefix) && len(values) > 0 {
+			props.Metadata[strings.ToLower(strings.TrimPrefix(name, s3MetadataPrefix))] = values[0]
+		}
+	}
+
+	// Tags are not part of the HEAD response; only fetch them when there are any
+	if count, _ := strconv.Atoi(resp.Header.Get("X-Amz-Tagging-Count")); count > 0 {
+		var tagging struct {
+			Tags []struct {
+				Key   string `xml:"Key"`
+				Value string `xml:"Value"`
+			} `xml:"TagSet>Tag"`
+		}
+		if err := s.doXML(ctx, http.MethodGet, container, blobName, url.Values{"tagging": {""}}, nil, nil, &tagging); err != nil {
+			logger.Error("Failed to get blob tags", logging.NewField("error", err))
+			return nil, s3BlobError(err, "failed to get blob tags", container, blobName)
+		}
+		for _, tag := range tagging.Tags {
+			props.Tags[tag.Key] = tag.Value
+		}
+	}
+
+	return props, nil
+}
+
+// SetMetadata replaces the metadata of an object by copying it onto itself, keeping its
+// headers, storage class and tags. S3 has no leases, so opts.LeaseID is ignored.
+func (s *S3BlobClient) SetMetadata(ctx context.Context, container, blobName string, metadata map[string]string, opts SetMetadataOptions) error {
+	resp, err := s.do(ctx, http.MethodHead, container, blobName, nil, nil, nil)
+	if err != nil {
+		return s3BlobError(err, "failed to set blob metadata", container, blobName)
+	}
+	resp.Body.Close()
+
+	header := http.Header{}
+	for _, name := range []string{"Content-Type", "Cache-Control", "Content-Disposition", "X-Amz-Storage-Class"} {
+		if value := resp.Header.Get(name); value != "" {
+			header.Set(name, value)
+		}
+	}
+	for key, value := range metadata {
+		header.Set(s3MetadataPrefix+key, value)
+	}
+	header.Set("X-Amz-Copy-Source", s3Escape(container+"/"+blobName, false))
+	header.Set("X-Amz-Metadata-Directive", "REPLACE")
+	if opts.IfMatch != "" {
+		header.Set("X-Amz-Copy-Source-If-Match", opts.IfMatch)
+	}
+
+	var result s3CopyResult
+	if err := s.doXML(ctx, http.MethodPut, container, blobName, nil, header, nil, &result); err != nil {
+		s.logger.Error("Failed to set blob metadata",
+			logging.NewField("container", container),
+			logging.NewField("blob", blobName),
+			logging.NewField("error", err),
+		)
+		return s3BlobError(err, "failed to set blob metadata", container, blobName)
+	}
+	return nil
+}
+
+// SetAccessTier changes the storage class of an object by copying it onto itself. Objects
+// in GLACIER must be restored before they can be moved to another class.
+func (s *S3BlobClient) SetAccessTier(ctx context.Context, container, blobName, tier string) error {
+	storageClass, err := s3StorageClass(tier)
+	if err != nil {
+		return err
+	}
+
+	header := http.Header{}
+	header.Set("X-Amz-Copy-Source", s3Escape(container+"/"+blobName, false))
+	header.Set("X-Amz-Storage-Class", storageClass)
+	var result s3CopyResult
+	if err := s.doXML(ctx, http.MethodPut, container, blobName, nil, header, nil, &result); err != nil {
+		s.logger.Error("Failed to set blob access tier",
+			logging.NewField("container", container),
+			logging.NewField("blob", blobName),
+			logging.NewField("tier", tier),
+			logging.NewField("error", err),
+		)
+		return s3BlobError(err, "failed to set access tier", container, blobName)
+	}
+	return nil
+}
+
+// GenerateReadURL returns a presigned GET URL. S3 presigned URLs cannot be restricted
+// to an IP range or carry permissions other than the default.
+func (s *S3BlobClient) GenerateReadURL(ctx context.Context, container, blobName string, opts SASOptions) (string, error) {
+	opts, err := s.resolvePresign(opts, readPermissions)
+	if err != nil {
+		return "", err
+	}
+
+	u := s.objectURL(container, blobName)
+	query := url.Values{}
+	if opts.ContentDisposition != "" {
+		query.Set("response-content-disposition", opts.ContentDisposition)
+	}
+	if opts.ContentType != "" {
+		query.Set("response-content-type", opts.ContentType)
+	}
+	u.RawQuery = query.Encode()
+
+	return s.signer.presign(http.MethodGet, u, opts.ExpiresIn, s.now()), nil
+}
+
+// GenerateWriteURL returns a presigned PUT URL. ContentDisposition and ContentType do
+// not apply to uploads and are ignored.
+func (s *S3BlobClient) GenerateWriteURL(ctx context.Context, container, blobName string, opts SASOptions) (string, error) {
+	opts, err := s.resolvePresign(opts, writePermissions)
+	if err != nil {
+		return "", err
+	}
+	return s.signer.presign(http.MethodPut, s.objectURL(container, blobName), opts.ExpiresIn, s.now()), nil
+}
+
+// resolvePresign validates SAS options for a presigned URL.
+func (s *S3BlobClient) resolvePresign(opts SASOptions, defaults SASPermissions) (SASOptions, error) {
+	if opts.IPRange != "" {
+		return opts, fmt.Errorf("S3 presigned URLs do not support IP ranges")
+	}
+	if !opts.Permissions.isZero() && opts.Permissions != defaults {
+		return opts, fmt.Errorf("S3 presigned URLs do not support permissions %q", opts.Permissions.String())
+	}
+	return opts.resolve(defaults)
+}
+
+// Delete deletes an object. S3 deletes succeed for missing keys, so existence is
+// checked first to report missing blobs like the other backends.
+func (s *S3BlobClient) Delete(ctx context.Context, container, blobName string) error {
+	return s.DeleteWithOptions(ctx, container, blobName, DeleteOptions{})
+}
+
+// DeleteWithOptions deletes an object if its ETag matches opts.IfMatch. S3 has no leases,
+// so opts.LeaseID is ignored.
+func (s *S3BlobClient) DeleteWithOptions(ctx context.Context, container, blobName string, opts DeleteOptions) error {
+	logger := s.logger.With(
+		logging.NewField("operation", "blob.delete"),
+		logging.NewField("container", container),
+		logging.NewField("blob", blobName),
+	)
+
+	logger.Info("Deleting blob")
+
+	exists, err := s.Exists(ctx, container, blobName)
+	if err == nil && !exists {
+		err = blobNotFound(container, blobName, nil)
+	}
+	if err == nil {
+		var resp *http.Response
+		if resp, err = s.do(ctx, http.MethodDelete, container, blobName, nil, conditionHeaders(opts.IfMatch, ""), nil); err == nil {
+			resp.Body.Close()
+		}
+	}
+	if err != nil {
+		logger.Error("Failed to delete blob", logging.NewField("error", err))
+		return s3BlobError(err, "failed to delete blob", container, blobName)
+	}
+
+	logger.Info("Blob deleted successfully")
+	return nil
+}
+
+// Exists checks if an object exists.
+func (s *S3BlobClient) Exists(ctx context.Context, container, blobName string) (bool, error) {
+	resp, err := s.do(ctx, http.MethodHead, container, blobName, nil, nil, nil)
+	if err != nil {
+		err = s3BlobError(err, "failed to check blob existence", container, blobName)
+		if errors.Is(err, ErrBlobNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	resp.Body.Close()
+	return true, nil
+}
+
+// s3CopyResult is the CopyObject response.
+type s3CopyResult struct {
+	ETag string `xml:"ETag"`
+}
+
+// Copy copies an object with CopyObject, keeping its metadata and tags. S3 copies are
+// synchronous and limited to objects of up to 5 GiB.
+func (s *S3BlobClient) Copy(ctx context.Context, srcContainer, srcBlob, dstContainer, dstBlob string) (*CopyStatus, error) {
+	logger := s.logger.With(
+		logging.NewField("operation", "blob.copy"),
+		logging.NewField("container", srcContainer),
+		logging.NewField("blob", srcBlob),
+		logging.NewField("dst_container", dstContainer),
+		logging.NewField("dst_blob", dstBlob),
+	)
+
+	logger.Info("Starting blob copy")
+	s.ensureBucket(ctx, dstContainer, logger)
+
+	header := http.Header{}
+	header.Set("X-Amz-Copy-Source", s3Escape(srcContainer+"/"+srcBlob, false))
+	if s.defaultStorageClass != "" {
+		header.Set("X-Amz-Storage-Class", s.defaultStorageClass)
+	}
+
+	var result s3CopyResult
+	if err := s.doXML(ctx, http.MethodPut, dstContainer, dstBlob, nil, header, nil, &result); err != nil {
+		logger.Error("Failed to copy blob", logging.NewField("error", err))
+		return nil, s3BlobError(err, "failed to copy blob", srcContainer, srcBlob)
+	}
+
+	logger.Info("Blob copy successful")
+	return s.GetCopyStatus(ctx, dstContainer, dstBlob)
+}
+
+// GetCopyStatus reports a completed copy for any existing object, since copies are synchronous.
+func (s *S3BlobClient) GetCopyStatus(ctx context.Context, container, blobName string) (*CopyStatus, error) {
+	resp, err := s.do(ctx, http.MethodHead, container, blobName, nil, nil, nil)
+	if err != nil {
+		return nil, s3BlobError(err, "failed to get copy status", container, blobName)
+	}
+	resp.Body.Close()
+
+	return &CopyStatus{
+		ID:          strings.Trim(resp.Header.Get("ETag"), `"`),
+		Status:      CopyStatusSuccess,
+		BytesCopied: resp.ContentLength,
+		TotalBytes:  resp.ContentLength,
+	}, nil
+}
+
+// Move copies an object and deletes the source.
+func (s *S3BlobClient) Move(ctx context.Context, srcContainer, srcBlob, dstContainer, dstBlob string) (string, error) {
+	if err := moveBlob(ctx, s, DefaultCopyPollInterval, srcContainer, srcBlob, dstContainer, dstBlob); err != nil {
+		return "", err
+	}
+	return s.objectURL(dstContainer, dstBlob).String(), nil
+}
+
+// Snapshot is not supported: S3 keeps history through bucket versioning instead.
+func (s *S3BlobClient) Snapshot(ctx context.Context, container, blobName string) (string, error) {
+	return "", fmt.Errorf("failed to create snapshot of %s/%s: %w", container, blobName, ErrNotSupported)
+}
+
+// ListSnapshots is not supported: S3 keeps history through bucket versioning instead.
+func (s *S3BlobClient) ListSnapshots(ctx context.Context, container, blobName string) ([]BlobInfo, error) {
+	return nil, fmt.Errorf("failed to list snapshots of %s/%s: %w", container, blobName, ErrNotSupported)
+}
+
+// Undelete is not supported: S3 keeps history through bucket versioning instead.
+func (s *S3BlobClient) Undelete(ctx context.Context, container, blobName string) error {
+	return fmt.Errorf("failed to undelete blob %s/%s: %w", container, blobName, ErrNotSupported)
+}
+
+// AcquireLease is not supported: S3 has no leases. Use conditional writes with IfMatch instead.
+func (s *S3BlobClient) AcquireLease(ctx context.Context, container, blobName string, duration time.Duration, proposedLeaseID string) (string, error) {
+	return "", fmt.Errorf("failed to acquire lease on %s/%s: %w", container, blobName, ErrNotSupported)
+}
+
+// RenewLease is not supported: S3 has no leases.
+func (s *S3BlobClient) RenewLease(ctx context.Context, container, blobName, leaseID string) error {
+	return fmt.Errorf("failed to renew lease on %s/%s: %w", container, blobName, ErrNotSupported)
+}
+
+// ReleaseLease is not supported: S3 has no leases.
+func (s *S3BlobClient) ReleaseLease(ctx context.Context, container, blobName, leaseID string) error {
+	return fmt.Errorf("failed to release lease on %s/%s: %w", container, blobName, ErrNotSupported)
+}
+
+// BreakLease is not supported: S3 has no leases.
+func (s *S3BlobClient) BreakLease(ctx context.Context, container, blobName string, breakPeriod time.Duration) (time.Duration, error) {
+	return 0, fmt.Errorf("failed to break lease on %s/%s: %w", container, blobName, ErrNotSupported)
+}
+
+// conditionHeaders returns the If-Match and If-None-Match headers of a conditional write.
+func conditionHeaders(ifMatch, ifNoneMatch string) http.Header {
+	header := http.Header{}
+	if ifMatch != "" {
+		header.Set("If-Match", ifMatch)
+	}
+	if ifNoneMatch != "" {
+		header.Set("If-None-Match", ifNoneMatch)
+	}
+	return header
+}
+
+// s3BlobError maps a service error to the typed BlobClient errors and wraps other errors
+// with message. HEAD responses have no body, so their 404s are reported as ErrBlobNotFound.
+func s3BlobError(err error, message, container, blobName string) error {
+	var s3Err *s3Error
+	if !errors.As(err, &s3Err) {
+		return fmt.Errorf("%s: %w", message, err)
+	}
+
+	switch s3Err.Code {
+	case "NoSuchBucket":
+		return containerNotFound(container, err)
+	case "NoSuchKey":
+		return blobNotFound(container, blobName, err)
+	case "SlowDown":
+		return blobError(ErrThrottled, message+": "+s3Err.Code, err)
+	}
+	if sentinel := classifyStatus(s3Err.StatusCode); sentinel == ErrBlobNotFound {
+		return blobNotFound(container, blobName, err)
+	} else if sentinel != nil {
+		return blobError(sentinel, message+": "+s3Err.Code, err)
+	}
+	return fmt.Errorf("%s: %w", message, err)
+}
+
+// s3ListResult is the ListObjectsV2 response.
+type s3ListResult struct {
+	Contents []struct {
+		Key          string `xml:"Key"`
+		Size         int64  `xml:"Size"`
+		LastModified string `xml:"LastModified"`
+		ETag         string `xml:"ETag"`
+		StorageClass string `xml:"StorageClass"`
+	} `xml:"Contents"`
+	CommonPrefixes []struct {
+		Prefix string `xml:"Prefix"`
+	} `xml:"CommonPrefixes"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// List lists objects in a bucket with optional prefix. S3 listings do not include the
+// content type, so ContentType is empty.
+func (s *S3BlobClient) List(ctx context.Context, container, prefix string) ([]BlobInfo, error) {
+	logger := s.logger.With(
+		logging.NewField("operation", "blob.list"),
+		logging.NewField("container", container),
+		logging.NewField("prefix", prefix),
+	)
+
+	logger.Info("Listing blobs")
+
+	var blobs []BlobInfo
+	var token string
+	for {
+		page, err := s.listObjects(ctx, container, token, ListOptions{Prefix: prefix})
+		if err != nil {
+			logger.Error("Failed to list blobs", logging.NewField("error", err))
+			return nil, s3BlobError(err, "failed to list blobs", container, "")
+		}
+		blobs = append(blobs, page.Blobs...)
+
+		if page.ContinuationToken == "" {
+			break
+		}
+		token = page.ContinuationToken
+	}
+
+	logger.Info("Blob listing completed", logging.NewField("count", len(blobs)))
+	return blobs, nil
+}
+
+// ListPage returns one page of objects in name order.
+func (s *S3BlobClient) ListPage(ctx context.Context, container, prefix, continuationToken string, pageSize int) (*BlobPage, error) {
+	return s.ListPageWithOptions(ctx, container, continuationToken, ListOptions{Prefix: prefix, PageSize: pageSize})
+}
+
+// ListPageWithOptions returns one page of objects and common prefixes in name order.
+// S3 returns at most 1000 entries per page. Listings do not carry metadata or tags, so
+// IncludeMetadata and IncludeTags cost one or two extra requests per object.
+func (s *S3BlobClient) ListPageWithOptions(ctx context.Context, container, continuationToken string, opts ListOptions) (*BlobPage, error) {
+	page, err := s.listObjects(ctx, container, continuationToken, opts)
+	if err != nil {
+		s.logger.Error("Failed to list blobs",
+			logging.NewField("container", container),
+			logging.NewField("prefix", opts.Prefix),
+			logging.NewField("error", err),
+		)
+		return nil, s3BlobError(err, "failed to list blobs", container, "")
+	}
+
+	if opts.IncludeMetadata || opts.IncludeTags {
+		for i := range page.Blobs {
+			blob := &page.Blobs[i]
+			if blob.IsPrefix {
+				continue
+			}
+			props, err := s.GetProperties(ctx, container, blob.Name)
+			if err != nil {
+				return nil, err
+			}
+			blob.ContentType = props.ContentType
+			if opts.IncludeMetadata {
+				blob.Metadata = props.Metadata
+			}
+			if opts.IncludeTags {
+				blob.Tags = props.Tags
+			}
+		}
+	}
+
+	return page, nil
+}
+
+// listObjects sends one ListObjectsV2 request.
+func (s *S3BlobClient) listObjects(ctx context.Context, container, continuationToken string, opts ListOptions) (*BlobPage, error) {
+	query := url.Values{"list-type": {"2"}}
+	if opts.Prefix != "" {
+		query.Set("prefix", opts.Prefix)
+	}
+	if opts.Delimiter != "" {
+		query.Set("delimiter", opts.Delimiter)
+	}
+	if opts.PageSize > 0 {
+		query.Set("max-keys", strconv.Itoa(opts.PageSize))
+	}
+	if continuationToken != "" {
+		query.Set("continuation-token", continuationToken)
+	}
+
+	var result s3ListResult
+	if err := s.doXML(ctx, http.MethodGet, container, "", query, nil, nil, &result); err != nil {
+		return nil, err
+	}
+
+	page := &BlobPage{Blobs: make([]BlobInfo, 0, len(result.Contents)+len(result.CommonPrefixes))}
+	for _, item := range result.Contents {
+		blobInfo := BlobInfo{
+			Name:       item.Key,
+			Size:       item.Size,
+			URL:        s.objectURL(container, item.Key).String(),
+			ETag:       item.ETag,
+			AccessTier: s3AccessTier(item.StorageClass),
+		}
+		if lastModified, err := time.Parse(time.RFC3339, item.LastModified); err == nil {
+			blobInfo.LastModified = lastModified.UTC().Format(time.RFC3339)
+		}
+		page.Blobs = append(page.Blobs, blobInfo)
+	}
+	for _, prefix := range result.CommonPrefixes {
+		page.Blobs = append(page.Blobs, BlobInfo{Name: prefix.Prefix, IsPrefix: true})
+	}
+	// The service returns objects and common prefixes separately
+	sortBlobs(page.Blobs)
+
+	if result.IsTruncated {
+		page.ContinuationToken = result.NextContinuationToken
+	}
+	return page, nil
+}
+
+// s3StorageClasses maps access tiers to S3 storage classes.
+var s3StorageClasses = map[string]string{
+	"hot":     "STANDARD",
+	"cool":    "STANDARD_IA",
+	"cold":    "GLACIER_IR",
+	"archive": "GLACIER",
+}
+
+// s3StorageClass returns the storage class for an access tier, or tier itself if it is
+// already an S3 storage class.
+func s3StorageClass(tier string) (string, error) {
+	if class, ok := s3StorageClasses[strings.ToLower(tier)]; ok {
+		return class, nil
+	}
+	switch class := strings.ToUpper(tier); class {
+	case "STANDARD", "STANDARD_IA", "ONEZONE_IA", "INTELLIGENT_TIERING", "GLACIER_IR", "GLACIER", "DEEP_ARCHIVE", "REDUCED_REDUNDANCY":
+		return class, nil
+	}
+	return "", fmt.Errorf("unsupported access tier: %s", tier)
+}
+
+// s3AccessTier returns the access tier of a storage class. S3 omits the header for STANDARD.
+func s3AccessTier(storageClass string) string {
+	switch storageClass {
+	case "", "STANDARD":
+		return "Hot"
+	case "STANDARD_IA", "ONEZONE_IA":
+		return "Cool"
+	case "GLACIER_IR":
+		return "Cold"
+	case "GLACIER", "DEEP_ARCHIVE":
+		return "Archive"
+	default:
+		return storageClass
+	}
+}
@@ -0,0 +1,267 @@
+package blobclient
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultSASExpiry is the lifetime of signed URLs when SASOptions.ExpiresIn is not set.
+	DefaultSASExpiry = 15 * time.Minute
+	// MaxSASExpiry is the longest lifetime accepted for signed URLs.
+	MaxSASExpiry = 7 * 24 * time.Hour
+
+	// sasClockSkew backdates the start time of signed URLs to tolerate clock differences.
+	sasClockSkew = 5 * time.Minute
+)
+
+// ErrSignedURLRejected is returned when a signed URL is invalid, expired or not
+// permitted for the attempted operation.
+var ErrSignedURLRejected = errors.New("signed URL rejected")
+
+// SASPermissions are the operations a signed URL grants.
+type SASPermissions struct {
+	Read   bool
+	Add    bool
+	Create bool
+	Write  bool
+	Delete bool
+	Tag    bool
+}
+
+// String returns the permissions in canonical SAS order ("racwdt").
+func (p SASPermissions) String() string {
+	var b strings.Builder
+	for _, perm := range []struct {
+		set    bool
+		letter byte
+	}{
+		{p.Read, 'r'}, {p.Add, 'a'}, {p.Create, 'c'}, {p.Write, 'w'}, {p.Delete, 'd'}, {p.Tag, 't'},
+	} {
+		if perm.set {
+			b.WriteByte(perm.letter)
+		}
+	}
+	return b.String()
+}
+
+// isZero reports whether no permission is set.
+func (p SASPermissions) isZero() bool {
+	return p == SASPermissions{}
+}
+
+// parseSASPermissions parses a canonical permission string.
+func parseSASPermissions(s string) (SASPermissions, error) {
+	var p SASPermissions
+	for _, r := range s {
+		switch r {
+		case 'r':
+			p.Read = true
+		case 'a':
+			p.Add = true
+		case 'c':
+			p.Create = true
+		case 'w':
+			p.Write = true
+		case 'd':
+			p.Delete = true
+		case 't':
+			p.Tag = true
+		default:
+			return SASPermissions{}, fmt.Errorf("invalid permission: %q", r)
+		}
+	}
+	return p, nil
+}
+
+// SASOptions configures a signed URL.
+type SASOptions struct {
+	// ExpiresIn is the lifetime of the URL (default: DefaultSASExpiry, max: MaxSASExpiry).
+	ExpiresIn time.Duration
+	// Permissions overrides the default permissions: read for GenerateReadURL,
+	// create and write for GenerateWriteURL.
+	Permissions SASPermissions
+	// IPRange restricts the URL to a client IP or an inclusive range,
+	// e.g. "203.0.113.7" or "203.0.113.0-203.0.113.255".
+	IPRange string
+	// ContentDisposition overrides the Content-Disposition header served with the blob,
+	// e.g. `attachment; filename="report.pdf"`.
+	ContentDisposition string
+	// ContentType overrides the Content-Type header served with the blob.
+	ContentType string
+}
+
+// resolve validates opts and fills in the expiry and default permissions.
+func (o SASOptions) resolve(defaults SASPermissions) (SASOptions, error) {
+	if o.ExpiresIn <= 0 {
+		o.ExpiresIn = DefaultSASExpiry
+	}
+	if o.ExpiresIn > MaxSASExpiry {
+		return o, fmt.Errorf("SAS expiry %s exceeds maximum of %s", o.ExpiresIn, MaxSASExpiry)
+	}
+	if o.Permissions.isZero() {
+		o.Permissions = defaults
+	}
+	if _, _, err := parseIPRange(o.IPRange); err != nil {
+		return o, err
+	}
+	return o, nil
+}
+
+var (
+	readPermissions  = SASPermissions{Read: true}
+	writePermissions = SASPermissions{Create: true, Write: true}
+)
+
+// parseIPRange parses "ip" or "start-end". An empty string yields nil IPs.
+func parseIPRange(s string) (start, end net.IP, err error) {
+	if s == "" {
+		return nil, nil, nil
+	}
+
+	startText, endText, isRange := strings.Cut(s, "-")
+	start = net.ParseIP(strings.TrimSpace(startText))
+	if start == nil {
+		return nil, nil, fmt.Errorf("invalid IP range: %s", s)
+	}
+	if !isRange {
+		return start, nil, nil
+	}
+
+	end = net.ParseIP(strings.TrimSpace(endText))
+	if end == nil {
+		return nil, nil, fmt.Errorf("invalid IP range: %s", s)
+	}
+	return start, end, nil
+}
+
+// ipInRange reports whether ip falls within the range string (empty allows all).
+func ipInRange(ipRange, ip string) bool {
+	start, end, err := parseIPRange(ipRange)
+	if err != nil {
+		return false
+	}
+	if start == nil {
+		return true
+	}
+
+	client := net.ParseIP(ip)
+	if client == nil {
+		return false
+	}
+	if end == nil {
+		return client.Equal(start)
+	}
+
+	client16, start16, end16 := client.To16(), start.To16(), end.To16()
+	return compareIP(client16, start16) >= 0 && compareIP(client16, end16) <= 0
+}
+
+// compareIP compares two 16-byte IPs.
+func compareIP(a, b net.IP) int {
+	for i := range a {
+		if a[i] != b[i] {
+			if a[i] < b[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// urlSigner issues and verifies HMAC-signed <scheme>://container/blob URLs for backends
+// without a native SAS mechanism. The key is random per signer, so URLs are only valid
+// for the client instance that issued them.
+type urlSigner struct {
+	scheme string
+	key    []byte
+	now    func() time.Time
+}
+
+// newURLSigner creates a signer for URLs with the given scheme.
+func newURLSigner(scheme string) *urlSigner {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic(fmt.Sprintf("failed to generate URL signing key: %v", err))
+	}
+	return &urlSigner{scheme: scheme, key: key, now: time.Now}
+}
+
+// sign builds a URL whose SAS-style query parameters are protected by an HMAC.
+func (s *urlSigner) sign(container, blobName string, opts SASOptions, defaults SASPermissions) (string, error) {
+	opts, err := opts.resolve(defaults)
+	if err != nil {
+		return "", err
+	}
+
+	query := url.Values{}
+	query.Set("sp", opts.Permissions.String())
+	query.Set("se", s.now().Add(opts.ExpiresIn).UTC().Format(time.RFC3339))
+	if opts.IPRange != "" {
+		query.Set("sip", opts.IPRange)
+	}
+	if opts.ContentDisposition != "" {
+		query.Set("rscd", opts.ContentDisposition)
+	}
+	if opts.ContentType != "" {
+		query.Set("rsct", opts.ContentType)
+	}
+	query.Set("sig", s.signature(container, blobName, query))
+
+	return fmt.Sprintf("%s://%s/%s?%s", s.scheme, container, blobName, query.Encode()), nil
+}
+
+// signature computes the HMAC of the signed fields of a URL.
+func (s *urlSigner) signature(container, blobName string, query url.Values) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(strings.Join([]string{
+		container, blobName, query.Get("sp"), query.Get("se"), query.Get("sip"), query.Get("rscd"), query.Get("rsct"),
+	}, "\n")))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// verify checks the signature, expiry, permissions and client IP of rawURL and returns
+// the container, blob and query parameters it carries.
+func (s *urlSigner) verify(rawURL string, required SASPermissions, clientIP string) (container, blobName string, query url.Values, err error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != s.scheme {
+		return "", "", nil, fmt.Errorf("%w: not a %s URL", ErrSignedURLRejected, s.scheme)
+	}
+	container = u.Host
+	blobName = strings.TrimPrefix(u.Path, "/")
+	query = u.Query()
+
+	expected := s.signature(container, blobName, query)
+	if !hmac.Equal([]byte(expected), []byte(query.Get("sig"))) {
+		return "", "", nil, fmt.Errorf("%w: signature mismatch", ErrSignedURLRejected)
+	}
+
+	expiry, err := time.Parse(time.RFC3339, query.Get("se"))
+	if err != nil || !s.now().Before(expiry) {
+		return "", "", nil, fmt.Errorf("%w: expired", ErrSignedURLRejected)
+	}
+
+	granted, err := parseSASPermissions(query.Get("sp"))
+	if err != nil {
+		return "", "", nil, fmt.Errorf("%w: %v", ErrSignedURLRejected, err)
+	}
+	if (required.Read && !granted.Read) || (required.Add && !granted.Add) || (required.Create && !granted.Create) ||
+		(required.Write && !granted.Write) || (required.Delete && !granted.Delete) || (required.Tag && !granted.Tag) {
+		return "", "", nil, fmt.Errorf("%w: permission %q not granted", ErrSignedURLRejected, required.String())
+	}
+
+	if ipRange := query.Get("sip"); ipRange != "" && !ipInRange(ipRange, clientIP) {
+		return "", "", nil, fmt.Errorf("%w: client IP %q not allowed", ErrSignedURLRejected, clientIP)
+	}
+
+	return container, blobName, query, nil
+}
@@ -0,0 +1,142 @@
+package blobclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yourorg/go-service-kit/pkg/logging"
+)
+
+func TestMockBlobClient_SignedURLRoundTrip(t *testing.T) {
+	client := NewMockBlobClient()
+	ctx := context.Background()
+
+	writeURL, err := client.GenerateWriteURL(ctx, "uploads", "avatar.png", SASOptions{})
+	if err != nil {
+		t.Fatalf("GenerateWriteURL failed: %v", err)
+	}
+	if err := client.UploadToSignedURL(ctx, writeURL, "", strings.NewReader("png"), "image/png"); err != nil {
+		t.Fatalf("UploadToSignedURL failed: %v", err)
+	}
+
+	readURL, err := client.GenerateReadURL(ctx, "uploads", "avatar.png", SASOptions{
+		ContentDisposition: `attachment; filename="me.png"`,
+	})
+	if err != nil {
+		t.Fatalf("GenerateReadURL failed: %v", err)
+	}
+
+	reader, props, err := client.OpenSignedURL(ctx, readURL, "")
+	if err != nil {
+		t.Fatalf("OpenSignedURL failed: %v", err)
+	}
+	defer reader.Close()
+	content, _ := io.ReadAll(reader)
+	if string(content) != "png" {
+		t.Errorf("Expected 'png', got %q", content)
+	}
+	if props.ContentDisposition != `attachment; filename="me.png"` || props.ContentType != "image/png" {
+		t.Errorf("Unexpected served headers: %+v", props)
+	}
+
+	// A read URL cannot be used to upload
+	if err := client.UploadToSignedURL(ctx, readURL, "", strings.NewReader("x"), "image/png"); !errors.Is(err, ErrSignedURLRejected) {
+		t.Errorf("Expected ErrSignedURLRejected for upload with read URL, got %v", err)
+	}
+}
+
+func TestMockBlobClient_SignedURLRejections(t *testing.T) {
+	client := NewMockBlobClient()
+	ctx := context.Background()
+	if _, err := client.Upload(ctx, "docs", "a.pdf", strings.NewReader("a"), "application/pdf"); err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+
+	readURL, err := client.GenerateReadURL(ctx, "docs", "a.pdf", SASOptions{
+		ExpiresIn: time.Minute,
+		IPRange:   "10.0.0.1-10.0.0.9",
+	})
+	if err != nil {
+		t.Fatalf("GenerateReadURL failed: %v", err)
+	}
+
+	if _, _, err := client.VerifySignedURL(readURL, readPermissions, "10.0.0.5"); err != nil {
+		t.Errorf("Expected URL to be valid, got %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		url      string
+		clientIP string
+	}{
+		{"ip outside range", readURL, "10.0.1.5"},
+		{"tampered blob", strings.Replace(readURL, "a.pdf", "b.pdf", 1), "10.0.0.5"},
+		{"tampered permissions", strings.Replace(readURL, "sp=r", "sp=rw", 1), "10.0.0.5"},
+	}
+	for _, tt := range tests {
+		if _, _, err := client.VerifySignedURL(tt.url, readPermissions, tt.clientIP); !errors.Is(err, ErrSignedURLRejected) {
+			t.Errorf("%s: expected ErrSignedURLRejected, got %v", tt.name, err)
+		}
+	}
+
+	client.signer.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	if _, _, err := client.VerifySignedURL(readURL, readPermissions, "10.0.0.5"); !errors.Is(err, ErrSignedURLRejected) {
+		t.Errorf("Expected expired URL to be rejected, got %v", err)
+	}
+}
+
+func TestSASOptions_Validation(t *testing.T) {
+	client := NewMockBlobClient()
+	ctx := context.Background()
+
+	if _, err := client.GenerateReadURL(ctx, "c", "b", SASOptions{ExpiresIn: 8 * 24 * time.Hour}); err == nil {
+		t.Error("Expected error for expiry beyond maximum")
+	}
+	if _, err := client.GenerateReadURL(ctx, "c", "b", SASOptions{IPRange: "not-an-ip"}); err == nil {
+		t.Error("Expected error for invalid IP range")
+	}
+	if got := (SASPermissions{Write: true, Read: true, Create: true}).String(); got != "rcw" {
+		t.Errorf("Expected canonical permissions 'rcw', got %q", got)
+	}
+}
+
+func TestAzureBlobClient_SignedURLProtocol(t *testing.T) {
+	// The well-known Azurite key; signing needs no connection
+	const accountKey = "Eby8vdM02xNOcqFlqUwJPLlmEtlCDXJ1OUzFT50uSRZ6IFsuFq2UVErCz4I6tq/K1SZFPTOtr/KBHBeksoGMGw=="
+	logger, _ := logging.NewLogger("error", "json")
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		name     string
+		client   func() (*AzureBlobClient, error)
+		protocol string
+	}{
+		{"azure", func() (*AzureBlobClient, error) {
+			return NewAzureBlobClient("devstoreaccount1", accountKey, false, logger)
+		}, "https"},
+		{"azurite", func() (*AzureBlobClient, error) {
+			return NewAzureBlobClientFromConnectionString(AzuriteConnectionString, logger)
+		}, "https,http"},
+	} {
+		client, err := tc.client()
+		if err != nil {
+			t.Fatalf("%s: failed to create client: %v", tc.name, err)
+		}
+		signed, err := client.GenerateReadURL(ctx, "docs", "a.pdf", SASOptions{})
+		if err != nil {
+			t.Fatalf("%s: GenerateReadURL failed: %v", tc.name, err)
+		}
+		parsed, err := url.Parse(signed)
+		if err != nil {
+			t.Fatalf("%s: invalid signed URL %q: %v", tc.name, signed, err)
+		}
+		if got := parsed.Query().Get("spr"); got != tc.protocol {
+			t.Errorf("%s: expected spr=%s, got %q", tc.name, tc.protocol, got)
+		}
+	}
+}
@@ -0,0 +1,376 @@
+package blobclient
+
+import (
+	"context"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/yourorg/go-service-kit/pkg/tenant"
+)
+
+// TenantBlobClient is a BlobClient decorator that confines every operation to the
+// tenant in the context (see tenant.WithTenant and jwt.TenantMiddleware).
+//
+// Blob names are transparently stored under "tenants/<tenant_id>/", and listings only
+// ever see the current tenant's blobs with the prefix stripped. Calls without a tenant
+// fail with tenant.ErrMissingTenant, and names that could escape the prefix, such as
+// "../other/x", with ErrInvalidBlobName.
+type TenantBlobClient struct {
+	client BlobClient
+}
+
+// NewTenantBlobClient wraps client with tenant path isolation.
+func NewTenantBlobClient(client BlobClient) *TenantBlobClient {
+	return &TenantBlobClient{client: client}
+}
+
+// tenantPrefix returns the blob path prefix for the tenant in ctx.
+func tenantPrefix(ctx context.Context) (string, error) {
+	tenantID, err := tenant.Require(ctx)
+	if err != nil {
+		return "", err
+	}
+	return "tenants/" + tenantID + "/", nil
+}
+
+// tenantBlobName returns blobName under the tenant's prefix in ctx.
+func tenantBlobName(ctx context.Context, blobName string) (string, error) {
+	prefix, err := tenantPrefix(ctx)
+	if err != nil {
+		return "", err
+	}
+	return scopeBlobName(prefix, blobName)
+}
+
+// scopeBlobName returns blobName under prefix. Names that a backend could resolve outside
+// the prefix, with "." or ".." segments, a leading or doubled "/" or a backslash, are
+// rejected with ErrInvalidBlobName.
+func scopeBlobName(prefix, blobName string) (string, error) {
+	name := prefix + blobName
+	if blobName == "" || strings.Contains(blobName, "\\") || path.Clean(name) != name {
+		return "", invalidBlobName(blobName)
+	}
+	return name, nil
+}
+
+// scopeListPrefix returns a listing prefix under prefix, validated like a blob name.
+func scopeListPrefix(prefix, listPrefix string) (string, error) {
+	if listPrefix == "" {
+		return prefix, nil
+	}
+	if _, err := scopeBlobName(prefix, strings.TrimSuffix(listPrefix, "/")); err != nil {
+		return "", err
+	}
+	return prefix + listPrefix, nil
+}
+
+// Upload uploads data under the tenant's prefix.
+func (t *TenantBlobClient) Upload(ctx context.Context, container, blobName string, data io.Reader, contentType string) (string, error) {
+	name, err := tenantBlobName(ctx, blobName)
+	if err != nil {
+		return "", err
+	}
+	return t.client.Upload(ctx, container, name, data, contentType)
+}
+
+// UploadWithOptions uploads data with options under the tenant's prefix.
+func (t *TenantBlobClient) UploadWithOptions(ctx context.Context, container, blobName string, data io.Reader, opts UploadOptions) (string, error) {
+	name, err := tenantBlobName(ctx, blobName)
+	if err != nil {
+		return "", err
+	}
+	return t.client.UploadWithOptions(ctx, container, name, data, opts)
+}
+
+// UploadChunked uploads data as parallel blocks under the tenant's prefix.
+func (t *TenantBlobClient) UploadChunked(ctx context.Context, container, blobName string, data io.Reader, opts UploadOptions, transfer TransferOptions) (string, error) {
+	name, err := tenantBlobName(ctx, blobName)
+	if err != nil {
+		return "", err
+	}
+	return t.client.UploadChunked(ctx, container, name, data, opts, transfer)
+}
+
+// Get retrieves a blob from the tenant's prefix.
+func (t *TenantBlobClient) Get(ctx context.Context, container, blobName string) (io.ReadCloser, error) {
+	name, err := tenantBlobName(ctx, blobName)
+	if err != nil {
+		return nil, err
+	}
+	return t.client.Get(ctx, container, name)
+}
+
+// GetRange retrieves a byte range of a blob from the tenant's prefix.
+func (t *TenantBlobClient) GetRange(ctx context.Context, container, blobName string, offset, count int64) (io.ReadCloser, error) {
+	name, err := tenantBlobName(ctx, blobName)
+	if err != nil {
+		return nil, err
+	}
+	return t.client.GetRange(ctx, container, name, offset, count)
+}
+
+// GetRangeWithOptions retrieves a byte range of a blob from the tenant's prefix with conditions.
+func (t *TenantBlobClient) GetRangeWithOptions(ctx context.Context, container, blobName string, offset, count int64, opts GetOptions) (io.ReadCloser, error) {
+	name, err := tenantBlobName(ctx, blobName)
+	if err != nil {
+		return nil, err
+	}
+	return t.client.GetRangeWithOptions(ctx, container, name, offset, count, opts)
+}
+
+// DownloadChunked writes a blob from the tenant's prefix to w.
+func (t *TenantBlobClient) DownloadChunked(ctx context.Context, container, blobName string, w io.Writer, transfer TransferOptions) (int64, error) {
+	name, err := tenantBlobName(ctx, blobName)
+	if err != nil {
+		return 0, err
+	}
+	return t.client.DownloadChunked(ctx, container, name, w, transfer)
+}
+
+// Delete deletes a blob from the tenant's prefix.
+func (t *TenantBlobClient) Delete(ctx context.Context, container, blobName string) error {
+	name, err := tenantBlobName(ctx, blobName)
+	if err != nil {
+		return err
+	}
+	return t.client.Delete(ctx, container, name)
+}
+
+// DeleteWithOptions conditionally deletes a blob from the tenant's prefix.
+func (t *TenantBlobClient) DeleteWithOptions(ctx context.Context, container, blobName string, opts DeleteOptions) error {
+	name, err := tenantBlobName(ctx, blobName)
+	if err != nil {
+		return err
+	}
+	return t.client.DeleteWithOptions(ctx, container, name, opts)
+}
+
+// Exists checks if a blob exists under the tenant's prefix.
+func (t *TenantBlobClient) Exists(ctx context.Context, container, blobName string) (bool, error) {
+	name, err := tenantBlobName(ctx, blobName)
+	if err != nil {
+		return false, err
+	}
+	return t.client.Exists(ctx, container, name)
+}
+
+// List lists the tenant's blobs. Returned names are relative to the tenant prefix.
+func (t *TenantBlobClient) List(ctx context.Context, container, prefix string) ([]BlobInfo, error) {
+	tPrefix, err := tenantPrefix(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	listPrefix, err := scopeListPrefix(tPrefix, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	blobs, err := t.client.List(ctx, container, listPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range blobs {
+		blobs[i].Name = strings.TrimPrefix(blobs[i].Name, tPrefix)
+	}
+	return blobs, nil
+}
+
+// ListPage returns one page of the tenant's blobs with the tenant prefix stripped.
+func (t *TenantBlobClient) ListPage(ctx context.Context, container, prefix, continuationToken string, pageSize int) (*BlobPage, error) {
+	return t.ListPageWithOptions(ctx, container, continuationToken, ListOptions{Prefix: prefix, PageSize: pageSize})
+}
+
+// ListPageWithOptions returns one page of the tenant's blobs and virtual directories with
+// the tenant prefix stripped.
+func (t *TenantBlobClient) ListPageWithOptions(ctx context.Context, container, continuationToken string, opts ListOptions) (*BlobPage, error) {
+	tPrefix, err := tenantPrefix(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	opts.Prefix, err = scopeListPrefix(tPrefix, opts.Prefix)
+	if err != nil {
+		return nil, err
+	}
+	page, err := t.client.ListPageWithOptions(ctx, container, continuationToken, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range page.Blobs {
+		page.Blobs[i].Name = strings.TrimPrefix(page.Blobs[i].Name, tPrefix)
+	}
+	return page, nil
+}
+
+// Copy copies a blob within the tenant's prefix.
+func (t *TenantBlobClient) Copy(ctx context.Context, srcContainer, srcBlob, dstContainer, dstBlob string) (*CopyStatus, error) {
+	src, err := tenantBlobName(ctx, srcBlob)
+	if err != nil {
+		return nil, err
+	}
+	dst, err := tenantBlobName(ctx, dstBlob)
+	if err != nil {
+		return nil, err
+	}
+	return t.client.Copy(ctx, srcContainer, src, dstContainer, dst)
+}
+
+// GetCopyStatus returns the status of the last copy into a blob under the tenant's prefix.
+func (t *TenantBlobClient) GetCopyStatus(ctx context.Context, container, blobName string) (*CopyStatus, error) {
+	name, err := tenantBlobName(ctx, blobName)
+	if err != nil {
+		return nil, err
+	}
+	return t.client.GetCopyStatus(ctx, container, name)
+}
+
+// Move moves a blob within the tenant's prefix.
+func (t *TenantBlobClient) Move(ctx context.Context, srcContainer, srcBlob, dstContainer, dstBlob string) (string, error) {
+	src, err := tenantBlobName(ctx, srcBlob)
+	if err != nil {
+		return "", err
+	}
+	dst, err := tenantBlobName(ctx, dstBlob)
+	if err != nil {
+		return "", err
+	}
+	return t.client.Move(ctx, srcContainer, src, dstContainer, dst)
+}
+
+// Snapshot creates a snapshot of a blob under the tenant's prefix.
+func (t *TenantBlobClient) Snapshot(ctx context.Context, container, blobName string) (string, error) {
+	name, err := tenantBlobName(ctx, blobName)
+	if err != nil {
+		return "", err
+	}
+	return t.client.Snapshot(ctx, container, name)
+}
+
+// ListSnapshots lists the snapshots of a blob under the tenant's prefix.
+// Returned names are relative to the tenant prefix.
+func (t *TenantBlobClient) ListSnapshots(ctx context.Context, container, blobName string) ([]BlobInfo, error) {
+	prefix, err := tenantPrefix(ctx)
+	if err != nil {
+		return nil, err
+	}
+	name, err := scopeBlobName(prefix, blobName)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots, err := t.client.ListSnapshots(ctx, container, name)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range snapshots {
+		snapshots[i].Name = strings.TrimPrefix(snapshots[i].Name, prefix)
+	}
+	return snapshots, nil
+}
+
+// Undelete restores a deleted blob under the tenant's prefix.
+func (t *TenantBlobClient) Undelete(ctx context.Context, container, blobName string) error {
+	name, err := tenantBlobName(ctx, blobName)
+	if err != nil {
+		return err
+	}
+	return t.client.Undelete(ctx, container, name)
+}
+
+// AcquireLease takes a lease on a blob under the tenant's prefix.
+func (t *TenantBlobClient) AcquireLease(ctx context.Context, container, blobName string, duration time.Duration, proposedLeaseID string) (string, error) {
+	name, err := tenantBlobName(ctx, blobName)
+	if err != nil {
+		return "", err
+	}
+	return t.client.AcquireLease(ctx, container, name, duration, proposedLeaseID)
+}
+
+// RenewLease renews a lease on a blob under the tenant's prefix.
+func (t *TenantBlobClient) RenewLease(ctx context.Context, container, blobName, leaseID string) error {
+	name, err := tenantBlobName(ctx, blobName)
+	if err != nil {
+		return err
+	}
+	return t.client.RenewLease(ctx, container, name, leaseID)
+}
+
+// ReleaseLease releases a lease on a blob under the tenant's prefix.
+func (t *TenantBlobClient) ReleaseLease(ctx context.Context, container, blobName, leaseID string) error {
+	name, err := tenantBlobName(ctx, blobName)
+	if err != nil {
+		return err
+	}
+	return t.client.ReleaseLease(ctx, container, name, leaseID)
+}
+
+// BreakLease breaks a lease on a blob under the tenant's prefix.
+func (t *TenantBlobClient) BreakLease(ctx context.Context, container, blobName string, breakPeriod time.Duration) (time.Duration, error) {
+	name, err := tenantBlobName(ctx, blobName)
+	if err != nil {
+		return 0, err
+	}
+	return t.client.BreakLease(ctx, container, name, breakPeriod)
+}
+
+// GetProperties returns the properties of a blob under the tenant's prefix.
+// The returned name is relative to the tenant prefix.
+func (t *TenantBlobClient) GetProperties(ctx context.Context, container, blobName string) (*BlobProperties, error) {
+	prefix, err := tenantPrefix(ctx)
+	if err != nil {
+		return nil, err
+	}
+	name, err := scopeBlobName(prefix, blobName)
+	if err != nil {
+		return nil, err
+	}
+
+	props, err := t.client.GetProperties(ctx, container, name)
+	if err != nil {
+		return nil, err
+	}
+	props.Name = strings.TrimPrefix(props.Name, prefix)
+	return props, nil
+}
+
+// SetMetadata replaces the metadata of a blob under the tenant's prefix.
+func (t *TenantBlobClient) SetMetadata(ctx context.Context, container, blobName string, metadata map[string]string, opts SetMetadataOptions) error {
+	name, err := tenantBlobName(ctx, blobName)
+	if err != nil {
+		return err
+	}
+	return t.client.SetMetadata(ctx, container, name, metadata, opts)
+}
+
+// SetAccessTier changes the access tier of a blob under the tenant's prefix.
+func (t *TenantBlobClient) SetAccessTier(ctx context.Context, container, blobName, tier string) error {
+	name, err := tenantBlobName(ctx, blobName)
+	if err != nil {
+		return err
+	}
+	return t.client.SetAccessTier(ctx, container, name, tier)
+}
+
+// GenerateReadURL returns a signed read URL for a blob under the tenant's prefix.
+func (t *TenantBlobClient) GenerateReadURL(ctx context.Context, container, blobName string, opts SASOptions) (string, error) {
+	name, err := tenantBlobName(ctx, blobName)
+	if err != nil {
+		return "", err
+	}
+	return t.client.GenerateReadURL(ctx, container, name, opts)
+}
+
+// GenerateWriteURL returns a signed write URL for a blob under the tenant's prefix.
+func (t *TenantBlobClient) GenerateWriteURL(ctx context.Context, container, blobName string, opts SASOptions) (string, error) {
+	name, err := tenantBlobName(ctx, blobName)
+	if err != nil {
+		return "", err
+	}
+	return t.client.GenerateWriteURL(ctx, container, name, opts)
+}
@@ -0,0 +1,89 @@
+package blobclient
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/yourorg/go-service-kit/pkg/tenant"
+)
+
+func TestTenantBlobClient_IsolatesTenants(t *testing.T) {
+	mock := NewMockBlobClient()
+	client := NewTenantBlobClient(mock)
+
+	acme := tenant.WithTenant(context.Background(), "acme")
+	globex := tenant.WithTenant(context.Background(), "globex")
+
+	if _, err := client.Upload(acme, "docs", "payslip.pdf", strings.NewReader("acme"), "application/pdf"); err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+
+	exists, err := mock.Exists(context.Background(), "docs", "tenants/acme/payslip.pdf")
+	if err != nil || !exists {
+		t.Fatalf("Expected blob under tenant prefix, exists=%v err=%v", exists, err)
+	}
+
+	exists, err = client.Exists(globex, "docs", "payslip.pdf")
+	if err != nil {
+		t.Fatalf("Exists failed: %v", err)
+	}
+	if exists {
+		t.Error("Expected blob to be invisible to another tenant")
+	}
+
+	blobs, err := client.List(acme, "docs", "")
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(blobs) != 1 || blobs[0].Name != "payslip.pdf" {
+		t.Errorf("Expected [payslip.pdf], got %v", blobs)
+	}
+
+	page, err := client.ListPageWithOptions(acme, "docs", "", ListOptions{Delimiter: "/"})
+	if err != nil {
+		t.Fatalf("ListPageWithOptions failed: %v", err)
+	}
+	if len(page.Blobs) != 1 || page.Blobs[0].Name != "payslip.pdf" {
+		t.Errorf("Expected [payslip.pdf] without tenant prefix, got %v", page.Blobs)
+	}
+
+	if _, err := client.Get(context.Background(), "docs", "payslip.pdf"); err != tenant.ErrMissingTenant {
+		t.Errorf("Expected ErrMissingTenant, got %v", err)
+	}
+}
+
+func TestTenantBlobClient_RejectsTraversal(t *testing.T) {
+	fs, _ := newTestFileSystemClient(t)
+	client := NewTenantBlobClient(fs)
+	acme := tenant.WithTenant(context.Background(), "acme")
+	globex := tenant.WithTenant(context.Background(), "globex")
+
+	if _, err := client.Upload(globex, "docs", "secret.txt", strings.NewReader("globex"), "text/plain"); err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+
+	for _, name := range []string{"../globex/secret.txt", "a/../../globex/secret.txt", "/secret.txt", "./secret.txt", "a//b", "a\\..\\b", "..", ""} {
+		if _, err := client.Get(acme, "docs", name); !errors.Is(err, ErrInvalidBlobName) {
+			t.Errorf("Get(%q): expected ErrInvalidBlobName, got %v", name, err)
+		}
+		if _, err := client.Upload(acme, "docs", name, strings.NewReader("x"), "text/plain"); !errors.Is(err, ErrInvalidBlobName) {
+			t.Errorf("Upload(%q): expected ErrInvalidBlobName, got %v", name, err)
+		}
+	}
+	if _, err := client.Copy(acme, "docs", "../globex/secret.txt", "docs", "stolen.txt"); !errors.Is(err, ErrInvalidBlobName) {
+		t.Errorf("Copy: expected ErrInvalidBlobName, got %v", err)
+	}
+	if _, err := client.List(acme, "docs", "../globex/"); !errors.Is(err, ErrInvalidBlobName) {
+		t.Errorf("List: expected ErrInvalidBlobName, got %v", err)
+	}
+
+	// Names containing dots that stay inside the prefix are fine
+	if _, err := client.Upload(acme, "docs", "reports/..hidden/v1.2.csv", strings.NewReader("acme"), "text/csv"); err != nil {
+		t.Errorf("Upload failed: %v", err)
+	}
+	if blobs, err := client.List(acme, "docs", "reports/"); err != nil || len(blobs) != 1 {
+		t.Errorf("List = %v, %v", blobs, err)
+	}
+}
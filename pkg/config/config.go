@@ -80,8 +80,16 @@ func (f *FileConfigSource) Get(key string) (string, bool) {
 		}
 	}
 	
-	if str, ok := current.(string); ok {
-		return str, true
+	switch val := current.(type) {
+	case string:
+		return val, true
+	case map[string]interface{}, []interface{}:
+		// Structured values are returned as JSON, e.g. a list of rules
+		encoded, err := json.Marshal(val)
+		if err != nil {
+			return "", false
+		}
+		return string(encoded), true
 	}
 	return fmt.Sprintf("%v", current), true
 }
@@ -100,7 +108,24 @@ type Config struct {
 	BlobStorageAccountName string
 	BlobStorageAccountKey  string
 	BlobContainer          string
+	BlobArchiveContainer   string // processed uploads are moved here
 	BlobAccessTier         string // Hot, Cool, Archive
+	BlobProvider           string // azure, s3, filesystem, mock; empty selects azure when configured, else mock
+	BlobConnectionString   string // e.g. UseDevelopmentStorage=true for Azurite
+	BlobLocalPath          string // root directory of the filesystem provider
+	
+	// Blob lifecycle configuration
+	BlobLifecycleRules     []BlobLifecycleRule // JSON array in BLOB_LIFECYCLE_RULES
+	BlobLifecycleInterval  int                 // seconds between sweeps
+	BlobLifecycleDryRun    bool                // report changes without applying them
+	
+	// S3-compatible storage configuration (BlobProvider "s3")
+	S3Endpoint             string // empty uses AWS for S3Region; e.g. http://localhost:9000 for MinIO
+	S3Region               string
+	S3AccessKeyID          string
+	S3SecretAccessKey      string
+	S3SessionToken         string
+	S3UsePathStyle         bool // required by MinIO and most S3-compatible stores
 	
 	// Service Bus configuration
 	ServiceBusNamespace    string
@@ -115,6 +140,17 @@ type Config struct {
 	HTTPWriteTimeout       int // seconds
 	HTTPIdleTimeout        int // seconds
 	
+	// Rate limiting configuration
+	RateLimitPolicies      []RateLimitPolicy // JSON array in RATE_LIMIT_POLICIES
+	RateLimitCosts         []RateLimitCost   // JSON array in RATE_LIMIT_COSTS
+	RateLimitExemptRoles   []string          // JWT roles never limited, comma-separated in RATE_LIMIT_EXEMPT_ROLES
+	RateLimitStore         string            // memory, postgres (shared by replicas, needs DatabaseURL)
+	
+	// JWT configuration
+	JWTSecretKey           string // at least jwt.MinSecretKeyLength characters; empty disables JWT
+	JWTAccessTokenExpiry   int    // minutes
+	JWTRefreshTokenExpiry  int    // hours
+	
 	// Logging configuration
 	LogLevel               string // debug, info, warn, error
 	LogFormat              string // json, text
@@ -131,6 +167,50 @@ type Config struct {
 	
 	// Service Bus Consumer configuration
 	ServiceBusConcurrency  int // number of concurrent message handlers
+	
+	// Database configuration
+	DatabaseURL            string
+	DatabaseReplicaURLs    []string // read replicas, comma-separated in DATABASE_REPLICA_URLS
+	DBMaxOpenConns         int
+	DBMaxIdleConns         int
+	DBConnMaxLifetime      int // seconds
+	DBConnMaxIdleTime      int // seconds
+	DBStatementTimeout     int // milliseconds, 0 disables
+	DBApplicationName      string // defaults to AppName
+	DBConnectMaxAttempts   int
+	DBStatsInterval        int // seconds, 0 disables
+	DBSlowQueryThreshold   int // milliseconds
+}
+
+// BlobLifecycleRule applies retention to the blobs of a container under a prefix.
+// Zero values disable the respective action.
+type BlobLifecycleRule struct {
+	Container              string `json:"container"`
+	Prefix                 string `json:"prefix"`
+	DeleteAfterDays        int    `json:"delete_after_days"`
+	TierAfterDays          int    `json:"tier_after_days"`
+	Tier                   string `json:"tier"`                      // Cool, Cold or Archive
+	KeepNewestPerDirectory int    `json:"keep_newest_per_directory"` // blobs per virtual directory
+}
+
+// RateLimitPolicy limits the requests matching a route, methods and JWT roles. Empty
+// match fields match everything.
+type RateLimitPolicy struct {
+	Name    string   `json:"name"`
+	Route   string   `json:"route"`   // route pattern, e.g. /api/v1/reports/:id, or a prefix ending in *
+	Methods []string `json:"methods"`
+	Roles   []string `json:"roles"`
+	RPS     float64  `json:"rps"`
+	Burst   int      `json:"burst"`
+	Key     string   `json:"key"` // user (default), ip, api_key or route
+}
+
+// RateLimitCost weights the requests matching a route and methods, e.g. 10 for an
+// expensive export, in every policy they count towards.
+type RateLimitCost struct {
+	Route   string   `json:"route"`
+	Methods []string `json:"methods"`
+	Cost    int      `json:"cost"`
 }
 
 // LoadConfig loads configuration from the provided source.
@@ -148,10 +228,38 @@ func LoadConfig(source ConfigSource) (*Config, error) {
 		return val
 	}
 	
+	// Helper to get bool from config
+	getBool := func(key string, defaultValue bool) bool {
+		str := source.GetWithDefault(key, strconv.FormatBool(defaultValue))
+		val, err := strconv.ParseBool(str)
+		if err != nil {
+			return defaultValue
+		}
+		return val
+	}
+	
 	cfg.BlobStorageAccountName = source.GetWithDefault("BLOB_STORAGE_ACCOUNT_NAME", "")
 	cfg.BlobStorageAccountKey = source.GetWithDefault("BLOB_STORAGE_ACCOUNT_KEY", "")
 	cfg.BlobContainer = source.GetWithDefault("BLOB_CONTAINER", "default-container")
+	cfg.BlobArchiveContainer = source.GetWithDefault("BLOB_ARCHIVE_CONTAINER", "archive")
 	cfg.BlobAccessTier = source.GetWithDefault("BLOB_ACCESS_TIER", "Hot")
+	cfg.BlobProvider = source.GetWithDefault("BLOB_PROVIDER", "")
+	cfg.BlobConnectionString = source.GetWithDefault("BLOB_CONNECTION_STRING", "")
+	cfg.BlobLocalPath = source.GetWithDefault("BLOB_LOCAL_PATH", "./data/blobs")
+	if rules := source.GetWithDefault("BLOB_LIFECYCLE_RULES", ""); rules != "" {
+		if err := json.Unmarshal([]byte(rules), &cfg.BlobLifecycleRules); err != nil {
+			return nil, fmt.Errorf("invalid BLOB_LIFECYCLE_RULES: %w", err)
+		}
+	}
+	cfg.BlobLifecycleInterval = getInt("BLOB_LIFECYCLE_INTERVAL", 3600)
+	cfg.BlobLifecycleDryRun = getBool("BLOB_LIFECYCLE_DRY_RUN", true)
+	
+	cfg.S3Endpoint = source.GetWithDefault("S3_ENDPOINT", "")
+	cfg.S3Region = source.GetWithDefault("S3_REGION", "us-east-1")
+	cfg.S3AccessKeyID = source.GetWithDefault("S3_ACCESS_KEY_ID", "")
+	cfg.S3SecretAccessKey = source.GetWithDefault("S3_SECRET_ACCESS_KEY", "")
+	cfg.S3SessionToken = source.GetWithDefault("S3_SESSION_TOKEN", "")
+	cfg.S3UsePathStyle = getBool("S3_USE_PATH_STYLE", false)
 	
 	cfg.ServiceBusNamespace = source.GetWithDefault("SERVICE_BUS_NAMESPACE", "")
 	cfg.ServiceBusKeyName = source.GetWithDefault("SERVICE_BUS_KEY_NAME", "")
@@ -165,6 +273,27 @@ func LoadConfig(source ConfigSource) (*Config, error) {
 	cfg.HTTPWriteTimeout = getInt("HTTP_WRITE_TIMEOUT", 30)
 	cfg.HTTPIdleTimeout = getInt("HTTP_IDLE_TIMEOUT", 120)
 	
+	if policies := source.GetWithDefault("RATE_LIMIT_POLICIES", ""); policies != "" {
+		if err := json.Unmarshal([]byte(policies), &cfg.RateLimitPolicies); err != nil {
+			return nil, fmt.Errorf("invalid RATE_LIMIT_POLICIES: %w", err)
+		}
+	}
+	if costs := source.GetWithDefault("RATE_LIMIT_COSTS", ""); costs != "" {
+		if err := json.Unmarshal([]byte(costs), &cfg.RateLimitCosts); err != nil {
+			return nil, fmt.Errorf("invalid RATE_LIMIT_COSTS: %w", err)
+		}
+	}
+	for _, role := range strings.Split(source.GetWithDefault("RATE_LIMIT_EXEMPT_ROLES", "service"), ",") {
+		if role = strings.TrimSpace(role); role != "" {
+			cfg.RateLimitExemptRoles = append(cfg.RateLimitExemptRoles, role)
+		}
+	}
+	cfg.RateLimitStore = source.GetWithDefault("RATE_LIMIT_STORE", "memory")
+	
+	cfg.JWTSecretKey = source.GetWithDefault("JWT_SECRET_KEY", "")
+	cfg.JWTAccessTokenExpiry = getInt("JWT_ACCESS_TOKEN_EXPIRY", 15)
+	cfg.JWTRefreshTokenExpiry = getInt("JWT_REFRESH_TOKEN_EXPIRY", 168)
+	
 	cfg.LogLevel = source.GetWithDefault("LOG_LEVEL", "info")
 	cfg.LogFormat = source.GetWithDefault("LOG_FORMAT", "json")
 	
@@ -176,6 +305,22 @@ func LoadConfig(source ConfigSource) (*Config, error) {
 	cfg.RetryInitialDelay = getInt("RETRY_INITIAL_DELAY", 100)
 	cfg.RetryMaxDelay = getInt("RETRY_MAX_DELAY", 5000)
 	
+	cfg.DatabaseURL = source.GetWithDefault("DATABASE_URL", "")
+	for _, replicaURL := range strings.Split(source.GetWithDefault("DATABASE_REPLICA_URLS", ""), ",") {
+		if replicaURL = strings.TrimSpace(replicaURL); replicaURL != "" {
+			cfg.DatabaseReplicaURLs = append(cfg.DatabaseReplicaURLs, replicaURL)
+		}
+	}
+	cfg.DBMaxOpenConns = getInt("DB_MAX_OPEN_CONNS", 25)
+	cfg.DBMaxIdleConns = getInt("DB_MAX_IDLE_CONNS", 5)
+	cfg.DBConnMaxLifetime = getInt("DB_CONN_MAX_LIFETIME", 1800)
+	cfg.DBConnMaxIdleTime = getInt("DB_CONN_MAX_IDLE_TIME", 300)
+	cfg.DBStatementTimeout = getInt("DB_STATEMENT_TIMEOUT", 0)
+	cfg.DBApplicationName = source.GetWithDefault("DB_APPLICATION_NAME", "")
+	cfg.DBConnectMaxAttempts = getInt("DB_CONNECT_MAX_ATTEMPTS", 10)
+	cfg.DBStatsInterval = getInt("DB_STATS_INTERVAL", 30)
+	cfg.DBSlowQueryThreshold = getInt("DB_SLOW_QUERY_THRESHOLD", 500)
+	
 	return cfg, nil
 }
 
@@ -0,0 +1,124 @@
+package db
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yourorg/go-service-kit/pkg/config"
+	"github.com/yourorg/go-service-kit/pkg/utils"
+)
+
+// PostgresConfig holds connection pool and startup settings for PostgreSQL.
+type PostgresConfig struct {
+	// ConnectionString is the PostgreSQL URL or key=value connection string.
+	ConnectionString string
+
+	// Pool settings (see database/sql.DB for semantics)
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+
+	// StatementTimeout aborts any statement running longer than this (0 disables).
+	StatementTimeout time.Duration
+	// ApplicationName is reported in pg_stat_activity.
+	ApplicationName string
+
+	// ConnectTimeout bounds each startup ping attempt.
+	ConnectTimeout time.Duration
+	// ConnectRetry controls how long startup waits for the database to become reachable.
+	ConnectRetry utils.RetryConfig
+
+	// StatsInterval is how often pool statistics are reported (0 disables).
+	StatsInterval time.Duration
+}
+
+// DefaultPostgresConfig returns a PostgresConfig with sensible pool and retry defaults.
+func DefaultPostgresConfig(connectionString string) PostgresConfig {
+	return PostgresConfig{
+		ConnectionString: connectionString,
+		MaxOpenConns:     25,
+		MaxIdleConns:     5,
+		ConnMaxLifetime:  30 * time.Minute,
+		ConnMaxIdleTime:  5 * time.Minute,
+		ConnectTimeout:   5 * time.Second,
+		ConnectRetry: utils.RetryConfig{
+			MaxAttempts:  10,
+			InitialDelay: 500 * time.Millisecond,
+			MaxDelay:     10 * time.Second,
+			Multiplier:   2.0,
+			Jitter:       utils.JitterEqual, // replicas starting together do not reconnect in lockstep
+		},
+		StatsInterval: 30 * time.Second,
+	}
+}
+
+// PostgresConfigFromConfig builds a PostgresConfig from the application configuration.
+func PostgresConfigFromConfig(cfg *config.Config) PostgresConfig {
+	pgCfg := DefaultPostgresConfig(cfg.DatabaseURL)
+
+	pgCfg.MaxOpenConns = cfg.DBMaxOpenConns
+	pgCfg.MaxIdleConns = cfg.DBMaxIdleConns
+	pgCfg.ConnMaxLifetime = time.Duration(cfg.DBConnMaxLifetime) * time.Second
+	pgCfg.ConnMaxIdleTime = time.Duration(cfg.DBConnMaxIdleTime) * time.Second
+	pgCfg.StatementTimeout = time.Duration(cfg.DBStatementTimeout) * time.Millisecond
+	pgCfg.StatsInterval = time.Duration(cfg.DBStatsInterval) * time.Second
+
+	pgCfg.ApplicationName = cfg.DBApplicationName
+	if pgCfg.ApplicationName == "" {
+		pgCfg.ApplicationName = cfg.AppName
+	}
+
+	if cfg.DBConnectMaxAttempts > 0 {
+		pgCfg.ConnectRetry.MaxAttempts = cfg.DBConnectMaxAttempts
+	}
+
+	return pgCfg
+}
+
+// dsn returns the connection string with runtime parameters applied.
+// lib/pq forwards unknown connection parameters to the server as run-time settings,
+// so statement_timeout and application_name can be set per connection this way.
+func (c PostgresConfig) dsn() (string, error) {
+	var params [][2]string
+	if c.ApplicationName != "" {
+		params = append(params, [2]string{"application_name", c.ApplicationName})
+	}
+	if c.StatementTimeout > 0 {
+		params = append(params, [2]string{"statement_timeout", strconv.FormatInt(c.StatementTimeout.Milliseconds(), 10)})
+	}
+
+	if len(params) == 0 {
+		return c.ConnectionString, nil
+	}
+
+	if strings.HasPrefix(c.ConnectionString, "postgres://") || strings.HasPrefix(c.ConnectionString, "postgresql://") {
+		u, err := url.Parse(c.ConnectionString)
+		if err != nil {
+			return "", fmt.Errorf("invalid connection string: %w", err)
+		}
+		q := u.Query()
+		for _, param := range params {
+			// Explicit values in the connection string win
+			if q.Get(param[0]) == "" {
+				q.Set(param[0], param[1])
+			}
+		}
+		u.RawQuery = q.Encode()
+		return u.String(), nil
+	}
+
+	// key=value form
+	dsn := c.ConnectionString
+	for _, param := range params {
+		if strings.Contains(dsn, param[0]+"=") {
+			continue
+		}
+		escaped := strings.ReplaceAll(strings.ReplaceAll(param[1], `\`, `\\`), `'`, `\'`)
+		dsn = strings.TrimSpace(dsn + fmt.Sprintf(" %s='%s'", param[0], escaped))
+	}
+	return dsn, nil
+}
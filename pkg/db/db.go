@@ -3,8 +3,11 @@ package db
 import (
 	"context"
 	"database/sql"
+	"fmt"
 
 	_ "github.com/lib/pq" // PostgreSQL driver
+	"github.com/yourorg/go-service-kit/pkg/logging"
+	"github.com/yourorg/go-service-kit/pkg/utils"
 )
 
 // DB defines the interface for database operations.
@@ -77,6 +80,65 @@ func NewPostgresDB(connectionString string) (*PostgresDB, error) {
 	return &PostgresDB{db: db}, nil
 }
 
+// NewPostgresDBWithConfig creates a PostgreSQL connection pool from cfg.
+// The pool settings are applied before connecting, and the initial ping is retried
+// with cfg.ConnectRetry so a database that is briefly unavailable at startup
+// does not crash the service.
+func NewPostgresDBWithConfig(ctx context.Context, cfg PostgresConfig, logger logging.Logger) (*PostgresDB, error) {
+	if logger == nil {
+		return nil, fmt.Errorf("logger is required")
+	}
+
+	dsn, err := cfg.dsn()
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+
+	attempt := 0
+	err = utils.Retry(ctx, cfg.ConnectRetry, func() error {
+		attempt++
+
+		pingCtx := ctx
+		if cfg.ConnectTimeout > 0 {
+			var cancel context.CancelFunc
+			pingCtx, cancel = context.WithTimeout(ctx, cfg.ConnectTimeout)
+			defer cancel()
+		}
+
+		if err := db.PingContext(pingCtx); err != nil {
+			logger.Warn("Database not reachable, retrying",
+				logging.NewField("attempt", attempt),
+				logging.NewField("max_attempts", cfg.ConnectRetry.MaxAttempts),
+				logging.NewField("error", err),
+			)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+
+	logger.Info("Database connection established",
+		logging.NewField("attempts", attempt),
+		logging.NewField("max_open_conns", cfg.MaxOpenConns),
+		logging.NewField("max_idle_conns", cfg.MaxIdleConns),
+	)
+
+	return &PostgresDB{db: db}, nil
+}
+
 // Exec executes a query without returning rows.
 func (p *PostgresDB) Exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
 	return p.db.ExecContext(ctx, query, args...)
@@ -116,6 +178,11 @@ func (p *PostgresDB) Ping(ctx context.Context) error {
 	return p.db.PingContext(ctx)
 }
 
+// Stats returns connection pool statistics.
+func (p *PostgresDB) Stats() sql.DBStats {
+	return p.db.Stats()
+}
+
 // PostgresTx implements Tx for PostgreSQL.
 type PostgresTx struct {
 	tx *sql.Tx
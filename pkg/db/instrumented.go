@@ -0,0 +1,315 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yourorg/go-service-kit/pkg/logging"
+)
+
+// Context keys populated by middleware.TracingMiddleware and middleware.ServiceRequestIDMiddleware.
+const (
+	traceIDContextKey   = "trace_id"
+	requestIDContextKey = "request_id"
+)
+
+// maxTrackedStatements caps the number of distinct statements kept in memory.
+// Further statements are aggregated under "other".
+const maxTrackedStatements = 1000
+
+// SlowQueryRecorder receives slow query alerts.
+// Implemented by telemetry.NewRelicClient (see middleware.TelemetryClient).
+type SlowQueryRecorder interface {
+	RecordSlowRequest(ctx interface{}, path string, durationMs int64, traceID, requestID string)
+}
+
+// InstrumentationConfig configures query instrumentation.
+type InstrumentationConfig struct {
+	// SlowQueryThreshold is the duration above which a query is logged and alerted (default: 500ms).
+	SlowQueryThreshold time.Duration
+	// AnnotateQueries prefixes each query with a SQL comment carrying the trace and request IDs,
+	// so they show up in pg_stat_activity and the Postgres slow query log.
+	AnnotateQueries bool
+	// Telemetry receives slow query alerts (optional).
+	Telemetry SlowQueryRecorder
+}
+
+// StatementStats holds aggregated metrics for one normalised statement.
+type StatementStats struct {
+	Statement     string
+	Count         int64
+	Errors        int64
+	TotalDuration time.Duration
+	MaxDuration   time.Duration
+}
+
+// AvgDuration returns the mean duration of the statement.
+func (s StatementStats) AvgDuration() time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+	return s.TotalDuration / time.Duration(s.Count)
+}
+
+// InstrumentedDB is a DB decorator that records per-statement metrics, logs slow queries
+// with the contextual logger and annotates queries with trace and request IDs.
+// Query arguments are never logged.
+type InstrumentedDB struct {
+	db     DB
+	config InstrumentationConfig
+	logger logging.Logger
+
+	mu    sync.Mutex
+	stats map[string]*StatementStats
+}
+
+// NewInstrumentedDB wraps db with query instrumentation.
+// logger is used when the request context carries no contextual logger.
+func NewInstrumentedDB(db DB, config InstrumentationConfig, logger logging.Logger) (*InstrumentedDB, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database is required")
+	}
+	if logger == nil {
+		return nil, fmt.Errorf("logger is required")
+	}
+	if config.SlowQueryThreshold <= 0 {
+		config.SlowQueryThreshold = 500 * time.Millisecond
+	}
+
+	return &InstrumentedDB{
+		db:     db,
+		config: config,
+		logger: logger,
+		stats:  make(map[string]*StatementStats),
+	}, nil
+}
+
+// Exec executes a query without returning rows.
+func (i *InstrumentedDB) Exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	start := time.Now()
+	result, err := i.db.Exec(ctx, i.annotate(ctx, query), args...)
+	i.observe(ctx, query, len(args), time.Since(start), err)
+	return result, err
+}
+
+// Query executes a query that returns rows.
+func (i *InstrumentedDB) Query(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	start := time.Now()
+	rows, err := i.db.Query(ctx, i.annotate(ctx, query), args...)
+	i.observe(ctx, query, len(args), time.Since(start), err)
+	return rows, err
+}
+
+// QueryRow executes a query that returns a single row.
+func (i *InstrumentedDB) QueryRow(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	start := time.Now()
+	row := i.db.QueryRow(ctx, i.annotate(ctx, query), args...)
+	i.observe(ctx, query, len(args), time.Since(start), rowErr(row))
+	return row
+}
+
+// Prepare creates a prepared statement. Executions of the statement are not instrumented.
+func (i *InstrumentedDB) Prepare(ctx context.Context, query string) (*sql.Stmt, error) {
+	return i.db.Prepare(ctx, query)
+}
+
+// BeginTx starts a transaction whose statements are instrumented.
+func (i *InstrumentedDB) BeginTx(ctx context.Context, opts *sql.TxOptions) (Tx, error) {
+	tx, err := i.db.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &instrumentedTx{tx: tx, parent: i}, nil
+}
+
+// Close closes the underlying database.
+func (i *InstrumentedDB) Close() error {
+	return i.db.Close()
+}
+
+// Ping checks the underlying database connection.
+func (i *InstrumentedDB) Ping(ctx context.Context) error {
+	return i.db.Ping(ctx)
+}
+
+// Stats returns a snapshot of per-statement metrics, slowest total time first.
+func (i *InstrumentedDB) Stats() []StatementStats {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	snapshot := make([]StatementStats, 0, len(i.stats))
+	for _, s := range i.stats {
+		snapshot = append(snapshot, *s)
+	}
+	sort.Slice(snapshot, func(a, b int) bool {
+		return snapshot[a].TotalDuration > snapshot[b].TotalDuration
+	})
+	return snapshot
+}
+
+// ResetStats clears the collected per-statement metrics.
+func (i *InstrumentedDB) ResetStats() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.stats = make(map[string]*StatementStats)
+}
+
+// observe records metrics for a completed statement and reports it if slow.
+func (i *InstrumentedDB) observe(ctx context.Context, query string, argCount int, duration time.Duration, err error) {
+	statement := NormalizeQuery(query)
+
+	i.mu.Lock()
+	s, ok := i.stats[statement]
+	if !ok {
+		key := statement
+		if len(i.stats) >= maxTrackedStatements {
+			key = "other"
+		}
+		if s, ok = i.stats[key]; !ok {
+			s = &StatementStats{Statement: key}
+			i.stats[key] = s
+		}
+	}
+	s.Count++
+	s.TotalDuration += duration
+	if duration > s.MaxDuration {
+		s.MaxDuration = duration
+	}
+	if err != nil && err != sql.ErrNoRows {
+		s.Errors++
+	}
+	i.mu.Unlock()
+
+	if duration < i.config.SlowQueryThreshold {
+		return
+	}
+
+	traceID := contextString(ctx, traceIDContextKey)
+	requestID := contextString(ctx, requestIDContextKey)
+
+	logging.FromContextOrDefault(ctx, i.logger).Warn("Slow query detected",
+		logging.NewField("statement", statement),
+		logging.NewField("duration_ms", duration.Milliseconds()),
+		logging.NewField("threshold_ms", i.config.SlowQueryThreshold.Milliseconds()),
+		logging.NewField("args", fmt.Sprintf("[%d redacted]", argCount)),
+	)
+
+	if i.config.Telemetry != nil {
+		i.config.Telemetry.RecordSlowRequest(ctx, "SQL "+statement, duration.Milliseconds(), traceID, requestID)
+	}
+}
+
+// annotate prefixes query with a comment carrying the trace and request IDs.
+func (i *InstrumentedDB) annotate(ctx context.Context, query string) string {
+	if !i.config.AnnotateQueries {
+		return query
+	}
+
+	var parts []string
+	if traceID := sanitizeCommentValue(contextString(ctx, traceIDContextKey)); traceID != "" {
+		parts = append(parts, "trace_id='"+traceID+"'")
+	}
+	if requestID := sanitizeCommentValue(contextString(ctx, requestIDContextKey)); requestID != "" {
+		parts = append(parts, "request_id='"+requestID+"'")
+	}
+	if len(parts) == 0 {
+		return query
+	}
+
+	return "/* " + strings.Join(parts, ",") + " */ " + query
+}
+
+// instrumentedTx is a Tx decorator sharing its parent's instrumentation.
+type instrumentedTx struct {
+	tx     Tx
+	parent *InstrumentedDB
+}
+
+// Exec executes a query within the transaction.
+func (t *instrumentedTx) Exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	start := time.Now()
+	result, err := t.tx.Exec(ctx, t.parent.annotate(ctx, query), args...)
+	t.parent.observe(ctx, query, len(args), time.Since(start), err)
+	return result, err
+}
+
+// Query executes a query that returns rows within the transaction.
+func (t *instrumentedTx) Query(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	start := time.Now()
+	rows, err := t.tx.Query(ctx, t.parent.annotate(ctx, query), args...)
+	t.parent.observe(ctx, query, len(args), time.Since(start), err)
+	return rows, err
+}
+
+// QueryRow executes a query that returns a single row within the transaction.
+func (t *instrumentedTx) QueryRow(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	start := time.Now()
+	row := t.tx.QueryRow(ctx, t.parent.annotate(ctx, query), args...)
+	t.parent.observe(ctx, query, len(args), time.Since(start), rowErr(row))
+	return row
+}
+
+// Prepare creates a prepared statement for use within the transaction.
+func (t *instrumentedTx) Prepare(ctx context.Context, query string) (*sql.Stmt, error) {
+	return t.tx.Prepare(ctx, query)
+}
+
+// Commit commits the transaction.
+func (t *instrumentedTx) Commit() error {
+	return t.tx.Commit()
+}
+
+// Rollback rolls back the transaction.
+func (t *instrumentedTx) Rollback() error {
+	return t.tx.Rollback()
+}
+
+var (
+	stringLiteralPattern = regexp.MustCompile(`'(?:[^']|'')*'`)
+	numberLiteralPattern = regexp.MustCompile(`\b\d+(?:\.\d+)?\b`)
+	placeholderPattern   = regexp.MustCompile(`\$\d+`)
+	inListPattern        = regexp.MustCompile(`(?i)\bIN\s*\(\s*\?(?:\s*,\s*\?)*\s*\)`)
+	commentPattern       = regexp.MustCompile(`/\*.*?\*/|--[^\n]*`)
+	whitespacePattern    = regexp.MustCompile(`\s+`)
+	commentValuePattern  = regexp.MustCompile(`[^A-Za-z0-9._:-]`)
+)
+
+// NormalizeQuery reduces a query to a statement fingerprint by stripping comments,
+// replacing literals and placeholders with '?', collapsing IN lists and whitespace.
+// Queries differing only in their parameters share a fingerprint.
+func NormalizeQuery(query string) string {
+	normalized := commentPattern.ReplaceAllString(query, " ")
+	normalized = stringLiteralPattern.ReplaceAllString(normalized, "?")
+	normalized = placeholderPattern.ReplaceAllString(normalized, "?")
+	normalized = numberLiteralPattern.ReplaceAllString(normalized, "?")
+	normalized = inListPattern.ReplaceAllString(normalized, "IN (?)")
+	normalized = whitespacePattern.ReplaceAllString(normalized, " ")
+	return strings.TrimSpace(normalized)
+}
+
+// contextString reads a string value stored under a plain string context key.
+func contextString(ctx context.Context, key string) string {
+	if value, ok := ctx.Value(key).(string); ok {
+		return value
+	}
+	return ""
+}
+
+// sanitizeCommentValue strips characters that could terminate or escape a SQL comment.
+func sanitizeCommentValue(value string) string {
+	return commentValuePattern.ReplaceAllString(value, "")
+}
+
+// rowErr returns the error of a QueryRow result, if already known.
+func rowErr(row *sql.Row) error {
+	if row == nil {
+		return nil
+	}
+	return row.Err()
+}
@@ -0,0 +1,101 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourorg/go-service-kit/pkg/logging"
+)
+
+func TestNormalizeQuery(t *testing.T) {
+	tests := []struct {
+		query    string
+		expected string
+	}{
+		{"SELECT * FROM users WHERE id = $1", "SELECT * FROM users WHERE id = ?"},
+		{"SELECT *\n  FROM users\n WHERE name = 'O''Brien' AND age > 30", "SELECT * FROM users WHERE name = ? AND age > ?"},
+		{"DELETE FROM jobs WHERE id IN (1, 2, 3)", "DELETE FROM jobs WHERE id IN (?)"},
+		{"/* trace_id='abc' */ SELECT 1 -- note", "SELECT ?"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, NormalizeQuery(tt.query))
+	}
+}
+
+func TestInstrumentedDB_Annotate(t *testing.T) {
+	logger, _ := logging.NewLogger("error", "json")
+	idb, err := NewInstrumentedDB(&stubDB{}, InstrumentationConfig{AnnotateQueries: true}, logger)
+	require.NoError(t, err)
+
+	ctx := context.WithValue(context.Background(), "trace_id", "abc-123")
+	ctx = context.WithValue(ctx, "request_id", "req*/; DROP")
+
+	assert.Equal(t, "/* trace_id='abc-123',request_id='reqDROP' */ SELECT 1", idb.annotate(ctx, "SELECT 1"))
+	assert.Equal(t, "SELECT 1", idb.annotate(context.Background(), "SELECT 1"))
+}
+
+func TestInstrumentedDB_Stats(t *testing.T) {
+	logger, _ := logging.NewLogger("error", "json")
+	idb, err := NewInstrumentedDB(&stubDB{}, InstrumentationConfig{}, logger)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	_, _ = idb.Exec(ctx, "UPDATE users SET name = $1 WHERE id = $2", "a", 1)
+	_, _ = idb.Exec(ctx, "UPDATE users SET name = $1 WHERE id = $2", "b", 2)
+
+	stats := idb.Stats()
+	assert.Len(t, stats, 1)
+	assert.Equal(t, "UPDATE users SET name = ? WHERE id = ?", stats[0].Statement)
+	assert.Equal(t, int64(2), stats[0].Count)
+}
+
+// slowDB delays every Exec past the slow query threshold.
+type slowDB struct {
+	stubDB
+	delay time.Duration
+}
+
+func (s *slowDB) Exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	time.Sleep(s.delay)
+	return s.stubDB.Exec(ctx, query, args...)
+}
+
+// slowQueryRecorder records the slow query alerts it receives.
+type slowQueryRecorder struct {
+	paths      []string
+	traceIDs   []string
+	requestIDs []string
+}
+
+func (r *slowQueryRecorder) RecordSlowRequest(ctx interface{}, path string, durationMs int64, traceID, requestID string) {
+	r.paths = append(r.paths, path)
+	r.traceIDs = append(r.traceIDs, traceID)
+	r.requestIDs = append(r.requestIDs, requestID)
+}
+
+func TestInstrumentedDB_SlowQuery(t *testing.T) {
+	_, err := NewInstrumentedDB(&stubDB{}, InstrumentationConfig{}, nil)
+	assert.Error(t, err)
+
+	logger, _ := logging.NewLogger("error", "json")
+	recorder := &slowQueryRecorder{}
+	idb, err := NewInstrumentedDB(&slowDB{delay: 5 * time.Millisecond}, InstrumentationConfig{
+		SlowQueryThreshold: time.Millisecond,
+		Telemetry:          recorder,
+	}, logger)
+	require.NoError(t, err)
+
+	// No contextual logger: the slow query is logged with the fallback logger
+	ctx := context.WithValue(context.Background(), "trace_id", "trace-1")
+	ctx = context.WithValue(ctx, "request_id", "req-1")
+	_, _ = idb.Exec(ctx, "DELETE FROM sessions WHERE expires_at < $1", time.Now())
+
+	assert.Equal(t, []string{"SQL DELETE FROM sessions WHERE expires_at < ?"}, recorder.paths)
+	assert.Equal(t, []string{"trace-1"}, recorder.traceIDs)
+	assert.Equal(t, []string{"req-1"}, recorder.requestIDs)
+}
@@ -0,0 +1,481 @@
+package db
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yourorg/go-service-kit/pkg/logging"
+)
+
+var (
+	// ErrChecksumMismatch is returned when an applied migration file was modified after it ran.
+	ErrChecksumMismatch = errors.New("applied migration checksum mismatch")
+	// ErrMissingDownMigration is returned when rolling back a migration without a .down.sql file.
+	ErrMissingDownMigration = errors.New("down migration not found")
+)
+
+// migrationFilePattern matches files like "0001_create_users.up.sql".
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// tableNamePattern matches plain and schema-qualified table names.
+var tableNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*$`)
+
+// ValidateTableName returns an error unless name is a plain or schema-qualified table
+// name. Stores that take their table name from configuration check it with this before
+// interpolating it into SQL.
+func ValidateTableName(name string) error {
+	if !tableNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid table name: %q", name)
+	}
+	return nil
+}
+
+// MigratorConfig configures the migration runner.
+type MigratorConfig struct {
+	// Dir is the directory inside the filesystem containing the migration files (default: ".").
+	Dir string
+	// Table is the table that records applied versions (default: "schema_migrations").
+	Table string
+	// LockID is the Postgres advisory lock key used to serialize migrations across replicas.
+	LockID int64
+}
+
+// DefaultMigratorConfig returns the default migrator configuration.
+func DefaultMigratorConfig() MigratorConfig {
+	return MigratorConfig{
+		Dir:    ".",
+		Table:  "schema_migrations",
+		LockID: 7249001, // arbitrary, shared by every replica of every service using this kit
+	}
+}
+
+// Migration is a versioned schema change loaded from the migration filesystem.
+type Migration struct {
+	Version  int64
+	Name     string
+	UpSQL    string
+	DownSQL  string
+	Checksum string // SHA-256 of UpSQL
+}
+
+// MigrationStatus describes the state of a single migration.
+type MigrationStatus struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+	// Drifted is true when the applied checksum differs from the current file.
+	Drifted bool
+	// Missing is true when the version is recorded as applied but has no file.
+	Missing bool
+}
+
+// Migrator applies versioned SQL migrations from an fs.FS (typically an embed.FS).
+//
+// Each migration runs in its own transaction which first takes a transaction-scoped
+// Postgres advisory lock, so concurrent replicas apply every migration exactly once.
+type Migrator struct {
+	db     DB
+	fsys   fs.FS
+	cfg    MigratorConfig
+	logger logging.Logger
+}
+
+// NewMigrator creates a migration runner.
+func NewMigrator(database DB, fsys fs.FS, cfg MigratorConfig, logger logging.Logger) (*Migrator, error) {
+	if database == nil {
+		return nil, fmt.Errorf("database is required")
+	}
+	if fsys == nil {
+		return nil, fmt.Errorf("migration filesystem is required")
+	}
+	if logger == nil {
+		return nil, fmt.Errorf("logger is required")
+	}
+
+	defaults := DefaultMigratorConfig()
+	if cfg.Dir == "" {
+		cfg.Dir = defaults.Dir
+	}
+	if cfg.Table == "" {
+		cfg.Table = defaults.Table
+	}
+	if cfg.LockID == 0 {
+		cfg.LockID = defaults.LockID
+	}
+	if err := ValidateTableName(cfg.Table); err != nil {
+		return nil, fmt.Errorf("invalid migrations table: %w", err)
+	}
+
+	return &Migrator{
+		db:     database,
+		fsys:   fsys,
+		cfg:    cfg,
+		logger: logger,
+	}, nil
+}
+
+// Migrations loads and validates all migrations, sorted by version.
+func (m *Migrator) Migrations() ([]Migration, error) {
+	entries, err := fs.ReadDir(m.fsys, m.cfg.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations directory: %w", err)
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		match := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %s: %w", entry.Name(), err)
+		}
+
+		content, err := fs.ReadFile(m.fsys, path.Join(m.cfg.Dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+
+		mig, exists := byVersion[version]
+		if !exists {
+			mig = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = mig
+		} else if mig.Name != match[2] {
+			return nil, fmt.Errorf("conflicting names for migration version %d: %s and %s", version, mig.Name, match[2])
+		}
+
+		switch match[3] {
+		case "up":
+			mig.UpSQL = string(content)
+			sum := sha256.Sum256(content)
+			mig.Checksum = hex.EncodeToString(sum[:])
+		case "down":
+			mig.DownSQL = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if mig.UpSQL == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", mig.Version, mig.Name)
+		}
+		migrations = append(migrations, *mig)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// appliedMigration is a row of the migrations table.
+type appliedMigration struct {
+	version   int64
+	name      string
+	checksum  string
+	appliedAt time.Time
+}
+
+// Up applies all pending migrations in version order.
+// It refuses to run if any applied migration has been modified since it ran.
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	migrations, err := m.Migrations()
+	if err != nil {
+		return 0, err
+	}
+
+	if err := m.ensureTable(ctx); err != nil {
+		return 0, err
+	}
+
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if err := verifyChecksums(migrations, applied); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, mig := range migrations {
+		if _, done := applied[mig.Version]; done {
+			continue
+		}
+
+		ran, err := m.apply(ctx, mig)
+		if err != nil {
+			return count, err
+		}
+		if ran {
+			count++
+		}
+	}
+
+	m.logger.Info("Migrations complete", logging.NewField("applied", count))
+	return count, nil
+}
+
+// Down rolls back the most recently applied migrations, up to steps of them.
+func (m *Migrator) Down(ctx context.Context, steps int) (int, error) {
+	if steps <= 0 {
+		return 0, nil
+	}
+
+	migrations, err := m.Migrations()
+	if err != nil {
+		return 0, err
+	}
+	byVersion := make(map[int64]Migration, len(migrations))
+	for _, mig := range migrations {
+		byVersion[mig.Version] = mig
+	}
+
+	if err := m.ensureTable(ctx); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for count < steps {
+		rolledBack, err := m.rollbackLatest(ctx, byVersion)
+		if err != nil {
+			return count, err
+		}
+		if !rolledBack {
+			break
+		}
+		count++
+	}
+
+	m.logger.Info("Rollback complete", logging.NewField("rolled_back", count))
+	return count, nil
+}
+
+// Status reports every known migration and whether it has been applied.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	migrations, err := m.Migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.ensureTable(ctx); err != nil {
+		return nil, err
+	}
+
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	seen := make(map[int64]bool, len(migrations))
+	for _, mig := range migrations {
+		seen[mig.Version] = true
+		status := MigrationStatus{Version: mig.Version, Name: mig.Name}
+		if row, ok := applied[mig.Version]; ok {
+			status.Applied = true
+			status.AppliedAt = row.appliedAt
+			status.Drifted = row.checksum != mig.Checksum
+		}
+		statuses = append(statuses, status)
+	}
+
+	for version, row := range applied {
+		if !seen[version] {
+			statuses = append(statuses, MigrationStatus{
+				Version:   version,
+				Name:      row.name,
+				Applied:   true,
+				AppliedAt: row.appliedAt,
+				Missing:   true,
+			})
+		}
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Version < statuses[j].Version
+	})
+
+	return statuses, nil
+}
+
+// apply runs a single migration under the advisory lock.
+// It returns false if another replica applied the migration while this one waited for the lock.
+func (m *Migrator) apply(ctx context.Context, mig Migration) (bool, error) {
+	logger := m.logger.With(
+		logging.NewField("version", mig.Version),
+		logging.NewField("name", mig.Name),
+	)
+
+	tx, err := m.lockedTx(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	err = tx.QueryRow(ctx, fmt.Sprintf("SELECT EXISTS (SELECT 1 FROM %s WHERE version = $1)", m.cfg.Table), mig.Version).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check migration %d: %w", mig.Version, err)
+	}
+	if exists {
+		logger.Debug("Migration already applied by another instance")
+		return false, nil
+	}
+
+	logger.Info("Applying migration")
+	start := time.Now()
+
+	if _, err := tx.Exec(ctx, mig.UpSQL); err != nil {
+		logger.Error("Migration failed", logging.NewField("error", err))
+		return false, fmt.Errorf("migration %d_%s failed: %w", mig.Version, mig.Name, err)
+	}
+
+	_, err = tx.Exec(ctx,
+		fmt.Sprintf("INSERT INTO %s (version, name, checksum, applied_at) VALUES ($1, $2, $3, $4)", m.cfg.Table),
+		mig.Version, mig.Name, mig.Checksum, time.Now().UTC(),
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to record migration %d: %w", mig.Version, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit migration %d: %w", mig.Version, err)
+	}
+
+	logger.Info("Migration applied", logging.NewField("duration_ms", time.Since(start).Milliseconds()))
+	return true, nil
+}
+
+// rollbackLatest reverts the most recently applied migration under the advisory lock.
+// It returns false if nothing is left to roll back.
+func (m *Migrator) rollbackLatest(ctx context.Context, byVersion map[int64]Migration) (bool, error) {
+	tx, err := m.lockedTx(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var version int64
+	err = tx.QueryRow(ctx, fmt.Sprintf("SELECT version FROM %s ORDER BY version DESC LIMIT 1", m.cfg.Table)).Scan(&version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to find latest migration: %w", err)
+	}
+
+	mig, ok := byVersion[version]
+	if !ok || mig.DownSQL == "" {
+		return false, fmt.Errorf("%w: version %d", ErrMissingDownMigration, version)
+	}
+
+	logger := m.logger.With(
+		logging.NewField("version", mig.Version),
+		logging.NewField("name", mig.Name),
+	)
+	logger.Info("Rolling back migration")
+
+	if _, err := tx.Exec(ctx, mig.DownSQL); err != nil {
+		logger.Error("Rollback failed", logging.NewField("error", err))
+		return false, fmt.Errorf("rollback of %d_%s failed: %w", mig.Version, mig.Name, err)
+	}
+
+	if _, err := tx.Exec(ctx, fmt.Sprintf("DELETE FROM %s WHERE version = $1", m.cfg.Table), version); err != nil {
+		return false, fmt.Errorf("failed to unrecord migration %d: %w", version, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit rollback of %d: %w", version, err)
+	}
+
+	logger.Info("Migration rolled back")
+	return true, nil
+}
+
+// ensureTable creates the migrations table if it does not exist.
+func (m *Migrator) ensureTable(ctx context.Context) error {
+	tx, err := m.lockedTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	version BIGINT PRIMARY KEY,
+	name TEXT NOT NULL,
+	checksum TEXT NOT NULL,
+	applied_at TIMESTAMPTZ NOT NULL
+)`, m.cfg.Table))
+	if err != nil {
+		return fmt.Errorf("failed to create migrations table: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// lockedTx begins a transaction holding the migration advisory lock.
+// The lock is released automatically when the transaction ends.
+func (m *Migrator) lockedTx(ctx context.Context) (Tx, error) {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin migration transaction: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock($1)", m.cfg.LockID); err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+
+	return tx, nil
+}
+
+// applied returns the recorded migrations keyed by version.
+func (m *Migrator) applied(ctx context.Context) (map[int64]appliedMigration, error) {
+	rows, err := m.db.Query(ctx, fmt.Sprintf("SELECT version, name, checksum, applied_at FROM %s", m.cfg.Table))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]appliedMigration)
+	for rows.Next() {
+		var row appliedMigration
+		if err := rows.Scan(&row.version, &row.name, &row.checksum, &row.appliedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan applied migration: %w", err)
+		}
+		applied[row.version] = row
+	}
+
+	return applied, rows.Err()
+}
+
+// verifyChecksums ensures no applied migration has been edited since it ran.
+func verifyChecksums(migrations []Migration, applied map[int64]appliedMigration) error {
+	var drifted []string
+	for _, mig := range migrations {
+		if row, ok := applied[mig.Version]; ok && row.checksum != mig.Checksum {
+			drifted = append(drifted, fmt.Sprintf("%d_%s", mig.Version, mig.Name))
+		}
+	}
+
+	if len(drifted) > 0 {
+		return fmt.Errorf("%w: %s", ErrChecksumMismatch, strings.Join(drifted, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+package db
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourorg/go-service-kit/pkg/logging"
+)
+
+func TestMigrator_Migrations(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/0002_add_email.up.sql":      {Data: []byte("ALTER TABLE users ADD COLUMN email TEXT;")},
+		"migrations/0002_add_email.down.sql":    {Data: []byte("ALTER TABLE users DROP COLUMN email;")},
+		"migrations/0001_create_users.up.sql":   {Data: []byte("CREATE TABLE users (id TEXT PRIMARY KEY);")},
+		"migrations/0001_create_users.down.sql": {Data: []byte("DROP TABLE users;")},
+		"migrations/README.md":                  {Data: []byte("ignored")},
+	}
+
+	logger, _ := logging.NewLogger("info", "json")
+	migrator, err := NewMigrator(&PostgresDB{}, fsys, MigratorConfig{Dir: "migrations"}, logger)
+	require.NoError(t, err)
+
+	migrations, err := migrator.Migrations()
+	require.NoError(t, err)
+	require.Len(t, migrations, 2)
+
+	assert.Equal(t, int64(1), migrations[0].Version)
+	assert.Equal(t, "create_users", migrations[0].Name)
+	assert.Equal(t, "DROP TABLE users;", migrations[0].DownSQL)
+	assert.Len(t, migrations[0].Checksum, 64)
+	assert.Equal(t, int64(2), migrations[1].Version)
+}
+
+func TestMigrator_MigrationsRequiresUpFile(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0001_create_users.down.sql": {Data: []byte("DROP TABLE users;")},
+	}
+
+	logger, _ := logging.NewLogger("info", "json")
+	migrator, err := NewMigrator(&PostgresDB{}, fsys, MigratorConfig{}, logger)
+	require.NoError(t, err)
+
+	_, err = migrator.Migrations()
+	assert.Error(t, err)
+}
+
+// migrationFS returns two migrations; the second has no down file.
+func migrationFS() fstest.MapFS {
+	return fstest.MapFS{
+		"0001_create_users.up.sql":   {Data: []byte("CREATE TABLE users (id TEXT PRIMARY KEY);")},
+		"0001_create_users.down.sql": {Data: []byte("DROP TABLE users;")},
+		"0002_add_email.up.sql":      {Data: []byte("ALTER TABLE users ADD COLUMN email TEXT;")},
+	}
+}
+
+func checksum(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+func newTestMigrator(t *testing.T, mock *MockDB) *Migrator {
+	logger, _ := logging.NewLogger("error", "json")
+	migrator, err := NewMigrator(mock, migrationFS(), MigratorConfig{}, logger)
+	require.NoError(t, err)
+	return migrator
+}
+
+// expectLockedTx expects a transaction taking the migration advisory lock.
+func expectLockedTx(mock *MockDB) {
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT pg_advisory_xact_lock($1)").WithArgs(DefaultMigratorConfig().LockID)
+}
+
+func expectEnsureTable(mock *MockDB) {
+	expectLockedTx(mock)
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations")
+	mock.ExpectCommit()
+}
+
+func TestMigrator_Up(t *testing.T) {
+	mock := NewMockDB()
+	defer mock.Close()
+	migrator := newTestMigrator(t, mock)
+	appliedAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	expectEnsureTable(mock)
+	mock.ExpectQuery("SELECT version, name, checksum, applied_at FROM schema_migrations").
+		WillReturnRows(NewMockRows("version", "name", "checksum", "applied_at").
+			AddRow(1, "create_users", checksum("CREATE TABLE users (id TEXT PRIMARY KEY);"), appliedAt))
+	expectLockedTx(mock)
+	mock.ExpectQuery("SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1)").WithArgs(2).
+		WillReturnRows(NewMockRows("exists").AddRow(false))
+	mock.ExpectExec("ALTER TABLE users ADD COLUMN email TEXT;")
+	mock.ExpectExec("INSERT INTO schema_migrations (version, name, checksum, applied_at)").
+		WithArgs(2, "add_email", checksum("ALTER TABLE users ADD COLUMN email TEXT;"), AnyArg())
+	mock.ExpectCommit()
+
+	count, err := migrator.Up(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigrator_UpSkipsMigrationAppliedConcurrently(t *testing.T) {
+	mock := NewMockDB()
+	defer mock.Close()
+	migrator := newTestMigrator(t, mock)
+
+	expectEnsureTable(mock)
+	mock.ExpectQuery("SELECT version, name, checksum, applied_at FROM schema_migrations").
+		WillReturnRows(NewMockRows("version", "name", "checksum", "applied_at"))
+	for _, version := range []int{1, 2} {
+		expectLockedTx(mock)
+		mock.ExpectQuery("SELECT EXISTS").WithArgs(version).WillReturnRows(NewMockRows("exists").AddRow(true))
+		mock.ExpectRollback()
+	}
+
+	count, err := migrator.Up(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigrator_UpRejectsChecksumDrift(t *testing.T) {
+	mock := NewMockDB()
+	defer mock.Close()
+	migrator := newTestMigrator(t, mock)
+
+	expectEnsureTable(mock)
+	mock.ExpectQuery("SELECT version, name, checksum, applied_at FROM schema_migrations").
+		WillReturnRows(NewMockRows("version", "name", "checksum", "applied_at").
+			AddRow(1, "create_users", checksum("CREATE TABLE users (id BIGINT);"), time.Now()))
+
+	_, err := migrator.Up(context.Background())
+	assert.ErrorIs(t, err, ErrChecksumMismatch)
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.Equal(t, 1, mock.Begins())
+}
+
+func TestMigrator_Down(t *testing.T) {
+	mock := NewMockDB()
+	defer mock.Close()
+	migrator := newTestMigrator(t, mock)
+
+	expectEnsureTable(mock)
+	expectLockedTx(mock)
+	mock.ExpectQuery("SELECT version FROM schema_migrations ORDER BY version DESC LIMIT 1").
+		WillReturnRows(NewMockRows("version").AddRow(1))
+	mock.ExpectExec("DROP TABLE users;")
+	mock.ExpectExec("DELETE FROM schema_migrations WHERE version = $1").WithArgs(1)
+	mock.ExpectCommit()
+	expectLockedTx(mock)
+	mock.ExpectQuery("SELECT version FROM schema_migrations ORDER BY version DESC LIMIT 1").
+		WillReturnRows(NewMockRows("version"))
+
+	count, err := migrator.Down(context.Background(), 2)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigrator_DownRequiresDownFile(t *testing.T) {
+	mock := NewMockDB()
+	defer mock.Close()
+	migrator := newTestMigrator(t, mock)
+
+	expectEnsureTable(mock)
+	expectLockedTx(mock)
+	mock.ExpectQuery("SELECT version FROM schema_migrations ORDER BY version DESC LIMIT 1").
+		WillReturnRows(NewMockRows("version").AddRow(2))
+	mock.ExpectRollback()
+
+	count, err := migrator.Down(context.Background(), 1)
+	assert.ErrorIs(t, err, ErrMissingDownMigration)
+	assert.Equal(t, 0, count)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigrator_LockFailure(t *testing.T) {
+	mock := NewMockDB()
+	defer mock.Close()
+	migrator := newTestMigrator(t, mock)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT pg_advisory_xact_lock($1)").WillReturnError(errors.New("lock timeout"))
+	mock.ExpectRollback()
+
+	_, err := migrator.Up(context.Background())
+	assert.ErrorContains(t, err, "failed to acquire migration lock")
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.Equal(t, 0, mock.Commits())
+}
@@ -0,0 +1,531 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// MockDB is a scripted, expectation-based implementation of DB for unit tests.
+//
+// Queries are served by an in-process database/sql driver, so callers receive real
+// *sql.Rows and *sql.Row values and scan them exactly as they would with Postgres.
+// Query and exec expectations are matched in the order they were declared.
+// Transaction boundaries (begin, commit, rollback) are always recorded and are
+// verified only when an expectation for them has been declared.
+type MockDB struct {
+	db *sql.DB
+
+	mu           sync.Mutex
+	expectations []*MockExpectation
+	unexpected   []string
+	pingErr      error
+	begins       int
+	commits      int
+	rollbacks    int
+}
+
+// NewMockDB creates a new mock database.
+func NewMockDB() *MockDB {
+	m := &MockDB{}
+	m.db = sql.OpenDB(&mockConnector{mock: m})
+	return m
+}
+
+// mockKind identifies the operation an expectation matches.
+type mockKind string
+
+const (
+	mockKindQuery    mockKind = "query"
+	mockKindExec     mockKind = "exec"
+	mockKindBegin    mockKind = "begin"
+	mockKindCommit   mockKind = "commit"
+	mockKindRollback mockKind = "rollback"
+)
+
+// MockExpectation describes an expected database call and its scripted outcome.
+type MockExpectation struct {
+	kind      mockKind
+	query     string
+	args      []interface{}
+	checkArgs bool
+	rows      *MockRows
+	result    driver.Result
+	err       error
+	met       bool
+}
+
+// ExpectQuery expects a Query or QueryRow call whose whitespace-normalised SQL contains query.
+func (m *MockDB) ExpectQuery(query string) *MockExpectation {
+	return m.expect(&MockExpectation{kind: mockKindQuery, query: collapseWhitespace(query)})
+}
+
+// ExpectExec expects an Exec call whose whitespace-normalised SQL contains query.
+func (m *MockDB) ExpectExec(query string) *MockExpectation {
+	return m.expect(&MockExpectation{kind: mockKindExec, query: collapseWhitespace(query), result: driver.RowsAffected(0)})
+}
+
+// ExpectBegin expects a transaction to be started.
+func (m *MockDB) ExpectBegin() *MockExpectation {
+	return m.expect(&MockExpectation{kind: mockKindBegin})
+}
+
+// ExpectCommit expects a transaction to be committed.
+func (m *MockDB) ExpectCommit() *MockExpectation {
+	return m.expect(&MockExpectation{kind: mockKindCommit})
+}
+
+// ExpectRollback expects a transaction to be rolled back.
+func (m *MockDB) ExpectRollback() *MockExpectation {
+	return m.expect(&MockExpectation{kind: mockKindRollback})
+}
+
+func (m *MockDB) expect(e *MockExpectation) *MockExpectation {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.expectations = append(m.expectations, e)
+	return e
+}
+
+// WithArgs sets the arguments the call must be made with.
+// Use AnyArg to match any value in a position.
+func (e *MockExpectation) WithArgs(args ...interface{}) *MockExpectation {
+	e.args = args
+	e.checkArgs = true
+	return e
+}
+
+// WillReturnRows sets the rows returned by an expected query.
+func (e *MockExpectation) WillReturnRows(rows *MockRows) *MockExpectation {
+	e.rows = rows
+	return e
+}
+
+// WillReturnResult sets the result returned by an expected exec.
+func (e *MockExpectation) WillReturnResult(lastInsertID, rowsAffected int64) *MockExpectation {
+	e.result = mockResult{lastInsertID: lastInsertID, rowsAffected: rowsAffected}
+	return e
+}
+
+// WillReturnError makes the expected call fail with err.
+func (e *MockExpectation) WillReturnError(err error) *MockExpectation {
+	e.err = err
+	return e
+}
+
+// MockArgument matches a single query argument.
+type MockArgument interface {
+	Match(value interface{}) bool
+}
+
+type anyArg struct{}
+
+func (anyArg) Match(interface{}) bool { return true }
+
+// AnyArg returns an argument matcher that accepts any value.
+func AnyArg() MockArgument {
+	return anyArg{}
+}
+
+// MockRows holds the rows returned by an expected query.
+type MockRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+// NewMockRows creates an empty result set with the given columns.
+func NewMockRows(columns ...string) *MockRows {
+	return &MockRows{columns: columns}
+}
+
+// AddRow appends a row. Values are converted like query arguments (e.g. int to int64).
+func (r *MockRows) AddRow(values ...interface{}) *MockRows {
+	row := make([]driver.Value, len(values))
+	for i, v := range values {
+		converted, err := driver.DefaultParameterConverter.ConvertValue(v)
+		if err != nil {
+			panic(fmt.Sprintf("mock rows: unsupported value %v in column %d: %v", v, i, err))
+		}
+		row[i] = converted
+	}
+	r.rows = append(r.rows, row)
+	return r
+}
+
+// SetPingError makes Ping fail with err (nil to succeed again).
+func (m *MockDB) SetPingError(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pingErr = err
+}
+
+// ExpectationsWereMet returns an error if any expectation was not met
+// or any unexpected query or exec was made.
+func (m *MockDB) ExpectationsWereMet() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var problems []string
+	for _, e := range m.expectations {
+		if !e.met {
+			problems = append(problems, "unmet expectation: "+e.String())
+		}
+	}
+	problems = append(problems, m.unexpected...)
+
+	if len(problems) > 0 {
+		return fmt.Errorf("mock db: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// Begins returns the number of transactions started.
+func (m *MockDB) Begins() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.begins
+}
+
+// Commits returns the number of transactions committed.
+func (m *MockDB) Commits() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.commits
+}
+
+// Rollbacks returns the number of transactions rolled back.
+func (m *MockDB) Rollbacks() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.rollbacks
+}
+
+// String describes the expectation for failure messages.
+func (e *MockExpectation) String() string {
+	switch e.kind {
+	case mockKindQuery, mockKindExec:
+		if e.checkArgs {
+			return fmt.Sprintf("%s %q with args %v", e.kind, e.query, e.args)
+		}
+		return fmt.Sprintf("%s %q", e.kind, e.query)
+	default:
+		return string(e.kind)
+	}
+}
+
+// nextStatement consumes the next unmet expectation for a query or exec.
+func (m *MockDB) nextStatement(kind mockKind, query string, args []driver.NamedValue) (*MockExpectation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	query = collapseWhitespace(query)
+	for _, e := range m.expectations {
+		if e.met {
+			continue
+		}
+		// Unmet transaction boundaries do not block statements; they are reported later.
+		if e.kind != mockKindQuery && e.kind != mockKindExec {
+			continue
+		}
+
+		if e.kind != kind || !strings.Contains(query, e.query) {
+			break
+		}
+		if e.checkArgs && !argsMatch(e.args, args) {
+			err := fmt.Errorf("mock db: %s %q called with args %v, expected %v", kind, query, namedValues(args), e.args)
+			m.unexpected = append(m.unexpected, err.Error())
+			return nil, err
+		}
+
+		e.met = true
+		return e, e.err
+	}
+
+	err := fmt.Errorf("mock db: unexpected %s %q with args %v", kind, query, namedValues(args))
+	m.unexpected = append(m.unexpected, err.Error())
+	return nil, err
+}
+
+// txBoundary records a begin, commit or rollback and consumes its expectation if it is next.
+func (m *MockDB) txBoundary(kind mockKind) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	switch kind {
+	case mockKindBegin:
+		m.begins++
+	case mockKindCommit:
+		m.commits++
+	case mockKindRollback:
+		m.rollbacks++
+	}
+
+	for _, e := range m.expectations {
+		if e.met {
+			continue
+		}
+		if e.kind == kind {
+			e.met = true
+			return e.err
+		}
+		break
+	}
+	return nil
+}
+
+// Exec executes a query without returning rows.
+func (m *MockDB) Exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return m.db.ExecContext(ctx, query, args...)
+}
+
+// Query executes a query that returns rows.
+func (m *MockDB) Query(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return m.db.QueryContext(ctx, query, args...)
+}
+
+// QueryRow executes a query that returns a single row.
+func (m *MockDB) QueryRow(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return m.db.QueryRowContext(ctx, query, args...)
+}
+
+// Prepare creates a prepared statement. Its executions are matched against expectations.
+func (m *MockDB) Prepare(ctx context.Context, query string) (*sql.Stmt, error) {
+	return m.db.PrepareContext(ctx, query)
+}
+
+// BeginTx starts a transaction.
+func (m *MockDB) BeginTx(ctx context.Context, opts *sql.TxOptions) (Tx, error) {
+	tx, err := m.db.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &MockTx{tx: tx}, nil
+}
+
+// Close closes the mock database.
+func (m *MockDB) Close() error {
+	return m.db.Close()
+}
+
+// Ping returns the error configured with SetPingError.
+func (m *MockDB) Ping(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.pingErr
+}
+
+// MockTx implements Tx on top of MockDB.
+type MockTx struct {
+	tx *sql.Tx
+}
+
+// Exec executes a query within the transaction.
+func (t *MockTx) Exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return t.tx.ExecContext(ctx, query, args...)
+}
+
+// Query executes a query that returns rows within the transaction.
+func (t *MockTx) Query(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return t.tx.QueryContext(ctx, query, args...)
+}
+
+// QueryRow executes a query that returns a single row within the transaction.
+func (t *MockTx) QueryRow(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return t.tx.QueryRowContext(ctx, query, args...)
+}
+
+// Prepare creates a prepared statement for use within the transaction.
+func (t *MockTx) Prepare(ctx context.Context, query string) (*sql.Stmt, error) {
+	return t.tx.PrepareContext(ctx, query)
+}
+
+// Commit commits the transaction.
+func (t *MockTx) Commit() error {
+	return t.tx.Commit()
+}
+
+// Rollback rolls back the transaction.
+func (t *MockTx) Rollback() error {
+	return t.tx.Rollback()
+}
+
+// argsMatch compares expected arguments with those received by the driver.
+func argsMatch(expected []interface{}, actual []driver.NamedValue) bool {
+	if len(expected) != len(actual) {
+		return false
+	}
+	for i, want := range expected {
+		got := actual[i].Value
+		if matcher, ok := want.(MockArgument); ok {
+			if !matcher.Match(got) {
+				return false
+			}
+			continue
+		}
+		if reflect.DeepEqual(want, got) {
+			continue
+		}
+		// Compare in driver form so int and int64, etc. are equal
+		wantValue, err := driver.DefaultParameterConverter.ConvertValue(want)
+		if err != nil {
+			return false
+		}
+		gotValue, err := driver.DefaultParameterConverter.ConvertValue(got)
+		if err != nil || !reflect.DeepEqual(wantValue, gotValue) {
+			return false
+		}
+	}
+	return true
+}
+
+func namedValues(args []driver.NamedValue) []interface{} {
+	values := make([]interface{}, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	return values
+}
+
+func collapseWhitespace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// mockResult implements driver.Result.
+type mockResult struct {
+	lastInsertID int64
+	rowsAffected int64
+}
+
+func (r mockResult) LastInsertId() (int64, error) { return r.lastInsertID, nil }
+func (r mockResult) RowsAffected() (int64, error) { return r.rowsAffected, nil }
+
+// mockConnector hands out connections that all share the MockDB state.
+type mockConnector struct {
+	mock *MockDB
+}
+
+func (c *mockConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return &mockConn{mock: c.mock}, nil
+}
+
+func (c *mockConnector) Driver() driver.Driver {
+	return mockDriver{}
+}
+
+// mockDriver exists only to satisfy driver.Connector; connections come from the connector.
+type mockDriver struct{}
+
+func (mockDriver) Open(name string) (driver.Conn, error) {
+	return nil, fmt.Errorf("mock db: use NewMockDB")
+}
+
+// mockConn implements the driver connection interfaces used by database/sql.
+type mockConn struct {
+	mock *MockDB
+}
+
+func (c *mockConn) Prepare(query string) (driver.Stmt, error) {
+	return &mockStmt{conn: c, query: query}, nil
+}
+
+func (c *mockConn) Close() error { return nil }
+
+func (c *mockConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *mockConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if err := c.mock.txBoundary(mockKindBegin); err != nil {
+		return nil, err
+	}
+	return &mockDriverTx{mock: c.mock}, nil
+}
+
+// CheckNamedValue accepts any argument type so expectations can compare raw values.
+func (c *mockConn) CheckNamedValue(nv *driver.NamedValue) error {
+	return nil
+}
+
+func (c *mockConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	e, err := c.mock.nextStatement(mockKindExec, query, args)
+	if err != nil {
+		return nil, err
+	}
+	return e.result, nil
+}
+
+func (c *mockConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	e, err := c.mock.nextStatement(mockKindQuery, query, args)
+	if err != nil {
+		return nil, err
+	}
+	if e.rows == nil {
+		return &mockDriverRows{}, nil
+	}
+	return &mockDriverRows{columns: e.rows.columns, rows: e.rows.rows}, nil
+}
+
+// mockStmt routes prepared statement executions through the connection.
+type mockStmt struct {
+	conn  *mockConn
+	query string
+}
+
+func (s *mockStmt) Close() error  { return nil }
+func (s *mockStmt) NumInput() int { return -1 }
+
+func (s *mockStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), toNamedValues(args))
+}
+
+func (s *mockStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), toNamedValues(args))
+}
+
+func (s *mockStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	return s.conn.ExecContext(ctx, s.query, args)
+}
+
+func (s *mockStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	return s.conn.QueryContext(ctx, s.query, args)
+}
+
+func toNamedValues(args []driver.Value) []driver.NamedValue {
+	named := make([]driver.NamedValue, len(args))
+	for i, v := range args {
+		named[i] = driver.NamedValue{Ordinal: i + 1, Value: v}
+	}
+	return named
+}
+
+// mockDriverTx records commit and rollback on the shared mock.
+type mockDriverTx struct {
+	mock *MockDB
+}
+
+func (t *mockDriverTx) Commit() error   { return t.mock.txBoundary(mockKindCommit) }
+func (t *mockDriverTx) Rollback() error { return t.mock.txBoundary(mockKindRollback) }
+
+// mockDriverRows iterates scripted rows.
+type mockDriverRows struct {
+	columns []string
+	rows    [][]driver.Value
+	pos     int
+}
+
+func (r *mockDriverRows) Columns() []string { return r.columns }
+func (r *mockDriverRows) Close() error      { return nil }
+
+func (r *mockDriverRows) Next(dest []driver.Value) error {
+	if r.pos >= len(r.rows) {
+		return io.EOF
+	}
+	copy(dest, r.rows[r.pos])
+	r.pos++
+	return nil
+}
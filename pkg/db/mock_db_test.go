@@ -0,0 +1,84 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMockDB_QueryRow(t *testing.T) {
+	mock := NewMockDB()
+	defer mock.Close()
+	ctx := context.Background()
+
+	mock.ExpectQuery("SELECT id, name FROM users WHERE id = $1").
+		WithArgs("u1").
+		WillReturnRows(NewMockRows("id", "name").AddRow("u1", "Ann"))
+
+	var id, name string
+	err := mock.QueryRow(ctx, "SELECT id, name\n  FROM users WHERE id = $1", "u1").Scan(&id, &name)
+	require.NoError(t, err)
+	assert.Equal(t, "Ann", name)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMockDB_QueryRowNoRows(t *testing.T) {
+	mock := NewMockDB()
+	defer mock.Close()
+
+	mock.ExpectQuery("SELECT name FROM users").WillReturnRows(NewMockRows("name"))
+
+	var name string
+	err := mock.QueryRow(context.Background(), "SELECT name FROM users WHERE id = $1", 7).Scan(&name)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestMockDB_Transaction(t *testing.T) {
+	mock := NewMockDB()
+	defer mock.Close()
+	ctx := context.Background()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE accounts SET balance").WithArgs(AnyArg(), 42).WillReturnResult(0, 1)
+	mock.ExpectCommit()
+
+	tx, err := mock.BeginTx(ctx, nil)
+	require.NoError(t, err)
+
+	result, err := tx.Exec(ctx, "UPDATE accounts SET balance = balance - $1 WHERE id = $2", 100, 42)
+	require.NoError(t, err)
+	affected, _ := result.RowsAffected()
+	assert.Equal(t, int64(1), affected)
+
+	require.NoError(t, tx.Commit())
+	assert.Equal(t, 1, mock.Commits())
+	assert.Equal(t, 0, mock.Rollbacks())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMockDB_ReportsMismatches(t *testing.T) {
+	mock := NewMockDB()
+	defer mock.Close()
+	ctx := context.Background()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO audit").WillReturnError(errors.New("disk full"))
+	mock.ExpectCommit()
+
+	tx, err := mock.BeginTx(ctx, nil)
+	require.NoError(t, err)
+
+	_, err = tx.Exec(ctx, "INSERT INTO audit (msg) VALUES ($1)", "hello")
+	assert.EqualError(t, err, "disk full")
+	require.NoError(t, tx.Rollback())
+
+	_, err = mock.Exec(ctx, "DELETE FROM users")
+	assert.Error(t, err)
+
+	assert.Equal(t, 1, mock.Rollbacks())
+	assert.Error(t, mock.ExpectationsWereMet())
+}
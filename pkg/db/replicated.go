@@ -0,0 +1,227 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/yourorg/go-service-kit/pkg/logging"
+)
+
+type contextKey string
+
+const primaryKey contextKey = "db_use_primary"
+
+// WithPrimary returns a context that forces ReplicatedDB reads to the primary.
+// Use it after a write when the following read must observe that write.
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey, true)
+}
+
+// UsePrimary reports whether ctx forces reads to the primary.
+func UsePrimary(ctx context.Context) bool {
+	usePrimary, _ := ctx.Value(primaryKey).(bool)
+	return usePrimary
+}
+
+// ReplicaConfig configures read replica routing.
+type ReplicaConfig struct {
+	// HealthCheckInterval is how often replicas are pinged (default: 5s).
+	HealthCheckInterval time.Duration
+	// HealthCheckTimeout bounds each replica ping (default: 2s).
+	HealthCheckTimeout time.Duration
+	// FailureThreshold is the number of consecutive failed pings before a replica
+	// is ejected from rotation (default: 2). A single successful ping readmits it.
+	FailureThreshold int
+}
+
+// replica is a read replica with its health state.
+type replica struct {
+	name     string
+	db       DB
+	healthy  atomic.Bool
+	failures int // consecutive failed pings, only touched by the health loop
+}
+
+// ReplicatedDB implements DB by routing reads to read replicas and everything else to the primary.
+//
+// Query and QueryRow go to a healthy replica chosen round-robin, falling back to the
+// primary when no replica is healthy or the context was marked with WithPrimary.
+// Exec, Prepare and BeginTx always go to the primary.
+type ReplicatedDB struct {
+	primary  DB
+	replicas []*replica
+	next     atomic.Uint64
+	config   ReplicaConfig
+	logger   logging.Logger
+	stopChan chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+// NewReplicatedDB creates a DB that routes reads across replicas.
+// Call Start to begin health checking; replicas are considered healthy until a check fails.
+func NewReplicatedDB(primary DB, replicas []DB, config ReplicaConfig, logger logging.Logger) (*ReplicatedDB, error) {
+	if primary == nil {
+		return nil, fmt.Errorf("primary database is required")
+	}
+	if logger == nil {
+		return nil, fmt.Errorf("logger is required")
+	}
+	if config.HealthCheckInterval <= 0 {
+		config.HealthCheckInterval = 5 * time.Second
+	}
+	if config.HealthCheckTimeout <= 0 {
+		config.HealthCheckTimeout = 2 * time.Second
+	}
+	if config.FailureThreshold <= 0 {
+		config.FailureThreshold = 2
+	}
+
+	r := &ReplicatedDB{
+		primary:  primary,
+		config:   config,
+		logger:   logger,
+		stopChan: make(chan struct{}),
+	}
+	for i, db := range replicas {
+		rep := &replica{name: fmt.Sprintf("replica-%d", i), db: db}
+		rep.healthy.Store(true)
+		r.replicas = append(r.replicas, rep)
+	}
+
+	return r, nil
+}
+
+// Start begins periodic replica health checks until ctx is cancelled or Close is called.
+func (r *ReplicatedDB) Start(ctx context.Context) {
+	if len(r.replicas) == 0 {
+		return
+	}
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+
+		ticker := time.NewTicker(r.config.HealthCheckInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-r.stopChan:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				r.checkReplicas(ctx)
+			}
+		}
+	}()
+}
+
+// checkReplicas pings every replica and updates its rotation state.
+func (r *ReplicatedDB) checkReplicas(ctx context.Context) {
+	for _, rep := range r.replicas {
+		pingCtx, cancel := context.WithTimeout(ctx, r.config.HealthCheckTimeout)
+		err := rep.db.Ping(pingCtx)
+		cancel()
+
+		if err != nil {
+			rep.failures++
+			if rep.failures >= r.config.FailureThreshold && rep.healthy.Load() {
+				rep.healthy.Store(false)
+				r.logger.Warn("Ejecting unhealthy read replica",
+					logging.NewField("replica", rep.name),
+					logging.NewField("failures", rep.failures),
+					logging.NewField("error", err),
+				)
+			}
+			continue
+		}
+
+		rep.failures = 0
+		if !rep.healthy.Load() {
+			rep.healthy.Store(true)
+			r.logger.Info("Read replica healthy again, readmitting", logging.NewField("replica", rep.name))
+		}
+	}
+}
+
+// reader selects the database for a read query.
+func (r *ReplicatedDB) reader(ctx context.Context) DB {
+	if len(r.replicas) == 0 || UsePrimary(ctx) {
+		return r.primary
+	}
+
+	start := r.next.Add(1)
+	for i := 0; i < len(r.replicas); i++ {
+		rep := r.replicas[(start+uint64(i))%uint64(len(r.replicas))]
+		if rep.healthy.Load() {
+			return rep.db
+		}
+	}
+
+	return r.primary
+}
+
+// HealthyReplicas returns the number of replicas currently in rotation.
+func (r *ReplicatedDB) HealthyReplicas() int {
+	count := 0
+	for _, rep := range r.replicas {
+		if rep.healthy.Load() {
+			count++
+		}
+	}
+	return count
+}
+
+// Exec executes a query on the primary.
+func (r *ReplicatedDB) Exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return r.primary.Exec(ctx, query, args...)
+}
+
+// Query executes a query on a healthy replica, or the primary.
+func (r *ReplicatedDB) Query(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return r.reader(ctx).Query(ctx, query, args...)
+}
+
+// QueryRow executes a single-row query on a healthy replica, or the primary.
+func (r *ReplicatedDB) QueryRow(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return r.reader(ctx).QueryRow(ctx, query, args...)
+}
+
+// Prepare creates a prepared statement on the primary.
+func (r *ReplicatedDB) Prepare(ctx context.Context, query string) (*sql.Stmt, error) {
+	return r.primary.Prepare(ctx, query)
+}
+
+// BeginTx starts a transaction on the primary.
+func (r *ReplicatedDB) BeginTx(ctx context.Context, opts *sql.TxOptions) (Tx, error) {
+	return r.primary.BeginTx(ctx, opts)
+}
+
+// Ping checks the primary connection. Replica health is tracked separately.
+func (r *ReplicatedDB) Ping(ctx context.Context) error {
+	return r.primary.Ping(ctx)
+}
+
+// Close stops health checking and closes the primary and all replicas.
+func (r *ReplicatedDB) Close() error {
+	r.stopOnce.Do(func() { close(r.stopChan) })
+	r.wg.Wait()
+
+	var errs []error
+	if err := r.primary.Close(); err != nil {
+		errs = append(errs, err)
+	}
+	for _, rep := range r.replicas {
+		if err := rep.db.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", rep.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
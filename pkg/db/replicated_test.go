@@ -0,0 +1,82 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourorg/go-service-kit/pkg/logging"
+)
+
+// stubDB records which operations reached it.
+type stubDB struct {
+	queries int
+	execs   int
+	pingErr error
+}
+
+func (s *stubDB) Exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	s.execs++
+	return nil, nil
+}
+
+func (s *stubDB) Query(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	s.queries++
+	return nil, nil
+}
+
+func (s *stubDB) QueryRow(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	s.queries++
+	return nil
+}
+
+func (s *stubDB) Prepare(ctx context.Context, query string) (*sql.Stmt, error) { return nil, nil }
+func (s *stubDB) BeginTx(ctx context.Context, opts *sql.TxOptions) (Tx, error) { return nil, nil }
+func (s *stubDB) Close() error                                                 { return nil }
+func (s *stubDB) Ping(ctx context.Context) error                               { return s.pingErr }
+
+func TestReplicatedDB_Routing(t *testing.T) {
+	logger, _ := logging.NewLogger("info", "json")
+	primary, r1, r2 := &stubDB{}, &stubDB{}, &stubDB{}
+
+	rdb, err := NewReplicatedDB(primary, []DB{r1, r2}, ReplicaConfig{}, logger)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	for i := 0; i < 4; i++ {
+		_, _ = rdb.Query(ctx, "SELECT 1")
+	}
+	_, _ = rdb.Exec(ctx, "UPDATE t SET x = 1")
+	_, _ = rdb.Query(WithPrimary(ctx), "SELECT x FROM t")
+
+	assert.Equal(t, 2, r1.queries)
+	assert.Equal(t, 2, r2.queries)
+	assert.Equal(t, 1, primary.execs)
+	assert.Equal(t, 1, primary.queries)
+}
+
+func TestReplicatedDB_EjectsUnhealthyReplica(t *testing.T) {
+	logger, _ := logging.NewLogger("info", "json")
+	primary, healthy, broken := &stubDB{}, &stubDB{}, &stubDB{pingErr: errors.New("connection refused")}
+
+	rdb, err := NewReplicatedDB(primary, []DB{healthy, broken}, ReplicaConfig{FailureThreshold: 1}, logger)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	rdb.checkReplicas(ctx)
+	assert.Equal(t, 1, rdb.HealthyReplicas())
+
+	for i := 0; i < 3; i++ {
+		_, _ = rdb.Query(ctx, "SELECT 1")
+	}
+	assert.Equal(t, 3, healthy.queries)
+	assert.Equal(t, 0, broken.queries)
+
+	// Recovered replicas are readmitted
+	broken.pingErr = nil
+	rdb.checkReplicas(ctx)
+	assert.Equal(t, 2, rdb.HealthyReplicas())
+}
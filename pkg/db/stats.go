@@ -0,0 +1,73 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/yourorg/go-service-kit/pkg/logging"
+	"github.com/yourorg/go-service-kit/pkg/utils"
+)
+
+// StatsProvider exposes connection pool statistics.
+// Implemented by PostgresDB.
+type StatsProvider interface {
+	Stats() sql.DBStats
+}
+
+// StartStatsReporter periodically exports pool statistics from source to the logger
+// and, if recorder is non-nil, as a "DBPoolStats" custom event.
+// It runs in the background until ctx is cancelled.
+func StartStatsReporter(ctx context.Context, source StatsProvider, interval time.Duration, logger logging.Logger, recorder utils.MetricsRecorder) {
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		var lastWaitCount int64
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				stats := source.Stats()
+				attributes := statsAttributes(stats)
+
+				fields := make([]logging.Field, 0, len(attributes))
+				for key, value := range attributes {
+					fields = append(fields, logging.NewField(key, value))
+				}
+
+				// New waiters since the last tick mean the pool was exhausted
+				if stats.WaitCount > lastWaitCount {
+					logger.Warn("Database connection pool exhausted", fields...)
+				} else {
+					logger.Debug("Database connection pool stats", fields...)
+				}
+				lastWaitCount = stats.WaitCount
+
+				if recorder != nil {
+					recorder.RecordCustomEvent("DBPoolStats", attributes)
+				}
+			}
+		}
+	}()
+}
+
+// statsAttributes flattens sql.DBStats into metric attributes.
+func statsAttributes(stats sql.DBStats) map[string]interface{} {
+	return map[string]interface{}{
+		"max_open_connections": stats.MaxOpenConnections,
+		"open_connections":     stats.OpenConnections,
+		"in_use":               stats.InUse,
+		"idle":                 stats.Idle,
+		"wait_count":           stats.WaitCount,
+		"wait_duration_ms":     stats.WaitDuration.Milliseconds(),
+		"max_idle_closed":      stats.MaxIdleClosed,
+		"max_idle_time_closed": stats.MaxIdleTimeClosed,
+		"max_lifetime_closed":  stats.MaxLifetimeClosed,
+	}
+}
@@ -0,0 +1,192 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+
+	"github.com/yourorg/go-service-kit/pkg/tenant"
+)
+
+// TenantSetting is the Postgres setting that carries the current tenant.
+// Row-level security policies should compare against current_setting('app.tenant_id', true).
+const TenantSetting = "app.tenant_id"
+
+// TenantDB decorates a DB to scope every statement to the tenant in the context
+// (see tenant.WithTenant and jwt.TenantMiddleware).
+//
+// BeginTx runs the equivalent of SET LOCAL app.tenant_id for the transaction, so the
+// setting can never leak to other requests sharing a pooled connection. Exec runs in
+// its own short transaction, and Query and QueryRow in a read-only one that ends with
+// the rows or the scan. Since committing a transaction closes its rows, these return
+// TenantRows and TenantRow rather than the *sql types, so TenantDB is not itself a DB.
+// Prepare is not supported, since a prepared statement outlives any transaction;
+// prepare statements on the Tx from BeginTx instead.
+type TenantDB struct {
+	db DB
+}
+
+// NewTenantDB wraps db with per-transaction tenant scoping.
+func NewTenantDB(db DB) *TenantDB {
+	return &TenantDB{db: db}
+}
+
+// BeginTx starts a transaction scoped to the tenant in ctx.
+// It returns tenant.ErrMissingTenant if ctx carries no tenant.
+func (t *TenantDB) BeginTx(ctx context.Context, opts *sql.TxOptions) (Tx, error) {
+	tenantID, err := tenant.Require(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := t.db.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// set_config with is_local=true is SET LOCAL with a bind parameter
+	if _, err := tx.Exec(ctx, "SELECT set_config($1, $2, true)", TenantSetting, tenantID); err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to set tenant for transaction: %w", err)
+	}
+
+	return tx, nil
+}
+
+// RunInTx runs fn in a tenant-scoped transaction, committing if fn succeeds
+// and rolling back otherwise.
+func (t *TenantDB) RunInTx(ctx context.Context, fn func(tx Tx) error) error {
+	tx, err := t.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Exec executes a query in its own tenant-scoped transaction.
+func (t *TenantDB) Exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	var result sql.Result
+	err := t.RunInTx(ctx, func(tx Tx) error {
+		var err error
+		result, err = tx.Exec(ctx, query, args...)
+		return err
+	})
+	return result, err
+}
+
+// Query executes a query in its own read-only tenant-scoped transaction. The rows must be
+// closed, as always, to end the transaction and release its connection.
+func (t *TenantDB) Query(ctx context.Context, query string, args ...interface{}) (*TenantRows, error) {
+	tx, err := t.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(ctx, query, args...)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	return &TenantRows{Rows: rows, tx: tx}, nil
+}
+
+// QueryRow executes a query in its own read-only tenant-scoped transaction, which ends when
+// the row is scanned. Without a tenant in ctx no query is run and Scan returns
+// tenant.ErrMissingTenant.
+func (t *TenantDB) QueryRow(ctx context.Context, query string, args ...interface{}) *TenantRow {
+	tx, err := t.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return &TenantRow{err: err}
+	}
+	return &TenantRow{row: tx.QueryRow(ctx, query, args...), tx: tx}
+}
+
+// TenantRows are the rows of TenantDB.Query. The transaction is committed once the rows
+// are exhausted or closed; committing earlier would close them.
+type TenantRows struct {
+	*sql.Rows
+	tx   Tx
+	once sync.Once
+	err  error
+}
+
+// Next prepares the next row like sql.Rows.Next, ending the transaction after the last.
+func (r *TenantRows) Next() bool {
+	if r.Rows.Next() {
+		return true
+	}
+	r.end()
+	return false
+}
+
+// Close closes the rows and ends the transaction.
+func (r *TenantRows) Close() error {
+	if err := r.Rows.Close(); err != nil {
+		r.end()
+		return err
+	}
+	return r.end()
+}
+
+// end commits the transaction once, after closing the rows.
+func (r *TenantRows) end() error {
+	r.once.Do(func() {
+		r.Rows.Close()
+		r.err = r.tx.Commit()
+	})
+	return r.err
+}
+
+// TenantRow is the row of TenantDB.QueryRow.
+type TenantRow struct {
+	row *sql.Row
+	tx  Tx
+	err error
+}
+
+// Scan copies the row into dest like sql.Row.Scan and ends the transaction. It returns
+// sql.ErrNoRows if there is no row, and the error of starting the transaction, such as
+// tenant.ErrMissingTenant, if the query did not run.
+func (r *TenantRow) Scan(dest ...interface{}) error {
+	if r.err != nil {
+		return r.err
+	}
+	if err := r.row.Scan(dest...); err != nil {
+		r.tx.Rollback()
+		return err
+	}
+	return r.tx.Commit()
+}
+
+// Prepare always fails: a prepared statement outside a transaction cannot be scoped to a
+// tenant. Prepare statements on the Tx returned by BeginTx.
+func (t *TenantDB) Prepare(ctx context.Context, query string) (*sql.Stmt, error) {
+	return nil, fmt.Errorf("tenant-scoped statements must be prepared within BeginTx")
+}
+
+// Close closes the underlying database.
+func (t *TenantDB) Close() error {
+	return t.db.Close()
+}
+
+// Ping checks the underlying database connection.
+func (t *TenantDB) Ping(ctx context.Context) error {
+	return t.db.Ping(ctx)
+}
+
+// TenantPolicySQL returns the statements that enable row-level security on table,
+// restricting every row to the tenant stored in column. Intended for use in migrations.
+func TenantPolicySQL(table, column string) string {
+	return fmt.Sprintf(`ALTER TABLE %[1]s ENABLE ROW LEVEL SECURITY;
+ALTER TABLE %[1]s FORCE ROW LEVEL SECURITY;
+CREATE POLICY %[1]s_tenant_isolation ON %[1]s
+	USING (%[2]s = current_setting('%[3]s', true))
+	WITH CHECK (%[2]s = current_setting('%[3]s', true));`, table, column, TenantSetting)
+}
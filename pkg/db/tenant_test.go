@@ -0,0 +1,84 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourorg/go-service-kit/pkg/tenant"
+)
+
+func TestTenantDB_ScopesTransaction(t *testing.T) {
+	mock := NewMockDB()
+	defer mock.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config($1, $2, true)").WithArgs(TenantSetting, "acme")
+	mock.ExpectExec("INSERT INTO invoices").WillReturnResult(0, 1)
+	mock.ExpectCommit()
+
+	tdb := NewTenantDB(mock)
+	ctx := tenant.WithTenant(context.Background(), "acme")
+
+	_, err := tdb.Exec(ctx, "INSERT INTO invoices (amount) VALUES ($1)", 10)
+	require.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTenantDB_RequiresTenant(t *testing.T) {
+	mock := NewMockDB()
+	defer mock.Close()
+
+	_, err := NewTenantDB(mock).BeginTx(context.Background(), nil)
+	assert.ErrorIs(t, err, tenant.ErrMissingTenant)
+	assert.Equal(t, 0, mock.Begins())
+}
+
+func TestTenantDB_ScopesReads(t *testing.T) {
+	mock := NewMockDB()
+	defer mock.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config($1, $2, true)").WithArgs(TenantSetting, "acme")
+	mock.ExpectQuery("SELECT id FROM invoices").WillReturnRows(NewMockRows("id").AddRow(1).AddRow(2))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config($1, $2, true)").WithArgs(TenantSetting, "acme")
+	mock.ExpectQuery("SELECT count(*) FROM invoices").WillReturnRows(NewMockRows("count").AddRow(2))
+	mock.ExpectCommit()
+
+	tdb := NewTenantDB(mock)
+	ctx := tenant.WithTenant(context.Background(), "acme")
+
+	rows, err := tdb.Query(ctx, "SELECT id FROM invoices")
+	require.NoError(t, err)
+	// The transaction stays open while the rows are read, however late that is
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, 0, mock.Commits())
+	ids := 0
+	for rows.Next() {
+		ids++
+	}
+	require.NoError(t, rows.Err())
+	require.NoError(t, rows.Close())
+	assert.Equal(t, 2, ids)
+	assert.Equal(t, 1, mock.Commits())
+
+	var count int
+	row := tdb.QueryRow(ctx, "SELECT count(*) FROM invoices")
+	time.Sleep(20 * time.Millisecond)
+	require.NoError(t, row.Scan(&count))
+	assert.Equal(t, 2, count)
+	assert.Equal(t, 2, mock.Commits())
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	// Without a tenant nothing reaches the database
+	_, err = tdb.Query(context.Background(), "SELECT id FROM invoices")
+	assert.ErrorIs(t, err, tenant.ErrMissingTenant)
+	assert.ErrorIs(t, tdb.QueryRow(context.Background(), "SELECT id FROM invoices").Scan(&count), tenant.ErrMissingTenant)
+	_, err = tdb.Prepare(ctx, "SELECT id FROM invoices")
+	assert.Error(t, err)
+	assert.Equal(t, 2, mock.Begins())
+}
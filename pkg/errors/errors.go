@@ -1,6 +1,7 @@
 package errors
 
 import (
+	stderrors "errors"
 	"fmt"
 	"net/http"
 )
@@ -126,14 +127,15 @@ func ToHTTPStatus(code ErrorCode) int {
 }
 
 // FromError converts a standard error to an AppError.
-// If the error is already an AppError, it returns it as-is.
+// If the error is or wraps an AppError, it returns that AppError.
 // Otherwise, it wraps it as an internal error.
 func FromError(err error) *AppError {
 	if err == nil {
 		return nil
 	}
 	
-	if appErr, ok := err.(*AppError); ok {
+	var appErr *AppError
+	if stderrors.As(err, &appErr) {
 		return appErr
 	}
 	
@@ -0,0 +1,356 @@
+// Package httpclient provides an HTTP client for calls to other services. It bounds
+// every attempt with a timeout, retries idempotent requests on transient failures,
+// forwards the request and trace IDs of the incoming request, logs every call and maps
+// error responses to AppErrors. Clients of third-party services can use WithRedactedPath
+// and WithoutIDForwarding to keep secrets out of the logs and IDs inside the system.
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/yourorg/go-service-kit/pkg/config"
+	"github.com/yourorg/go-service-kit/pkg/jwt"
+	"github.com/yourorg/go-service-kit/pkg/logging"
+	"github.com/yourorg/go-service-kit/pkg/middleware"
+	"github.com/yourorg/go-service-kit/pkg/utils"
+)
+
+// IdempotencyKeyHeader marks a POST or PATCH request as safe to retry.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// Config configures a Client.
+type Config struct {
+	// BaseURL is prepended to relative request URLs, e.g. "http://billing:8080".
+	BaseURL string
+	// Timeout bounds each attempt, including reading the response body (default 10 seconds).
+	Timeout time.Duration
+	// Retry applies to idempotent requests: GET, HEAD, OPTIONS, TRACE, PUT, DELETE and
+	// requests with an Idempotency-Key header. Other requests are sent once.
+	Retry utils.RetryConfig
+	// UserAgent is sent with every request, e.g. the service name.
+	UserAgent string
+}
+
+// DefaultConfig returns the default client configuration.
+func DefaultConfig() Config {
+	return Config{
+		Timeout: 10 * time.Second,
+		Retry:   utils.DefaultRetryConfig(),
+	}
+}
+
+// ConfigFromConfig builds a Config for baseURL using the retry settings of the
+// application configuration.
+func ConfigFromConfig(cfg *config.Config, baseURL string) Config {
+	clientCfg := DefaultConfig()
+	clientCfg.BaseURL = baseURL
+	clientCfg.UserAgent = cfg.AppName
+	if cfg.RetryMaxAttempts > 0 {
+		clientCfg.Retry.MaxAttempts = cfg.RetryMaxAttempts
+	}
+	if cfg.RetryInitialDelay > 0 {
+		clientCfg.Retry.InitialDelay = time.Duration(cfg.RetryInitialDelay) * time.Millisecond
+	}
+	if cfg.RetryMaxDelay > 0 {
+		clientCfg.Retry.MaxDelay = time.Duration(cfg.RetryMaxDelay) * time.Millisecond
+	}
+	return clientCfg
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithTransport sets the transport of the underlying http.Client, e.g. for tests.
+func WithTransport(transport http.RoundTripper) Option {
+	return func(c *Client) {
+		c.http.Transport = transport
+	}
+}
+
+// WithServiceToken authenticates every request that has no Authorization header with an
+// access token issued by tokens for serviceID, in role roleID (default DefaultServiceRole).
+func WithServiceToken(tokens *jwt.JWTService, serviceID, roleID string) Option {
+	return func(c *Client) {
+		c.token = newServiceToken(tokens, serviceID, roleID)
+	}
+}
+
+// WithRedactedPath keeps the request path out of the logs and errors, e.g. for webhook
+// URLs whose path is the secret. Only the method and host are reported.
+func WithRedactedPath() Option {
+	return func(c *Client) {
+		c.redactPath = true
+	}
+}
+
+// WithoutIDForwarding stops sending the request and trace IDs to the server, e.g. for
+// third-party services. The calls are still logged with the IDs of the incoming request.
+func WithoutIDForwarding() Option {
+	return func(c *Client) {
+		c.noIDForwarding = true
+	}
+}
+
+// redactedPath replaces the path of redacted requests in logs and errors.
+const redactedPath = "/[REDACTED]"
+
+// Client sends HTTP requests to another service.
+type Client struct {
+	cfg            Config
+	http           *http.Client
+	logger         logging.Logger
+	token          *serviceToken
+	redactPath     bool
+	noIDForwarding bool
+}
+
+// New creates a client.
+func New(cfg Config, logger logging.Logger, opts ...Option) (*Client, error) {
+	if logger == nil {
+		return nil, fmt.Errorf("logger is required")
+	}
+
+	defaults := DefaultConfig()
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaults.Timeout
+	}
+	if cfg.Retry.MaxAttempts <= 0 {
+		cfg.Retry = defaults.Retry
+	}
+	if cfg.BaseURL != "" {
+		if _, err := url.Parse(cfg.BaseURL); err != nil {
+			return nil, fmt.Errorf("invalid base URL: %w", err)
+		}
+	}
+
+	c := &Client{
+		cfg:    cfg,
+		http:   &http.Client{Timeout: cfg.Timeout},
+		logger: logger.With(logging.NewField("operation", "http.client")),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+// Do sends req and returns the response whatever its status; use ErrorFromResponse to
+// turn error statuses into AppErrors. Idempotent requests are retried on network errors
+// and on 429, 502, 503 and 504 responses, honouring Retry-After. Transport failures are
+// returned as AppErrors with ErrorCodeTimeout or ErrorCodeServiceUnavailable.
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	req, err := c.prepare(req)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := req.Context()
+	retry := c.cfg.Retry
+	if !isIdempotent(req) {
+		retry.MaxAttempts = 1
+	}
+
+	start := time.Now()
+	attempts := 0
+	resp, err := utils.RetryWithResult(ctx, retry, func() (*http.Response, error) {
+		attempts++
+		attempt := req
+		if attempts > 1 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			attempt = req.Clone(ctx)
+			attempt.Body = body
+		}
+
+		resp, err := c.http.Do(attempt)
+		if err != nil {
+			if c.redactPath {
+				redactURLError(err)
+			}
+			return nil, err
+		}
+		// The last attempt returns the response as it is
+		if attempts < retry.MaxAttempts && isRetryableStatus(resp.StatusCode) {
+			return nil, ErrorFromResponse(resp)
+		}
+		return resp, nil
+	})
+	if err != nil {
+		err = transportError(req, err)
+	}
+	c.log(req, resp, err, attempts, time.Since(start))
+	return resp, err
+}
+
+// DoJSON sends in, if non-nil, as a JSON body and decodes a successful JSON response into
+// out, if non-nil. Error responses are returned as AppErrors.
+func (c *Client) DoJSON(ctx context.Context, method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("failed to encode request body: %w", err)
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, path, body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return err
+	}
+	if err := ErrorFromResponse(resp); err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if out == nil {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response from %s: %w", req.URL.Host, err)
+	}
+	return nil
+}
+
+// GetJSON sends a GET request and decodes the JSON response into out.
+func (c *Client) GetJSON(ctx context.Context, path string, out interface{}) error {
+	return c.DoJSON(ctx, http.MethodGet, path, nil, out)
+}
+
+// PostJSON sends in as a JSON POST request and decodes the JSON response into out.
+func (c *Client) PostJSON(ctx context.Context, path string, in, out interface{}) error {
+	return c.DoJSON(ctx, http.MethodPost, path, in, out)
+}
+
+// prepare returns a copy of req with an absolute URL and the forwarded headers.
+func (c *Client) prepare(req *http.Request) (*http.Request, error) {
+	ctx := req.Context()
+	req = req.Clone(ctx)
+
+	if !req.URL.IsAbs() && c.cfg.BaseURL != "" {
+		resolved, err := url.Parse(strings.TrimSuffix(c.cfg.BaseURL, "/") + "/" + strings.TrimPrefix(req.URL.String(), "/"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid request URL: %w", err)
+		}
+		req.URL = resolved
+		req.Host = ""
+	}
+
+	// Keep the incoming request's IDs so that logs of both services can be correlated
+	if c.noIDForwarding {
+		req.Header.Del(middleware.RequestIDHeader)
+		req.Header.Del(middleware.TraceIDHeader)
+	} else if req.Header.Get(middleware.RequestIDHeader) == "" {
+		requestID := middleware.GetRequestID(ctx)
+		if requestID == "" {
+			requestID = utils.GenerateRequestID()
+		}
+		req.Header.Set(middleware.RequestIDHeader, requestID)
+	}
+	if traceID := middleware.GetTraceID(ctx); traceID != "" && !c.noIDForwarding && req.Header.Get(middleware.TraceIDHeader) == "" {
+		req.Header.Set(middleware.TraceIDHeader, traceID)
+	}
+	if c.cfg.UserAgent != "" && req.Header.Get("User-Agent") == "" {
+		req.Header.Set("User-Agent", c.cfg.UserAgent)
+	}
+	if c.token != nil && req.Header.Get("Authorization") == "" {
+		token, err := c.token.get()
+		if err != nil {
+			return nil, fmt.Errorf("failed to issue service token: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return req, nil
+}
+
+// log records a completed call like httpservice.LoggingMiddleware records served requests.
+func (c *Client) log(req *http.Request, resp *http.Response, err error, attempts int, latency time.Duration) {
+	path := req.URL.Path
+	if c.redactPath {
+		path = redactedPath
+	}
+	requestID, traceID := req.Header.Get(middleware.RequestIDHeader), req.Header.Get(middleware.TraceIDHeader)
+	if c.noIDForwarding {
+		requestID, traceID = middleware.GetRequestID(req.Context()), middleware.GetTraceID(req.Context())
+	}
+	fields := []logging.Field{
+		logging.NewField("method", req.Method),
+		logging.NewField("host", req.URL.Host),
+		logging.NewField("path", path),
+		logging.NewField("latency_ms", latency.Milliseconds()),
+		logging.NewField("attempts", attempts),
+		logging.NewField("request_id", requestID),
+	}
+	if traceID != "" {
+		fields = append(fields, logging.NewField("trace_id", traceID))
+	}
+
+	switch {
+	case err != nil:
+		c.logger.Error("HTTP client request failed", append(fields, logging.NewField("error", err))...)
+	case resp.StatusCode >= 500:
+		c.logger.Error("HTTP client request", append(fields, logging.NewField("status", resp.StatusCode))...)
+	case resp.StatusCode >= 400:
+		c.logger.Warn("HTTP client request", append(fields, logging.NewField("status", resp.StatusCode))...)
+	default:
+		c.logger.Info("HTTP client request", append(fields, logging.NewField("status", resp.StatusCode))...)
+	}
+}
+
+// redactURLError strips the path and query from the URL reported by a *url.Error in err
+// before the retry error wraps its message.
+func redactURLError(err error) {
+	var urlErr *url.Error
+	if !errors.As(err, &urlErr) {
+		return
+	}
+	if u, parseErr := url.Parse(urlErr.URL); parseErr == nil {
+		urlErr.URL = (&url.URL{Scheme: u.Scheme, Host: u.Host, Path: redactedPath}).String()
+	} else {
+		urlErr.URL = redactedPath
+	}
+}
+
+// isIdempotent reports whether req may be sent more than once.
+func isIdempotent(req *http.Request) bool {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false // the body cannot be replayed
+	}
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return req.Header.Get(IdempotencyKeyHeader) != ""
+	}
+}
+
+// isRetryableStatus reports whether a response status is worth retrying.
+func isRetryableStatus(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,242 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	apperrors "github.com/yourorg/go-service-kit/pkg/errors"
+	"github.com/yourorg/go-service-kit/pkg/jwt"
+	"github.com/yourorg/go-service-kit/pkg/logging"
+	"github.com/yourorg/go-service-kit/pkg/middleware"
+	"github.com/yourorg/go-service-kit/pkg/utils"
+)
+
+// newTestClient creates a client for server that retries quickly.
+func newTestClient(t *testing.T, server *httptest.Server, opts ...Option) *Client {
+	t.Helper()
+	logger, _ := logging.NewLogger("error", "json")
+	client, err := New(Config{
+		BaseURL: server.URL,
+		Timeout: time.Second,
+		Retry:   utils.RetryConfig{MaxAttempts: 3, InitialDelay: time.Millisecond, MaxDelay: time.Millisecond, Multiplier: 2},
+	}, logger, opts...)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	return client
+}
+
+func TestClient_ForwardsHeaders(t *testing.T) {
+	var header http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Clone()
+		w.Write([]byte(`{"id":"42"}`))
+	}))
+	defer server.Close()
+
+	logger, _ := logging.NewLogger("error", "json")
+	tokens, err := jwt.NewJWTService(strings.Repeat("s", jwt.MinSecretKeyLength), time.Hour, time.Hour, logger)
+	if err != nil {
+		t.Fatalf("NewJWTService failed: %v", err)
+	}
+	client := newTestClient(t, server, WithServiceToken(tokens, "billing", ""))
+
+	ctx := context.WithValue(context.Background(), middleware.RequestIDKey, "req-1")
+	ctx = context.WithValue(ctx, middleware.TraceIDKey, "trace-1")
+	var out struct{ ID string }
+	if err := client.GetJSON(ctx, "/invoices/42", &out); err != nil {
+		t.Fatalf("GetJSON failed: %v", err)
+	}
+	if out.ID != "42" {
+		t.Errorf("Expected id 42, got %q", out.ID)
+	}
+	if header.Get(middleware.RequestIDHeader) != "req-1" || header.Get(middleware.TraceIDHeader) != "trace-1" {
+		t.Errorf("Expected forwarded request and trace IDs, got %v", header)
+	}
+
+	claims, err := tokens.ValidateToken(strings.TrimPrefix(header.Get("Authorization"), "Bearer "))
+	if err != nil {
+		t.Fatalf("Expected a valid service token: %v", err)
+	}
+	if claims.UserID != "billing" || claims.RoleID != DefaultServiceRole {
+		t.Errorf("Unexpected service token claims %+v", claims)
+	}
+}
+
+// recordingLogger records the fields of every message logged through it.
+type recordingLogger struct {
+	logging.Logger
+	mu     sync.Mutex
+	fields []logging.Field
+}
+
+func (l *recordingLogger) record(fields []logging.Field) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.fields = append(l.fields, fields...)
+}
+
+func (l *recordingLogger) Info(msg string, fields ...logging.Field)  { l.record(fields) }
+func (l *recordingLogger) Warn(msg string, fields ...logging.Field)  { l.record(fields) }
+func (l *recordingLogger) Error(msg string, fields ...logging.Field) { l.record(fields) }
+func (l *recordingLogger) With(fields ...logging.Field) logging.Logger {
+	l.record(fields)
+	return l
+}
+
+func TestClient_RedactsPathAndSkipsIDs(t *testing.T) {
+	const secret = "/services/T000/B000/XXXXSECRET"
+	var header http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Clone()
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	base, _ := logging.NewLogger("error", "json")
+	logger := &recordingLogger{Logger: base}
+	client, err := New(Config{Timeout: time.Second, Retry: utils.RetryConfig{MaxAttempts: 1}}, logger,
+		WithRedactedPath(), WithoutIDForwarding())
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	ctx := context.WithValue(context.Background(), middleware.RequestIDKey, "req-1")
+	ctx = context.WithValue(ctx, middleware.TraceIDKey, "trace-1")
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, server.URL+secret, strings.NewReader("{}"))
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Do failed: %v", err)
+	}
+	resp.Body.Close()
+	if header.Get(middleware.RequestIDHeader) != "" || header.Get(middleware.TraceIDHeader) != "" {
+		t.Errorf("Expected no forwarded IDs, got %v", header)
+	}
+
+	// A transport failure reports the URL in the underlying *url.Error
+	server.Close()
+	req, _ = http.NewRequestWithContext(ctx, http.MethodPost, server.URL+secret, strings.NewReader("{}"))
+	if _, err := client.Do(req); err == nil {
+		t.Fatal("Expected a transport error")
+	} else if strings.Contains(err.Error(), "SECRET") {
+		t.Errorf("Expected the path to be redacted from the error, got %v", err)
+	}
+
+	var requestIDs int
+	for _, field := range logger.fields {
+		if strings.Contains(fmt.Sprint(field.Value), "SECRET") {
+			t.Errorf("Expected the path to be redacted, got %s=%v", field.Key, field.Value)
+		}
+		if field.Key == "request_id" && field.Value == "req-1" {
+			requestIDs++
+		}
+	}
+	if requestIDs != 2 {
+		t.Errorf("Expected both calls to be logged with the incoming request ID, got %d", requestIDs)
+	}
+}
+
+func TestClient_Retries(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if calls.Add(1) < 3 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write(body)
+	}))
+	defer server.Close()
+	client := newTestClient(t, server)
+	ctx := context.Background()
+
+	if err := client.GetJSON(ctx, "/rates", nil); err != nil {
+		t.Fatalf("Expected GET to succeed after retries, got %v", err)
+	}
+	if n := calls.Load(); n != 3 {
+		t.Errorf("Expected 3 attempts, got %d", n)
+	}
+
+	// POST is sent once unless it carries an Idempotency-Key
+	calls.Store(0)
+	err := client.PostJSON(ctx, "/payments", map[string]int{"amount": 10}, nil)
+	var appErr *apperrors.AppError
+	if !errors.As(err, &appErr) || calls.Load() != 1 {
+		t.Fatalf("Expected one attempt and an AppError, got %d attempts (err %v)", calls.Load(), err)
+	}
+
+	calls.Store(0)
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, "/payments", strings.NewReader(`{"amount":10}`))
+	req.Header.Set(IdempotencyKeyHeader, "payment-1")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Expected POST with an Idempotency-Key to succeed, got %v", err)
+	}
+	defer resp.Body.Close()
+	if body, _ := io.ReadAll(resp.Body); string(body) != `{"amount":10}` || calls.Load() != 3 {
+		t.Errorf("Expected the body to be replayed over 3 attempts, got %q after %d", body, calls.Load())
+	}
+}
+
+func TestClient_ErrorMapping(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"code":"NOT_FOUND","message":"invoice not found"}`))
+		case "/slow":
+			time.Sleep(200 * time.Millisecond)
+		}
+	}))
+	defer server.Close()
+	client := newTestClient(t, server)
+
+	err := client.GetJSON(context.Background(), "/missing", nil)
+	var appErr *apperrors.AppError
+	if !errors.As(err, &appErr) || appErr.Code != apperrors.ErrorCodeNotFound || appErr.HTTPStatus != http.StatusNotFound {
+		t.Fatalf("Expected a NOT_FOUND AppError, got %v", err)
+	}
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.Message != "invoice not found" {
+		t.Errorf("Expected the upstream message in the StatusError, got %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err = client.GetJSON(ctx, "/slow", nil)
+	if !errors.As(err, &appErr) || appErr.Code != apperrors.ErrorCodeTimeout {
+		t.Errorf("Expected a TIMEOUT AppError, got %v", err)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		header string
+		want   time.Duration
+		ok     bool
+	}{
+		{"120", 2 * time.Minute, true},
+		{now.Add(30 * time.Second).Format(http.TimeFormat), 30 * time.Second, true},
+		{now.Add(-time.Minute).Format(http.TimeFormat), 0, true},
+		{"", 0, false},
+		{"soon", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := ParseRetryAfter(tt.header, now)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("ParseRetryAfter(%q) = %s, %v; want %s, %v", tt.header, got, ok, tt.want, tt.ok)
+		}
+	}
+}
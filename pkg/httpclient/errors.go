@@ -0,0 +1,129 @@
+package httpclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	apperrors "github.com/yourorg/go-service-kit/pkg/errors"
+	"github.com/yourorg/go-service-kit/pkg/utils"
+)
+
+// maxErrorBody bounds the part of an error response kept in a StatusError.
+const maxErrorBody = 64 * 1024
+
+// StatusError is the cause of an AppError returned for an error response.
+type StatusError struct {
+	StatusCode int
+	Body       []byte
+	// Code and Message are read from an apperrors.ErrorResponse body, if any.
+	Code    apperrors.ErrorCode
+	Message string
+}
+
+// Error implements the error interface.
+func (e *StatusError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("status %d: %s", e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("status %d", e.StatusCode)
+}
+
+// statusCodes maps response statuses to the AppError codes returned for them. Other 4xx
+// statuses map to ErrorCodeBadRequest and other 5xx statuses to ErrorCodeInternal.
+var statusCodes = map[int]apperrors.ErrorCode{
+	http.StatusBadRequest:          apperrors.ErrorCodeBadRequest,
+	http.StatusUnauthorized:        apperrors.ErrorCodeUnauthorized,
+	http.StatusForbidden:           apperrors.ErrorCodeForbidden,
+	http.StatusNotFound:            apperrors.ErrorCodeNotFound,
+	http.StatusConflict:            apperrors.ErrorCodeConflict,
+	http.StatusUnprocessableEntity: apperrors.ErrorCodeValidation,
+	http.StatusRequestTimeout:      apperrors.ErrorCodeTimeout,
+	http.StatusGatewayTimeout:      apperrors.ErrorCodeTimeout,
+	http.StatusTooManyRequests:     apperrors.ErrorCodeServiceUnavailable,
+	http.StatusBadGateway:          apperrors.ErrorCodeServiceUnavailable,
+	http.StatusServiceUnavailable:  apperrors.ErrorCodeServiceUnavailable,
+}
+
+// ErrorFromResponse returns nil for a 2xx or 3xx response. For other responses it reads
+// and closes the body and returns an AppError whose code follows the status, caused by a
+// *StatusError. A Retry-After header is available through utils.RetryAfter.
+func ErrorFromResponse(resp *http.Response) error {
+	if resp.StatusCode < 400 {
+		return nil
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	io.Copy(io.Discard, resp.Body)
+
+	statusErr := &StatusError{StatusCode: resp.StatusCode, Body: body}
+	var errResp apperrors.ErrorResponse
+	if json.Unmarshal(body, &errResp) == nil {
+		statusErr.Code = errResp.Code
+		statusErr.Message = errResp.Message
+	}
+
+	code, ok := statusCodes[resp.StatusCode]
+	if !ok {
+		code = apperrors.ErrorCodeInternal
+		if resp.StatusCode < 500 {
+			code = apperrors.ErrorCodeBadRequest
+		}
+	}
+
+	var cause error = statusErr
+	if after, ok := ParseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+		cause = utils.WithRetryAfter(statusErr, after)
+	}
+
+	message := fmt.Sprintf("%s %s returned %d", resp.Request.Method, resp.Request.URL.Host, resp.StatusCode)
+	if statusErr.Message != "" {
+		message += ": " + statusErr.Message
+	}
+	appErr := apperrors.NewAppErrorWithErr(code, message, apperrors.ToHTTPStatus(code), cause)
+	details := map[string]interface{}{"upstream_status": resp.StatusCode}
+	if statusErr.Code != "" {
+		details["upstream_code"] = statusErr.Code
+	}
+	return appErr.WithDetails(details)
+}
+
+// ParseRetryAfter parses a Retry-After header given in seconds or as an HTTP date.
+func ParseRetryAfter(header string, now time.Time) (time.Duration, bool) {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(header); err == nil {
+		return time.Duration(max(seconds, 0)) * time.Second, true
+	}
+	if date, err := http.ParseTime(header); err == nil {
+		return max(date.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+// transportError maps a failure to get a response to an AppError. Cancellation by the
+// caller is returned as it is.
+func transportError(req *http.Request, err error) error {
+	var appErr *apperrors.AppError
+	if errors.As(err, &appErr) || errors.Is(err, context.Canceled) {
+		return err
+	}
+
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || errors.As(err, &netErr) && netErr.Timeout() {
+		return apperrors.NewAppErrorWithErr(apperrors.ErrorCodeTimeout,
+			fmt.Sprintf("%s %s timed out", req.Method, req.URL.Host), http.StatusGatewayTimeout, err)
+	}
+	return apperrors.NewAppErrorWithErr(apperrors.ErrorCodeServiceUnavailable,
+		fmt.Sprintf("%s %s failed", req.Method, req.URL.Host), http.StatusServiceUnavailable, err)
+}
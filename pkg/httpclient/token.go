@@ -0,0 +1,58 @@
+package httpclient
+
+import (
+	"sync"
+	"time"
+
+	"github.com/yourorg/go-service-kit/pkg/jwt"
+)
+
+// DefaultServiceRole is the role of service tokens issued without an explicit role.
+const DefaultServiceRole = "service"
+
+// tokenRefreshMargin is how long before expiry a cached service token is replaced.
+const tokenRefreshMargin = 30 * time.Second
+
+// serviceToken issues and caches the access token a service presents to other services.
+type serviceToken struct {
+	tokens    *jwt.JWTService
+	serviceID string
+	roleID    string
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+func newServiceToken(tokens *jwt.JWTService, serviceID, roleID string) *serviceToken {
+	if roleID == "" {
+		roleID = DefaultServiceRole
+	}
+	return &serviceToken{tokens: tokens, serviceID: serviceID, roleID: roleID}
+}
+
+// get returns the cached token, issuing a new one shortly before it expires.
+func (s *serviceToken) get() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token != "" && time.Now().Add(tokenRefreshMargin).Before(s.expires) {
+		return s.token, nil
+	}
+
+	token, err := s.tokens.GenerateAccessToken(s.serviceID, s.roleID, "")
+	if err != nil {
+		return "", err
+	}
+	claims, err := s.tokens.ExtractClaims(token)
+	if err != nil {
+		return "", err
+	}
+
+	s.token = token
+	s.expires = time.Time{}
+	if claims.ExpiresAt != nil {
+		s.expires = claims.ExpiresAt.Time
+	}
+	return token, nil
+}
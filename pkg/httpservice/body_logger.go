@@ -21,6 +21,11 @@ func (w *responseWriter) Write(b []byte) (int, error) {
 	return w.ResponseWriter.Write(b)
 }
 
+func (w *responseWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
 // BodyLoggingMiddleware logs full request and response bodies for complete observability.
 // This provides comprehensive logging for debugging and monitoring.
 func BodyLoggingMiddleware(logger logging.Logger) gin.HandlerFunc {
@@ -0,0 +1,58 @@
+package httpservice
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourorg/go-service-kit/pkg/logging"
+)
+
+// HealthCheckFunc reports the health of a dependency. A nil error means healthy.
+// db.DB.Ping satisfies this signature.
+type HealthCheckFunc func(ctx context.Context) error
+
+// healthCheckTimeout bounds the total time spent running health checks.
+const healthCheckTimeout = 5 * time.Second
+
+// healthHandler runs the registered health checks and reports their status.
+// It responds 200 when every check passes and 503 otherwise. Errors are logged
+// rather than returned, since the endpoint is unauthenticated and they may reveal
+// hosts or credentials.
+func healthHandler(checks map[string]HealthCheckFunc, logger logging.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if len(checks) == 0 {
+			c.JSON(http.StatusOK, gin.H{"status": "ok"})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), healthCheckTimeout)
+		defer cancel()
+
+		status := http.StatusOK
+		results := make(map[string]string, len(checks))
+		for name, check := range checks {
+			if err := check(ctx); err != nil {
+				status = http.StatusServiceUnavailable
+				results[name] = "unavailable"
+				logger.Warn("Health check failed",
+					logging.NewField("check", name),
+					logging.NewField("error", err),
+				)
+				continue
+			}
+			results[name] = "ok"
+		}
+
+		overall := "ok"
+		if status != http.StatusOK {
+			overall = "unavailable"
+		}
+
+		c.JSON(status, gin.H{
+			"status": overall,
+			"checks": results,
+		})
+	}
+}
@@ -0,0 +1,364 @@
+package httpservice
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourorg/go-service-kit/pkg/jwt"
+	"github.com/yourorg/go-service-kit/pkg/logging"
+	"github.com/yourorg/go-service-kit/pkg/utils"
+)
+
+// Idempotency headers. IdempotentReplayedHeader marks responses replayed from the store.
+const (
+	IdempotencyKeyHeader     = "Idempotency-Key"
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+)
+
+// maxIdempotencyKeyLength bounds the Idempotency-Key header.
+const maxIdempotencyKeyLength = 255
+
+// Default size limits of IdempotencyMiddleware.
+const (
+	DefaultIdempotencyMaxBodyBytes     = 10 << 20
+	DefaultIdempotencyMaxResponseBytes = 1 << 20
+)
+
+// idempotencyVolatileHeaders describe a single response and are not replayed.
+var idempotencyVolatileHeaders = []string{
+	"Content-Length",
+	"Date",
+	"Retry-After",
+	"X-Request-ID",
+	"X-Trace-ID",
+	RateLimitLimitHeader,
+	RateLimitRemainingHeader,
+	RateLimitResetHeader,
+}
+
+// IdempotencyConfig configures IdempotencyMiddleware.
+type IdempotencyConfig struct {
+	// Store keeps the responses (default: a new IdempotencyMemoryStore). Use an
+	// IdempotencyPostgresStore to share them between replicas.
+	Store IdempotencyStore
+	// TTL is how long responses are replayed (default: 24 hours)
+	TTL time.Duration
+	// LockTimeout is how long a request in progress holds its key without renewal,
+	// bounding the wait after a crash. The lock is renewed every half LockTimeout while
+	// the handler runs (default: 1 minute)
+	LockTimeout time.Duration
+	// MaxBodyBytes bounds the request bodies read to fingerprint requests; larger bodies
+	// are rejected with 413 (default: DefaultIdempotencyMaxBodyBytes)
+	MaxBodyBytes int64
+	// MaxResponseBytes bounds the responses stored; larger responses are sent but not
+	// stored, so that a retry runs the handler again (default:
+	// DefaultIdempotencyMaxResponseBytes)
+	MaxResponseBytes int
+	// Methods are the methods honouring the header (default: POST and PATCH)
+	Methods []string
+	// Required rejects requests without an Idempotency-Key with 400
+	Required bool
+	// AnonymousScope, if set, separates the keys of requests without a JWT user, e.g.
+	// RateLimitByAPIKey("X-API-Key"). By default all anonymous clients share one key
+	// space, so their keys should be random, e.g. UUIDs.
+	AnonymousScope func(c *gin.Context) string
+	// Logger reports store failures (optional)
+	Logger logging.Logger
+}
+
+// IdempotencyMiddleware makes retries of mutating requests safe. The first request with
+// an Idempotency-Key runs the handler and its response, unless a 5xx, is stored per key
+// and user; retries get that response replayed with an Idempotent-Replayed header.
+// A retry while the first request is in progress gets 409, and reusing a key for a
+// different request (method, URL or body) gets 422. Keys of anonymous requests are
+// shared by all anonymous clients unless AnonymousScope separates them; the client IP
+// alone would let clients behind one NAT collide. Store failures are answered with 503
+// rather than risking a duplicate.
+func IdempotencyMiddleware(cfg IdempotencyConfig) gin.HandlerFunc {
+	if cfg.Store == nil {
+		cfg.Store = NewIdempotencyMemoryStore()
+	}
+	if cfg.TTL <= 0 {
+		cfg.TTL = 24 * time.Hour
+	}
+	if cfg.LockTimeout <= 0 {
+		cfg.LockTimeout = time.Minute
+	}
+	if cfg.MaxBodyBytes <= 0 {
+		cfg.MaxBodyBytes = DefaultIdempotencyMaxBodyBytes
+	}
+	if cfg.MaxResponseBytes <= 0 {
+		cfg.MaxResponseBytes = DefaultIdempotencyMaxResponseBytes
+	}
+	if len(cfg.Methods) == 0 {
+		cfg.Methods = []string{http.MethodPost, http.MethodPatch}
+	}
+
+	return func(c *gin.Context) {
+		if !slices.Contains(cfg.Methods, c.Request.Method) {
+			c.Next()
+			return
+		}
+
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			if cfg.Required {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key header is required"})
+				return
+			}
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key header is too long"})
+			return
+		}
+
+		var body []byte
+		if c.Request.Body != nil {
+			var err error
+			body, err = io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, cfg.MaxBodyBytes))
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Request body too large"})
+				return
+			}
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+				return
+			}
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		}
+		fingerprint := idempotencyFingerprint(c.Request, body)
+		storeKey := idempotencyScope(c, cfg.AnonymousScope) + ":" + key
+		ctx := context.WithoutCancel(c.Request.Context())
+
+		// The owner token tells this request's reservation from a later one of the same
+		// key, taken over after the lock expired
+		owner := utils.GenerateRequestID()
+		now := time.Now()
+		existing, reserved, err := cfg.Store.Reserve(ctx, storeKey, fingerprint, owner, now, now.Add(cfg.LockTimeout))
+		if err != nil {
+			logIdempotencyFailure(cfg.Logger, "Idempotency check failed", c, err)
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "Idempotency check unavailable"})
+			return
+		}
+		if !reserved {
+			switch {
+			case existing.Fingerprint != fingerprint:
+				c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "Idempotency-Key was already used for a different request"})
+			case !existing.Completed:
+				c.Header("Retry-After", "1")
+				c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "A request with this Idempotency-Key is in progress"})
+			default:
+				for name, values := range existing.Header {
+					c.Writer.Header()[name] = values
+				}
+				c.Header(IdempotentReplayedHeader, "true")
+				c.Writer.WriteHeader(existing.StatusCode)
+				c.Writer.Write(existing.Body)
+				c.Abort()
+			}
+			return
+		}
+
+		// The key is released unless a response is stored, e.g. after a 5xx or a panic,
+		// so that the client can retry
+		stored := false
+		defer func() {
+			if stored {
+				return
+			}
+			if err := cfg.Store.Release(ctx, storeKey, fingerprint, owner); err != nil {
+				logIdempotencyFailure(cfg.Logger, "Failed to release idempotency key", c, err)
+			}
+		}()
+
+		stopRenewal := renewIdempotencyLock(ctx, cfg, storeKey, fingerprint, owner, c.Request.URL.Path)
+		defer stopRenewal()
+
+		writer := &idempotencyResponseWriter{ResponseWriter: c.Writer, limit: cfg.MaxResponseBytes}
+		c.Writer = writer
+		c.Next()
+		stopRenewal()
+
+		status := c.Writer.Status()
+		if status >= http.StatusInternalServerError {
+			return
+		}
+		if writer.overflow {
+			if cfg.Logger != nil {
+				cfg.Logger.Warn("Idempotent response too large to store, releasing key",
+					logging.NewField("path", c.Request.URL.Path),
+					logging.NewField("max_bytes", cfg.MaxResponseBytes),
+				)
+			}
+			return
+		}
+		header := c.Writer.Header().Clone()
+		for _, name := range idempotencyVolatileHeaders {
+			header.Del(name)
+		}
+		err = cfg.Store.Complete(ctx, storeKey, IdempotencyRecord{
+			Fingerprint: fingerprint,
+			Owner:       owner,
+			Completed:   true,
+			StatusCode:  status,
+			Header:      header,
+			Body:        writer.body.Bytes(),
+			ExpiresAt:   time.Now().Add(cfg.TTL),
+		})
+		if err != nil {
+			logIdempotencyFailure(cfg.Logger, "Failed to store idempotent response", c, err)
+			return
+		}
+		stored = true
+	}
+}
+
+// idempotencyScope separates the keys of different users, and of anonymous clients as
+// told apart by anonymousScope, if set.
+func idempotencyScope(c *gin.Context, anonymousScope func(c *gin.Context) string) string {
+	if userID, ok := jwt.GetUserID(c); ok && userID != "" {
+		return "user:" + userID
+	}
+	if anonymousScope != nil {
+		return "anon:" + anonymousScope(c)
+	}
+	return "anon"
+}
+
+// renewIdempotencyLock extends the lock on key every half LockTimeout until the returned
+// function is called, which waits for the renewal to stop and may be called repeatedly.
+func renewIdempotencyLock(ctx context.Context, cfg IdempotencyConfig, key, fingerprint, owner, path string) func() {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(cfg.LockTimeout / 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				if err := cfg.Store.Extend(ctx, key, fingerprint, owner, now.Add(cfg.LockTimeout)); err != nil && cfg.Logger != nil && ctx.Err() == nil {
+					cfg.Logger.Error("Failed to extend idempotency key",
+						logging.NewField("error", err),
+						logging.NewField("path", path),
+					)
+				}
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// idempotencyResponseWriter keeps a copy of the response body up to limit bytes.
+type idempotencyResponseWriter struct {
+	gin.ResponseWriter
+	body     bytes.Buffer
+	limit    int
+	overflow bool
+}
+
+func (w *idempotencyResponseWriter) Write(b []byte) (int, error) {
+	w.keep(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *idempotencyResponseWriter) WriteString(s string) (int, error) {
+	w.keep([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+// keep copies b unless the body exceeds the limit, in which case the copy is dropped.
+func (w *idempotencyResponseWriter) keep(b []byte) {
+	if w.overflow {
+		return
+	}
+	if w.body.Len()+len(b) > w.limit {
+		w.overflow = true
+		w.body = bytes.Buffer{}
+		return
+	}
+	w.body.Write(b)
+}
+
+// idempotencyFingerprint hashes the method, URL and body of a request. Multipart bodies
+// are hashed part by part, since clients pick a new boundary for every attempt.
+func idempotencyFingerprint(req *http.Request, body []byte) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s\n", req.Method, req.URL.RequestURI())
+
+	mediaType, params, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if err == nil && strings.HasPrefix(mediaType, "multipart/") {
+		parts := sha256.New()
+		if hashMultipart(parts, body, params["boundary"]) == nil {
+			h.Write(parts.Sum(nil))
+			return hex.EncodeToString(h.Sum(nil))
+		}
+	}
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// hashMultipart writes the headers and content of every part of body to h.
+func hashMultipart(h hash.Hash, body []byte, boundary string) error {
+	reader := multipart.NewReader(bytes.NewReader(body), boundary)
+	for {
+		part, err := reader.NextRawPart()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		writeSortedHeader(h, part.Header)
+		if _, err := io.Copy(h, part); err != nil {
+			return err
+		}
+		h.Write([]byte{0})
+	}
+}
+
+// writeSortedHeader writes header to h in a stable order.
+func writeSortedHeader(h hash.Hash, header textproto.MIMEHeader) {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(h, "%s: %s\n", name, strings.Join(header[name], ", "))
+	}
+}
+
+// logIdempotencyFailure logs a store failure if logger is set.
+func logIdempotencyFailure(logger logging.Logger, msg string, c *gin.Context, err error) {
+	if logger == nil {
+		return
+	}
+	logger.Error(msg,
+		logging.NewField("error", err),
+		logging.NewField("path", c.Request.URL.Path),
+	)
+}
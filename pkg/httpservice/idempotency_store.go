@@ -0,0 +1,259 @@
+package httpservice
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/yourorg/go-service-kit/pkg/db"
+)
+
+// IdempotencyRecord is the state of an idempotency key.
+type IdempotencyRecord struct {
+	Fingerprint string // hash of the request that reserved the key
+	Owner       string // token of the reservation, unique per request
+	Completed   bool   // false while the request is in progress
+	StatusCode  int
+	Header      http.Header
+	Body        []byte
+	ExpiresAt   time.Time // the key is free again afterwards
+}
+
+// ErrIdempotencyKeyLost is returned by IdempotencyStore.Complete when the reservation
+// expired and the key was reserved again by another request.
+var ErrIdempotencyKeyLost = errors.New("idempotency key is no longer held by the request")
+
+// IdempotencyStore keeps idempotency keys and the responses stored for them. Complete,
+// Extend and Release only act on the reservation of the given fingerprint and owner,
+// so a request whose lock expired cannot touch the reservation of the next one.
+type IdempotencyStore interface {
+	// Reserve atomically claims key for a request with fingerprint and owner until
+	// expiresAt, unless it is held by a record that has not expired at now. It returns
+	// that record and false in this case.
+	Reserve(ctx context.Context, key, fingerprint, owner string, now, expiresAt time.Time) (*IdempotencyRecord, bool, error)
+	// Complete stores the response of the request holding key, as identified by the
+	// record's Fingerprint and Owner, or returns ErrIdempotencyKeyLost.
+	Complete(ctx context.Context, key string, record IdempotencyRecord) error
+	// Extend moves the expiry of key to expiresAt while the request holding it is still
+	// in progress.
+	Extend(ctx context.Context, key, fingerprint, owner string, expiresAt time.Time) error
+	// Release frees key if the request holding it is still in progress.
+	Release(ctx context.Context, key, fingerprint, owner string) error
+	// Cleanup removes records that expired before now and returns how many were removed.
+	Cleanup(ctx context.Context, now time.Time) (int, error)
+}
+
+// idempotencySweepInterval is how often an IdempotencyMemoryStore drops expired records.
+const idempotencySweepInterval = time.Minute
+
+// IdempotencyMemoryStore is an IdempotencyStore local to the process, for single
+// instances and tests. Expired records are dropped while serving requests.
+type IdempotencyMemoryStore struct {
+	mu        sync.Mutex
+	records   map[string]IdempotencyRecord
+	lastSweep time.Time
+}
+
+// NewIdempotencyMemoryStore creates an empty in-memory store.
+func NewIdempotencyMemoryStore() *IdempotencyMemoryStore {
+	return &IdempotencyMemoryStore{records: make(map[string]IdempotencyRecord)}
+}
+
+// Reserve implements IdempotencyStore.
+func (m *IdempotencyMemoryStore) Reserve(ctx context.Context, key, fingerprint, owner string, now, expiresAt time.Time) (*IdempotencyRecord, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if now.Sub(m.lastSweep) >= idempotencySweepInterval {
+		m.sweep(now)
+	}
+	if record, ok := m.records[key]; ok && !record.ExpiresAt.Before(now) {
+		return &record, false, nil
+	}
+	m.records[key] = IdempotencyRecord{Fingerprint: fingerprint, Owner: owner, ExpiresAt: expiresAt}
+	return nil, true, nil
+}
+
+// Complete implements IdempotencyStore.
+func (m *IdempotencyMemoryStore) Complete(ctx context.Context, key string, record IdempotencyRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.holds(key, record.Fingerprint, record.Owner) {
+		return ErrIdempotencyKeyLost
+	}
+	m.records[key] = record
+	return nil
+}
+
+// Extend implements IdempotencyStore.
+func (m *IdempotencyMemoryStore) Extend(ctx context.Context, key, fingerprint, owner string, expiresAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.holds(key, fingerprint, owner) {
+		record := m.records[key]
+		record.ExpiresAt = expiresAt
+		m.records[key] = record
+	}
+	return nil
+}
+
+// Release implements IdempotencyStore.
+func (m *IdempotencyMemoryStore) Release(ctx context.Context, key, fingerprint, owner string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.holds(key, fingerprint, owner) {
+		delete(m.records, key)
+	}
+	return nil
+}
+
+// holds reports whether the request of fingerprint and owner holds the in-progress
+// reservation of key. The caller must hold m.mu.
+func (m *IdempotencyMemoryStore) holds(key, fingerprint, owner string) bool {
+	record, ok := m.records[key]
+	return ok && !record.Completed && record.Fingerprint == fingerprint && record.Owner == owner
+}
+
+// Cleanup implements IdempotencyStore.
+func (m *IdempotencyMemoryStore) Cleanup(ctx context.Context, now time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.sweep(now), nil
+}
+
+// sweep removes records that expired before now. The caller must hold m.mu.
+func (m *IdempotencyMemoryStore) sweep(now time.Time) int {
+	removed := 0
+	for key, record := range m.records {
+		if record.ExpiresAt.Before(now) {
+			delete(m.records, key)
+			removed++
+		}
+	}
+	m.lastSweep = now
+	return removed
+}
+
+// DefaultIdempotencyTable is the table used by an IdempotencyPostgresStore when none is given.
+const DefaultIdempotencyTable = "idempotency_keys"
+
+// IdempotencyMigrations create the DefaultIdempotencyTable of an IdempotencyPostgresStore.
+// Apply them with a db.Migrator using Dir "migrations" and Table
+// IdempotencyMigrationsTable, as their versions are numbered independently of other
+// packages' migrations.
+//
+//go:embed migrations/*.sql
+var IdempotencyMigrations embed.FS
+
+// IdempotencyMigrationsTable records the applied IdempotencyMigrations.
+const IdempotencyMigrationsTable = "idempotency_schema_migrations"
+
+// IdempotencyPostgresStore is an IdempotencyStore in a Postgres table with one row per
+// key, shared by all replicas. The table (key TEXT PRIMARY KEY, fingerprint, owner,
+// completed, status_code, header, body and expires_at, indexed by expires_at) is created by
+// IdempotencyMigrations. Run utils.StartCleanup to remove expired rows.
+type IdempotencyPostgresStore struct {
+	db    db.DB
+	table string
+}
+
+// NewIdempotencyPostgresStore creates a store in table (default: DefaultIdempotencyTable).
+func NewIdempotencyPostgresStore(database db.DB, table string) (*IdempotencyPostgresStore, error) {
+	if database == nil {
+		return nil, fmt.Errorf("database is required")
+	}
+	if table == "" {
+		table = DefaultIdempotencyTable
+	}
+	if err := db.ValidateTableName(table); err != nil {
+		return nil, fmt.Errorf("invalid idempotency table: %w", err)
+	}
+	return &IdempotencyPostgresStore{db: database, table: table}, nil
+}
+
+// Reserve implements IdempotencyStore, taking over expired rows in the same statement.
+func (p *IdempotencyPostgresStore) Reserve(ctx context.Context, key, fingerprint, owner string, now, expiresAt time.Time) (*IdempotencyRecord, bool, error) {
+	ctx = db.WithPrimary(ctx)
+
+	// A row removed between the two statements is reserved on the next attempt
+	for attempt := 0; attempt < 2; attempt++ {
+		var reserved string
+		err := p.db.QueryRow(ctx, fmt.Sprintf(`INSERT INTO %[1]s AS r (key, fingerprint, owner, expires_at) VALUES ($1, $2, $5, $4)
+ON CONFLICT (key) DO UPDATE SET fingerprint = EXCLUDED.fingerprint, owner = EXCLUDED.owner, completed = FALSE, status_code = 0, header = '{}', body = NULL, expires_at = EXCLUDED.expires_at
+WHERE r.expires_at < $3
+RETURNING key`, p.table), key, fingerprint, now, expiresAt, owner).Scan(&reserved)
+		if err == nil {
+			return nil, true, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return nil, false, fmt.Errorf("failed to reserve idempotency key: %w", err)
+		}
+
+		var record IdempotencyRecord
+		var header string
+		err = p.db.QueryRow(ctx, fmt.Sprintf("SELECT fingerprint, completed, status_code, header, body, expires_at FROM %s WHERE key = $1", p.table), key).
+			Scan(&record.Fingerprint, &record.Completed, &record.StatusCode, &header, &record.Body, &record.ExpiresAt)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to read idempotency key: %w", err)
+		}
+		if err := json.Unmarshal([]byte(header), &record.Header); err != nil {
+			return nil, false, fmt.Errorf("failed to decode stored headers: %w", err)
+		}
+		return &record, false, nil
+	}
+	return nil, false, fmt.Errorf("failed to reserve idempotency key: removed concurrently")
+}
+
+// Complete implements IdempotencyStore.
+func (p *IdempotencyPostgresStore) Complete(ctx context.Context, key string, record IdempotencyRecord) error {
+	header, err := json.Marshal(record.Header)
+	if err != nil {
+		return fmt.Errorf("failed to encode headers: %w", err)
+	}
+	result, err := p.db.Exec(db.WithPrimary(ctx), fmt.Sprintf(`UPDATE %s SET completed = TRUE, status_code = $2, header = $3, body = $4, expires_at = $5
+WHERE key = $1 AND fingerprint = $6 AND owner = $7 AND NOT completed`, p.table), key, record.StatusCode, string(header), record.Body, record.ExpiresAt, record.Fingerprint, record.Owner)
+	if err != nil {
+		return fmt.Errorf("failed to store idempotent response: %w", err)
+	}
+	if updated, err := result.RowsAffected(); err == nil && updated == 0 {
+		return ErrIdempotencyKeyLost
+	}
+	return nil
+}
+
+// Extend implements IdempotencyStore.
+func (p *IdempotencyPostgresStore) Extend(ctx context.Context, key, fingerprint, owner string, expiresAt time.Time) error {
+	_, err := p.db.Exec(db.WithPrimary(ctx), fmt.Sprintf("UPDATE %s SET expires_at = $4 WHERE key = $1 AND fingerprint = $2 AND owner = $3 AND NOT completed", p.table), key, fingerprint, owner, expiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to extend idempotency key: %w", err)
+	}
+	return nil
+}
+
+// Release implements IdempotencyStore.
+func (p *IdempotencyPostgresStore) Release(ctx context.Context, key, fingerprint, owner string) error {
+	_, err := p.db.Exec(db.WithPrimary(ctx), fmt.Sprintf("DELETE FROM %s WHERE key = $1 AND fingerprint = $2 AND owner = $3 AND NOT completed", p.table), key, fingerprint, owner)
+	if err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+	return nil
+}
+
+// Cleanup implements IdempotencyStore.
+func (p *IdempotencyPostgresStore) Cleanup(ctx context.Context, now time.Time) (int, error) {
+	result, err := p.db.Exec(db.WithPrimary(ctx), fmt.Sprintf("DELETE FROM %s WHERE expires_at < $1", p.table), now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to clean up idempotency keys: %w", err)
+	}
+	removed, _ := result.RowsAffected()
+	return int(removed), nil
+}
@@ -0,0 +1,304 @@
+package httpservice
+
+import (
+	"bytes"
+	"context"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/yourorg/go-service-kit/pkg/db"
+	"github.com/yourorg/go-service-kit/pkg/jwt"
+)
+
+func TestIdempotencyMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	calls := 0
+	release := make(chan struct{})
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		if userID := c.GetHeader("X-User"); userID != "" {
+			c.Set(jwt.ContextKeyUserID, userID)
+		}
+	})
+	router.Use(IdempotencyMiddleware(IdempotencyConfig{Logger: &MockLogger{}, MaxBodyBytes: 64, MaxResponseBytes: 32}))
+	router.POST("/orders", func(c *gin.Context) {
+		calls++
+		c.Header("Location", "/orders/1")
+		c.JSON(http.StatusCreated, gin.H{"id": calls})
+	})
+	router.POST("/slow", func(c *gin.Context) {
+		<-release
+		c.Status(http.StatusAccepted)
+	})
+	router.POST("/fail", func(c *gin.Context) {
+		calls++
+		c.Status(http.StatusBadGateway)
+	})
+	router.POST("/export", func(c *gin.Context) {
+		calls++
+		c.String(http.StatusOK, strings.Repeat("x", 64))
+	})
+
+	sendAs := func(userID, path, key, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", path, bytes.NewBufferString(body))
+		if key != "" {
+			req.Header.Set(IdempotencyKeyHeader, key)
+		}
+		if userID != "" {
+			req.Header.Set("X-User", userID)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+	send := func(path, key, body string) *httptest.ResponseRecorder {
+		return sendAs("alice", path, key, body)
+	}
+
+	// The first response is replayed for retries
+	first := send("/orders", "k1", `{"item":"a"}`)
+	assert.Equal(t, http.StatusCreated, first.Code)
+	retry := send("/orders", "k1", `{"item":"a"}`)
+	assert.Equal(t, http.StatusCreated, retry.Code)
+	assert.Equal(t, first.Body.String(), retry.Body.String())
+	assert.Equal(t, "/orders/1", retry.Header().Get("Location"))
+	assert.Equal(t, "true", retry.Header().Get(IdempotentReplayedHeader))
+	assert.Empty(t, first.Header().Get(IdempotentReplayedHeader))
+	assert.Equal(t, 1, calls)
+
+	// Reusing the key for another request is rejected; other keys and no key run the handler
+	assert.Equal(t, http.StatusUnprocessableEntity, send("/orders", "k1", `{"item":"b"}`).Code)
+	assert.Equal(t, http.StatusCreated, send("/orders", "k2", `{"item":"b"}`).Code)
+	assert.Equal(t, http.StatusCreated, send("/orders", "", `{"item":"b"}`).Code)
+	assert.Equal(t, 3, calls)
+
+	// 5xx responses are not stored, so that the client can retry
+	assert.Equal(t, http.StatusBadGateway, send("/fail", "k3", "").Code)
+	assert.Equal(t, http.StatusBadGateway, send("/fail", "k3", "").Code)
+	assert.Equal(t, 5, calls)
+
+	// Duplicates of a request in progress get 409
+	done := make(chan int)
+	go func() { done <- send("/slow", "k4", "").Code }()
+	assert.Eventually(t, func() bool {
+		w := send("/slow", "k4", "")
+		if w.Code == http.StatusConflict {
+			assert.Equal(t, "1", w.Header().Get("Retry-After"))
+			return true
+		}
+		return false
+	}, time.Second, 5*time.Millisecond)
+	close(release)
+	assert.Equal(t, http.StatusAccepted, <-done)
+	assert.Equal(t, http.StatusAccepted, send("/slow", "k4", "").Code)
+
+	// Keys are scoped per user; anonymous clients share theirs, and reuse is still checked
+	calls = 0
+	assert.Equal(t, http.StatusCreated, sendAs("bob", "/orders", "k1", `{"item":"a"}`).Code)
+	assert.Equal(t, http.StatusCreated, sendAs("", "/orders", "k5", `{"item":"a"}`).Code)
+	assert.Equal(t, "true", sendAs("", "/orders", "k5", `{"item":"a"}`).Header().Get(IdempotentReplayedHeader))
+	assert.Equal(t, http.StatusUnprocessableEntity, sendAs("", "/orders", "k5", `{"item":"b"}`).Code)
+	assert.Equal(t, 2, calls)
+
+	// Large bodies are rejected; large responses are sent but not stored
+	assert.Equal(t, http.StatusRequestEntityTooLarge, send("/orders", "k6", strings.Repeat("x", 65)).Code)
+	calls = 0
+	for i := 0; i < 2; i++ {
+		w := send("/export", "k7", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Len(t, w.Body.String(), 64)
+	}
+	assert.Equal(t, 2, calls)
+}
+
+func TestIdempotencyMiddleware_AnonymousScope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	calls := 0
+	router := gin.New()
+	router.Use(IdempotencyMiddleware(IdempotencyConfig{AnonymousScope: RateLimitByAPIKey("X-API-Key")}))
+	router.POST("/orders", func(c *gin.Context) {
+		calls++
+		c.Status(http.StatusCreated)
+	})
+	send := func(apiKey, body string) int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/orders", bytes.NewBufferString(body))
+		req.Header.Set(IdempotencyKeyHeader, "k")
+		req.Header.Set("X-API-Key", apiKey)
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusCreated, send("client-a", `{"item":"a"}`))
+	assert.Equal(t, http.StatusCreated, send("client-b", `{"item":"b"}`))
+	assert.Equal(t, http.StatusUnprocessableEntity, send("client-a", `{"item":"b"}`))
+	assert.Equal(t, 2, calls)
+}
+
+func TestIdempotencyMemoryStore_Ownership(t *testing.T) {
+	store := NewIdempotencyMemoryStore()
+	ctx := context.Background()
+	now := time.Now()
+
+	// The first request's lock expires and a retry takes the key over
+	_, reserved, _ := store.Reserve(ctx, "k", "fp", "first", now, now.Add(time.Second))
+	assert.True(t, reserved)
+	later := now.Add(2 * time.Second)
+	_, reserved, _ = store.Reserve(ctx, "k", "fp", "second", later, later.Add(time.Minute))
+	assert.True(t, reserved)
+
+	// The first request can no longer release, extend or complete the key
+	assert.NoError(t, store.Release(ctx, "k", "fp", "first"))
+	assert.NoError(t, store.Extend(ctx, "k", "fp", "first", later.Add(time.Hour)))
+	err := store.Complete(ctx, "k", IdempotencyRecord{Fingerprint: "fp", Owner: "first", Completed: true, StatusCode: 500, ExpiresAt: later.Add(time.Hour)})
+	assert.ErrorIs(t, err, ErrIdempotencyKeyLost)
+	existing, reserved, _ := store.Reserve(ctx, "k", "fp", "third", later, later.Add(time.Minute))
+	assert.False(t, reserved)
+	if assert.NotNil(t, existing) {
+		assert.False(t, existing.Completed)
+		assert.Equal(t, "second", existing.Owner)
+		assert.Equal(t, later.Add(time.Minute), existing.ExpiresAt)
+	}
+
+	assert.NoError(t, store.Complete(ctx, "k", IdempotencyRecord{Fingerprint: "fp", Owner: "second", Completed: true, StatusCode: 201, ExpiresAt: later.Add(time.Hour)}))
+	assert.NoError(t, store.Release(ctx, "k", "fp", "second"))
+	existing, _, _ = store.Reserve(ctx, "k", "fp", "third", later, later.Add(time.Minute))
+	if assert.NotNil(t, existing) {
+		assert.Equal(t, 201, existing.StatusCode)
+	}
+}
+
+func TestIdempotencyMiddleware_RenewsLock(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var calls atomic.Int32
+	release := make(chan struct{})
+	router := gin.New()
+	router.Use(IdempotencyMiddleware(IdempotencyConfig{LockTimeout: 20 * time.Millisecond}))
+	router.POST("/slow", func(c *gin.Context) {
+		if calls.Add(1) == 1 {
+			<-release
+		}
+		c.Status(http.StatusAccepted)
+	})
+	send := func() int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/slow", nil)
+		req.Header.Set(IdempotencyKeyHeader, "k")
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	done := make(chan int)
+	go func() { done <- send() }()
+	assert.Eventually(t, func() bool { return send() == http.StatusConflict }, time.Second, time.Millisecond)
+
+	// Long after the lock timeout the key is still held by the running request
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, http.StatusConflict, send())
+	close(release)
+	assert.Equal(t, http.StatusAccepted, <-done)
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestIdempotencyFingerprint_Multipart(t *testing.T) {
+	request := func() *http.Request {
+		var body bytes.Buffer
+		writer := multipart.NewWriter(&body)
+		part, _ := writer.CreateFormFile("file", "data.csv")
+		part.Write([]byte("a,b\n1,2\n"))
+		writer.Close()
+		req, _ := http.NewRequest("POST", "/upload", &body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		return req
+	}
+
+	// Every writer picks a random boundary
+	a, b := request(), request()
+	assert.NotEqual(t, a.Header.Get("Content-Type"), b.Header.Get("Content-Type"))
+	bodyA, bodyB := new(bytes.Buffer), new(bytes.Buffer)
+	bodyA.ReadFrom(a.Body)
+	bodyB.ReadFrom(b.Body)
+	assert.Equal(t, idempotencyFingerprint(a, bodyA.Bytes()), idempotencyFingerprint(b, bodyB.Bytes()))
+	assert.NotEqual(t, idempotencyFingerprint(a, bodyA.Bytes()), idempotencyFingerprint(a, []byte("other")))
+}
+
+func TestIdempotencyMigrations(t *testing.T) {
+	migrator, err := db.NewMigrator(db.NewMockDB(), IdempotencyMigrations, db.MigratorConfig{Dir: "migrations", Table: IdempotencyMigrationsTable}, &MockLogger{})
+	assert.NoError(t, err)
+	migrations, err := migrator.Migrations()
+	assert.NoError(t, err)
+	if assert.Len(t, migrations, 2) {
+		assert.Contains(t, migrations[0].UpSQL, DefaultIdempotencyTable)
+		assert.NotEmpty(t, migrations[0].DownSQL)
+		assert.Contains(t, migrations[1].UpSQL, "owner")
+	}
+}
+
+func TestIdempotencyPostgresStore(t *testing.T) {
+	mock := db.NewMockDB()
+	ctx := context.Background()
+	store, err := NewIdempotencyPostgresStore(mock, "")
+	assert.NoError(t, err)
+
+	now := time.Unix(1700000000, 0).UTC()
+	lock := now.Add(time.Minute)
+	expires := now.Add(24 * time.Hour)
+	columns := []string{"fingerprint", "completed", "status_code", "header", "body", "expires_at"}
+	mock.ExpectQuery("INSERT INTO idempotency_keys").WithArgs("user:1:k", "fp", now, lock, "o1").
+		WillReturnRows(db.NewMockRows("key").AddRow("user:1:k"))
+	mock.ExpectExec("UPDATE idempotency_keys SET completed = TRUE").
+		WithArgs("user:1:k", 201, `{"Location":["/orders/1"]}`, []byte(`{"id":1}`), expires, "fp", "o1").WillReturnResult(0, 1)
+	mock.ExpectQuery("INSERT INTO idempotency_keys").WithArgs("user:1:k", "fp", now, lock, "o1").
+		WillReturnRows(db.NewMockRows("key"))
+	mock.ExpectQuery("SELECT fingerprint, completed, status_code, header, body, expires_at FROM idempotency_keys").WithArgs("user:1:k").
+		WillReturnRows(db.NewMockRows(columns...).AddRow("fp", true, 201, `{"Location":["/orders/1"]}`, []byte(`{"id":1}`), expires))
+	mock.ExpectExec("UPDATE idempotency_keys SET expires_at = $4").WithArgs("user:1:k", "fp", "o1", expires)
+	mock.ExpectExec("DELETE FROM idempotency_keys WHERE key = $1 AND fingerprint = $2 AND owner = $3 AND NOT completed").WithArgs("user:1:k", "fp", "o1")
+	mock.ExpectExec("DELETE FROM idempotency_keys WHERE expires_at < $1").WithArgs(now).WillReturnResult(0, 3)
+
+	existing, reserved, err := store.Reserve(ctx, "user:1:k", "fp", "o1", now, lock)
+	assert.NoError(t, err)
+	assert.True(t, reserved)
+	assert.Nil(t, existing)
+
+	err = store.Complete(ctx, "user:1:k", IdempotencyRecord{
+		Fingerprint: "fp",
+		Owner:       "o1",
+		Completed:   true,
+		StatusCode:  201,
+		Header:      http.Header{"Location": {"/orders/1"}},
+		Body:        []byte(`{"id":1}`),
+		ExpiresAt:   expires,
+	})
+	assert.NoError(t, err)
+
+	existing, reserved, err = store.Reserve(ctx, "user:1:k", "fp", "o1", now, lock)
+	assert.NoError(t, err)
+	assert.False(t, reserved)
+	if assert.NotNil(t, existing) {
+		assert.True(t, existing.Completed)
+		assert.Equal(t, 201, existing.StatusCode)
+		assert.Equal(t, "/orders/1", existing.Header.Get("Location"))
+		assert.Equal(t, `{"id":1}`, string(existing.Body))
+	}
+
+	assert.NoError(t, store.Extend(ctx, "user:1:k", "fp", "o1", expires))
+	assert.NoError(t, store.Release(ctx, "user:1:k", "fp", "o1"))
+	removed, err := store.Cleanup(ctx, now)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, removed)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	_, err = NewIdempotencyPostgresStore(mock, "keys; DROP TABLE users")
+	assert.Error(t, err)
+}
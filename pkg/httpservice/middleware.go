@@ -4,67 +4,13 @@ import (
 	"net/http"
 	"regexp"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/yourorg/go-service-kit/pkg/logging"
 	"github.com/yourorg/go-service-kit/pkg/utils"
-	"golang.org/x/time/rate"
 )
 
-// RateLimitConfig holds configuration for rate limiting.
-type RateLimitConfig struct {
-	RPS   float64 // Requests per second
-	Burst int     // Maximum burst size
-}
-
-// RateLimitMiddleware limits the number of requests per second per IP.
-func RateLimitMiddleware(cfg RateLimitConfig) gin.HandlerFunc {
-	type client struct {
-		limiter  *rate.Limiter
-		lastSeen time.Time
-	}
-	var (
-		mu      sync.Mutex
-		clients = make(map[string]*client)
-	)
-
-	// Background goroutine to clean up old clients
-	go func() {
-		for {
-			time.Sleep(time.Minute)
-			mu.Lock()
-			for ip, c := range clients {
-				if time.Since(c.lastSeen) > 3*time.Minute {
-					delete(clients, ip)
-				}
-			}
-			mu.Unlock()
-		}
-	}()
-
-	return func(c *gin.Context) {
-		ip := c.ClientIP()
-		mu.Lock()
-		if _, found := clients[ip]; !found {
-			clients[ip] = &client{
-				limiter: rate.NewLimiter(rate.Limit(cfg.RPS), cfg.Burst),
-			}
-		}
-		clients[ip].lastSeen = time.Now()
-		if !clients[ip].limiter.Allow() {
-			mu.Unlock()
-			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
-				"error": "Too many requests",
-			})
-			return
-		}
-		mu.Unlock()
-		c.Next()
-	}
-}
-
 // SecurityHeadersMiddleware adds security-related headers to responses.
 func SecurityHeadersMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
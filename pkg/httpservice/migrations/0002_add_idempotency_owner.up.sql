@@ -0,0 +1,3 @@
+-- Token of the request holding an in-progress key, so that a request whose lock expired
+-- cannot complete or release the reservation of the next one
+ALTER TABLE idempotency_keys ADD COLUMN IF NOT EXISTS owner TEXT NOT NULL DEFAULT '';
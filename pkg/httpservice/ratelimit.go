@@ -0,0 +1,154 @@
+package httpservice
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourorg/go-service-kit/pkg/jwt"
+	"github.com/yourorg/go-service-kit/pkg/logging"
+	"github.com/yourorg/go-service-kit/pkg/ratelimit"
+)
+
+// Rate limit response headers, following the IETF RateLimit header fields draft.
+const (
+	RateLimitLimitHeader     = "RateLimit-Limit"
+	RateLimitRemainingHeader = "RateLimit-Remaining"
+	RateLimitResetHeader     = "RateLimit-Reset"
+)
+
+// RateLimitKeyFunc returns the key a request is counted against.
+type RateLimitKeyFunc func(c *gin.Context) string
+
+// RateLimitByIP counts requests per client IP.
+func RateLimitByIP(c *gin.Context) string {
+	return "ip:" + c.ClientIP()
+}
+
+// RateLimitByUser counts requests per JWT user ID, falling back to the client IP for
+// anonymous requests. It needs the JWT middleware to run first, e.g. on a route group, or
+// a RateLimitPolicyConfig with Tokens.
+func RateLimitByUser(c *gin.Context) string {
+	if userID, ok := jwt.GetUserID(c); ok && userID != "" {
+		return "user:" + userID
+	}
+	if userID := c.GetString(rateLimitUserKey); userID != "" {
+		return "user:" + userID
+	}
+	return RateLimitByIP(c)
+}
+
+// RateLimitByAPIKey counts requests per value of header, falling back to the client IP
+// when it is missing. Keys are hashed so that the store never holds them.
+func RateLimitByAPIKey(header string) RateLimitKeyFunc {
+	return func(c *gin.Context) string {
+		apiKey := c.GetHeader(header)
+		if apiKey == "" {
+			return RateLimitByIP(c)
+		}
+		sum := sha256.Sum256([]byte(apiKey))
+		return "key:" + hex.EncodeToString(sum[:16])
+	}
+}
+
+// RateLimitByRoute counts requests per method and route pattern, across all clients.
+func RateLimitByRoute(c *gin.Context) string {
+	route := c.FullPath()
+	if route == "" {
+		route = c.Request.URL.Path
+	}
+	return "route:" + c.Request.Method + " " + route
+}
+
+// RateLimitKeys combines key functions, e.g. RateLimitKeys(RateLimitByRoute, RateLimitByUser)
+// gives every user a separate budget per route.
+func RateLimitKeys(funcs ...RateLimitKeyFunc) RateLimitKeyFunc {
+	return func(c *gin.Context) string {
+		parts := make([]string, len(funcs))
+		for i, fn := range funcs {
+			parts[i] = fn(c)
+		}
+		return strings.Join(parts, "|")
+	}
+}
+
+// RateLimitConfig holds configuration for rate limiting.
+type RateLimitConfig struct {
+	RPS   float64 // Requests per second
+	Burst int     // Maximum burst size
+	// Store holds the limiter state (default: a new ratelimit.MemoryStore). Use a
+	// ratelimit.PostgresStore to share the limit between replicas.
+	Store ratelimit.Store
+	// Key selects what requests are counted against (default: RateLimitByIP)
+	Key RateLimitKeyFunc
+	// Name prefixes the keys, separating limits that share a store (default: "http")
+	Name string
+	// Logger reports store failures, during which requests are let through (optional)
+	Logger logging.Logger
+}
+
+// RateLimitMiddleware limits the rate of requests per key, by default per client IP. It
+// sets the RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset headers on every
+// response and Retry-After on 429 responses.
+func RateLimitMiddleware(cfg RateLimitConfig) gin.HandlerFunc {
+	if cfg.Store == nil {
+		cfg.Store = ratelimit.NewMemoryStore()
+	}
+	if cfg.Key == nil {
+		cfg.Key = RateLimitByIP
+	}
+	if cfg.Name == "" {
+		cfg.Name = "http"
+	}
+	limiter, _ := ratelimit.NewLimiter(cfg.Store)
+	limit := ratelimit.Limit{RPS: cfg.RPS, Burst: cfg.Burst}
+
+	return func(c *gin.Context) {
+		key := cfg.Name + ":" + cfg.Key(c)
+		result, err := limiter.Allow(c.Request.Context(), key, limit, 1)
+		if err != nil {
+			if cfg.Logger != nil {
+				cfg.Logger.Warn("Rate limit check failed, allowing request",
+					logging.NewField("error", err),
+					logging.NewField("path", c.Request.URL.Path),
+				)
+			}
+			c.Next()
+			return
+		}
+
+		if !applyRateLimitResult(c, result) {
+			return
+		}
+		c.Next()
+	}
+}
+
+// applyRateLimitResult writes the rate limit headers and, if the request was denied,
+// aborts it with 429. It reports whether the request may proceed.
+func applyRateLimitResult(c *gin.Context, result ratelimit.Result) bool {
+	c.Header(RateLimitLimitHeader, strconv.Itoa(result.Limit))
+	c.Header(RateLimitRemainingHeader, strconv.Itoa(result.Remaining))
+	c.Header(RateLimitResetHeader, strconv.Itoa(ceilSeconds(result.ResetAfter)))
+	if result.Allowed {
+		return true
+	}
+
+	if result.RetryAfter > 0 {
+		c.Header("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+	}
+	c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+		"error": "Too many requests",
+	})
+	return false
+}
+
+// ceilSeconds rounds d up to whole seconds.
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,256 @@
+package httpservice
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourorg/go-service-kit/pkg/config"
+	"github.com/yourorg/go-service-kit/pkg/jwt"
+	"github.com/yourorg/go-service-kit/pkg/logging"
+	"github.com/yourorg/go-service-kit/pkg/ratelimit"
+)
+
+// rateLimitUserKey holds the user ID a RateLimitPolicyConfig read from the bearer token,
+// for RateLimitByUser, without marking the request as authenticated.
+const rateLimitUserKey = "rate_limit_user_id"
+
+// RateLimitPolicy limits the requests matching its route, methods and roles. Empty match
+// fields match everything; Roles never match anonymous requests.
+type RateLimitPolicy struct {
+	Name    string   // identifies the budget in the store; required and unique
+	Route   string   // route pattern, e.g. "/api/v1/reports/:id", or a prefix ending in "*"
+	Methods []string // e.g. POST
+	Roles   []string // JWT role IDs (jwt.ContextKeyRoleID)
+	Limit   ratelimit.Limit
+	Key     RateLimitKeyFunc // default: RateLimitByUser
+}
+
+// RateLimitCost weights the requests matching a route and methods in every policy they
+// count towards, e.g. 10 for an expensive export that shares a budget with cheap reads.
+type RateLimitCost struct {
+	Route   string
+	Methods []string
+	Cost    int
+}
+
+// RateLimitPolicyConfig configures RateLimitPolicyMiddleware.
+type RateLimitPolicyConfig struct {
+	// Policies all apply to the requests they match, each with its own budget
+	Policies []RateLimitPolicy
+	// Costs are matched in order; requests matching none cost 1
+	Costs []RateLimitCost
+	// ExemptRoles are never limited, e.g. httpclient.DefaultServiceRole for service tokens
+	ExemptRoles []string
+	// Tokens, if set, reads the user and role from a valid bearer token when no JWT
+	// middleware ran before, as is the case for server-wide middleware. Invalid tokens
+	// are treated as anonymous and left to the JWT middleware to reject.
+	Tokens *jwt.JWTService
+	// Store holds the limiter state (default: a new ratelimit.MemoryStore)
+	Store ratelimit.Store
+	// Logger reports store failures, during which requests are let through (optional)
+	Logger logging.Logger
+}
+
+// RateLimitPolicyConfigFromConfig builds a RateLimitPolicyConfig from the application
+// configuration. Store, Tokens and Logger are left for the caller to set.
+func RateLimitPolicyConfigFromConfig(cfg *config.Config) (RateLimitPolicyConfig, error) {
+	policyCfg := RateLimitPolicyConfig{ExemptRoles: cfg.RateLimitExemptRoles}
+	for _, policy := range cfg.RateLimitPolicies {
+		key, err := rateLimitKeyByName(policy.Key)
+		if err != nil {
+			return RateLimitPolicyConfig{}, fmt.Errorf("invalid rate limit policy %q: %w", policy.Name, err)
+		}
+		policyCfg.Policies = append(policyCfg.Policies, RateLimitPolicy{
+			Name:    policy.Name,
+			Route:   policy.Route,
+			Methods: policy.Methods,
+			Roles:   policy.Roles,
+			Limit:   ratelimit.Limit{RPS: policy.RPS, Burst: policy.Burst},
+			Key:     key,
+		})
+	}
+	for _, cost := range cfg.RateLimitCosts {
+		policyCfg.Costs = append(policyCfg.Costs, RateLimitCost{Route: cost.Route, Methods: cost.Methods, Cost: cost.Cost})
+	}
+	return policyCfg, nil
+}
+
+// rateLimitKeyByName returns the key function of a configured policy.
+func rateLimitKeyByName(name string) (RateLimitKeyFunc, error) {
+	switch name {
+	case "", "user":
+		return RateLimitByUser, nil
+	case "ip":
+		return RateLimitByIP, nil
+	case "api_key":
+		return RateLimitByAPIKey("X-API-Key"), nil
+	case "route":
+		return RateLimitByRoute, nil
+	default:
+		return nil, fmt.Errorf("unknown key %q: must be user, ip, api_key or route", name)
+	}
+}
+
+// RateLimitPolicyMiddleware applies every policy matching a request, charging the cost of
+// its route, and rejects it with 429 if any budget is exhausted; the other budgets are
+// still charged. The headers describe the policy with the fewest remaining requests.
+func RateLimitPolicyMiddleware(cfg RateLimitPolicyConfig) (gin.HandlerFunc, error) {
+	names := make(map[string]bool, len(cfg.Policies))
+	for i, policy := range cfg.Policies {
+		if policy.Name == "" {
+			return nil, fmt.Errorf("rate limit policy %d has no name", i)
+		}
+		if names[policy.Name] {
+			return nil, fmt.Errorf("duplicate rate limit policy %q", policy.Name)
+		}
+		if policy.Limit.RPS <= 0 {
+			return nil, fmt.Errorf("rate limit policy %q needs a positive rate", policy.Name)
+		}
+		names[policy.Name] = true
+	}
+	for _, cost := range cfg.Costs {
+		if cost.Cost < 1 {
+			return nil, fmt.Errorf("rate limit cost of %q must be at least 1", cost.Route)
+		}
+		// A request costing more than a policy's burst could never be allowed
+		for _, policy := range cfg.Policies {
+			if burst := policy.Limit.EffectiveBurst(); cost.Cost > burst && routesOverlap(cost.Route, cost.Methods, policy.Route, policy.Methods) {
+				return nil, fmt.Errorf("rate limit cost %d of %q exceeds the burst %d of policy %q", cost.Cost, cost.Route, burst, policy.Name)
+			}
+		}
+	}
+	if cfg.Store == nil {
+		cfg.Store = ratelimit.NewMemoryStore()
+	}
+	limiter, err := ratelimit.NewLimiter(cfg.Store)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(c *gin.Context) {
+		roleID := rateLimitIdentify(c, cfg.Tokens)
+		if roleID != "" && slices.Contains(cfg.ExemptRoles, roleID) {
+			c.Next()
+			return
+		}
+
+		cost := 1
+		for _, rule := range cfg.Costs {
+			if matchRoute(c, rule.Route, rule.Methods) {
+				cost = rule.Cost
+				break
+			}
+		}
+
+		var tightest, denied *ratelimit.Result
+		for _, policy := range cfg.Policies {
+			if !matchRoute(c, policy.Route, policy.Methods) || (len(policy.Roles) > 0 && !slices.Contains(policy.Roles, roleID)) {
+				continue
+			}
+			key := policy.Key
+			if key == nil {
+				key = RateLimitByUser
+			}
+
+			result, err := limiter.Allow(c.Request.Context(), "policy:"+policy.Name+":"+key(c), policy.Limit, cost)
+			if err != nil {
+				if cfg.Logger != nil {
+					cfg.Logger.Warn("Rate limit check failed, allowing request",
+						logging.NewField("error", err),
+						logging.NewField("policy", policy.Name),
+						logging.NewField("path", c.Request.URL.Path),
+					)
+				}
+				continue
+			}
+			if !result.Allowed && (denied == nil || result.RetryAfter > denied.RetryAfter) {
+				denied = &result
+			}
+			if tightest == nil || result.Remaining < tightest.Remaining {
+				tightest = &result
+			}
+		}
+
+		switch {
+		case denied != nil:
+			applyRateLimitResult(c, *denied)
+		case tightest != nil:
+			applyRateLimitResult(c, *tightest)
+			c.Next()
+		default:
+			c.Next()
+		}
+	}, nil
+}
+
+// rateLimitIdentify returns the JWT role of the request, reading the bearer token with
+// tokens if no JWT middleware ran before.
+func rateLimitIdentify(c *gin.Context, tokens *jwt.JWTService) string {
+	if roleID, ok := jwt.GetRoleID(c); ok {
+		return roleID
+	}
+	if tokens == nil {
+		return ""
+	}
+
+	token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !ok {
+		return ""
+	}
+	claims, err := tokens.ValidateToken(strings.TrimSpace(token))
+	if err != nil {
+		return ""
+	}
+	c.Set(rateLimitUserKey, claims.UserID)
+	return claims.RoleID
+}
+
+// matchRoute reports whether the request matches a route pattern, or prefix ending in
+// "*", and methods. Empty values match every request.
+func matchRoute(c *gin.Context, route string, methods []string) bool {
+	if len(methods) > 0 && !slices.ContainsFunc(methods, func(method string) bool {
+		return strings.EqualFold(method, c.Request.Method)
+	}) {
+		return false
+	}
+	if route == "" {
+		return true
+	}
+
+	path := c.FullPath()
+	if path == "" {
+		path = c.Request.URL.Path
+	}
+	if prefix, ok := strings.CutSuffix(route, "*"); ok {
+		return strings.HasPrefix(path, prefix)
+	}
+	return path == route
+}
+
+// routesOverlap reports whether some request could match both route patterns and methods
+// as matchRoute matches them.
+func routesOverlap(routeA string, methodsA []string, routeB string, methodsB []string) bool {
+	if len(methodsA) > 0 && len(methodsB) > 0 && !slices.ContainsFunc(methodsA, func(a string) bool {
+		return slices.ContainsFunc(methodsB, func(b string) bool { return strings.EqualFold(a, b) })
+	}) {
+		return false
+	}
+	if routeA == "" || routeB == "" {
+		return true
+	}
+
+	prefixA, wildA := strings.CutSuffix(routeA, "*")
+	prefixB, wildB := strings.CutSuffix(routeB, "*")
+	switch {
+	case wildA && wildB:
+		return strings.HasPrefix(prefixA, prefixB) || strings.HasPrefix(prefixB, prefixA)
+	case wildA:
+		return strings.HasPrefix(routeB, prefixA)
+	case wildB:
+		return strings.HasPrefix(routeA, prefixB)
+	default:
+		return routeA == routeB
+	}
+}
@@ -2,6 +2,7 @@ package httpservice
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -9,7 +10,9 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"github.com/yourorg/go-service-kit/pkg/jwt"
 	"github.com/yourorg/go-service-kit/pkg/logging"
+	"github.com/yourorg/go-service-kit/pkg/ratelimit"
 )
 
 // MockLogger implements logging.Logger for testing
@@ -70,6 +73,124 @@ func TestRateLimitMiddleware(t *testing.T) {
 	assert.Equal(t, http.StatusOK, w.Code)
 }
 
+func TestRateLimitMiddleware_HeadersAndKeys(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := ratelimit.NewMemoryStore()
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		if user := c.GetHeader("X-Test-User"); user != "" {
+			c.Set(jwt.ContextKeyUserID, user)
+		}
+	})
+	router.Use(RateLimitMiddleware(RateLimitConfig{RPS: 1, Burst: 1, Store: store, Key: RateLimitByUser}))
+	router.GET("/", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	send := func(user string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/", nil)
+		req.Header.Set("X-Test-User", user)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send("alice")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "1", w.Header().Get(RateLimitLimitHeader))
+	assert.Equal(t, "0", w.Header().Get(RateLimitRemainingHeader))
+	assert.Equal(t, "1", w.Header().Get(RateLimitResetHeader))
+
+	w = send("alice")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+
+	// Users share neither their budget nor the one of anonymous clients
+	assert.Equal(t, http.StatusOK, send("bob").Code)
+	assert.Equal(t, http.StatusOK, send("").Code)
+	assert.Equal(t, 3, store.Len())
+}
+
+func TestRateLimitPolicyMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tokens, err := jwt.NewJWTService("0123456789abcdef0123456789abcdef", time.Hour, time.Hour, &MockLogger{})
+	assert.NoError(t, err)
+	token := func(userID, roleID string) string {
+		tokenString, err := tokens.GenerateAccessToken(userID, roleID, "")
+		assert.NoError(t, err)
+		return tokenString
+	}
+
+	middleware, err := RateLimitPolicyMiddleware(RateLimitPolicyConfig{
+		Policies: []RateLimitPolicy{
+			{Name: "default", Limit: ratelimit.Limit{RPS: 1, Burst: 10}},
+			{Name: "pdf", Route: "/api/v1/csv-to-pdf", Methods: []string{"POST"}, Limit: ratelimit.Limit{RPS: 1, Burst: 8}},
+			{Name: "guests", Methods: []string{"GET"}, Roles: []string{"guest"}, Limit: ratelimit.Limit{RPS: 1, Burst: 1}},
+		},
+		Costs:       []RateLimitCost{{Route: "/api/v1/*", Methods: []string{"post"}, Cost: 4}},
+		ExemptRoles: []string{"service"},
+		Tokens:      tokens,
+	})
+	assert.NoError(t, err)
+
+	router := gin.New()
+	router.Use(middleware)
+	router.POST("/api/v1/csv-to-pdf", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.GET("/api/v1/items/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	send := func(method, path, bearer string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, nil)
+		if bearer != "" {
+			req.Header.Set("Authorization", "Bearer "+bearer)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Each PDF costs 4 of both the route's and the user's budget
+	alice := token("alice", "admin")
+	w := send("POST", "/api/v1/csv-to-pdf", alice)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "4", w.Header().Get(RateLimitRemainingHeader))
+	assert.Equal(t, http.StatusOK, send("POST", "/api/v1/csv-to-pdf", alice).Code)
+	assert.Equal(t, http.StatusTooManyRequests, send("POST", "/api/v1/csv-to-pdf", alice).Code)
+
+	// Cheap reads only count towards the user's budget
+	w = send("GET", "/api/v1/items/1", alice)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "1", w.Header().Get(RateLimitRemainingHeader))
+	assert.Equal(t, http.StatusOK, send("GET", "/api/v1/items/1", "").Code)
+
+	// Service tokens are exempt; role policies apply to their role only
+	service := token("billing", "service")
+	for i := 0; i < 5; i++ {
+		w = send("POST", "/api/v1/csv-to-pdf", service)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Header().Get(RateLimitLimitHeader))
+	}
+	guest := token("bob", "guest")
+	assert.Equal(t, http.StatusOK, send("GET", "/api/v1/items/1", guest).Code)
+	assert.Equal(t, http.StatusTooManyRequests, send("GET", "/api/v1/items/1", guest).Code)
+
+	_, err = RateLimitPolicyMiddleware(RateLimitPolicyConfig{Policies: []RateLimitPolicy{{Name: "zero"}}})
+	assert.Error(t, err)
+
+	// A cost above the burst of an overlapping policy could never be allowed
+	_, err = RateLimitPolicyMiddleware(RateLimitPolicyConfig{
+		Policies: []RateLimitPolicy{{Name: "reports", Route: "/api/v1/reports/*", Limit: ratelimit.Limit{RPS: 1, Burst: 3}}},
+		Costs:    []RateLimitCost{{Route: "/api/v1/*", Methods: []string{"POST"}, Cost: 4}},
+	})
+	assert.ErrorContains(t, err, `policy "reports"`)
+	_, err = RateLimitPolicyMiddleware(RateLimitPolicyConfig{
+		Policies: []RateLimitPolicy{{Name: "reads", Methods: []string{"GET"}, Limit: ratelimit.Limit{RPS: 1, Burst: 1}}},
+		Costs:    []RateLimitCost{{Route: "/api/v1/*", Methods: []string{"POST"}, Cost: 4}},
+	})
+	assert.NoError(t, err)
+}
+
 func TestSecurityHeadersMiddleware(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	router := gin.New()
@@ -172,3 +293,21 @@ func TestCORSMiddleware(t *testing.T) {
 		})
 	}
 }
+
+func TestHealthHandler_HidesErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.GET("/health", healthHandler(map[string]HealthCheckFunc{
+		"database": func(ctx context.Context) error { return errors.New("dial tcp db.internal:5432: connection refused") },
+		"blob":     func(ctx context.Context) error { return nil },
+	}, &MockLogger{}))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/health", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.NotContains(t, w.Body.String(), "db.internal")
+	assert.JSONEq(t, `{"status":"unavailable","checks":{"database":"unavailable","blob":"ok"}}`, w.Body.String())
+}
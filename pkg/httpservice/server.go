@@ -4,10 +4,13 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"slices"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/yourorg/go-service-kit/pkg/logging"
+	"github.com/yourorg/go-service-kit/pkg/ratelimit"
+	"github.com/yourorg/go-service-kit/pkg/utils"
 )
 
 // Server wraps a Gin server with configuration and middleware.
@@ -16,6 +19,7 @@ type Server struct {
 	httpServer *http.Server
 	logger     logging.Logger
 	port       int
+	stop       context.CancelFunc // stops background jobs
 }
 
 // ServerConfig configures the HTTP server.
@@ -26,12 +30,19 @@ type ServerConfig struct {
 	IdleTimeout  time.Duration
 	Logger       logging.Logger
 	// Security Configuration
+	// Deprecated: RateLimitRPS and RateLimitBurst are replaced by RateLimit. A positive
+	// RateLimitRPS adds a policy limiting every route per client IP.
 	RateLimitRPS   float64
-	RateLimitBurst int
+	RateLimitBurst int // Deprecated: see RateLimitRPS.
+	// RateLimit assigns rate limits by route, method and JWT role. Expired keys of its
+	// Store, e.g. a ratelimit.PostgresStore shared by replicas, are removed until Shutdown.
+	RateLimit      RateLimitPolicyConfig
 	AllowedOrigins []string
 	AllowedMethods []string
 	AllowedHeaders []string
 	MaxBodySize    int64 // Maximum request body size in bytes (default: 10MB)
+	// HealthChecks are run by the /health endpoint, keyed by dependency name
+	HealthChecks map[string]HealthCheckFunc
 }
 
 // NewServer creates a new HTTP server with the provided configuration and handlers.
@@ -76,11 +87,28 @@ func NewServer(cfg ServerConfig, handlers ...Handler) (*Server, error) {
 	router.Use(CORSMiddleware(corsCfg))
 
 	// Configure Rate Limiting if enabled
+	rateLimit := cfg.RateLimit
 	if cfg.RateLimitRPS > 0 {
-		router.Use(RateLimitMiddleware(RateLimitConfig{
-			RPS:   cfg.RateLimitRPS,
-			Burst: cfg.RateLimitBurst,
-		}))
+		rateLimit.Policies = append(slices.Clip(rateLimit.Policies), RateLimitPolicy{
+			Name:  "default",
+			Limit: ratelimit.Limit{RPS: cfg.RateLimitRPS, Burst: cfg.RateLimitBurst},
+			Key:   RateLimitByIP,
+		})
+	}
+	bgCtx, stop := context.WithCancel(context.Background())
+	if len(rateLimit.Policies) > 0 {
+		if rateLimit.Logger == nil {
+			rateLimit.Logger = cfg.Logger
+		}
+		rateLimitMiddleware, err := RateLimitPolicyMiddleware(rateLimit)
+		if err != nil {
+			stop()
+			return nil, fmt.Errorf("invalid rate limit configuration: %w", err)
+		}
+		router.Use(rateLimitMiddleware)
+		if rateLimit.Store != nil {
+			utils.StartCleanup(bgCtx, "rate_limit", rateLimit.Store, time.Minute, cfg.Logger)
+		}
 	}
 
 	// Register handlers
@@ -89,9 +117,7 @@ func NewServer(cfg ServerConfig, handlers ...Handler) (*Server, error) {
 	}
 
 	// Health check endpoint
-	router.GET("/health", func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{"status": "ok"})
-	})
+	router.GET("/health", healthHandler(cfg.HealthChecks, cfg.Logger))
 
 	httpServer := &http.Server{
 		Addr:         fmt.Sprintf(":%d", cfg.Port),
@@ -106,6 +132,7 @@ func NewServer(cfg ServerConfig, handlers ...Handler) (*Server, error) {
 		httpServer: httpServer,
 		logger:     cfg.Logger,
 		port:       cfg.Port,
+		stop:       stop,
 	}, nil
 }
 
@@ -139,6 +166,7 @@ func (s *Server) StartTLS(certFile, keyFile string) error {
 // Shutdown gracefully shuts down the server.
 func (s *Server) Shutdown(ctx context.Context) error {
 	s.logger.Info("Shutting down HTTP server")
+	s.stop()
 	return s.httpServer.Shutdown(ctx)
 }
 
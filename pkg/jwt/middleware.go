@@ -6,6 +6,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/yourorg/go-service-kit/pkg/logging"
+	"github.com/yourorg/go-service-kit/pkg/tenant"
 )
 
 const (
@@ -234,6 +235,36 @@ func CompanyBasedMiddleware(requiredCompanyID string, logger logging.Logger) gin
 	}
 }
 
+// TenantMiddleware creates a middleware that scopes the request to the tenant in the token's company_id.
+// The tenant is attached to the request context (see tenant.FromContext) so that tenant-aware
+// components such as db.TenantDB and blobclient.TenantBlobClient isolate data automatically.
+// Must run after JWTMiddleware.
+func TenantMiddleware(logger logging.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		companyIDStr, ok := GetCompanyID(c)
+		if !ok || companyIDStr == "" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Company information not found in token"})
+			c.Abort()
+			return
+		}
+
+		if err := tenant.Validate(companyIDStr); err != nil {
+			logger.Warn("Invalid tenant in token",
+				logging.NewField("ip", c.ClientIP()),
+				logging.NewField("path", c.Request.URL.Path),
+			)
+			c.JSON(http.StatusForbidden, gin.H{"error": "Invalid company in token"})
+			c.Abort()
+			return
+		}
+
+		ctx := tenant.WithTenant(c.Request.Context(), companyIDStr)
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+	}
+}
+
 // GetUserID extracts user ID from context
 func GetUserID(c *gin.Context) (string, bool) {
 	userID, exists := c.Get(ContextKeyUserID)
@@ -22,6 +22,15 @@ func FromContext(ctx context.Context) Logger {
 	return &noOpLogger{}
 }
 
+// FromContextOrDefault retrieves the logger from the context.
+// Returns fallback if no logger is attached.
+func FromContextOrDefault(ctx context.Context, fallback Logger) Logger {
+	if logger, ok := ctx.Value(loggerKey).(Logger); ok {
+		return logger
+	}
+	return fallback
+}
+
 // noOpLogger is a logger that does nothing (useful for tests or when logger is not available).
 type noOpLogger struct{}
 
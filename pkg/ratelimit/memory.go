@@ -0,0 +1,72 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// memorySweepInterval is how often a MemoryStore drops expired keys on its own.
+const memorySweepInterval = time.Minute
+
+// MemoryStore is a Store local to the process, for single instances and tests. With
+// several replicas each one enforces the limit separately; use PostgresStore to share it.
+// Expired keys are dropped while serving requests, so no cleanup job is needed.
+type MemoryStore struct {
+	mu        sync.Mutex
+	tats      map[string]time.Time
+	lastSweep time.Time
+}
+
+// NewMemoryStore creates an empty in-memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{tats: make(map[string]time.Time)}
+}
+
+// Advance implements Store.
+func (m *MemoryStore) Advance(ctx context.Context, key string, now time.Time, increment, tolerance time.Duration) (time.Time, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if now.Sub(m.lastSweep) >= memorySweepInterval {
+		m.sweep(now)
+	}
+
+	tat, ok := m.tats[key]
+	if !ok || tat.Before(now) {
+		tat = now
+	}
+	next := tat.Add(increment)
+	if next.Sub(now) > tolerance {
+		return tat, false, nil
+	}
+	m.tats[key] = next
+	return next, true, nil
+}
+
+// Cleanup implements Store.
+func (m *MemoryStore) Cleanup(ctx context.Context, now time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.sweep(now), nil
+}
+
+// sweep removes keys whose TAT is before now. The caller must hold m.mu.
+func (m *MemoryStore) sweep(now time.Time) int {
+	removed := 0
+	for key, tat := range m.tats {
+		if tat.Before(now) {
+			delete(m.tats, key)
+			removed++
+		}
+	}
+	m.lastSweep = now
+	return removed
+}
+
+// Len returns the number of keys held.
+func (m *MemoryStore) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.tats)
+}
@@ -0,0 +1,6 @@
+-- GCRA theoretical arrival times in Unix nanoseconds (ratelimit.PostgresStore)
+CREATE TABLE IF NOT EXISTS rate_limits (
+	key TEXT PRIMARY KEY,
+	tat BIGINT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS rate_limits_tat_idx ON rate_limits (tat);
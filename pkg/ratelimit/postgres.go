@@ -0,0 +1,86 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/yourorg/go-service-kit/pkg/db"
+)
+
+// DefaultTable is the table used by a PostgresStore when none is given.
+const DefaultTable = "rate_limits"
+
+// Migrations create the DefaultTable of a PostgresStore. Apply them with a db.Migrator
+// using Dir "migrations" and Table MigrationsTable, as their versions are numbered
+// independently of other packages' migrations.
+//
+//go:embed migrations/*.sql
+var Migrations embed.FS
+
+// MigrationsTable records the applied Migrations.
+const MigrationsTable = "ratelimit_schema_migrations"
+
+// PostgresStore is a Store in a Postgres table with one row per key, shared by all
+// replicas. TATs are stored as Unix nanoseconds computed from the replicas' clocks, so
+// those should be synchronized. The table (key TEXT PRIMARY KEY, tat BIGINT, indexed by
+// tat) is created by Migrations. Run utils.StartCleanup to remove expired rows.
+type PostgresStore struct {
+	db    db.DB
+	table string
+}
+
+// NewPostgresStore creates a store in table (default: DefaultTable).
+func NewPostgresStore(database db.DB, table string) (*PostgresStore, error) {
+	if database == nil {
+		return nil, fmt.Errorf("database is required")
+	}
+	if table == "" {
+		table = DefaultTable
+	}
+	if err := db.ValidateTableName(table); err != nil {
+		return nil, fmt.Errorf("invalid rate limit table: %w", err)
+	}
+	return &PostgresStore{db: database, table: table}, nil
+}
+
+// Advance implements Store in a single statement; a denied request costs a second query
+// to read the current TAT.
+func (p *PostgresStore) Advance(ctx context.Context, key string, now time.Time, increment, tolerance time.Duration) (time.Time, bool, error) {
+	ctx = db.WithPrimary(ctx)
+	nowNanos := now.UnixNano()
+
+	var tat int64
+	err := p.db.QueryRow(ctx, fmt.Sprintf(`INSERT INTO %[1]s AS r (key, tat) VALUES ($1, $2)
+ON CONFLICT (key) DO UPDATE SET tat = GREATEST(r.tat, $3) + $4
+WHERE GREATEST(r.tat, $3) + $4 <= $5
+RETURNING tat`, p.table), key, nowNanos+int64(increment), nowNanos, int64(increment), nowNanos+int64(tolerance)).Scan(&tat)
+	if err == nil {
+		return time.Unix(0, tat), true, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, false, fmt.Errorf("failed to update rate limit of %s: %w", key, err)
+	}
+
+	err = p.db.QueryRow(ctx, fmt.Sprintf("SELECT tat FROM %s WHERE key = $1", p.table), key).Scan(&tat)
+	if errors.Is(err, sql.ErrNoRows) {
+		return now, false, nil // removed by a concurrent cleanup
+	}
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("failed to read rate limit of %s: %w", key, err)
+	}
+	return time.Unix(0, tat), false, nil
+}
+
+// Cleanup implements Store.
+func (p *PostgresStore) Cleanup(ctx context.Context, now time.Time) (int, error) {
+	result, err := p.db.Exec(db.WithPrimary(ctx), fmt.Sprintf("DELETE FROM %s WHERE tat < $1", p.table), now.UnixNano())
+	if err != nil {
+		return 0, fmt.Errorf("failed to clean up rate limits: %w", err)
+	}
+	removed, _ := result.RowsAffected()
+	return int(removed), nil
+}
@@ -0,0 +1,120 @@
+// Package ratelimit implements rate limiting with the generic cell rate algorithm (GCRA)
+// over a pluggable store, so that replicas of a service can share one budget per key.
+//
+// GCRA keeps a single "theoretical arrival time" (TAT) per key: each request moves it
+// forward by the interval between requests at the sustained rate, and a request is
+// allowed while the TAT stays within the burst tolerance of the current time. Stores only
+// need to advance that timestamp atomically.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Limit is a sustained rate with a burst allowance.
+type Limit struct {
+	RPS   float64 // Requests per second
+	Burst int     // Maximum burst size (default: RPS rounded up, at least 1)
+}
+
+// PerMinute returns a limit of n requests per minute with a burst of burst.
+func PerMinute(n, burst int) Limit {
+	return Limit{RPS: float64(n) / 60, Burst: burst}
+}
+
+// EffectiveBurst returns the burst size applied, which bounds the cost of a single request.
+func (l Limit) EffectiveBurst() int {
+	if l.Burst > 0 {
+		return l.Burst
+	}
+	return max(int(math.Ceil(l.RPS)), 1)
+}
+
+// interval returns the time between requests at the sustained rate.
+func (l Limit) interval() time.Duration {
+	return time.Duration(float64(time.Second) / l.RPS)
+}
+
+// Result is the outcome of a Limiter.Allow call.
+type Result struct {
+	Allowed    bool
+	Limit      int           // burst size, the most requests allowed at once
+	Remaining  int           // requests that could be made right now
+	RetryAfter time.Duration // when denied, how long until the request would be allowed
+	ResetAfter time.Duration // how long until the full burst is available again
+}
+
+// Store keeps the theoretical arrival time of every key.
+type Store interface {
+	// Advance atomically moves the TAT of key to max(TAT, now) + increment, provided the
+	// result is no later than now + tolerance. It returns the TAT after the call and
+	// whether it was moved. Keys without a TAT behave as if their TAT were now.
+	Advance(ctx context.Context, key string, now time.Time, increment, tolerance time.Duration) (time.Time, bool, error)
+	// Cleanup removes keys whose TAT is before now, since they behave like missing keys,
+	// and returns how many were removed.
+	Cleanup(ctx context.Context, now time.Time) (int, error)
+}
+
+// LimiterOption configures a Limiter.
+type LimiterOption func(*Limiter)
+
+// WithClock sets the clock of the limiter (default: time.Now).
+func WithClock(now func() time.Time) LimiterOption {
+	return func(l *Limiter) {
+		l.now = now
+	}
+}
+
+// Limiter applies limits to keys using a store.
+type Limiter struct {
+	store Store
+	now   func() time.Time
+}
+
+// NewLimiter creates a limiter backed by store.
+func NewLimiter(store Store, opts ...LimiterOption) (*Limiter, error) {
+	if store == nil {
+		return nil, fmt.Errorf("store is required")
+	}
+
+	l := &Limiter{store: store, now: time.Now}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l, nil
+}
+
+// Allow spends cost requests (at least 1) of key's budget under limit. A cost larger than
+// the burst is never allowed.
+func (l *Limiter) Allow(ctx context.Context, key string, limit Limit, cost int) (Result, error) {
+	if limit.RPS <= 0 {
+		return Result{}, fmt.Errorf("invalid rate limit: %g requests per second", limit.RPS)
+	}
+	cost = max(cost, 1)
+
+	now := l.now()
+	burst := limit.EffectiveBurst()
+	interval := limit.interval()
+	tolerance := interval * time.Duration(burst)
+	result := Result{Limit: burst}
+	if cost > burst {
+		return result, nil
+	}
+
+	tat, allowed, err := l.store.Advance(ctx, key, now, interval*time.Duration(cost), tolerance)
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to apply rate limit: %w", err)
+	}
+
+	ahead := max(tat.Sub(now), 0)
+	result.Allowed = allowed
+	result.Remaining = int((tolerance - ahead) / interval)
+	result.ResetAfter = ahead
+	if !allowed {
+		result.RetryAfter = max(ahead+interval*time.Duration(cost)-tolerance, 0)
+	}
+	return result, nil
+}
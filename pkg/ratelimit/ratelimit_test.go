@@ -0,0 +1,127 @@
+package ratelimit
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yourorg/go-service-kit/pkg/db"
+	"github.com/yourorg/go-service-kit/pkg/logging"
+)
+
+func TestLimiter_GCRA(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	store := NewMemoryStore()
+	limiter, err := NewLimiter(store, WithClock(func() time.Time { return now }))
+	if err != nil {
+		t.Fatalf("NewLimiter failed: %v", err)
+	}
+	ctx := context.Background()
+	limit := Limit{RPS: 2, Burst: 3}
+
+	for i := 0; i < 3; i++ {
+		result, err := limiter.Allow(ctx, "ip:1", limit, 1)
+		if err != nil || !result.Allowed || result.Remaining != 2-i || result.Limit != 3 {
+			t.Fatalf("Request %d: expected allowed with %d remaining, got %+v (err %v)", i, 2-i, result, err)
+		}
+	}
+	result, _ := limiter.Allow(ctx, "ip:1", limit, 1)
+	if result.Allowed || result.Remaining != 0 || result.RetryAfter != 500*time.Millisecond || result.ResetAfter != 1500*time.Millisecond {
+		t.Fatalf("Expected a denial with a 500ms retry, got %+v", result)
+	}
+
+	// Other keys have their own budget
+	if result, _ := limiter.Allow(ctx, "ip:2", limit, 1); !result.Allowed {
+		t.Errorf("Expected another key to be allowed, got %+v", result)
+	}
+
+	// The budget refills at the sustained rate; a cost spends several requests at once
+	now = now.Add(time.Second)
+	if result, _ := limiter.Allow(ctx, "ip:1", limit, 3); result.Allowed {
+		t.Errorf("Expected a cost of 3 to exceed the 2 refilled requests, got %+v", result)
+	}
+	if result, _ := limiter.Allow(ctx, "ip:1", limit, 2); !result.Allowed || result.Remaining != 0 {
+		t.Errorf("Expected a cost of 2 to be allowed, got %+v", result)
+	}
+	if result, _ := limiter.Allow(ctx, "ip:1", limit, 4); result.Allowed || result.RetryAfter != 0 {
+		t.Errorf("Expected a cost above the burst to be denied for good, got %+v", result)
+	}
+
+	if _, err := limiter.Allow(ctx, "ip:1", Limit{}, 1); err == nil {
+		t.Error("Expected an error for a zero rate")
+	}
+}
+
+func TestMemoryStore_Cleanup(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	store := NewMemoryStore()
+	ctx := context.Background()
+	store.Advance(ctx, "a", now, time.Second, time.Second)
+	store.Advance(ctx, "b", now, time.Minute, time.Minute)
+
+	removed, err := store.Cleanup(ctx, now.Add(2*time.Second))
+	if err != nil || removed != 1 || store.Len() != 1 {
+		t.Errorf("Expected one expired key to be removed, got %d (err %v), %d left", removed, err, store.Len())
+	}
+
+	// Advance sweeps expired keys by itself once a minute
+	store.Advance(ctx, "c", now.Add(2*time.Minute), time.Second, time.Second)
+	if store.Len() != 1 {
+		t.Errorf("Expected only the new key after a sweep, got %d", store.Len())
+	}
+}
+
+func TestMigrations(t *testing.T) {
+	logger, _ := logging.NewLogger("error", "json")
+	migrator, err := db.NewMigrator(db.NewMockDB(), Migrations, db.MigratorConfig{Dir: "migrations", Table: MigrationsTable}, logger)
+	if err != nil {
+		t.Fatalf("NewMigrator failed: %v", err)
+	}
+	migrations, err := migrator.Migrations()
+	if err != nil {
+		t.Fatalf("Migrations failed: %v", err)
+	}
+	if len(migrations) != 1 || !strings.Contains(migrations[0].UpSQL, DefaultTable) || migrations[0].DownSQL == "" {
+		t.Errorf("Expected one migration creating %s, got %+v", DefaultTable, migrations)
+	}
+}
+
+func TestPostgresStore(t *testing.T) {
+	mock := db.NewMockDB()
+	ctx := context.Background()
+	store, err := NewPostgresStore(mock, "")
+	if err != nil {
+		t.Fatalf("NewPostgresStore failed: %v", err)
+	}
+
+	now := time.Unix(1700000000, 0)
+	nanos := now.UnixNano()
+	second := int64(time.Second)
+	mock.ExpectQuery("INSERT INTO rate_limits").WithArgs("ip:1", nanos+second, nanos, second, nanos+2*second).
+		WillReturnRows(db.NewMockRows("tat").AddRow(nanos + second))
+	mock.ExpectQuery("INSERT INTO rate_limits").WithArgs("ip:1", nanos+second, nanos, second, nanos+2*second).
+		WillReturnRows(db.NewMockRows("tat"))
+	mock.ExpectQuery("SELECT tat FROM rate_limits WHERE key = $1").WithArgs("ip:1").
+		WillReturnRows(db.NewMockRows("tat").AddRow(nanos + 2*second))
+	mock.ExpectExec("DELETE FROM rate_limits WHERE tat < $1").WithArgs(nanos).WillReturnResult(0, 4)
+
+	tat, ok, err := store.Advance(ctx, "ip:1", now, time.Second, 2*time.Second)
+	if err != nil || !ok || !tat.Equal(now.Add(time.Second)) {
+		t.Errorf("Advance = %v, %v, %v", tat, ok, err)
+	}
+	tat, ok, err = store.Advance(ctx, "ip:1", now, time.Second, 2*time.Second)
+	if err != nil || ok || !tat.Equal(now.Add(2*time.Second)) {
+		t.Errorf("Advance over the tolerance = %v, %v, %v", tat, ok, err)
+	}
+	if removed, err := store.Cleanup(ctx, now); err != nil || removed != 4 {
+		t.Errorf("Cleanup = %d, %v", removed, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+
+	if _, err := NewPostgresStore(mock, "limits; DROP TABLE users"); err == nil {
+		t.Error("Expected error for an invalid table name")
+	}
+}
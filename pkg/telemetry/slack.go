@@ -9,6 +9,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/yourorg/go-service-kit/pkg/httpclient"
 	"github.com/yourorg/go-service-kit/pkg/logging"
 	"github.com/yourorg/go-service-kit/pkg/utils"
 )
@@ -19,7 +20,7 @@ type SlackClient struct {
 	serviceName string
 	logger      logging.Logger
 	enabled     bool
-	client      *http.Client
+	client      *httpclient.Client
 	mu          sync.Mutex
 	lastSent    time.Time
 	minInterval time.Duration
@@ -68,12 +69,23 @@ func NewSlackClient(cfg SlackConfig, logger logging.Logger) *SlackClient {
 		}
 	}
 
+	// The webhook path is the secret, and Slack has no use for our request IDs
+	client, err := httpclient.New(httpclient.Config{Timeout: 10 * time.Second}, logger,
+		httpclient.WithRedactedPath(), httpclient.WithoutIDForwarding())
+	if err != nil {
+		logger.Error("Failed to create Slack HTTP client", logging.NewField("error", err))
+		return &SlackClient{
+			enabled: false,
+			logger:  logger,
+		}
+	}
+
 	return &SlackClient{
 		webhookURL:  cfg.WebhookURL,
 		serviceName: cfg.ServiceName,
 		logger:      logger,
 		enabled:     true,
-		client:      &http.Client{Timeout: 10 * time.Second},
+		client:      client,
 		minInterval: 1 * time.Second, // Minimum interval between messages
 	}
 }
@@ -0,0 +1,57 @@
+package tenant
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+// ErrMissingTenant is returned when a tenant-scoped operation runs without a tenant in context.
+var ErrMissingTenant = errors.New("tenant not found in context")
+
+// ErrInvalidTenant is returned when a tenant ID is unsafe to use in paths or settings.
+var ErrInvalidTenant = errors.New("invalid tenant ID")
+
+type contextKey string
+
+const tenantKey contextKey = "tenant_id"
+
+// WithTenant attaches a tenant ID to the context.
+func WithTenant(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, tenantKey, tenantID)
+}
+
+// FromContext retrieves the tenant ID from the context.
+func FromContext(ctx context.Context) (string, bool) {
+	tenantID, ok := ctx.Value(tenantKey).(string)
+	return tenantID, ok && tenantID != ""
+}
+
+// Require retrieves and validates the tenant ID from the context.
+// It returns ErrMissingTenant if none is set and ErrInvalidTenant if it is unsafe.
+func Require(ctx context.Context) (string, error) {
+	tenantID, ok := FromContext(ctx)
+	if !ok {
+		return "", ErrMissingTenant
+	}
+	if err := Validate(tenantID); err != nil {
+		return "", err
+	}
+	return tenantID, nil
+}
+
+// Validate checks that a tenant ID cannot escape a path prefix or contain control characters.
+func Validate(tenantID string) error {
+	if tenantID == "" || len(tenantID) > 255 {
+		return ErrInvalidTenant
+	}
+	if tenantID == "." || tenantID == ".." || strings.ContainsAny(tenantID, "/\\") {
+		return ErrInvalidTenant
+	}
+	for _, r := range tenantID {
+		if r < 0x20 || r == 0x7f {
+			return ErrInvalidTenant
+		}
+	}
+	return nil
+}
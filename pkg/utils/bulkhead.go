@@ -0,0 +1,102 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// ErrBulkheadFull is returned by a Bulkhead that has no free slot for a call.
+var ErrBulkheadFull = errors.New("bulkhead full")
+
+// BulkheadConfig configures a Bulkhead.
+type BulkheadConfig struct {
+	// Name identifies the protected dependency in errors and metrics.
+	Name string
+	// MaxConcurrent is the number of calls allowed at the same time (default 10).
+	MaxConcurrent int
+	// MaxWait is how long a call waits for a free slot; zero rejects it at once.
+	MaxWait time.Duration
+}
+
+// BulkheadStats is a snapshot of a Bulkhead.
+type BulkheadStats struct {
+	Name          string
+	MaxConcurrent int
+	InFlight      int
+	Rejected      int64 // calls rejected since the bulkhead was created
+}
+
+// Bulkhead limits the number of concurrent calls to a dependency, so that a slow
+// dependency cannot tie up every request goroutine. It is safe for concurrent use.
+type Bulkhead struct {
+	cfg      BulkheadConfig
+	slots    chan struct{}
+	rejected atomic.Int64
+}
+
+// NewBulkhead creates a bulkhead with all slots free.
+func NewBulkhead(cfg BulkheadConfig) *Bulkhead {
+	if cfg.MaxConcurrent <= 0 {
+		cfg.MaxConcurrent = 10
+	}
+	return &Bulkhead{cfg: cfg, slots: make(chan struct{}, cfg.MaxConcurrent)}
+}
+
+// Acquire takes a slot, waiting up to MaxWait for one to become free. It returns a
+// function that releases the slot, or an error wrapping ErrBulkheadFull, or the context
+// error if ctx ends while waiting.
+func (b *Bulkhead) Acquire(ctx context.Context) (func(), error) {
+	release := func() { <-b.slots }
+
+	select {
+	case b.slots <- struct{}{}:
+		return release, nil
+	default:
+	}
+	if b.cfg.MaxWait <= 0 {
+		return nil, b.fullError()
+	}
+
+	timer := time.NewTimer(b.cfg.MaxWait)
+	defer timer.Stop()
+	select {
+	case b.slots <- struct{}{}:
+		return release, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-timer.C:
+		return nil, b.fullError()
+	}
+}
+
+// Execute runs fn in a slot of the bulkhead.
+func (b *Bulkhead) Execute(ctx context.Context, fn func(ctx context.Context) error) error {
+	release, err := b.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return fn(ctx)
+}
+
+// Stats returns a snapshot of the bulkhead.
+func (b *Bulkhead) Stats() BulkheadStats {
+	return BulkheadStats{
+		Name:          b.cfg.Name,
+		MaxConcurrent: b.cfg.MaxConcurrent,
+		InFlight:      len(b.slots),
+		Rejected:      b.rejected.Load(),
+	}
+}
+
+// fullError counts a rejection and returns ErrBulkheadFull naming the bulkhead.
+func (b *Bulkhead) fullError() error {
+	b.rejected.Add(1)
+	if b.cfg.Name == "" {
+		return ErrBulkheadFull
+	}
+	return fmt.Errorf("%w: %s", ErrBulkheadFull, b.cfg.Name)
+}
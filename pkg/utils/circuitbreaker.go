@@ -0,0 +1,323 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned by a CircuitBreaker that rejects a call without running it.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// CircuitState is the state of a CircuitBreaker.
+type CircuitState int
+
+const (
+	// CircuitClosed lets every call through and counts failures.
+	CircuitClosed CircuitState = iota
+	// CircuitOpen rejects every call until the open timeout has passed.
+	CircuitOpen
+	// CircuitHalfOpen lets a limited number of probe calls through to decide whether to close.
+	CircuitHalfOpen
+)
+
+// String returns the state name used in logs, metrics and health checks.
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// CircuitBreakerConfig configures a CircuitBreaker. Zero values select the defaults.
+type CircuitBreakerConfig struct {
+	// Name identifies the protected dependency in errors, metrics and health checks.
+	Name string
+	// FailureThreshold opens the circuit after this many consecutive failures. Zero disables
+	// it, unless FailureRate is also zero, in which case it defaults to 5.
+	FailureThreshold int
+	// FailureRate opens the circuit when the share of failed calls within a window reaches
+	// it, between 0 and 1. Zero disables it.
+	FailureRate float64
+	// MinRequests is the number of calls a window needs before FailureRate applies (default 10).
+	MinRequests int
+	// Window is the period calls are counted over for FailureRate (default 1 minute).
+	Window time.Duration
+	// OpenTimeout is how long the circuit stays open before probing (default 30 seconds).
+	OpenTimeout time.Duration
+	// HalfOpenMaxCalls is the number of probe calls allowed while half-open; that many
+	// successes close the circuit and any failure opens it again (default 1).
+	HalfOpenMaxCalls int
+	// IsFailure reports whether an error counts against the dependency (default: any
+	// error). Errors that do not count are successes; use it to ignore e.g. not-found errors.
+	IsFailure func(err error) bool
+	// OnStateChange is called after every state change, e.g. to log or alert.
+	OnStateChange func(name string, from, to CircuitState)
+	// Clock returns the current time (default time.Now).
+	Clock func() time.Time
+}
+
+// CircuitBreakerStats is a snapshot of a CircuitBreaker.
+type CircuitBreakerStats struct {
+	Name                string
+	State               CircuitState
+	Requests            int // calls counted in the current window
+	Failures            int // failed calls in the current window
+	ConsecutiveFailures int
+	Rejected            int64 // calls rejected since the breaker was created
+	OpenedAt            time.Time
+}
+
+// CircuitBreaker stops calls to a failing dependency. It opens after FailureThreshold
+// consecutive failures or when the failure rate of a window reaches FailureRate, rejects
+// calls with ErrCircuitOpen for OpenTimeout and then lets HalfOpenMaxCalls probe calls
+// through to decide whether to close again. It is safe for concurrent use.
+type CircuitBreaker struct {
+	cfg CircuitBreakerConfig
+
+	mu                  sync.Mutex
+	state               CircuitState
+	generation          uint64 // incremented on every state change to drop stale outcomes
+	windowStart         time.Time
+	requests            int
+	failures            int
+	consecutiveFailures int
+	rejected            int64
+	openedAt            time.Time
+	probes              int
+	probeSuccesses      int
+	changes             []circuitChange
+}
+
+// circuitChange is a state change waiting to be reported to OnStateChange.
+type circuitChange struct {
+	from, to CircuitState
+}
+
+// NewCircuitBreaker creates a closed circuit breaker.
+func NewCircuitBreaker(cfg CircuitBreakerConfig) *CircuitBreaker {
+	if cfg.FailureThreshold <= 0 && cfg.FailureRate <= 0 {
+		cfg.FailureThreshold = 5
+	}
+	if cfg.MinRequests <= 0 {
+		cfg.MinRequests = 10
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = time.Minute
+	}
+	if cfg.OpenTimeout <= 0 {
+		cfg.OpenTimeout = 30 * time.Second
+	}
+	if cfg.HalfOpenMaxCalls <= 0 {
+		cfg.HalfOpenMaxCalls = 1
+	}
+	if cfg.IsFailure == nil {
+		cfg.IsFailure = func(err error) bool { return err != nil }
+	}
+	if cfg.Clock == nil {
+		cfg.Clock = time.Now
+	}
+	return &CircuitBreaker{cfg: cfg, state: CircuitClosed, windowStart: cfg.Clock()}
+}
+
+// Name returns the configured name.
+func (cb *CircuitBreaker) Name() string {
+	return cb.cfg.Name
+}
+
+// Allow reserves a call. It returns an error wrapping ErrCircuitOpen if the call must not
+// be made, and otherwise a function to report its outcome with, which must be called
+// exactly once. Outcomes reported while ctx is done are not counted: the caller gave up,
+// which says nothing about the dependency.
+func (cb *CircuitBreaker) Allow() (func(ctx context.Context, err error), error) {
+	cb.mu.Lock()
+	defer cb.unlock()
+
+	cb.expire()
+	switch cb.state {
+	case CircuitOpen:
+		cb.rejected++
+		return nil, cb.openError()
+	case CircuitHalfOpen:
+		if cb.probes >= cb.cfg.HalfOpenMaxCalls {
+			cb.rejected++
+			return nil, cb.openError()
+		}
+		cb.probes++
+	}
+
+	generation := cb.generation
+	var once sync.Once
+	return func(ctx context.Context, err error) {
+		once.Do(func() { cb.done(ctx, generation, err) })
+	}, nil
+}
+
+// Execute runs fn through the circuit breaker.
+func (cb *CircuitBreaker) Execute(ctx context.Context, fn func(ctx context.Context) error) error {
+	done, err := cb.Allow()
+	if err != nil {
+		return err
+	}
+	err = fn(ctx)
+	done(ctx, err)
+	return err
+}
+
+// done records the outcome of a call allowed in generation.
+func (cb *CircuitBreaker) done(ctx context.Context, generation uint64, err error) {
+	cb.mu.Lock()
+	defer cb.unlock()
+
+	// The state changed while the call ran; its outcome belongs to the previous state
+	if generation != cb.generation {
+		return
+	}
+	if cb.state == CircuitHalfOpen {
+		cb.probes--
+	}
+	if err != nil && ctx.Err() != nil {
+		return
+	}
+
+	now := cb.cfg.Clock()
+	failed := cb.cfg.IsFailure(err)
+	switch cb.state {
+	case CircuitHalfOpen:
+		if failed {
+			cb.setState(CircuitOpen, now)
+			return
+		}
+		if cb.probeSuccesses++; cb.probeSuccesses >= cb.cfg.HalfOpenMaxCalls {
+			cb.setState(CircuitClosed, now)
+		}
+	case CircuitClosed:
+		if now.Sub(cb.windowStart) >= cb.cfg.Window {
+			cb.resetWindow(now)
+		}
+		cb.requests++
+		if !failed {
+			cb.consecutiveFailures = 0
+			return
+		}
+		cb.failures++
+		cb.consecutiveFailures++
+		if cb.tripped() {
+			cb.setState(CircuitOpen, now)
+		}
+	}
+}
+
+// tripped reports whether the failure counts of the closed state open the circuit.
+func (cb *CircuitBreaker) tripped() bool {
+	if cb.cfg.FailureThreshold > 0 && cb.consecutiveFailures >= cb.cfg.FailureThreshold {
+		return true
+	}
+	return cb.cfg.FailureRate > 0 && cb.requests >= cb.cfg.MinRequests &&
+		float64(cb.failures)/float64(cb.requests) >= cb.cfg.FailureRate
+}
+
+// expire moves an open circuit whose timeout has passed to half-open. Callers hold the lock.
+func (cb *CircuitBreaker) expire() {
+	now := cb.cfg.Clock()
+	if cb.state == CircuitOpen && now.Sub(cb.openedAt) >= cb.cfg.OpenTimeout {
+		cb.setState(CircuitHalfOpen, now)
+	}
+}
+
+// State returns the current state.
+func (cb *CircuitBreaker) State() CircuitState {
+	cb.mu.Lock()
+	defer cb.unlock()
+	cb.expire()
+	return cb.state
+}
+
+// Stats returns a snapshot of the state and counters.
+func (cb *CircuitBreaker) Stats() CircuitBreakerStats {
+	cb.mu.Lock()
+	defer cb.unlock()
+	cb.expire()
+	return CircuitBreakerStats{
+		Name:                cb.cfg.Name,
+		State:               cb.state,
+		Requests:            cb.requests,
+		Failures:            cb.failures,
+		ConsecutiveFailures: cb.consecutiveFailures,
+		Rejected:            cb.rejected,
+		OpenedAt:            cb.openedAt,
+	}
+}
+
+// HealthCheck fails while the circuit is open. It satisfies httpservice.HealthCheckFunc.
+func (cb *CircuitBreaker) HealthCheck(ctx context.Context) error {
+	if state := cb.State(); state == CircuitOpen {
+		return cb.openError()
+	}
+	return nil
+}
+
+// Reset closes the circuit and clears its counters.
+func (cb *CircuitBreaker) Reset() {
+	cb.mu.Lock()
+	defer cb.unlock()
+	now := cb.cfg.Clock()
+	cb.setState(CircuitClosed, now)
+	cb.consecutiveFailures = 0
+	cb.resetWindow(now)
+}
+
+// setState moves to state and resets the counters of the new state. Callers hold the lock.
+func (cb *CircuitBreaker) setState(state CircuitState, now time.Time) {
+	if state == cb.state {
+		return
+	}
+	cb.changes = append(cb.changes, circuitChange{from: cb.state, to: state})
+	cb.state = state
+	cb.generation++
+	cb.probes = 0
+	cb.probeSuccesses = 0
+	cb.consecutiveFailures = 0
+	cb.resetWindow(now)
+	if state == CircuitOpen {
+		cb.openedAt = now
+	}
+}
+
+// resetWindow starts a new counting window. Callers hold the lock.
+func (cb *CircuitBreaker) resetWindow(now time.Time) {
+	cb.windowStart = now
+	cb.requests = 0
+	cb.failures = 0
+}
+
+// unlock releases the lock and reports state changes made while it was held, so that
+// OnStateChange may call back into the breaker.
+func (cb *CircuitBreaker) unlock() {
+	changes := cb.changes
+	cb.changes = nil
+	cb.mu.Unlock()
+
+	if cb.cfg.OnStateChange == nil {
+		return
+	}
+	for _, change := range changes {
+		cb.cfg.OnStateChange(cb.cfg.Name, change.from, change.to)
+	}
+}
+
+// openError returns ErrCircuitOpen naming the breaker.
+func (cb *CircuitBreaker) openError() error {
+	if cb.cfg.Name == "" {
+		return ErrCircuitOpen
+	}
+	return fmt.Errorf("%w: %s", ErrCircuitOpen, cb.cfg.Name)
+}
@@ -0,0 +1,210 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testClock is a settable clock.
+type testClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *testClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+var (
+	errUnavailable = errors.New("unavailable")
+	errNotFound    = errors.New("not found")
+)
+
+// fail runs a failing call through cb.
+func fail(cb *CircuitBreaker) error {
+	return cb.Execute(context.Background(), func(ctx context.Context) error { return errUnavailable })
+}
+
+// succeed runs a successful call through cb.
+func succeed(cb *CircuitBreaker) error {
+	return cb.Execute(context.Background(), func(ctx context.Context) error { return nil })
+}
+
+func TestCircuitBreaker_ConsecutiveFailures(t *testing.T) {
+	clock := &testClock{now: time.Now()}
+	var changes []string
+	cb := NewCircuitBreaker(CircuitBreakerConfig{
+		Name:             "rates",
+		FailureThreshold: 3,
+		OpenTimeout:      time.Minute,
+		Clock:            clock.Now,
+		OnStateChange: func(name string, from, to CircuitState) {
+			changes = append(changes, name+":"+from.String()+"->"+to.String())
+		},
+	})
+
+	fail(cb)
+	fail(cb)
+	succeed(cb)
+	fail(cb)
+	fail(cb)
+	if state := cb.State(); state != CircuitClosed {
+		t.Fatalf("Expected a success to reset the count, got %s", state)
+	}
+	fail(cb)
+	if err := succeed(cb); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Expected ErrCircuitOpen, got %v", err)
+	}
+	if err := cb.HealthCheck(context.Background()); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected the health check to fail while open, got %v", err)
+	}
+
+	// One probe is let through after the timeout; its failure opens the circuit again
+	clock.Advance(time.Minute)
+	if err := fail(cb); !errors.Is(err, errUnavailable) {
+		t.Fatalf("Expected the probe to run, got %v", err)
+	}
+	if state := cb.State(); state != CircuitOpen {
+		t.Fatalf("Expected a failed probe to open the circuit, got %s", state)
+	}
+
+	clock.Advance(time.Minute)
+	if err := succeed(cb); err != nil {
+		t.Fatalf("Expected the probe to run, got %v", err)
+	}
+	stats := cb.Stats()
+	if stats.State != CircuitClosed || stats.Rejected != 1 {
+		t.Errorf("Expected a closed circuit with 1 rejection, got %+v", stats)
+	}
+
+	want := []string{"rates:closed->open", "rates:open->half-open", "rates:half-open->open", "rates:open->half-open", "rates:half-open->closed"}
+	if len(changes) != len(want) {
+		t.Fatalf("Expected state changes %v, got %v", want, changes)
+	}
+	for i := range want {
+		if changes[i] != want[i] {
+			t.Errorf("Expected state changes %v, got %v", want, changes)
+			break
+		}
+	}
+}
+
+func TestCircuitBreaker_FailureRate(t *testing.T) {
+	clock := &testClock{now: time.Now()}
+	cb := NewCircuitBreaker(CircuitBreakerConfig{FailureRate: 0.5, MinRequests: 4, Window: time.Minute, Clock: clock.Now})
+
+	// Below MinRequests the rate does not apply
+	fail(cb)
+	succeed(cb)
+	fail(cb)
+	if state := cb.State(); state != CircuitClosed {
+		t.Fatalf("Expected closed below MinRequests, got %s", state)
+	}
+
+	// A new window starts from zero
+	clock.Advance(time.Minute)
+	succeed(cb)
+	succeed(cb)
+	succeed(cb)
+	fail(cb)
+	if state := cb.State(); state != CircuitClosed {
+		t.Fatalf("Expected closed at a 25%% failure rate, got %s", state)
+	}
+	fail(cb)
+	fail(cb)
+	if state := cb.State(); state != CircuitOpen {
+		t.Fatalf("Expected open at a 50%% failure rate, got %s", state)
+	}
+}
+
+func TestCircuitBreaker_IgnoredOutcomes(t *testing.T) {
+	cb := NewCircuitBreaker(CircuitBreakerConfig{
+		FailureThreshold: 1,
+		IsFailure:        func(err error) bool { return err != nil && !errors.Is(err, errNotFound) },
+	})
+
+	// Errors that are not failures and calls abandoned by the caller are not counted
+	cb.Execute(context.Background(), func(ctx context.Context) error { return errNotFound })
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	cb.Execute(cancelled, func(ctx context.Context) error { return ctx.Err() })
+	if state := cb.State(); state != CircuitClosed {
+		t.Fatalf("Expected closed, got %s", state)
+	}
+
+	// Outcomes of calls that started before a state change are dropped
+	done, err := cb.Allow()
+	if err != nil {
+		t.Fatalf("Allow failed: %v", err)
+	}
+	fail(cb)
+	cb.Reset()
+	done(context.Background(), errUnavailable)
+	if state := cb.State(); state != CircuitClosed {
+		t.Errorf("Expected a stale failure to be ignored, got %s", state)
+	}
+}
+
+func TestBulkhead(t *testing.T) {
+	b := NewBulkhead(BulkheadConfig{Name: "pdf", MaxConcurrent: 2})
+	ctx := context.Background()
+
+	release1, err := b.Acquire(ctx)
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	release2, _ := b.Acquire(ctx)
+	if _, err := b.Acquire(ctx); !errors.Is(err, ErrBulkheadFull) {
+		t.Fatalf("Expected ErrBulkheadFull, got %v", err)
+	}
+	if stats := b.Stats(); stats.InFlight != 2 || stats.Rejected != 1 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+	release1()
+	release2()
+
+	// Waiting callers get a slot once one is released
+	waiting := NewBulkhead(BulkheadConfig{MaxConcurrent: 1, MaxWait: time.Second})
+	release, _ := waiting.Acquire(ctx)
+	time.AfterFunc(10*time.Millisecond, release)
+	if err := waiting.Execute(ctx, func(ctx context.Context) error { return nil }); err != nil {
+		t.Errorf("Expected to get a slot after waiting, got %v", err)
+	}
+}
+
+func TestOperationComposition(t *testing.T) {
+	cb := NewCircuitBreaker(CircuitBreakerConfig{FailureThreshold: 2})
+	calls := 0
+	op := WithRetry(RetryConfig{MaxAttempts: 5, InitialDelay: time.Millisecond, MaxDelay: time.Millisecond, Multiplier: 1},
+		WithCircuitBreaker(cb,
+			WithBulkhead(NewBulkhead(BulkheadConfig{MaxConcurrent: 1}),
+				WithTimeout(10*time.Millisecond, func(ctx context.Context) (string, error) {
+					calls++
+					<-ctx.Done()
+					return "", ctx.Err()
+				}))))
+
+	// Retries stop as soon as the breaker opens
+	if _, err := op(context.Background()); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Expected ErrCircuitOpen, got %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("Expected 2 calls before the circuit opened, got %d", calls)
+	}
+
+	ok := WithRetry(DefaultRetryConfig(), WithTimeout(time.Second, func(ctx context.Context) (int, error) { return 42, nil }))
+	if result, err := ok(context.Background()); err != nil || result != 42 {
+		t.Errorf("Expected 42, got %d (err %v)", result, err)
+	}
+}
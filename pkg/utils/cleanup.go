@@ -0,0 +1,43 @@
+package utils
+
+import (
+	"context"
+	"time"
+
+	"github.com/yourorg/go-service-kit/pkg/logging"
+)
+
+// Cleaner is a store whose entries expire, such as a rate limit or idempotency store.
+type Cleaner interface {
+	// Cleanup removes entries that expired before now and returns how many were removed.
+	Cleanup(ctx context.Context, now time.Time) (int, error)
+}
+
+// StartCleanup calls cleaner.Cleanup every interval until ctx is cancelled. name labels
+// the log entries, e.g. "rate_limit". A zero interval disables the background job.
+func StartCleanup(ctx context.Context, name string, cleaner Cleaner, interval time.Duration, logger logging.Logger) {
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				removed, err := cleaner.Cleanup(ctx, time.Now())
+				if err != nil {
+					if ctx.Err() == nil {
+						logger.Error("Cleanup failed", logging.NewField("store", name), logging.NewField("error", err))
+					}
+					continue
+				}
+				logger.Debug("Cleanup", logging.NewField("store", name), logging.NewField("removed", removed))
+			}
+		}
+	}()
+}
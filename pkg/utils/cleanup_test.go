@@ -0,0 +1,52 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/yourorg/go-service-kit/pkg/logging"
+)
+
+// countingCleaner counts Cleanup calls and fails every other one.
+type countingCleaner struct {
+	calls atomic.Int32
+}
+
+func (c *countingCleaner) Cleanup(ctx context.Context, now time.Time) (int, error) {
+	if c.calls.Add(1)%2 == 0 {
+		return 0, errors.New("database unavailable")
+	}
+	return 1, nil
+}
+
+func TestStartCleanup(t *testing.T) {
+	logger, _ := logging.NewLogger("error", "json")
+	ctx, cancel := context.WithCancel(context.Background())
+	cleaner := &countingCleaner{}
+
+	StartCleanup(ctx, "test", cleaner, 5*time.Millisecond, logger)
+	for deadline := time.Now().Add(5 * time.Second); cleaner.calls.Load() < 3 && time.Now().Before(deadline); {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if calls := cleaner.calls.Load(); calls < 3 {
+		t.Fatalf("Expected cleanups to continue after a failure, got %d calls", calls)
+	}
+
+	cancel()
+	time.Sleep(20 * time.Millisecond)
+	stopped := cleaner.calls.Load()
+	time.Sleep(20 * time.Millisecond)
+	if calls := cleaner.calls.Load(); calls != stopped {
+		t.Errorf("Expected no cleanups after cancellation, got %d more", calls-stopped)
+	}
+
+	disabled := &countingCleaner{}
+	StartCleanup(context.Background(), "test", disabled, 0, logger)
+	time.Sleep(20 * time.Millisecond)
+	if calls := disabled.calls.Load(); calls != 0 {
+		t.Errorf("Expected a zero interval to disable cleanup, got %d calls", calls)
+	}
+}
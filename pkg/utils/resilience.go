@@ -0,0 +1,148 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/yourorg/go-service-kit/pkg/logging"
+)
+
+// Operation is a call to a dependency. The helpers below wrap an Operation with one
+// resilience concern each and compose from the outside in, for example:
+//
+//	op := utils.WithRetry(retryCfg,
+//		utils.WithCircuitBreaker(breaker,
+//			utils.WithBulkhead(bulkhead,
+//				utils.WithTimeout(2*time.Second, fetchRates))))
+//	rates, err := op(ctx)
+//
+// With this order every attempt gets its own timeout and slot, counts towards the
+// breaker, and retries stop as soon as the breaker opens.
+type Operation[T any] func(ctx context.Context) (T, error)
+
+// WithTimeout bounds each call of op with a deadline. A zero timeout leaves it unbounded.
+func WithTimeout[T any](timeout time.Duration, op Operation[T]) Operation[T] {
+	return func(ctx context.Context) (T, error) {
+		if timeout <= 0 {
+			return op(ctx)
+		}
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		return op(ctx)
+	}
+}
+
+// WithCircuitBreaker runs op through cb.
+func WithCircuitBreaker[T any](cb *CircuitBreaker, op Operation[T]) Operation[T] {
+	return func(ctx context.Context) (T, error) {
+		done, err := cb.Allow()
+		if err != nil {
+			var zero T
+			return zero, err
+		}
+		result, err := op(ctx)
+		done(ctx, err)
+		return result, err
+	}
+}
+
+// WithBulkhead runs op in a slot of b.
+func WithBulkhead[T any](b *Bulkhead, op Operation[T]) Operation[T] {
+	return func(ctx context.Context) (T, error) {
+		release, err := b.Acquire(ctx)
+		if err != nil {
+			var zero T
+			return zero, err
+		}
+		defer release()
+		return op(ctx)
+	}
+}
+
+// WithRetry retries op with cfg. Rejections by an open circuit breaker are returned at
+// once, even with a custom RetryIf, since retrying them only delays the caller.
+func WithRetry[T any](cfg RetryConfig, op Operation[T]) Operation[T] {
+	retryIf := cfg.RetryIf
+	if retryIf == nil {
+		retryIf = IsRetryable
+	}
+	cfg.RetryIf = func(err error) bool {
+		return !errors.Is(err, ErrCircuitOpen) && retryIf(err)
+	}
+
+	return func(ctx context.Context) (T, error) {
+		return RetryWithResult(ctx, cfg, func() (T, error) {
+			return op(ctx)
+		})
+	}
+}
+
+// MetricsRecorder records custom metric events, e.g. for StartResilienceReporter and
+// db.StartStatsReporter. Implemented by telemetry.NewRelicClient.
+type MetricsRecorder interface {
+	RecordCustomEvent(eventType string, attributes map[string]interface{})
+}
+
+// StartResilienceReporter periodically exports the state of breakers and bulkheads to the
+// logger and, if recorder is non-nil, as "CircuitBreakerStats" and "BulkheadStats" custom
+// events. It runs in the background until ctx is cancelled.
+func StartResilienceReporter(ctx context.Context, interval time.Duration, logger logging.Logger, recorder MetricsRecorder, breakers []*CircuitBreaker, bulkheads []*Bulkhead) {
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				for _, cb := range breakers {
+					stats := cb.Stats()
+					attributes := map[string]interface{}{
+						"name":                 stats.Name,
+						"state":                stats.State.String(),
+						"requests":             stats.Requests,
+						"failures":             stats.Failures,
+						"consecutive_failures": stats.ConsecutiveFailures,
+						"rejected":             stats.Rejected,
+					}
+					report(logger, stats.State != CircuitClosed, "Circuit breaker is not closed", "Circuit breaker stats", attributes)
+					if recorder != nil {
+						recorder.RecordCustomEvent("CircuitBreakerStats", attributes)
+					}
+				}
+				for _, b := range bulkheads {
+					stats := b.Stats()
+					attributes := map[string]interface{}{
+						"name":           stats.Name,
+						"max_concurrent": stats.MaxConcurrent,
+						"in_flight":      stats.InFlight,
+						"rejected":       stats.Rejected,
+					}
+					report(logger, stats.InFlight >= stats.MaxConcurrent, "Bulkhead saturated", "Bulkhead stats", attributes)
+					if recorder != nil {
+						recorder.RecordCustomEvent("BulkheadStats", attributes)
+					}
+				}
+			}
+		}
+	}()
+}
+
+// report logs attributes as a warning if degraded and at debug level otherwise.
+func report(logger logging.Logger, degraded bool, warning, debug string, attributes map[string]interface{}) {
+	fields := make([]logging.Field, 0, len(attributes))
+	for key, value := range attributes {
+		fields = append(fields, logging.NewField(key, value))
+	}
+	if degraded {
+		logger.Warn(warning, fields...)
+		return
+	}
+	logger.Debug(debug, fields...)
+}
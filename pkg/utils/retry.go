@@ -2,9 +2,29 @@ package utils
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math"
+	"math/rand/v2"
 	"time"
+
+	apperrors "github.com/yourorg/go-service-kit/pkg/errors"
+)
+
+// JitterStrategy randomizes retry delays so that clients failing together do not retry
+// together.
+type JitterStrategy int
+
+const (
+	// JitterNone uses the exponential backoff delay as is.
+	JitterNone JitterStrategy = iota
+	// JitterFull waits a random time between zero and the backoff delay.
+	JitterFull
+	// JitterEqual waits half the backoff delay plus a random time up to the other half.
+	JitterEqual
+	// JitterDecorrelated waits a random time between InitialDelay and three times the
+	// previous delay, capped at MaxDelay; Multiplier is not used.
+	JitterDecorrelated
 )
 
 // RetryConfig configures retry behavior.
@@ -13,6 +33,14 @@ type RetryConfig struct {
 	InitialDelay time.Duration
 	MaxDelay     time.Duration
 	Multiplier   float64
+	// Jitter randomizes the delays (default JitterNone).
+	Jitter JitterStrategy
+	// RetryIf reports whether an error may be retried (default IsRetryable). Other errors
+	// are returned at once, without wrapping.
+	RetryIf func(err error) bool
+	// OnRetry is called before waiting for the next attempt, e.g. to log. attempt is the
+	// number of the attempt that failed, starting at 1.
+	OnRetry func(attempt int, err error, delay time.Duration)
 }
 
 // DefaultRetryConfig returns a default retry configuration.
@@ -22,88 +50,164 @@ func DefaultRetryConfig() RetryConfig {
 		InitialDelay: 100 * time.Millisecond,
 		MaxDelay:     5 * time.Second,
 		Multiplier:   2.0,
+		Jitter:       JitterFull,
 	}
 }
 
-// Retry executes a function with exponential backoff retry logic.
-func Retry(ctx context.Context, config RetryConfig, fn func() error) error {
-	var lastErr error
-	
-	for attempt := 0; attempt < config.MaxAttempts; attempt++ {
-		// Check context cancellation
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-		}
-		
-		// Execute the function
-		err := fn()
-		if err == nil {
-			return nil
-		}
-		
-		lastErr = err
-		
-		// Don't sleep after the last attempt
-		if attempt < config.MaxAttempts-1 {
-			delay := calculateDelay(config, attempt)
-			
-			select {
-			case <-ctx.Done():
-				return ctx.Err()
-			case <-time.After(delay):
-				// Continue to next attempt
-			}
+// RetryAfterError is implemented by errors that carry a hint from the server on when to
+// retry, such as an HTTP Retry-After header. Retry waits at least that long.
+type RetryAfterError interface {
+	error
+	RetryAfter() time.Duration
+}
+
+// retryAfterError attaches a retry hint to an error.
+type retryAfterError struct {
+	err   error
+	after time.Duration
+}
+
+func (e *retryAfterError) Error() string             { return e.err.Error() }
+func (e *retryAfterError) Unwrap() error             { return e.err }
+func (e *retryAfterError) RetryAfter() time.Duration { return e.after }
+
+// WithRetryAfter returns err with a hint to retry no sooner than after.
+func WithRetryAfter(err error, after time.Duration) error {
+	if err == nil {
+		return nil
+	}
+	return &retryAfterError{err: err, after: after}
+}
+
+// RetryAfter returns the retry hint carried by err, if any.
+func RetryAfter(err error) (time.Duration, bool) {
+	var hinted RetryAfterError
+	if errors.As(err, &hinted) && hinted.RetryAfter() > 0 {
+		return hinted.RetryAfter(), true
+	}
+	return 0, false
+}
+
+// IsRetryable is the default RetryConfig.RetryIf. It rejects cancellation, open circuit
+// breakers and AppErrors reporting a problem with the request itself (bad request,
+// validation, unauthorized, forbidden, not found, conflict), and accepts anything else.
+func IsRetryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, ErrCircuitOpen) {
+		return false
+	}
+	var appErr *apperrors.AppError
+	if errors.As(err, &appErr) {
+		switch appErr.Code {
+		case apperrors.ErrorCodeBadRequest, apperrors.ErrorCodeValidation, apperrors.ErrorCodeUnauthorized,
+			apperrors.ErrorCodeForbidden, apperrors.ErrorCodeNotFound, apperrors.ErrorCodeConflict:
+			return false
 		}
 	}
-	
-	return fmt.Errorf("max attempts (%d) reached, last error: %w", config.MaxAttempts, lastErr)
+	return true
+}
+
+// Retry executes a function with exponential backoff retry logic.
+func Retry(ctx context.Context, config RetryConfig, fn func() error) error {
+	_, err := RetryWithResult(ctx, config, func() (struct{}, error) {
+		return struct{}{}, fn()
+	})
+	return err
 }
 
 // calculateDelay calculates the delay for the given attempt using exponential backoff.
 func calculateDelay(config RetryConfig, attempt int) time.Duration {
 	delay := float64(config.InitialDelay) * math.Pow(config.Multiplier, float64(attempt))
-	
+
 	maxDelay := float64(config.MaxDelay)
 	if delay > maxDelay {
 		delay = maxDelay
 	}
-	
+
 	return time.Duration(delay)
 }
 
+// nextDelay returns the delay before the attempt after attempt, applying the jitter
+// strategy. previous is the delay before attempt.
+func nextDelay(config RetryConfig, attempt int, previous time.Duration) time.Duration {
+	switch config.Jitter {
+	case JitterFull:
+		return randomDuration(0, calculateDelay(config, attempt))
+	case JitterEqual:
+		delay := calculateDelay(config, attempt)
+		return delay/2 + randomDuration(0, delay-delay/2)
+	case JitterDecorrelated:
+		delay := randomDuration(config.InitialDelay, max(previous*3, config.InitialDelay))
+		if config.MaxDelay > 0 && delay > config.MaxDelay {
+			delay = config.MaxDelay
+		}
+		return delay
+	default:
+		return calculateDelay(config, attempt)
+	}
+}
+
+// randomDuration returns a random duration in [low, high].
+func randomDuration(low, high time.Duration) time.Duration {
+	if high <= low {
+		return low
+	}
+	return low + time.Duration(rand.Int64N(int64(high-low)+1))
+}
+
 // RetryWithResult executes a function that returns a result with exponential backoff retry logic.
+// It stops early if the next attempt could not start before the context deadline.
 func RetryWithResult[T any](ctx context.Context, config RetryConfig, fn func() (T, error)) (T, error) {
 	var zero T
 	var lastErr error
-	
+
+	retryIf := config.RetryIf
+	if retryIf == nil {
+		retryIf = IsRetryable
+	}
+
+	delay := config.InitialDelay
 	for attempt := 0; attempt < config.MaxAttempts; attempt++ {
 		select {
 		case <-ctx.Done():
 			return zero, ctx.Err()
 		default:
 		}
-		
+
 		result, err := fn()
 		if err == nil {
 			return result, nil
 		}
-		
+		if !retryIf(err) {
+			return zero, err
+		}
+
 		lastErr = err
-		
+
 		if attempt < config.MaxAttempts-1 {
-			delay := calculateDelay(config, attempt)
-			
+			delay = nextDelay(config, attempt, delay)
+			if hint, ok := RetryAfter(err); ok && hint > delay {
+				delay = hint
+			}
+
+			// Waiting past the deadline only delays the inevitable failure
+			if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+				return zero, fmt.Errorf("retry delay %s exceeds context deadline after %d attempts, last error: %w", delay, attempt+1, lastErr)
+			}
+
+			if config.OnRetry != nil {
+				config.OnRetry(attempt+1, err, delay)
+			}
+
+			timer := time.NewTimer(delay)
 			select {
 			case <-ctx.Done():
+				timer.Stop()
 				return zero, ctx.Err()
-			case <-time.After(delay):
+			case <-timer.C:
 				// Continue to next attempt
 			}
 		}
 	}
-	
+
 	return zero, fmt.Errorf("max attempts (%d) reached, last error: %w", config.MaxAttempts, lastErr)
 }
-
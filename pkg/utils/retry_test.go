@@ -0,0 +1,118 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	apperrors "github.com/yourorg/go-service-kit/pkg/errors"
+)
+
+// fastRetry retries quickly for tests.
+func fastRetry(attempts int) RetryConfig {
+	return RetryConfig{MaxAttempts: attempts, InitialDelay: time.Millisecond, MaxDelay: time.Millisecond, Multiplier: 2}
+}
+
+func TestRetry_RetryIf(t *testing.T) {
+	badRequest := apperrors.NewAppError(apperrors.ErrorCodeBadRequest, "invalid amount", http.StatusBadRequest)
+	unavailable := apperrors.NewAppError(apperrors.ErrorCodeServiceUnavailable, "busy", http.StatusServiceUnavailable)
+
+	tests := []struct {
+		name  string
+		err   error
+		calls int
+	}{
+		{"bad request", badRequest, 1},
+		{"wrapped validation", errors.Join(errors.New("parse"), apperrors.NewAppError(apperrors.ErrorCodeValidation, "missing id", http.StatusBadRequest)), 1},
+		{"circuit open", ErrCircuitOpen, 1},
+		{"service unavailable", unavailable, 3},
+		{"plain error", errUnavailable, 3},
+	}
+	for _, tt := range tests {
+		calls := 0
+		err := Retry(context.Background(), fastRetry(3), func() error {
+			calls++
+			return tt.err
+		})
+		if calls != tt.calls || !errors.Is(err, tt.err) {
+			t.Errorf("%s: expected %d calls, got %d (err %v)", tt.name, tt.calls, calls, err)
+		}
+	}
+
+	// A custom classifier replaces IsRetryable
+	calls := 0
+	cfg := fastRetry(3)
+	cfg.RetryIf = func(err error) bool { return false }
+	Retry(context.Background(), cfg, func() error { calls++; return errUnavailable })
+	if calls != 1 {
+		t.Errorf("Expected RetryIf to stop retries, got %d calls", calls)
+	}
+}
+
+func TestRetry_RetryAfterAndOnRetry(t *testing.T) {
+	cfg := fastRetry(3)
+	var delays []time.Duration
+	cfg.OnRetry = func(attempt int, err error, delay time.Duration) {
+		delays = append(delays, delay)
+	}
+
+	calls := 0
+	start := time.Now()
+	result, err := RetryWithResult(context.Background(), cfg, func() (string, error) {
+		if calls++; calls == 1 {
+			return "", WithRetryAfter(errUnavailable, 30*time.Millisecond)
+		}
+		return "ok", nil
+	})
+	if err != nil || result != "ok" {
+		t.Fatalf("Expected ok, got %q (err %v)", result, err)
+	}
+	if len(delays) != 1 || delays[0] != 30*time.Millisecond || time.Since(start) < 30*time.Millisecond {
+		t.Errorf("Expected to wait for the Retry-After hint, got delays %v", delays)
+	}
+	if after, ok := RetryAfter(WithRetryAfter(errUnavailable, time.Second)); !ok || after != time.Second {
+		t.Errorf("RetryAfter = %s, %v", after, ok)
+	}
+}
+
+func TestRetry_StopsBeforeDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	calls := 0
+	start := time.Now()
+	err := Retry(ctx, fastRetry(5), func() error {
+		calls++
+		return WithRetryAfter(errUnavailable, time.Minute)
+	})
+	if calls != 1 || !errors.Is(err, errUnavailable) {
+		t.Errorf("Expected to give up after 1 call, got %d (err %v)", calls, err)
+	}
+	if time.Since(start) > 40*time.Millisecond {
+		t.Error("Expected to return without waiting for the deadline")
+	}
+}
+
+func TestNextDelay_Jitter(t *testing.T) {
+	cfg := RetryConfig{InitialDelay: 100 * time.Millisecond, MaxDelay: time.Second, Multiplier: 2}
+	for i := 0; i < 100; i++ {
+		cfg.Jitter = JitterFull
+		if d := nextDelay(cfg, 2, 0); d < 0 || d > 400*time.Millisecond {
+			t.Fatalf("Full jitter delay %s out of range", d)
+		}
+		cfg.Jitter = JitterEqual
+		if d := nextDelay(cfg, 2, 0); d < 200*time.Millisecond || d > 400*time.Millisecond {
+			t.Fatalf("Equal jitter delay %s out of range", d)
+		}
+		cfg.Jitter = JitterDecorrelated
+		if d := nextDelay(cfg, 2, 500*time.Millisecond); d < 100*time.Millisecond || d > time.Second {
+			t.Fatalf("Decorrelated jitter delay %s out of range", d)
+		}
+	}
+	cfg.Jitter = JitterNone
+	if d := nextDelay(cfg, 2, 0); d != 400*time.Millisecond {
+		t.Errorf("Expected 400ms without jitter, got %s", d)
+	}
+}